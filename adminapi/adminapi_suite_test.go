@@ -0,0 +1,13 @@
+package adminapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAdminAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Admin API Suite")
+}
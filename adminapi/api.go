@@ -0,0 +1,310 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"github.com/tedsuo/ifrit"
+)
+
+const (
+	ModeGlobal = "global"
+	ModeLocal  = "local"
+
+	// TriggerTimeout is how long a sync or emit request waits for the watcher
+	// to pick it up.
+	TriggerTimeout = 5 * time.Second
+)
+
+const (
+	HTTPRoutesPath = "/v1/routes"
+	TCPRoutesPath  = "/v1/tcp_routes"
+	StatusPath     = "/v1/status"
+	SyncPath       = "/v1/sync"
+	EmitPath       = "/v1/emit"
+)
+
+// Status is the state of a running emitter.
+type Status struct {
+	Mode           string    `json:"mode"`
+	CellID         string    `json:"cell_id,omitempty"`
+	LockHeld       bool      `json:"lock_held"`
+	ConsulDownMode bool      `json:"consul_down_mode"`
+	LastSync       time.Time `json:"last_sync"`
+	LastEmit       time.Time `json:"last_emit"`
+	HTTPRoutes     int       `json:"http_routes"`
+	TCPRoutes      int       `json:"tcp_routes"`
+}
+
+// API keeps track of the routing tables and the state of the emitter, for its
+// Handler to serve them.
+type API struct {
+	logger      lager.Logger
+	clock       clock.Clock
+	cellID      string
+	emitMonitor *syncer.EmitMonitor
+
+	lock           sync.Mutex
+	natsTable      routingtable.NATSRoutingTable
+	tcpTable       routingtable.TCPRoutingTable
+	lastSync       time.Time
+	lockHeld       bool
+	consulDownMode bool
+}
+
+func NewAPI(logger lager.Logger, clock clock.Clock, cellID string, emitMonitor *syncer.EmitMonitor) *API {
+	return &API{
+		logger:      logger.Session("admin-api"),
+		clock:       clock,
+		cellID:      cellID,
+		emitMonitor: emitMonitor,
+	}
+}
+
+// NATSTable serves the routes of the table, and returns the table the emitter
+// has to swap for the API to know when it last synced.
+func (a *API) NATSTable(table routingtable.NATSRoutingTable) routingtable.NATSRoutingTable {
+	a.lock.Lock()
+	a.natsTable = table
+	a.lock.Unlock()
+
+	return &syncedNATSTable{NATSRoutingTable: table, synced: a.synced}
+}
+
+// TCPTable serves the routes of the table, and returns the table the emitter
+// has to swap for the API to know when it last synced.
+func (a *API) TCPTable(table routingtable.TCPRoutingTable) routingtable.TCPRoutingTable {
+	a.lock.Lock()
+	a.tcpTable = table
+	a.lock.Unlock()
+
+	return &syncedTCPTable{TCPRoutingTable: table, synced: a.synced}
+}
+
+func (a *API) synced() {
+	a.lock.Lock()
+	a.lastSync = a.clock.Now()
+	a.lock.Unlock()
+}
+
+type syncedNATSTable struct {
+	routingtable.NATSRoutingTable
+	synced func()
+}
+
+func (t *syncedNATSTable) Swap(newTable routingtable.NATSRoutingTable, domains models.DomainSet) routingtable.MessagesToEmit {
+	defer t.synced()
+	return t.NATSRoutingTable.Swap(newTable, domains)
+}
+
+type syncedTCPTable struct {
+	routingtable.TCPRoutingTable
+	synced func()
+}
+
+func (t *syncedTCPTable) Swap(newTable routingtable.TCPRoutingTable) event.RoutingEvents {
+	defer t.synced()
+	return t.TCPRoutingTable.Swap(newTable)
+}
+
+// LockHeld returns a runner that reports the lock as held while it runs. It
+// belongs right after the lock maintainer in an ordered group, which only
+// starts it once the lock is acquired.
+func (a *API) LockHeld() ifrit.Runner {
+	return &flagRunner{lock: &a.lock, flag: &a.lockHeld}
+}
+
+// ConsulDownMode returns a runner that reports consul down mode while it runs.
+// It belongs right after the consul down checker in an ordered group.
+func (a *API) ConsulDownMode() ifrit.Runner {
+	return &flagRunner{lock: &a.lock, flag: &a.consulDownMode}
+}
+
+type flagRunner struct {
+	lock *sync.Mutex
+	flag *bool
+}
+
+func (r *flagRunner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	r.set(true)
+	close(ready)
+	<-signals
+	r.set(false)
+	return nil
+}
+
+func (r *flagRunner) set(value bool) {
+	r.lock.Lock()
+	*r.flag = value
+	r.lock.Unlock()
+}
+
+func (a *API) tables() (routingtable.NATSRoutingTable, routingtable.TCPRoutingTable) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.natsTable, a.tcpTable
+}
+
+func (a *API) httpRoutes() []HTTPRoute {
+	natsTable, _ := a.tables()
+	if natsTable == nil {
+		return []HTTPRoute{}
+	}
+	return NewHTTPRoutes(natsTable.Snapshot())
+}
+
+func (a *API) tcpRoutes() []TCPRoute {
+	_, tcpTable := a.tables()
+	if tcpTable == nil {
+		return []TCPRoute{}
+	}
+	return NewTCPRoutes(tcpTable.Snapshot())
+}
+
+func (a *API) status() Status {
+	status := Status{
+		Mode:     ModeGlobal,
+		CellID:   a.cellID,
+		LastEmit: a.emitMonitor.LastEmit(),
+	}
+	natsTable, tcpTable := a.tables()
+	if natsTable != nil {
+		status.HTTPRoutes = natsTable.EntryCount()
+	}
+	if tcpTable != nil {
+		status.TCPRoutes = tcpTable.EntryCount()
+	}
+	if a.cellID != "" {
+		status.Mode = ModeLocal
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	status.LockHeld = a.lockHeld
+	status.ConsulDownMode = a.consulDownMode
+	status.LastSync = a.lastSync
+	return status
+}
+
+// Handler serves the routing tables and the status of the emitter, and
+// triggers syncs and emits through the events of its syncer:
+//
+//	GET  /v1/routes       the HTTP routes, optionally of a hostname or process_guid
+//	GET  /v1/tcp_routes   the TCP routes, optionally of a process_guid
+//	GET  /v1/status       the Status
+//	POST /v1/sync         starts a sync
+//	POST /v1/emit         emits every route
+func (a *API) Handler(syncEvents syncer.Events) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HTTPRoutesPath, method("GET", a.serveHTTPRoutes))
+	mux.HandleFunc(TCPRoutesPath, method("GET", a.serveTCPRoutes))
+	mux.HandleFunc(StatusPath, method("GET", a.serveStatus))
+	mux.HandleFunc(SyncPath, method("POST", a.trigger("sync", syncEvents.Sync)))
+	mux.HandleFunc(EmitPath, method("POST", a.trigger("emit", syncEvents.Emit)))
+	return mux
+}
+
+// ListenAddress returns the address to serve the API on: the address itself,
+// or the loopback address when it has no host, as in ":17019".
+func ListenAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// IsLoopback reports whether the address is only reachable from the local
+// host.
+func IsLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func method(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != name {
+			w.Header().Set("Allow", name)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (a *API) serveHTTPRoutes(w http.ResponseWriter, r *http.Request) {
+	hostname := r.URL.Query().Get("hostname")
+	processGUID := r.URL.Query().Get("process_guid")
+
+	routes := []HTTPRoute{}
+	for _, route := range a.httpRoutes() {
+		if hostname != "" && !route.HasHostname(hostname) {
+			continue
+		}
+		if processGUID != "" && route.ProcessGUID != processGUID {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	a.writeJSON(w, routes)
+}
+
+func (a *API) serveTCPRoutes(w http.ResponseWriter, r *http.Request) {
+	processGUID := r.URL.Query().Get("process_guid")
+
+	routes := []TCPRoute{}
+	for _, route := range a.tcpRoutes() {
+		if processGUID != "" && route.ProcessGUID != processGUID {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	a.writeJSON(w, routes)
+}
+
+func (a *API) serveStatus(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, a.status())
+}
+
+// trigger hands a request to the watcher, giving up after TriggerTimeout when
+// the watcher is busy or not running, as without the lock.
+func (a *API) trigger(name string, events chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timer := a.clock.NewTimer(TriggerTimeout)
+		defer timer.Stop()
+
+		select {
+		case events <- struct{}{}:
+			a.logger.Info("triggered", lager.Data{"trigger": name})
+			w.WriteHeader(http.StatusAccepted)
+		case <-timer.C():
+			a.logger.Info("trigger-timed-out", lager.Data{"trigger": name})
+			http.Error(w, name+" was not picked up in time", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+func (a *API) writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		a.logger.Error("failed-to-write-response", err)
+	}
+}
@@ -0,0 +1,232 @@
+package adminapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("API", func() {
+	var (
+		logger      *lagertest.TestLogger
+		clock       *fakeclock.FakeClock
+		emitMonitor *syncer.EmitMonitor
+		syncEvents  syncer.Events
+		cellID      string
+		api         *adminapi.API
+		natsTable   routingtable.NATSRoutingTable
+		server      *httptest.Server
+		client      *adminapi.Client
+	)
+
+	httpKey := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+	otherKey := endpoint.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+	tcpKey := endpoint.RoutingKey{ProcessGUID: "tcp-process-guid", ContainerPort: 5222}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		emitMonitor = syncer.NewEmitMonitor(clock, 0, false)
+		syncEvents = syncer.Events{Sync: make(chan struct{}), Emit: make(chan struct{})}
+		cellID = ""
+	})
+
+	JustBeforeEach(func() {
+		api = adminapi.NewAPI(logger, clock, cellID, emitMonitor)
+
+		natsTable = api.NATSTable(routingtable.NewNATSTable(logger))
+		natsTable.SetRoutes(httpKey, []routingtable.Route{{Hostname: "app.example.com"}, {Hostname: "app.example.com/api"}}, nil)
+		natsTable.AddEndpoint(httpKey, routingtable.Endpoint{InstanceGuid: "ig-1", Index: 1, Host: "10.0.0.1", Port: 61001, ContainerPort: 8080})
+		natsTable.SetRoutes(otherKey, []routingtable.Route{{Hostname: "other.example.com"}}, nil)
+		natsTable.AddEndpoint(otherKey, routingtable.Endpoint{InstanceGuid: "ig-2", Host: "10.0.0.2", Port: 61002, ContainerPort: 8080})
+
+		api.TCPTable(routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
+			tcpKey: {
+				ExternalEndpoints: endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 6000)},
+				Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+					endpoint.NewEndpointKey("ig-3", false): endpoint.NewEndpoint("ig-3", false, "10.0.0.3", 61003, 5222, nil),
+				},
+			},
+		}))
+
+		server = httptest.NewServer(api.Handler(syncEvents))
+		client = adminapi.NewClient(server.Listener.Addr().String(), http.DefaultClient)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("routes", func() {
+		It("returns every HTTP route", func() {
+			routes, err := client.HTTPRoutes("", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(HaveLen(2))
+		})
+
+		It("returns the HTTP routes of a hostname, whatever their path", func() {
+			routes, err := client.HTTPRoutes("APP.example.com", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(Equal([]adminapi.HTTPRoute{{
+				ProcessGUID:   "process-guid",
+				ContainerPort: 8080,
+				Routes:        []adminapi.Route{{Hostname: "app.example.com"}, {Hostname: "app.example.com/api"}},
+				Endpoints:     []adminapi.Endpoint{{InstanceGUID: "ig-1", Index: 1, Host: "10.0.0.1", Port: 61001}},
+			}}))
+		})
+
+		It("returns the HTTP routes of a process", func() {
+			routes, err := client.HTTPRoutes("", "other-process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(HaveLen(1))
+			Expect(routes[0].Routes).To(Equal([]adminapi.Route{{Hostname: "other.example.com"}}))
+		})
+
+		It("returns the TCP routes", func() {
+			routes, err := client.TCPRoutes("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(Equal([]adminapi.TCPRoute{{
+				ProcessGUID:       "tcp-process-guid",
+				ContainerPort:     5222,
+				ExternalEndpoints: []adminapi.ExternalEndpoint{{RouterGroupGUID: "router-group", Port: 6000}},
+				Endpoints:         []adminapi.TCPEndpoint{{InstanceGUID: "ig-3", Host: "10.0.0.3", Port: 61003}},
+			}}))
+
+			routes, err = client.TCPRoutes("process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(BeEmpty())
+		})
+	})
+
+	Describe("status", func() {
+		It("reports the mode and the size of the tables", func() {
+			status, err := client.Status()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Mode).To(Equal(adminapi.ModeGlobal))
+			Expect(status.HTTPRoutes).To(Equal(2))
+			Expect(status.TCPRoutes).To(Equal(1))
+			Expect(status.LastSync.IsZero()).To(BeTrue())
+			Expect(status.LastEmit.IsZero()).To(BeTrue())
+		})
+
+		It("does not count the routes without endpoints", func() {
+			natsTable.SetRoutes(endpoint.RoutingKey{ProcessGUID: "unrouted-process-guid", ContainerPort: 8080}, []routingtable.Route{{Hostname: "unrouted.example.com"}}, nil)
+
+			status, err := client.Status()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.HTTPRoutes).To(Equal(2))
+		})
+
+		It("reports when the tables were last synced and emitted", func() {
+			natsTable.Swap(routingtable.NewNATSTable(logger), models.NewDomainSet([]string{"cf-apps"}))
+			synced := clock.Now()
+			clock.Increment(time.Second)
+			emitMonitor.EmitCompleted(logger, emitMonitor.EmitStarted(), nil)
+
+			status, err := client.Status()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.LastSync.Equal(synced)).To(BeTrue())
+			Expect(status.LastEmit.Equal(clock.Now())).To(BeTrue())
+			Expect(status.HTTPRoutes).To(BeZero())
+		})
+
+		It("reports whether the lock is held and consul down mode", func() {
+			lockHeld := ifrit.Invoke(api.LockHeld())
+			status, err := client.Status()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.LockHeld).To(BeTrue())
+			Expect(status.ConsulDownMode).To(BeFalse())
+
+			lockHeld.Signal(os.Interrupt)
+			Eventually(lockHeld.Wait()).Should(Receive(BeNil()))
+			consulDownMode := ifrit.Invoke(api.ConsulDownMode())
+			defer consulDownMode.Signal(os.Interrupt)
+
+			status, err = client.Status()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.LockHeld).To(BeFalse())
+			Expect(status.ConsulDownMode).To(BeTrue())
+		})
+
+		Context("when the emitter runs on a cell", func() {
+			BeforeEach(func() {
+				cellID = "cell-id"
+			})
+
+			It("reports local mode", func() {
+				status, err := client.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Mode).To(Equal(adminapi.ModeLocal))
+				Expect(status.CellID).To(Equal("cell-id"))
+			})
+		})
+	})
+
+	Describe("triggers", func() {
+		It("starts a sync", func() {
+			errs := make(chan error, 1)
+			go func() { errs <- client.Sync() }()
+			Eventually(syncEvents.Sync).Should(Receive())
+			Eventually(errs).Should(Receive(BeNil()))
+		})
+
+		It("starts an emit", func() {
+			errs := make(chan error, 1)
+			go func() { errs <- client.Emit() }()
+			Eventually(syncEvents.Emit).Should(Receive())
+			Eventually(errs).Should(Receive(BeNil()))
+		})
+
+		It("gives up when nothing picks the trigger up", func() {
+			errs := make(chan error, 1)
+			go func() { errs <- client.Sync() }()
+			clock.WaitForWatcherAndIncrement(adminapi.TriggerTimeout)
+
+			var err error
+			Eventually(errs).Should(Receive(&err))
+			Expect(err).To(MatchError(ContainSubstring("503")))
+		})
+
+		It("only accepts POST requests", func() {
+			resp, err := http.Get(server.URL + adminapi.SyncPath)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
+
+var _ = Describe("ListenAddress", func() {
+	It("listens on the loopback address when the address has no host", func() {
+		Expect(adminapi.ListenAddress(":17019")).To(Equal("127.0.0.1:17019"))
+	})
+
+	It("keeps the host of the address", func() {
+		Expect(adminapi.ListenAddress("10.0.0.1:17019")).To(Equal("10.0.0.1:17019"))
+		Expect(adminapi.ListenAddress("[::1]:17019")).To(Equal("[::1]:17019"))
+	})
+})
+
+var _ = Describe("IsLoopback", func() {
+	It("reports whether the address is only reachable from the local host", func() {
+		Expect(adminapi.IsLoopback("127.0.0.1:17019")).To(BeTrue())
+		Expect(adminapi.IsLoopback("[::1]:17019")).To(BeTrue())
+		Expect(adminapi.IsLoopback("localhost:17019")).To(BeTrue())
+		Expect(adminapi.IsLoopback("0.0.0.0:17019")).To(BeFalse())
+		Expect(adminapi.IsLoopback("10.0.0.1:17019")).To(BeFalse())
+		Expect(adminapi.IsLoopback(":17019")).To(BeFalse())
+	})
+})
@@ -0,0 +1,99 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client talks to the admin API of a running emitter.
+type Client struct {
+	address    string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the admin API at the address, which defaults
+// to the http scheme.
+func NewClient(address string, httpClient *http.Client) *Client {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return &Client{
+		address:    strings.TrimSuffix(address, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// HTTPRoutes returns the HTTP routes of the hostname and of the process, or
+// all of them when both are empty.
+func (c *Client) HTTPRoutes(hostname, processGUID string) ([]HTTPRoute, error) {
+	query := url.Values{}
+	if hostname != "" {
+		query.Set("hostname", hostname)
+	}
+	if processGUID != "" {
+		query.Set("process_guid", processGUID)
+	}
+
+	routes := []HTTPRoute{}
+	err := c.do("GET", HTTPRoutesPath, query, &routes)
+	return routes, err
+}
+
+// TCPRoutes returns the TCP routes of the process, or all of them when it is
+// empty.
+func (c *Client) TCPRoutes(processGUID string) ([]TCPRoute, error) {
+	query := url.Values{}
+	if processGUID != "" {
+		query.Set("process_guid", processGUID)
+	}
+
+	routes := []TCPRoute{}
+	err := c.do("GET", TCPRoutesPath, query, &routes)
+	return routes, err
+}
+
+func (c *Client) Status() (Status, error) {
+	status := Status{}
+	err := c.do("GET", StatusPath, nil, &status)
+	return status, err
+}
+
+func (c *Client) Sync() error {
+	return c.do("POST", SyncPath, nil, nil)
+}
+
+func (c *Client) Emit() error {
+	return c.do("POST", EmitPath, nil, nil)
+}
+
+func (c *Client) do(method, path string, query url.Values, response interface{}) error {
+	requestURL := c.address + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
@@ -0,0 +1,222 @@
+package adminapi
+
+import (
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// HTTPRoute is an entry of the HTTP routing table: the routes to the
+// container port of a process, and the endpoints they route to.
+type HTTPRoute struct {
+	ProcessGUID   string     `json:"process_guid"`
+	ContainerPort uint32     `json:"container_port"`
+	Routes        []Route    `json:"routes"`
+	Endpoints     []Endpoint `json:"endpoints"`
+}
+
+type Route struct {
+	Hostname         string `json:"hostname"`
+	RouteServiceURL  string `json:"route_service_url,omitempty"`
+	IsolationSegment string `json:"isolation_segment,omitempty"`
+}
+
+type Endpoint struct {
+	InstanceGUID string `json:"instance_guid"`
+	Index        int32  `json:"index"`
+	Host         string `json:"host"`
+	Port         uint32 `json:"port"`
+	Evacuating   bool   `json:"evacuating,omitempty"`
+}
+
+// TCPRoute is an entry of the TCP routing table: the external ports routed to
+// the container port of a process, and the endpoints they route to.
+type TCPRoute struct {
+	ProcessGUID       string             `json:"process_guid"`
+	ContainerPort     uint32             `json:"container_port"`
+	ExternalEndpoints []ExternalEndpoint `json:"external_endpoints"`
+	Endpoints         []TCPEndpoint      `json:"endpoints"`
+}
+
+type ExternalEndpoint struct {
+	RouterGroupGUID string `json:"router_group_guid"`
+	Port            uint32 `json:"port"`
+}
+
+type TCPEndpoint struct {
+	InstanceGUID string `json:"instance_guid"`
+	Host         string `json:"host"`
+	Port         uint32 `json:"port"`
+	Evacuating   bool   `json:"evacuating,omitempty"`
+}
+
+func NewHTTPRoutes(entries []routingtable.NATSTableEntry) []HTTPRoute {
+	routes := make([]HTTPRoute, 0, len(entries))
+	for _, entry := range entries {
+		route := HTTPRoute{
+			ProcessGUID:   entry.Key.ProcessGUID,
+			ContainerPort: entry.Key.ContainerPort,
+			Routes:        make([]Route, 0, len(entry.Routes)),
+			Endpoints:     make([]Endpoint, 0, len(entry.Endpoints)),
+		}
+		for _, r := range entry.Routes {
+			route.Routes = append(route.Routes, Route{
+				Hostname:         r.Hostname,
+				RouteServiceURL:  r.RouteServiceUrl,
+				IsolationSegment: r.IsolationSegment,
+			})
+		}
+		for _, e := range entry.Endpoints {
+			route.Endpoints = append(route.Endpoints, Endpoint{
+				InstanceGUID: e.InstanceGuid,
+				Index:        e.Index,
+				Host:         e.Host,
+				Port:         e.Port,
+				Evacuating:   e.Evacuating,
+			})
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func NewTCPRoutes(entries []routingtable.TCPTableEntry) []TCPRoute {
+	routes := make([]TCPRoute, 0, len(entries))
+	for _, entry := range entries {
+		route := TCPRoute{
+			ProcessGUID:       entry.Key.ProcessGUID,
+			ContainerPort:     entry.Key.ContainerPort,
+			ExternalEndpoints: make([]ExternalEndpoint, 0, len(entry.ExternalEndpoints)),
+			Endpoints:         make([]TCPEndpoint, 0, len(entry.Endpoints)),
+		}
+		for _, e := range entry.ExternalEndpoints {
+			route.ExternalEndpoints = append(route.ExternalEndpoints, ExternalEndpoint{
+				RouterGroupGUID: e.RouterGroupGUID,
+				Port:            e.Port,
+			})
+		}
+		for _, e := range entry.Endpoints {
+			route.Endpoints = append(route.Endpoints, TCPEndpoint{
+				InstanceGUID: e.InstanceGUID,
+				Host:         e.Host,
+				Port:         e.Port,
+				Evacuating:   e.Evacuating,
+			})
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// HasHostname reports whether any of the routes is for the hostname, whatever
+// its path.
+func (r HTTPRoute) HasHostname(hostname string) bool {
+	for _, route := range r.Routes {
+		routeHostname := route.Hostname
+		if i := strings.Index(routeHostname, "/"); i >= 0 {
+			routeHostname = routeHostname[:i]
+		}
+		if strings.EqualFold(routeHostname, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registration is a route to a single endpoint, as registered with the
+// gorouters.
+type Registration struct {
+	ProcessGUID   string `json:"process_guid"`
+	ContainerPort uint32 `json:"container_port"`
+	Hostname      string `json:"hostname"`
+	InstanceGUID  string `json:"instance_guid"`
+	Host          string `json:"host"`
+	Port          uint32 `json:"port"`
+	Evacuating    bool   `json:"evacuating,omitempty"`
+}
+
+func (r HTTPRoute) Registrations() []Registration {
+	registrations := make([]Registration, 0, len(r.Routes)*len(r.Endpoints))
+	for _, route := range r.Routes {
+		for _, e := range r.Endpoints {
+			registrations = append(registrations, Registration{
+				ProcessGUID:   r.ProcessGUID,
+				ContainerPort: r.ContainerPort,
+				Hostname:      route.Hostname,
+				InstanceGUID:  e.InstanceGUID,
+				Host:          e.Host,
+				Port:          e.Port,
+				Evacuating:    e.Evacuating,
+			})
+		}
+	}
+	return registrations
+}
+
+// HTTPRoutesDiff lists the registrations that are Missing from a routing
+// table, and the Extra ones it has.
+type HTTPRoutesDiff struct {
+	Missing []Registration `json:"missing"`
+	Extra   []Registration `json:"extra"`
+}
+
+func (d HTTPRoutesDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0
+}
+
+// DiffHTTPRoutes compares the routes of a routing table with the routes it is
+// expected to have.
+func DiffHTTPRoutes(expected, actual []HTTPRoute) HTTPRoutesDiff {
+	expectedRegistrations := registrationSet(expected)
+	actualRegistrations := registrationSet(actual)
+
+	diff := HTTPRoutesDiff{
+		Missing: []Registration{},
+		Extra:   []Registration{},
+	}
+	for registration := range expectedRegistrations {
+		if !actualRegistrations[registration] {
+			diff.Missing = append(diff.Missing, registration)
+		}
+	}
+	for registration := range actualRegistrations {
+		if !expectedRegistrations[registration] {
+			diff.Extra = append(diff.Extra, registration)
+		}
+	}
+
+	sort.Sort(byHostname(diff.Missing))
+	sort.Sort(byHostname(diff.Extra))
+	return diff
+}
+
+func registrationSet(routes []HTTPRoute) map[Registration]bool {
+	set := map[Registration]bool{}
+	for _, route := range routes {
+		for _, registration := range route.Registrations() {
+			set[registration] = true
+		}
+	}
+	return set
+}
+
+type byHostname []Registration
+
+func (s byHostname) Len() int      { return len(s) }
+func (s byHostname) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byHostname) Less(i, j int) bool {
+	if s[i].Hostname != s[j].Hostname {
+		return s[i].Hostname < s[j].Hostname
+	}
+	if s[i].ProcessGUID != s[j].ProcessGUID {
+		return s[i].ProcessGUID < s[j].ProcessGUID
+	}
+	if s[i].ContainerPort != s[j].ContainerPort {
+		return s[i].ContainerPort < s[j].ContainerPort
+	}
+	if s[i].InstanceGUID != s[j].InstanceGUID {
+		return s[i].InstanceGUID < s[j].InstanceGUID
+	}
+	return !s[i].Evacuating && s[j].Evacuating
+}
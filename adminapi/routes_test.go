@@ -0,0 +1,52 @@
+package adminapi_test
+
+import (
+	"code.cloudfoundry.org/route-emitter/adminapi"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffHTTPRoutes", func() {
+	route := func(hostnames []string, endpoints ...adminapi.Endpoint) adminapi.HTTPRoute {
+		r := adminapi.HTTPRoute{ProcessGUID: "process-guid", ContainerPort: 8080, Endpoints: endpoints}
+		for _, hostname := range hostnames {
+			r.Routes = append(r.Routes, adminapi.Route{Hostname: hostname})
+		}
+		return r
+	}
+
+	endpoint1 := adminapi.Endpoint{InstanceGUID: "ig-1", Host: "10.0.0.1", Port: 61001}
+	endpoint2 := adminapi.Endpoint{InstanceGUID: "ig-2", Index: 1, Host: "10.0.0.2", Port: 61002}
+
+	registration := func(hostname string, e adminapi.Endpoint) adminapi.Registration {
+		return adminapi.Registration{
+			ProcessGUID:   "process-guid",
+			ContainerPort: 8080,
+			Hostname:      hostname,
+			InstanceGUID:  e.InstanceGUID,
+			Host:          e.Host,
+			Port:          e.Port,
+		}
+	}
+
+	It("is empty when the routes match", func() {
+		routes := []adminapi.HTTPRoute{route([]string{"a.example.com"}, endpoint1, endpoint2)}
+		Expect(adminapi.DiffHTTPRoutes(routes, routes).Empty()).To(BeTrue())
+	})
+
+	It("reports the missing and extra registrations", func() {
+		expected := []adminapi.HTTPRoute{route([]string{"a.example.com", "b.example.com"}, endpoint1, endpoint2)}
+		actual := []adminapi.HTTPRoute{route([]string{"a.example.com", "c.example.com"}, endpoint1)}
+
+		diff := adminapi.DiffHTTPRoutes(expected, actual)
+		Expect(diff.Missing).To(Equal([]adminapi.Registration{
+			registration("a.example.com", endpoint2),
+			registration("b.example.com", endpoint1),
+			registration("b.example.com", endpoint2),
+		}))
+		Expect(diff.Extra).To(Equal([]adminapi.Registration{
+			registration("c.example.com", endpoint1),
+		}))
+	})
+})
@@ -0,0 +1,228 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+const (
+	DefaultMaxSizeMB  = 100
+	DefaultMaxBackups = 5
+
+	ProtocolHTTP = "http"
+	ProtocolTCP  = "tcp"
+
+	ActionRegister   = "register"
+	ActionUnregister = "unregister"
+)
+
+var auditLogWriteFailures = metric.Counter("AuditLogWriteFailures")
+
+// Config configures the file the audit log is written to.
+type Config struct {
+	Path       string `json:"path,omitempty"`
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`
+	MaxBackups int    `json:"max_backups,omitempty"`
+}
+
+// Enabled reports whether the audit log is configured.
+func (c Config) Enabled() bool {
+	return c.Path != ""
+}
+
+// Record is a registration or unregistration of a backend for a route, and
+// why it was emitted. HTTP routes are hostnames, TCP routes are router group
+// guids and external ports.
+type Record struct {
+	Time            time.Time    `json:"time"`
+	Action          string       `json:"action"`
+	Protocol        string       `json:"protocol"`
+	Hostname        string       `json:"hostname,omitempty"`
+	RouterGroupGUID string       `json:"router_group_guid,omitempty"`
+	ExternalPort    uint32       `json:"external_port,omitempty"`
+	Backend         string       `json:"backend"`
+	ProcessGUID     string       `json:"process_guid,omitempty"`
+	InstanceGUID    string       `json:"instance_guid,omitempty"`
+	Index           string       `json:"index,omitempty"`
+	Reason          event.Reason `json:"reason,omitempty"`
+}
+
+// Log appends a Record for every registration and unregistration handed to
+// the NATS and routing API emitters to a file, one JSON encoded Record per
+// line, and rotates the file once it grows past the maximum size.
+type Log struct {
+	logger lager.Logger
+	clock  clock.Clock
+
+	lock sync.Mutex
+	file *rotatingFile
+}
+
+func NewLog(logger lager.Logger, clock clock.Clock, config Config) (*Log, error) {
+	maxSizeMB := config.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = DefaultMaxSizeMB
+	}
+	maxBackups := config.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	logger = logger.Session("audit-log")
+	file, err := openRotatingFile(logger, config.Path, int64(maxSizeMB)*1024*1024, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{
+		logger: logger,
+		clock:  clock,
+		file:   file,
+	}, nil
+}
+
+// NATSEmitter returns an emitter that records the registry messages before
+// handing them to next, which may be nil.
+func (l *Log) NATSEmitter(next emitter.NATSEmitter) emitter.NATSEmitter {
+	return &natsEmitter{log: l, next: next}
+}
+
+// RoutingAPIEmitter returns an emitter that records the routing events before
+// handing them to next, which may be nil.
+func (l *Log) RoutingAPIEmitter(next emitter.RoutingAPIEmitter) emitter.RoutingAPIEmitter {
+	return &routingAPIEmitter{log: l, next: next}
+}
+
+func (l *Log) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.file.Close()
+}
+
+func (l *Log) recordMessages(messagesToEmit routingtable.MessagesToEmit) {
+	records := []Record{}
+	add := func(action string, messages []routingtable.RegistryMessage) {
+		for _, message := range messages {
+			for _, uri := range message.URIs {
+				records = append(records, Record{
+					Action:       action,
+					Protocol:     ProtocolHTTP,
+					Hostname:     uri,
+					Backend:      fmt.Sprintf("%s:%d", message.Host, message.Port),
+					ProcessGUID:  message.ProcessGUID,
+					InstanceGUID: message.PrivateInstanceId,
+					Index:        message.PrivateInstanceIndex,
+					Reason:       message.Reason,
+				})
+			}
+		}
+	}
+	add(ActionRegister, messagesToEmit.RegistrationMessages)
+	add(ActionUnregister, messagesToEmit.UnregistrationMessages)
+
+	l.write(records)
+}
+
+func (l *Log) recordRoutingEvents(routingEvents event.RoutingEvents) {
+	records := []Record{}
+	for _, routingEvent := range routingEvents {
+		var action string
+		switch routingEvent.EventType {
+		case event.RouteRegistrationEvent:
+			action = ActionRegister
+		case event.RouteUnregistrationEvent:
+			action = ActionUnregister
+		default:
+			continue
+		}
+
+		for _, external := range routingEvent.Entry.ExternalEndpoints {
+			for _, e := range routingEvent.Entry.Endpoints {
+				var index string
+				if e.InstanceGUID != "" {
+					index = fmt.Sprintf("%d", e.Index)
+				}
+				records = append(records, Record{
+					Action:          action,
+					Protocol:        ProtocolTCP,
+					RouterGroupGUID: external.RouterGroupGUID,
+					ExternalPort:    external.Port,
+					Backend:         fmt.Sprintf("%s:%d", e.Host, e.Port),
+					ProcessGUID:     routingEvent.Key.ProcessGUID,
+					InstanceGUID:    e.InstanceGUID,
+					Index:           index,
+					Reason:          routingEvent.Reason,
+				})
+			}
+		}
+	}
+
+	l.write(records)
+}
+
+// write appends the records in a single write, for a rotation never to split
+// the records of an emit.
+func (l *Log) write(records []Record) {
+	if len(records) == 0 {
+		return
+	}
+
+	now := l.clock.Now()
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	for i := range records {
+		records[i].Time = now
+		err := encoder.Encode(records[i])
+		if err != nil {
+			l.logger.Error("failed-to-encode-record", err, lager.Data{"action": records[i].Action})
+			auditLogWriteFailures.Increment()
+			return
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, err := l.file.Write(buffer.Bytes())
+	if err != nil {
+		l.logger.Error("failed-to-write-records", err, lager.Data{"records": len(records)})
+		auditLogWriteFailures.Increment()
+	}
+}
+
+type natsEmitter struct {
+	log  *Log
+	next emitter.NATSEmitter
+}
+
+func (e *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	e.log.recordMessages(messagesToEmit)
+	if e.next == nil {
+		return nil
+	}
+	return e.next.Emit(messagesToEmit)
+}
+
+type routingAPIEmitter struct {
+	log  *Log
+	next emitter.RoutingAPIEmitter
+}
+
+func (e *routingAPIEmitter) Emit(routingEvents event.RoutingEvents) (int, int, error) {
+	e.log.recordRoutingEvents(routingEvents)
+	if e.next == nil {
+		return 0, 0, nil
+	}
+	return e.next.Emit(routingEvents)
+}
@@ -0,0 +1,238 @@
+package auditlog_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log", func() {
+	var (
+		logger   *lagertest.TestLogger
+		clock    *fakeclock.FakeClock
+		dir      string
+		path     string
+		config   auditlog.Config
+		auditLog *auditlog.Log
+	)
+
+	readRecords := func(path string) []auditlog.Record {
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		records := []auditlog.Record{}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record auditlog.Record
+			Expect(json.Unmarshal(scanner.Bytes(), &record)).To(Succeed())
+			records = append(records, record)
+		}
+		Expect(scanner.Err()).NotTo(HaveOccurred())
+		return records
+	}
+
+	message := func(hostname, host string, port uint32, reason event.Reason) routingtable.RegistryMessage {
+		return routingtable.RegistryMessage{
+			URIs:                 []string{hostname},
+			Host:                 host,
+			Port:                 port,
+			App:                  "log-guid",
+			PrivateInstanceId:    "ig-1",
+			PrivateInstanceIndex: "0",
+			ProcessGUID:          "process-guid",
+			Reason:               reason,
+		}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC))
+
+		var err error
+		dir, err = ioutil.TempDir("", "audit-log")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "audit.log")
+		config = auditlog.Config{Path: path}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		auditLog, err = auditlog.NewLog(logger, clock, config)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(auditLog.Close()).To(Succeed())
+		os.RemoveAll(dir)
+	})
+
+	It("records the registrations and unregistrations with their reason", func() {
+		err := auditLog.NATSEmitter(nil).Emit(routingtable.MessagesToEmit{
+			RegistrationMessages:   []routingtable.RegistryMessage{message("a.example.com", "1.1.1.1", 61000, event.ReasonActualChange)},
+			UnregistrationMessages: []routingtable.RegistryMessage{message("b.example.com", "1.1.1.1", 61000, event.ReasonDesiredChange)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		records := readRecords(path)
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Time.Equal(clock.Now())).To(BeTrue())
+		records[0].Time = time.Time{}
+		records[1].Time = time.Time{}
+		Expect(records).To(Equal([]auditlog.Record{
+			{
+				Action:       auditlog.ActionRegister,
+				Protocol:     auditlog.ProtocolHTTP,
+				Hostname:     "a.example.com",
+				Backend:      "1.1.1.1:61000",
+				ProcessGUID:  "process-guid",
+				InstanceGUID: "ig-1",
+				Index:        "0",
+				Reason:       event.ReasonActualChange,
+			},
+			{
+				Action:       auditlog.ActionUnregister,
+				Protocol:     auditlog.ProtocolHTTP,
+				Hostname:     "b.example.com",
+				Backend:      "1.1.1.1:61000",
+				ProcessGUID:  "process-guid",
+				InstanceGUID: "ig-1",
+				Index:        "0",
+				Reason:       event.ReasonDesiredChange,
+			},
+		}))
+	})
+
+	It("hands the messages to the next emitter", func() {
+		fakeNATSEmitter := &fakes.FakeNATSEmitter{}
+		messages := routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{message("a.example.com", "1.1.1.1", 61000, event.ReasonEmit)},
+		}
+		Expect(auditLog.NATSEmitter(fakeNATSEmitter).Emit(messages)).To(Succeed())
+		Expect(fakeNATSEmitter.EmitCallCount()).To(Equal(1))
+		Expect(fakeNATSEmitter.EmitArgsForCall(0)).To(Equal(messages))
+	})
+
+	It("records the routing events of the TCP routes", func() {
+		routingEvents := event.RoutingEvents{
+			{
+				EventType: event.RouteUnregistrationEvent,
+				Key:       endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 5222},
+				Entry: endpoint.RoutableEndpoints{
+					ExternalEndpoints: endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 5222)},
+					Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+						endpoint.EndpointKey{InstanceGUID: "ig-1"}: {InstanceGUID: "ig-1", Index: 1, Host: "1.1.1.1", Port: 61000},
+					},
+				},
+				Reason: event.ReasonSync,
+			},
+		}
+		fakeRoutingAPIEmitter := &fakes.FakeRoutingAPIEmitter{}
+		_, _, err := auditLog.RoutingAPIEmitter(fakeRoutingAPIEmitter).Emit(routingEvents)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
+
+		records := readRecords(path)
+		Expect(records).To(HaveLen(1))
+		records[0].Time = time.Time{}
+		Expect(records[0]).To(Equal(auditlog.Record{
+			Action:          auditlog.ActionUnregister,
+			Protocol:        auditlog.ProtocolTCP,
+			RouterGroupGUID: "router-group",
+			ExternalPort:    5222,
+			Backend:         "1.1.1.1:61000",
+			ProcessGUID:     "process-guid",
+			InstanceGUID:    "ig-1",
+			Index:           "1",
+			Reason:          event.ReasonSync,
+		}))
+	})
+
+	Context("when the file already exists", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(path, []byte("{\"action\":\"register\"}\n"), 0644)).To(Succeed())
+		})
+
+		It("appends to it", func() {
+			err := auditLog.NATSEmitter(nil).Emit(routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{message("a.example.com", "1.1.1.1", 61000, event.ReasonEmit)},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			records := readRecords(path)
+			Expect(records).To(HaveLen(2))
+			Expect(records[1].Hostname).To(Equal("a.example.com"))
+		})
+	})
+
+	Context("when the file grows past the maximum size", func() {
+		BeforeEach(func() {
+			config.MaxSizeMB = 1
+			config.MaxBackups = 2
+		})
+
+		emitMegabyte := func() {
+			messages := []routingtable.RegistryMessage{}
+			for i := 0; i < 1000; i++ {
+				messages = append(messages, message(fmt.Sprintf("app-%d.example.com", i), "1.1.1.1", 61000, event.ReasonEmit))
+			}
+			for written := 0; written < 1024*1024; written += 1000 * 200 {
+				err := auditLog.NATSEmitter(nil).Emit(routingtable.MessagesToEmit{RegistrationMessages: messages})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		It("rotates it and keeps the maximum number of backups", func() {
+			for i := 0; i < 4; i++ {
+				emitMegabyte()
+			}
+
+			Expect(path + ".1").To(BeAnExistingFile())
+			Expect(path + ".2").To(BeAnExistingFile())
+			Expect(path + ".3").NotTo(BeAnExistingFile())
+
+			for _, p := range []string{path, path + ".1", path + ".2"} {
+				info, err := os.Stat(p)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Size()).To(BeNumerically("<=", 1024*1024))
+				Expect(readRecords(p)).NotTo(BeEmpty())
+			}
+		})
+
+		Context("when the backups cannot be shifted", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(filepath.Join(path+".2", "in-the-way"), 0755)).To(Succeed())
+			})
+
+			It("keeps writing the records and logs the failure once", func() {
+				emitMegabyte()
+				emitMegabyte()
+
+				Expect(readRecords(path)).To(HaveLen(2 * 6 * 1000))
+
+				failures := 0
+				for _, message := range logger.LogMessages() {
+					if message == "test.audit-log.failed-to-rotate" {
+						failures++
+					}
+				}
+				Expect(failures).To(Equal(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,139 @@
+package auditlog
+
+import (
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// rotatingFile appends to the file at its path. Once the file would grow past
+// the maximum size, it is renamed to path.1, the older backups are shifted to
+// path.2 and on, and those past the maximum number of backups are removed.
+//
+// When the backups cannot be shifted, it keeps appending to the file and only
+// tries again once another maximum size is written, logging the failure once
+// until a rotation succeeds.
+type rotatingFile struct {
+	logger     lager.Logger
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file         *os.File
+	size         int64
+	rotateAt     int64
+	rotateFailed bool
+}
+
+func openRotatingFile(logger lager.Logger, path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		logger:     logger,
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		rotateAt:   maxSize,
+	}
+
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.file == nil {
+		err := f.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if f.size > 0 && f.size+int64(len(p)) > f.rotateAt {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate moves the file to the first backup and opens a new one. It only
+// fails when the file cannot be reopened, which the next write tries again.
+func (f *rotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return err
+	}
+
+	shiftErr := f.shiftBackups()
+
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	if shiftErr != nil {
+		f.rotateAt = f.size + f.maxSize
+		if !f.rotateFailed {
+			f.rotateFailed = true
+			f.logger.Error("failed-to-rotate", shiftErr, lager.Data{"path": f.path})
+		}
+		return nil
+	}
+
+	f.rotateAt = f.maxSize
+	if f.rotateFailed {
+		f.rotateFailed = false
+		f.logger.Info("rotated-after-failure", lager.Data{"path": f.path})
+	}
+	return nil
+}
+
+func (f *rotatingFile) shiftBackups() error {
+	err := os.Remove(f.backupPath(f.maxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		err = os.Rename(f.backupPath(i), f.backupPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(f.path, f.backupPath(1))
+}
+
+func (f *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", f.path, i)
+}
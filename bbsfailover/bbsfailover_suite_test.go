@@ -0,0 +1,13 @@
+package bbsfailover_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBBSFailover(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BBS Failover Suite")
+}
@@ -0,0 +1,272 @@
+package bbsfailover
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+// DefaultProbeInterval is how often the addresses that failed are probed
+// again.
+const DefaultProbeInterval = 30 * time.Second
+
+var ErrNoClients = errors.New("no bbs clients")
+
+// Client is a BBS client that fails over between the clients for several BBS
+// addresses. Every call goes to the address of the last call that succeeded,
+// so the route emitter sticks to a healthy BBS as long as it stays healthy.
+// When a call fails to reach the BBS, it is retried against the addresses
+// that have not failed yet, and only then against the ones that have.
+//
+// Errors returned by the BBS itself, such as a missing resource, are returned
+// right away: another BBS would give the same answer.
+//
+// Every call the route emitter makes fails over: the ones of the watcher, the
+// cell watcher and FetchNATSTable. A call the route emitter starts to make
+// must be added here, as the other calls go to the first address.
+//
+// Run probes the addresses that failed on a timer, so that they are tried
+// again before the ones that are still failing.
+type Client struct {
+	bbs.Client
+
+	logger        lager.Logger
+	clock         clock.Clock
+	probeInterval time.Duration
+	addresses     []string
+	clients       []bbs.Client
+
+	lock      sync.Mutex
+	preferred int
+	healthy   []bool
+}
+
+func NewClient(logger lager.Logger, clock clock.Clock, probeInterval time.Duration, addresses []string, clients []bbs.Client) (*Client, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoClients
+	}
+	if len(addresses) != len(clients) {
+		return nil, errors.New("bbs addresses and clients do not match")
+	}
+
+	healthy := make([]bool, len(clients))
+	for i := range healthy {
+		healthy[i] = true
+	}
+
+	return &Client{
+		Client:        clients[0],
+		logger:        logger.Session("bbs-failover"),
+		clock:         clock,
+		probeInterval: probeInterval,
+		addresses:     addresses,
+		clients:       clients,
+		healthy:       healthy,
+	}, nil
+}
+
+// Run probes the addresses that failed every probe interval, until it is
+// signalled.
+func (c *Client) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := c.clock.NewTicker(c.probeInterval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-ticker.C():
+			c.probe()
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// probe asks the BBS at each address that failed for its domains, the
+// cheapest call the route emitter makes, and marks the ones that answer as
+// healthy again. The preferred address is left alone.
+func (c *Client) probe() {
+	c.lock.Lock()
+	unhealthy := []int{}
+	for i, healthy := range c.healthy {
+		if !healthy {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+	c.lock.Unlock()
+
+	for _, i := range unhealthy {
+		_, err := c.clients[i].Domains(c.logger)
+		if unreachable(err) {
+			c.logger.Debug("probe-failed", lager.Data{"address": c.addresses[i], "error": err.Error()})
+			continue
+		}
+
+		c.logger.Info("address-recovered", lager.Data{"address": c.addresses[i]})
+		c.lock.Lock()
+		c.healthy[i] = true
+		c.lock.Unlock()
+	}
+}
+
+// PreferredAddress is the address of the last BBS that answered a call.
+func (c *Client) PreferredAddress() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.addresses[c.preferred]
+}
+
+func (c *Client) SubscribeToEvents(logger lager.Logger) (events.EventSource, error) {
+	var eventSource events.EventSource
+	err := c.do("subscribe-to-events", func(client bbs.Client) error {
+		var err error
+		eventSource, err = client.SubscribeToEvents(logger)
+		return err
+	})
+	return eventSource, err
+}
+
+func (c *Client) SubscribeToInstanceEvents(logger lager.Logger) (events.EventSource, error) {
+	var eventSource events.EventSource
+	err := c.do("subscribe-to-instance-events", func(client bbs.Client) error {
+		var err error
+		eventSource, err = client.SubscribeToInstanceEvents(logger)
+		return err
+	})
+	return eventSource, err
+}
+
+func (c *Client) Domains(logger lager.Logger) ([]string, error) {
+	var domains []string
+	err := c.do("domains", func(client bbs.Client) error {
+		var err error
+		domains, err = client.Domains(logger)
+		return err
+	})
+	return domains, err
+}
+
+func (c *Client) ActualLRPGroups(logger lager.Logger, filter models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+	var groups []*models.ActualLRPGroup
+	err := c.do("actual-lrp-groups", func(client bbs.Client) error {
+		var err error
+		groups, err = client.ActualLRPGroups(logger, filter)
+		return err
+	})
+	return groups, err
+}
+
+func (c *Client) ActualLRPs(logger lager.Logger, filter models.ActualLRPFilter) ([]*models.ActualLRP, error) {
+	var actualLRPs []*models.ActualLRP
+	err := c.do("actual-lrps", func(client bbs.Client) error {
+		var err error
+		actualLRPs, err = client.ActualLRPs(logger, filter)
+		return err
+	})
+	return actualLRPs, err
+}
+
+func (c *Client) ActualLRPGroupsByProcessGuid(logger lager.Logger, processGuid string) ([]*models.ActualLRPGroup, error) {
+	var groups []*models.ActualLRPGroup
+	err := c.do("actual-lrp-groups-by-process-guid", func(client bbs.Client) error {
+		var err error
+		groups, err = client.ActualLRPGroupsByProcessGuid(logger, processGuid)
+		return err
+	})
+	return groups, err
+}
+
+func (c *Client) Cells(logger lager.Logger) ([]*models.CellPresence, error) {
+	var cells []*models.CellPresence
+	err := c.do("cells", func(client bbs.Client) error {
+		var err error
+		cells, err = client.Cells(logger)
+		return err
+	})
+	return cells, err
+}
+
+func (c *Client) DesiredLRPSchedulingInfos(logger lager.Logger, filter models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+	var schedulingInfos []*models.DesiredLRPSchedulingInfo
+	err := c.do("desired-lrp-scheduling-infos", func(client bbs.Client) error {
+		var err error
+		schedulingInfos, err = client.DesiredLRPSchedulingInfos(logger, filter)
+		return err
+	})
+	return schedulingInfos, err
+}
+
+// do calls f with each client in turn until one of them reaches the BBS.
+func (c *Client) do(call string, f func(bbs.Client) error) error {
+	var err error
+	for _, i := range c.order() {
+		err = f(c.clients[i])
+		if !unreachable(err) {
+			c.succeeded(i)
+			return err
+		}
+
+		c.logger.Error("failed-to-reach-bbs", err, lager.Data{
+			"address": c.addresses[i],
+			"call":    call,
+		})
+		c.failed(i)
+	}
+	return err
+}
+
+// order returns the indexes of the clients in the order they should be
+// tried: the preferred client, then the healthy ones, then the rest.
+func (c *Client) order() []int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	order := make([]int, 0, len(c.clients))
+	order = append(order, c.preferred)
+	for _, healthy := range []bool{true, false} {
+		for i := range c.clients {
+			if i != c.preferred && c.healthy[i] == healthy {
+				order = append(order, i)
+			}
+		}
+	}
+	return order
+}
+
+func (c *Client) succeeded(i int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.preferred != i {
+		c.logger.Info("failed-over", lager.Data{
+			"from": c.addresses[c.preferred],
+			"to":   c.addresses[i],
+		})
+	}
+	c.preferred = i
+	c.healthy[i] = true
+}
+
+func (c *Client) failed(i int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.healthy[i] = false
+}
+
+// unreachable returns true if the error means that the BBS could not be
+// reached. The BBS reports its own errors as *models.Error.
+func unreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(*models.Error)
+	return !ok
+}
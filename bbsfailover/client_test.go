@@ -0,0 +1,222 @@
+package bbsfailover_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events/eventfakes"
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/bbsfailover"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		logger                 *lagertest.TestLogger
+		clock                  *fakeclock.FakeClock
+		fakeClient1            *fake_bbs.FakeClient
+		fakeClient2            *fake_bbs.FakeClient
+		fakeClient3            *fake_bbs.FakeClient
+		client                 *bbsfailover.Client
+		connectionRefusedError error
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeClient1 = new(fake_bbs.FakeClient)
+		fakeClient2 = new(fake_bbs.FakeClient)
+		fakeClient3 = new(fake_bbs.FakeClient)
+		connectionRefusedError = errors.New("connection refused")
+
+		var err error
+		client, err = bbsfailover.NewClient(
+			logger,
+			clock,
+			time.Minute,
+			[]string{"https://bbs-1:8889", "https://bbs-2:8889", "https://bbs-3:8889"},
+			[]bbs.Client{fakeClient1, fakeClient2, fakeClient3},
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails without clients", func() {
+		_, err := bbsfailover.NewClient(logger, clock, time.Minute, nil, nil)
+		Expect(err).To(Equal(bbsfailover.ErrNoClients))
+	})
+
+	It("calls the first address while it is healthy", func() {
+		fakeClient1.DomainsReturns([]string{"domain"}, nil)
+
+		domains, err := client.Domains(logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domains).To(Equal([]string{"domain"}))
+
+		Expect(fakeClient1.DomainsCallCount()).To(Equal(1))
+		Expect(fakeClient2.DomainsCallCount()).To(Equal(0))
+		Expect(client.PreferredAddress()).To(Equal("https://bbs-1:8889"))
+	})
+
+	Context("when the BBS cannot be reached", func() {
+		BeforeEach(func() {
+			fakeClient1.ActualLRPGroupsReturns(nil, connectionRefusedError)
+			fakeClient2.ActualLRPGroupsReturns([]*models.ActualLRPGroup{{}}, nil)
+		})
+
+		It("fails over to the next address", func() {
+			groups, err := client.ActualLRPGroups(logger, models.ActualLRPFilter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(HaveLen(1))
+
+			Expect(fakeClient1.ActualLRPGroupsCallCount()).To(Equal(1))
+			Expect(fakeClient2.ActualLRPGroupsCallCount()).To(Equal(1))
+			Expect(logger).To(gbytes.Say("failed-to-reach-bbs.*https://bbs-1:8889"))
+			Expect(logger).To(gbytes.Say("failed-over"))
+		})
+
+		It("sticks to the address that answered", func() {
+			_, err := client.ActualLRPGroups(logger, models.ActualLRPFilter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.PreferredAddress()).To(Equal("https://bbs-2:8889"))
+
+			fakeClient1.ActualLRPGroupsReturns(nil, nil)
+			_, err = client.ActualLRPGroups(logger, models.ActualLRPFilter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeClient1.ActualLRPGroupsCallCount()).To(Equal(1))
+			Expect(fakeClient2.ActualLRPGroupsCallCount()).To(Equal(2))
+		})
+
+		It("fails over the event subscription too", func() {
+			fakeClient1.SubscribeToEventsReturns(nil, connectionRefusedError)
+			eventSource := new(eventfakes.FakeEventSource)
+			fakeClient2.SubscribeToEventsReturns(eventSource, nil)
+
+			es, err := client.SubscribeToEvents(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(es).To(Equal(eventSource))
+		})
+
+		It("fails over the instance calls too", func() {
+			fakeClient1.SubscribeToInstanceEventsReturns(nil, connectionRefusedError)
+			eventSource := new(eventfakes.FakeEventSource)
+			fakeClient2.SubscribeToInstanceEventsReturns(eventSource, nil)
+			fakeClient2.ActualLRPsReturns([]*models.ActualLRP{{}}, nil)
+
+			es, err := client.SubscribeToInstanceEvents(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(es).To(Equal(eventSource))
+
+			actualLRPs, err := client.ActualLRPs(logger, models.ActualLRPFilter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualLRPs).To(HaveLen(1))
+		})
+
+		It("fails over the cell registry too", func() {
+			fakeClient1.CellsReturns(nil, connectionRefusedError)
+			fakeClient2.CellsReturns([]*models.CellPresence{{CellId: "cell-id"}}, nil)
+
+			cells, err := client.Cells(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cells).To(HaveLen(1))
+			Expect(fakeClient2.CellsCallCount()).To(Equal(1))
+		})
+
+		It("tries the addresses that failed last", func() {
+			_, err := client.ActualLRPGroups(logger, models.ActualLRPFilter{})
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeClient2.DesiredLRPSchedulingInfosReturns(nil, connectionRefusedError)
+			fakeClient1.DesiredLRPSchedulingInfosReturns(nil, connectionRefusedError)
+			fakeClient3.DesiredLRPSchedulingInfosStub = func(lager.Logger, models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+				Expect(fakeClient1.DesiredLRPSchedulingInfosCallCount()).To(Equal(0))
+				return nil, nil
+			}
+
+			_, err = client.DesiredLRPSchedulingInfos(logger, models.DesiredLRPFilter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.PreferredAddress()).To(Equal("https://bbs-3:8889"))
+		})
+
+		Context("at every address", func() {
+			BeforeEach(func() {
+				fakeClient2.ActualLRPGroupsReturns(nil, connectionRefusedError)
+				fakeClient3.ActualLRPGroupsReturns(nil, connectionRefusedError)
+			})
+
+			It("returns the last error", func() {
+				_, err := client.ActualLRPGroups(logger, models.ActualLRPFilter{})
+				Expect(err).To(Equal(connectionRefusedError))
+				Expect(fakeClient3.ActualLRPGroupsCallCount()).To(Equal(1))
+				Expect(client.PreferredAddress()).To(Equal("https://bbs-1:8889"))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		var process ifrit.Process
+
+		BeforeEach(func() {
+			fakeClient1.DomainsReturns(nil, connectionRefusedError)
+			fakeClient2.DomainsReturns(nil, connectionRefusedError)
+			fakeClient3.DomainsReturns(nil, nil)
+
+			_, err := client.Domains(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.PreferredAddress()).To(Equal("https://bbs-3:8889"))
+
+			process = ifrit.Invoke(client)
+		})
+
+		AfterEach(func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("probes the addresses that failed every probe interval", func() {
+			clock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(fakeClient1.DomainsCallCount).Should(Equal(2))
+			Eventually(fakeClient2.DomainsCallCount).Should(Equal(2))
+			Consistently(fakeClient3.DomainsCallCount).Should(Equal(1))
+		})
+
+		Context("when an address that failed recovers", func() {
+			BeforeEach(func() {
+				fakeClient2.DomainsReturns(nil, nil)
+			})
+
+			It("tries it before the ones that are still failing", func() {
+				clock.WaitForWatcherAndIncrement(time.Minute)
+				Eventually(logger).Should(gbytes.Say("address-recovered.*https://bbs-2:8889"))
+
+				fakeClient3.ActualLRPGroupsReturns(nil, connectionRefusedError)
+				_, err := client.ActualLRPGroups(logger, models.ActualLRPFilter{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeClient2.ActualLRPGroupsCallCount()).To(Equal(1))
+				Expect(fakeClient1.ActualLRPGroupsCallCount()).To(Equal(0))
+				Expect(client.PreferredAddress()).To(Equal("https://bbs-2:8889"))
+			})
+		})
+	})
+
+	Context("when the BBS returns an error", func() {
+		BeforeEach(func() {
+			fakeClient1.ActualLRPGroupsByProcessGuidReturns(nil, models.ErrResourceNotFound)
+		})
+
+		It("returns the error without failing over", func() {
+			_, err := client.ActualLRPGroupsByProcessGuid(logger, "process-guid")
+			Expect(err).To(Equal(models.ErrResourceNotFound))
+			Expect(fakeClient2.ActualLRPGroupsByProcessGuidCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1 @@
+package bbsfailover // import "code.cloudfoundry.org/route-emitter/bbsfailover"
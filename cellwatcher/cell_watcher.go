@@ -0,0 +1,139 @@
+package cellwatcher
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+var cellsLost = metric.Counter("RouteEmitterCellsLost")
+
+// Change reports a cell that left the BBS cell registry, or that came back
+// after leaving it. The cell is identified by its cell id, which is the
+// CellId of the actual LRPs running on it.
+type Change struct {
+	CellID string
+	Lost   bool
+}
+
+// CellWatcher polls the BBS cell registry and reports the cells that leave
+// it. A cell leaves the registry as soon as its presence expires, well before
+// the BBS has converged the actual LRPs that ran on it, so the routes to
+// those instances can be unregistered right away rather than after the BBS
+// or the routers catch up.
+type CellWatcher struct {
+	bbsClient    bbs.Client
+	clock        clock.Clock
+	pollInterval time.Duration
+	changes      chan Change
+
+	logger lager.Logger
+}
+
+func NewCellWatcher(bbsClient bbs.Client, clock clock.Clock, pollInterval time.Duration, logger lager.Logger) *CellWatcher {
+	return &CellWatcher{
+		bbsClient:    bbsClient,
+		clock:        clock,
+		pollInterval: pollInterval,
+		changes:      make(chan Change),
+		logger:       logger.Session("cell-watcher"),
+	}
+}
+
+// Changes returns the channel the changes to the cell registry are sent on.
+func (w *CellWatcher) Changes() <-chan Change {
+	return w.changes
+}
+
+func (w *CellWatcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	w.logger.Info("starting", lager.Data{"poll-interval": w.pollInterval.String()})
+	defer w.logger.Info("finished")
+
+	ticker := w.clock.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	// known holds every cell seen in the registry, lost every cell that left
+	// it and has not come back
+	var known map[string]struct{}
+	lost := map[string]struct{}{}
+
+	for {
+		select {
+		case <-ticker.C():
+			cells, err := w.pollCells()
+			if err != nil {
+				continue
+			}
+
+			var changes []Change
+			if known != nil {
+				changes = diff(known, lost, cells)
+			}
+			known = cells
+
+			for _, change := range changes {
+				if change.Lost {
+					w.logger.Info("cell-lost", lager.Data{"cell-id": change.CellID})
+					cellsLost.Increment()
+				} else {
+					w.logger.Info("cell-recovered", lager.Data{"cell-id": change.CellID})
+				}
+
+				select {
+				case w.changes <- change:
+				case <-signals:
+					return nil
+				}
+			}
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// pollCells returns the id of every cell in the registry.
+func (w *CellWatcher) pollCells() (map[string]struct{}, error) {
+	logger := w.logger.Session("poll-cells")
+
+	cellPresences, err := w.bbsClient.Cells(logger)
+	if err != nil {
+		logger.Error("failed-to-get-cells", err)
+		return nil, err
+	}
+
+	cells := make(map[string]struct{}, len(cellPresences))
+	for _, cellPresence := range cellPresences {
+		cells[cellPresence.CellId] = struct{}{}
+	}
+
+	return cells, nil
+}
+
+// diff returns the cells that left the registry since the last poll and the
+// lost cells that came back, and updates lost accordingly.
+func diff(known, lost, cells map[string]struct{}) []Change {
+	changes := []Change{}
+
+	for cellID := range known {
+		if _, ok := cells[cellID]; !ok {
+			lost[cellID] = struct{}{}
+			changes = append(changes, Change{CellID: cellID, Lost: true})
+		}
+	}
+
+	for cellID := range lost {
+		if _, ok := cells[cellID]; !ok {
+			continue
+		}
+		delete(lost, cellID)
+		changes = append(changes, Change{CellID: cellID})
+	}
+
+	return changes
+}
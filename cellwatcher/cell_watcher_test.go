@@ -0,0 +1,112 @@
+package cellwatcher_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cellwatcher"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("CellWatcher", func() {
+	const pollInterval = 10 * time.Second
+
+	var (
+		logger      *lagertest.TestLogger
+		bbsClient   *fake_bbs.FakeClient
+		clock       *fakeclock.FakeClock
+		cellWatcher *cellwatcher.CellWatcher
+		process     ifrit.Process
+
+		cell1, cell2 *models.CellPresence
+	)
+
+	pollReturning := func(cells []*models.CellPresence, err error) {
+		count := bbsClient.CellsCallCount()
+		bbsClient.CellsReturns(cells, err)
+		clock.WaitForWatcherAndIncrement(pollInterval)
+		Eventually(bbsClient.CellsCallCount).Should(Equal(count + 1))
+	}
+
+	poll := func(cells ...*models.CellPresence) {
+		pollReturning(cells, nil)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		bbsClient = new(fake_bbs.FakeClient)
+		clock = fakeclock.NewFakeClock(time.Now())
+
+		cell1 = &models.CellPresence{CellId: "cell-1", RepAddress: "http://10.0.0.1:1800"}
+		cell2 = &models.CellPresence{CellId: "cell-2", RepAddress: "http://10.0.0.2:1800"}
+
+		cellWatcher = cellwatcher.NewCellWatcher(bbsClient, clock, pollInterval, logger)
+		process = ifrit.Invoke(cellWatcher)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("does not report the cells of the first poll", func() {
+		poll(cell1, cell2)
+		Consistently(cellWatcher.Changes()).ShouldNot(Receive())
+	})
+
+	Context("when a cell leaves the registry", func() {
+		BeforeEach(func() {
+			poll(cell1, cell2)
+			poll(cell1)
+		})
+
+		It("reports the cell as lost", func() {
+			Eventually(cellWatcher.Changes()).Should(Receive(Equal(cellwatcher.Change{
+				CellID: "cell-2",
+				Lost:   true,
+			})))
+			Expect(logger).To(gbytes.Say("cell-lost"))
+		})
+
+		It("reports the cell only once", func() {
+			Eventually(cellWatcher.Changes()).Should(Receive())
+			poll(cell1)
+			Consistently(cellWatcher.Changes()).ShouldNot(Receive())
+		})
+
+		It("reports the cell as back once it is", func() {
+			Eventually(cellWatcher.Changes()).Should(Receive())
+			poll(cell1, cell2)
+			Eventually(cellWatcher.Changes()).Should(Receive(Equal(cellwatcher.Change{
+				CellID: "cell-2",
+			})))
+		})
+
+		It("does not report the cell as back when another cell registers with its address", func() {
+			Eventually(cellWatcher.Changes()).Should(Receive())
+			poll(cell1, &models.CellPresence{CellId: "cell-3", RepAddress: "http://10.0.0.2:1800"})
+			Consistently(cellWatcher.Changes()).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the cells cannot be fetched", func() {
+		BeforeEach(func() {
+			poll(cell1, cell2)
+			pollReturning(nil, errors.New("boom"))
+		})
+
+		It("does not report any cell as lost", func() {
+			Eventually(logger).Should(gbytes.Say("failed-to-get-cells"))
+			Consistently(cellWatcher.Changes()).ShouldNot(Receive())
+		})
+	})
+})
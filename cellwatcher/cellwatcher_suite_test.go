@@ -0,0 +1,13 @@
+package cellwatcher_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCellWatcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CellWatcher Suite")
+}
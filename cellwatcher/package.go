@@ -0,0 +1 @@
+package cellwatcher // import "code.cloudfoundry.org/route-emitter/cellwatcher"
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+	"code.cloudfoundry.org/route-emitter/watcher"
+)
+
+// diffWithBBS compares the HTTP routes of the emitter with the routes it
+// would build from the BBS during a sync. The emitter keeps handling events
+// in between, so changes made while it runs can show up as differences.
+func diffWithBBS(logger lager.Logger, cfg config.RouteEmitterConfig, client *adminapi.Client) (adminapi.HTTPRoutesDiff, error) {
+	bbsClient, err := newBBSClient(cfg)
+	if err != nil {
+		return adminapi.HTTPRoutesDiff{}, err
+	}
+
+	actual, err := client.HTTPRoutes("", "")
+	if err != nil {
+		return adminapi.HTTPRoutesDiff{}, err
+	}
+
+	expectedTable, err := watcher.FetchNATSTable(logger, bbsClient, cfg.CellID)
+	if err != nil {
+		return adminapi.HTTPRoutesDiff{}, err
+	}
+	expected := adminapi.NewHTTPRoutes(expectedTable.Snapshot())
+
+	return adminapi.DiffHTTPRoutes(expected, actual), nil
+}
+
+// newBBSClient returns a client of the first BBS address of the config.
+func newBBSClient(cfg config.RouteEmitterConfig) (bbs.Client, error) {
+	address := cfg.BBSAddress
+	if len(cfg.BBSAddresses) > 0 {
+		address = cfg.BBSAddresses[0]
+	}
+	if address == "" {
+		return nil, errors.New("the config has no BBS address")
+	}
+
+	bbsURL, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if bbsURL.Scheme != "https" {
+		return bbs.NewClient(address), nil
+	}
+
+	return bbs.NewSecureClient(
+		address,
+		cfg.BBSCACertFile,
+		cfg.BBSClientCertFile,
+		cfg.BBSClientKeyFile,
+		cfg.BBSClientSessionCacheSize,
+		cfg.BBSMaxIdleConnsPerHost,
+	)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+)
+
+var adminAddress = flag.String(
+	"adminAddress",
+	"",
+	"Address of the admin API of the route emitter, defaults to the admin_address of -config",
+)
+
+var adminCACertFile = flag.String(
+	"adminCACertFile",
+	"",
+	"Path to the CA certificate of the admin API, when it requires mutual TLS",
+)
+
+var adminClientCertFile = flag.String(
+	"adminClientCertFile",
+	"",
+	"Path to the client certificate for the admin API, when it requires mutual TLS",
+)
+
+var adminClientKeyFile = flag.String(
+	"adminClientKeyFile",
+	"",
+	"Path to the client key for the admin API, when it requires mutual TLS",
+)
+
+var configFilePath = flag.String(
+	"config",
+	"",
+	"Path to the JSON configuration file of the route emitter",
+)
+
+var timeout = flag.Duration(
+	"timeout",
+	30*time.Second,
+	"Timeout of the requests to the admin API",
+)
+
+const usage = `Usage: route-emitter-cli [flags] <command> [command flags]
+
+Commands:
+  routes [-hostname <hostname>] [-processGuid <guid>]  list the HTTP routes
+  tcp-routes [-processGuid <guid>]                     list the TCP routes
+  status                                               show the lock, mode and sync status
+  sync                                                 start a sync
+  emit                                                 emit every route
+  diff                                                 compare the HTTP routes with the BBS, with the BBS of -config
+
+Flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	logger := lager.NewLogger("route-emitter-cli")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.ERROR))
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var cfg config.RouteEmitterConfig
+	if *configFilePath != "" {
+		var err error
+		cfg, err = config.NewRouteEmitterConfig(*configFilePath)
+		if err != nil {
+			logger.Fatal("failed-to-parse-config", err, lager.Data{"path": *configFilePath})
+		}
+	}
+
+	address := *adminAddress
+	if address == "" {
+		address = adminapi.ListenAddress(cfg.AdminAddress)
+	}
+	if address == "" {
+		logger.Fatal("missing-admin-address", nil)
+	}
+
+	httpClient := &http.Client{Timeout: *timeout}
+	if *adminClientCertFile != "" {
+		tlsConfig, err := cfhttp.NewTLSConfig(*adminClientCertFile, *adminClientKeyFile, *adminCACertFile)
+		if err != nil {
+			logger.Fatal("failed-to-load-admin-tls-config", err)
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		if !strings.Contains(address, "://") {
+			address = "https://" + address
+		}
+	}
+	client := adminapi.NewClient(address, httpClient)
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "routes":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		hostname := flags.String("hostname", "", "Only list the routes of the hostname")
+		processGUID := flags.String("processGuid", "", "Only list the routes of the process")
+		flags.Parse(args)
+
+		routes, err := client.HTTPRoutes(*hostname, *processGUID)
+		if err != nil {
+			logger.Fatal("failed-to-get-routes", err)
+		}
+		printHTTPRoutes(routes)
+	case "tcp-routes":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		processGUID := flags.String("processGuid", "", "Only list the routes of the process")
+		flags.Parse(args)
+
+		routes, err := client.TCPRoutes(*processGUID)
+		if err != nil {
+			logger.Fatal("failed-to-get-tcp-routes", err)
+		}
+		printTCPRoutes(routes)
+	case "status":
+		status, err := client.Status()
+		if err != nil {
+			logger.Fatal("failed-to-get-status", err)
+		}
+		printStatus(status)
+	case "sync":
+		if err := client.Sync(); err != nil {
+			logger.Fatal("failed-to-sync", err)
+		}
+	case "emit":
+		if err := client.Emit(); err != nil {
+			logger.Fatal("failed-to-emit", err)
+		}
+	case "diff":
+		if *configFilePath == "" {
+			logger.Fatal("missing-config", nil)
+		}
+
+		diff, err := diffWithBBS(logger, cfg, client)
+		if err != nil {
+			logger.Fatal("failed-to-diff", err)
+		}
+		printDiff(diff)
+		if !diff.Empty() {
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func newTabWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+}
+
+func printHTTPRoutes(routes []adminapi.HTTPRoute) {
+	w := newTabWriter()
+	fmt.Fprintln(w, "HOSTNAME\tPROCESS GUID\tCONTAINER PORT\tINDEX\tINSTANCE GUID\tADDRESS\tROUTE SERVICE")
+	for _, route := range routes {
+		for _, r := range route.Routes {
+			for _, e := range route.Endpoints {
+				instanceGUID := e.InstanceGUID
+				if e.Evacuating {
+					instanceGUID += " (evacuating)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s:%d\t%s\n",
+					r.Hostname, route.ProcessGUID, route.ContainerPort, e.Index, instanceGUID, e.Host, e.Port, r.RouteServiceURL)
+			}
+		}
+	}
+	w.Flush()
+}
+
+func printTCPRoutes(routes []adminapi.TCPRoute) {
+	w := newTabWriter()
+	fmt.Fprintln(w, "ROUTER GROUP\tEXTERNAL PORT\tPROCESS GUID\tCONTAINER PORT\tINSTANCE GUID\tADDRESS")
+	for _, route := range routes {
+		for _, external := range route.ExternalEndpoints {
+			for _, e := range route.Endpoints {
+				instanceGUID := e.InstanceGUID
+				if e.Evacuating {
+					instanceGUID += " (evacuating)"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%s:%d\n",
+					external.RouterGroupGUID, external.Port, route.ProcessGUID, route.ContainerPort, instanceGUID, e.Host, e.Port)
+			}
+		}
+	}
+	w.Flush()
+}
+
+func printStatus(status adminapi.Status) {
+	w := newTabWriter()
+	fmt.Fprintf(w, "mode:\t%s\n", status.Mode)
+	if status.CellID != "" {
+		fmt.Fprintf(w, "cell id:\t%s\n", status.CellID)
+	} else {
+		fmt.Fprintf(w, "lock held:\t%t\n", status.LockHeld)
+		fmt.Fprintf(w, "consul down mode:\t%t\n", status.ConsulDownMode)
+	}
+	fmt.Fprintf(w, "last sync:\t%s\n", formatTime(status.LastSync))
+	fmt.Fprintf(w, "last emit:\t%s\n", formatTime(status.LastEmit))
+	fmt.Fprintf(w, "http routing keys:\t%d\n", status.HTTPRoutes)
+	fmt.Fprintf(w, "tcp routing keys:\t%d\n", status.TCPRoutes)
+	w.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), time.Since(t).Round(time.Second))
+}
+
+func printDiff(diff adminapi.HTTPRoutesDiff) {
+	w := newTabWriter()
+	fmt.Fprintln(w, "\tHOSTNAME\tPROCESS GUID\tCONTAINER PORT\tINSTANCE GUID\tADDRESS")
+	for _, r := range diff.Missing {
+		fmt.Fprintf(w, "missing\t%s\t%s\t%d\t%s\t%s:%d\n", r.Hostname, r.ProcessGUID, r.ContainerPort, r.InstanceGUID, r.Host, r.Port)
+	}
+	for _, r := range diff.Extra {
+		fmt.Fprintf(w, "extra\t%s\t%s\t%d\t%s\t%s:%d\n", r.Hostname, r.ProcessGUID, r.ContainerPort, r.InstanceGUID, r.Host, r.Port)
+	}
+	w.Flush()
+}
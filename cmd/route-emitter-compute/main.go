@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+)
+
+var tcpRouteTTL = flag.Duration(
+	"tcpRouteTTL",
+	2*time.Minute,
+	"TTL of the printed routing API mappings",
+)
+
+var localMode = flag.Bool(
+	"localMode",
+	false,
+	"Compute the routes as an emitter running on a cell would",
+)
+
+var logLevel = flag.String(
+	"logLevel",
+	"info",
+	"Log level of the route handlers: debug, info, error or fatal",
+)
+
+const usage = `Usage: route-emitter-compute [flags] <dump> [<later dump>]
+
+Prints the routes the route emitter builds from a BBS dump during a sync, or
+the registration and unregistration deltas between two dumps. A dump is a JSON
+object with the desired_lrp_scheduling_infos, actual_lrp_groups and domains of
+the BBS.
+
+Flags:
+`
+
+// Dump is the state of the BBS a sync reads.
+type Dump struct {
+	DesiredLRPSchedulingInfos []*models.DesiredLRPSchedulingInfo `json:"desired_lrp_scheduling_infos"`
+	ActualLRPGroups           []*models.ActualLRPGroup           `json:"actual_lrp_groups"`
+	Domains                   []string                           `json:"domains"`
+}
+
+// Output is the routes of a dump, or the routes to register and unregister to
+// go from the routes of one dump to those of the next.
+type Output struct {
+	RegistrationMessages     []routingtable.RegistryMessage `json:"registration_messages"`
+	UnregistrationMessages   []routingtable.RegistryMessage `json:"unregistration_messages"`
+	TCPRouteMappings         []apimodels.TcpRouteMapping    `json:"tcp_route_mappings"`
+	TCPRouteMappingDeletions []apimodels.TcpRouteMapping    `json:"tcp_route_mapping_deletions"`
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	logger := lager.NewLogger("route-emitter-compute")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, minLogLevel(*logLevel)))
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var dumps []Dump
+	for _, path := range flag.Args() {
+		dump, err := readDump(path)
+		if err != nil {
+			logger.Fatal("failed-to-read-dump", err, lager.Data{"path": path})
+		}
+		dumps = append(dumps, dump)
+	}
+
+	outputs := []Output{}
+	for _, dump := range dumps {
+		outputs = append(outputs, computeRoutes(logger, dump, int(tcpRouteTTL.Seconds()), *localMode))
+	}
+
+	output := outputs[0]
+	if len(outputs) == 2 {
+		output = diffRoutes(outputs[0], outputs[1])
+	}
+	output.sort()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		logger.Fatal("failed-to-print-output", err)
+	}
+}
+
+func readDump(path string) (Dump, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Dump{}, err
+	}
+	defer file.Close()
+
+	var dump Dump
+	err = json.NewDecoder(file).Decode(&dump)
+	return dump, err
+}
+
+func minLogLevel(level string) lager.LogLevel {
+	switch level {
+	case "debug":
+		return lager.DEBUG
+	case "error":
+		return lager.ERROR
+	case "fatal":
+		return lager.FATAL
+	default:
+		return lager.INFO
+	}
+}
+
+// computeRoutes syncs empty tables with the dump, the way the route emitter
+// does, and returns every route of the tables.
+func computeRoutes(logger lager.Logger, dump Dump, ttl int, localMode bool) Output {
+	natsTable := routingtable.NewNATSTable(logger)
+	tcpTable := routingtable.NewTCPTable(logger, nil)
+	natsHandler := routehandlers.NewNATSHandler(clock.NewClock(), natsTable, discardingNATSEmitter{}, nil, localMode)
+	routingAPIHandler := routehandlers.NewRoutingAPIHandler(tcpTable, discardingRoutingAPIEmitter{}, nil, localMode)
+
+	actuals := endpoint.RunningActualLRPRoutingInfos(dump.ActualLRPGroups)
+	domains := models.NewDomainSet(dump.Domains)
+	natsHandler.Sync(logger, dump.DesiredLRPSchedulingInfos, actuals, domains, nil)
+	routingAPIHandler.Sync(logger, dump.DesiredLRPSchedulingInfos, actuals, domains, nil)
+
+	mappings, _ := tcpTable.GetRoutingEvents().ToMappingRequests(logger, ttl)
+	return Output{
+		RegistrationMessages: natsTable.MessagesToEmit().RegistrationMessages,
+		TCPRouteMappings:     mappings,
+	}
+}
+
+type discardingNATSEmitter struct{}
+
+var _ emitter.NATSEmitter = discardingNATSEmitter{}
+
+func (discardingNATSEmitter) Emit(routingtable.MessagesToEmit) error {
+	return nil
+}
+
+type discardingRoutingAPIEmitter struct{}
+
+var _ emitter.RoutingAPIEmitter = discardingRoutingAPIEmitter{}
+
+func (discardingRoutingAPIEmitter) Emit(routingEvents event.RoutingEvents) (int, int, error) {
+	return 0, 0, nil
+}
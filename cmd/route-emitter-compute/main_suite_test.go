@@ -0,0 +1,29 @@
+package main_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+var computePath string
+
+func TestRouteEmitterCompute(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Route Emitter Compute Suite")
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	compute, err := gexec.Build("code.cloudfoundry.org/route-emitter/cmd/route-emitter-compute")
+	Expect(err).NotTo(HaveOccurred())
+	return []byte(compute)
+}, func(payload []byte) {
+	computePath = string(payload)
+})
+
+var _ = SynchronizedAfterSuite(func() {
+}, func() {
+	gexec.CleanupBuildArtifacts()
+})
@@ -0,0 +1,104 @@
+package main_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+type output struct {
+	RegistrationMessages   []routingtable.RegistryMessage `json:"registration_messages"`
+	UnregistrationMessages []routingtable.RegistryMessage `json:"unregistration_messages"`
+}
+
+var _ = Describe("route-emitter-compute", func() {
+	var dir string
+
+	desired := func() *models.DesiredLRPSchedulingInfo {
+		routes := cfroutes.CFRoutes{{Hostnames: []string{"app.example.com"}, Port: 8080}}.RoutingInfo()
+		return &models.DesiredLRPSchedulingInfo{
+			DesiredLRPKey: models.NewDesiredLRPKey("process-guid", "domain", "log-guid"),
+			Routes:        routes,
+			Instances:     2,
+		}
+	}
+
+	actual := func(index int32, host string, port uint32) *models.ActualLRPGroup {
+		return &models.ActualLRPGroup{Instance: &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", index, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("ig-"+host, "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo(host, "", models.NewPortMapping(port, 8080)),
+			State:                models.ActualLRPStateRunning,
+		}}
+	}
+
+	writeDump := func(name string, actuals ...*models.ActualLRPGroup) string {
+		contents, err := json.Marshal(map[string]interface{}{
+			"desired_lrp_scheduling_infos": []*models.DesiredLRPSchedulingInfo{desired()},
+			"actual_lrp_groups":            actuals,
+			"domains":                      []string{"domain"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, contents, 0644)).To(Succeed())
+		return path
+	}
+
+	compute := func(dumps ...string) output {
+		session, err := gexec.Start(exec.Command(computePath, dumps...), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		var o output
+		Expect(json.Unmarshal(session.Out.Contents(), &o)).To(Succeed())
+		return o
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "route-emitter-compute")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("prints every route of a dump", func() {
+		o := compute(writeDump("dump.json", actual(0, "10.0.0.1", 61001), actual(1, "10.0.0.2", 61002)))
+		Expect(o.RegistrationMessages).To(HaveLen(2))
+		Expect(o.RegistrationMessages[0].Host).To(Equal("10.0.0.1"))
+		Expect(o.RegistrationMessages[1].Host).To(Equal("10.0.0.2"))
+		Expect(o.UnregistrationMessages).To(BeEmpty())
+	})
+
+	It("prints only the routes that changed between two dumps", func() {
+		before := writeDump("before.json", actual(0, "10.0.0.1", 61001), actual(1, "10.0.0.2", 61002))
+		after := writeDump("after.json", actual(0, "10.0.0.1", 61001), actual(1, "10.0.0.3", 61003))
+
+		o := compute(before, after)
+		Expect(o.RegistrationMessages).To(HaveLen(1))
+		Expect(o.RegistrationMessages[0].Host).To(Equal("10.0.0.3"))
+		Expect(o.RegistrationMessages[0].URIs).To(Equal([]string{"app.example.com"}))
+		Expect(o.UnregistrationMessages).To(HaveLen(1))
+		Expect(o.UnregistrationMessages[0].Host).To(Equal("10.0.0.2"))
+	})
+
+	It("prints nothing for two identical dumps", func() {
+		dump := writeDump("dump.json", actual(0, "10.0.0.1", 61001))
+
+		o := compute(dump, dump)
+		Expect(o.RegistrationMessages).To(BeEmpty())
+		Expect(o.UnregistrationMessages).To(BeEmpty())
+	})
+})
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+)
+
+// diffRoutes returns the routes of to that are not in from, to register, and
+// those of from that are not in to, to unregister.
+func diffRoutes(from, to Output) Output {
+	return Output{
+		RegistrationMessages:     subtractMessages(to.RegistrationMessages, from.RegistrationMessages),
+		UnregistrationMessages:   subtractMessages(from.RegistrationMessages, to.RegistrationMessages),
+		TCPRouteMappings:         subtractMappings(to.TCPRouteMappings, from.TCPRouteMappings),
+		TCPRouteMappingDeletions: subtractMappings(from.TCPRouteMappings, to.TCPRouteMappings),
+	}
+}
+
+func subtractMessages(messages, other []routingtable.RegistryMessage) []routingtable.RegistryMessage {
+	seen := map[string]bool{}
+	for _, message := range other {
+		seen[jsonKey(message)] = true
+	}
+
+	difference := []routingtable.RegistryMessage{}
+	for _, message := range messages {
+		if !seen[jsonKey(message)] {
+			difference = append(difference, message)
+		}
+	}
+	return difference
+}
+
+func subtractMappings(mappings, other []apimodels.TcpRouteMapping) []apimodels.TcpRouteMapping {
+	seen := map[string]bool{}
+	for _, mapping := range other {
+		seen[jsonKey(mapping)] = true
+	}
+
+	difference := []apimodels.TcpRouteMapping{}
+	for _, mapping := range mappings {
+		if !seen[jsonKey(mapping)] {
+			difference = append(difference, mapping)
+		}
+	}
+	return difference
+}
+
+// jsonKey identifies a route by what the routers are sent for it. Registry
+// messages and route mappings always marshal.
+func jsonKey(v interface{}) string {
+	key, _ := json.Marshal(v)
+	return string(key)
+}
+
+// sort orders the output for two runs on the same dumps to print the same
+// JSON, and prints empty lists rather than null.
+func (o *Output) sort() {
+	if o.RegistrationMessages == nil {
+		o.RegistrationMessages = []routingtable.RegistryMessage{}
+	}
+	if o.UnregistrationMessages == nil {
+		o.UnregistrationMessages = []routingtable.RegistryMessage{}
+	}
+	if o.TCPRouteMappings == nil {
+		o.TCPRouteMappings = []apimodels.TcpRouteMapping{}
+	}
+	if o.TCPRouteMappingDeletions == nil {
+		o.TCPRouteMappingDeletions = []apimodels.TcpRouteMapping{}
+	}
+
+	sort.Sort(byURIs(o.RegistrationMessages))
+	sort.Sort(byURIs(o.UnregistrationMessages))
+	sort.Sort(byExternalPort(o.TCPRouteMappings))
+	sort.Sort(byExternalPort(o.TCPRouteMappingDeletions))
+}
+
+type byURIs []routingtable.RegistryMessage
+
+func (m byURIs) Len() int      { return len(m) }
+func (m byURIs) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m byURIs) Less(i, j int) bool {
+	iURIs, jURIs := strings.Join(m[i].URIs, ","), strings.Join(m[j].URIs, ",")
+	if iURIs != jURIs {
+		return iURIs < jURIs
+	}
+	if m[i].Host != m[j].Host {
+		return m[i].Host < m[j].Host
+	}
+	return m[i].Port < m[j].Port
+}
+
+type byExternalPort []apimodels.TcpRouteMapping
+
+func (m byExternalPort) Len() int      { return len(m) }
+func (m byExternalPort) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m byExternalPort) Less(i, j int) bool {
+	if m[i].RouterGroupGuid != m[j].RouterGroupGuid {
+		return m[i].RouterGroupGuid < m[j].RouterGroupGuid
+	}
+	if m[i].ExternalPort != m[j].ExternalPort {
+		return m[i].ExternalPort < m[j].ExternalPort
+	}
+	if m[i].HostIP != m[j].HostIP {
+		return m[i].HostIP < m[j].HostIP
+	}
+	return m[i].HostPort < m[j].HostPort
+}
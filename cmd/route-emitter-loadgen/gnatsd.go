@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const gnatsdStartTimeout = 5 * time.Second
+
+// startGnatsd starts a gnatsd on the port, as the gnatsdrunner of the tests
+// does, and waits for it to accept connections.
+func startGnatsd(port int) (*exec.Cmd, error) {
+	gnatsdPath, err := exec.LookPath("gnatsd")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(gnatsdPath, "-p", strconv.Itoa(port))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+	deadline := time.Now().Add(gnatsdStartTimeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			conn.Close()
+			return cmd, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	return nil, errors.New("gnatsd did not start in time")
+}
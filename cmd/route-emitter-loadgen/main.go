@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/workpool"
+	"github.com/nats-io/nats"
+)
+
+var natsAddress = flag.String(
+	"natsAddress",
+	"",
+	"Address of the NATS server to emit to, defaults to a gnatsd started on -natsPort",
+)
+
+var natsPort = flag.Int(
+	"natsPort",
+	4224,
+	"Port of the gnatsd started when no -natsAddress is given",
+)
+
+var apps = flag.Int(
+	"apps",
+	1000,
+	"Number of apps in the routing table",
+)
+
+var instances = flag.Int(
+	"instances",
+	2,
+	"Number of instances of each app",
+)
+
+var hostnames = flag.Int(
+	"hostnames",
+	2,
+	"Number of hostnames of the route of each app",
+)
+
+var churn = flag.Float64(
+	"churn",
+	10,
+	"Number of instances moved to another host per second",
+)
+
+var workers = flag.Int(
+	"workers",
+	20,
+	"Number of workers emitting to NATS, as route_emitting_workers",
+)
+
+var emitInterval = flag.Duration(
+	"emitInterval",
+	20*time.Second,
+	"Interval between the emits of every route",
+)
+
+var duration = flag.Duration(
+	"duration",
+	time.Minute,
+	"Duration of the run",
+)
+
+var settleTimeout = flag.Duration(
+	"settleTimeout",
+	10*time.Second,
+	"How long to wait for the last messages to arrive after the run",
+)
+
+// Results of a run. The latency of a message is the time from its publishing
+// by the emitter to its delivery to a subscriber on another connection, as a
+// router would receive it.
+type Results struct {
+	Apps      int     `json:"apps"`
+	Instances int     `json:"instances"`
+	Hostnames int     `json:"hostnames"`
+	Churn     float64 `json:"churn"`
+	Workers   int     `json:"workers"`
+	Duration  string  `json:"duration"`
+
+	// the number of registrations of an emit of every route
+	Routes                    int         `json:"routes"`
+	FullEmits                 Percentiles `json:"full_emits"`
+	FullEmitMessagesPerSecond float64     `json:"full_emit_messages_per_second"`
+	InstancesMoved            int         `json:"instances_moved"`
+	EmitErrors                int         `json:"emit_errors"`
+
+	MessagesPublished  int64       `json:"messages_published"`
+	MessagesReceived   int64       `json:"messages_received"`
+	MessagesLost       int64       `json:"messages_lost"`
+	MessagesPerSecond  float64     `json:"messages_per_second"`
+	Latency            Percentiles `json:"latency"`
+	SlowConsumerEvents int64       `json:"slow_consumer_events"`
+}
+
+func main() {
+	flag.Parse()
+
+	logger := lager.NewLogger("route-emitter-loadgen")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.INFO))
+
+	// interrupts end the run rather than the process, for gnatsd to be stopped
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	// fatal stops the gnatsd started for the run before exiting
+	stopGnatsd := func() {}
+	fatal := func(action string, err error, data ...lager.Data) {
+		stopGnatsd()
+		logger.Fatal(action, err, data...)
+	}
+
+	address := *natsAddress
+	if address == "" {
+		gnatsd, err := startGnatsd(*natsPort)
+		if err != nil {
+			logger.Fatal("failed-to-start-gnatsd", err, lager.Data{"port": *natsPort})
+		}
+		var stopOnce sync.Once
+		stopGnatsd = func() {
+			stopOnce.Do(func() {
+				gnatsd.Process.Kill()
+				gnatsd.Wait()
+			})
+		}
+		defer stopGnatsd()
+		address = fmt.Sprintf("nats://127.0.0.1:%d", *natsPort)
+	}
+
+	natsClient := diegonats.NewClient()
+	_, err := natsClient.Connect([]string{address})
+	if err != nil {
+		fatal("failed-to-connect-to-nats", err, lager.Data{"address": address})
+	}
+	defer natsClient.Close()
+	timingClient := newTimingClient(natsClient)
+
+	// the subscriber stands for a router, on a connection of its own
+	var slowConsumerEvents int64
+	options := nats.DefaultOptions
+	options.Servers = []string{address}
+	options.AsyncErrorCB = func(_ *nats.Conn, _ *nats.Subscription, err error) {
+		if err == nats.ErrSlowConsumer {
+			atomic.AddInt64(&slowConsumerEvents, 1)
+		}
+	}
+	routerConn, err := options.Connect()
+	if err != nil {
+		fatal("failed-to-connect-to-nats", err, lager.Data{"address": address})
+	}
+	defer routerConn.Close()
+	for _, subject := range []string{"router.register", "router.unregister"} {
+		_, err := routerConn.Subscribe(subject, timingClient.receive)
+		if err != nil {
+			fatal("failed-to-subscribe", err, lager.Data{"subject": subject})
+		}
+	}
+	err = routerConn.Flush()
+	if err != nil {
+		fatal("failed-to-subscribe", err)
+	}
+
+	workPool, err := workpool.NewWorkPool(*workers)
+	if err != nil {
+		fatal("failed-to-construct-nats-emitter-workpool", err, lager.Data{"num-workers": *workers})
+	}
+	natsEmitter := emitter.NewNATSEmitter(timingClient, workPool, logger)
+
+	logger.Info("building-routing-table", lager.Data{"apps": *apps, "instances": *instances, "hostnames": *hostnames})
+	table := newSyntheticTable(logger, *apps, *instances, *hostnames)
+
+	results := Results{
+		Apps:      *apps,
+		Instances: *instances,
+		Hostnames: *hostnames,
+		Churn:     *churn,
+		Workers:   *workers,
+	}
+
+	emit := func(messagesToEmit routingtable.MessagesToEmit) time.Duration {
+		started := time.Now()
+		err := natsEmitter.Emit(messagesToEmit)
+		if err != nil {
+			logger.Error("failed-to-emit", err)
+			results.EmitErrors++
+		}
+		return time.Since(started)
+	}
+
+	var fullEmits []time.Duration
+	fullEmitMessages := 0
+	emitAll := func() {
+		messagesToEmit := table.table.MessagesToEmit()
+		took := emit(messagesToEmit)
+		fullEmits = append(fullEmits, took)
+		fullEmitMessages += len(messagesToEmit.RegistrationMessages)
+		results.Routes = len(messagesToEmit.RegistrationMessages)
+		logger.Info("emitted-all-routes", lager.Data{"messages": len(messagesToEmit.RegistrationMessages), "duration": took.String()})
+	}
+
+	logger.Info("starting", lager.Data{"duration": duration.String(), "churn": *churn, "workers": *workers})
+	started := time.Now()
+	emitAll()
+
+	emitTicker := time.NewTicker(*emitInterval)
+	defer emitTicker.Stop()
+
+	var churnTicks <-chan time.Time
+	if *churn > 0 {
+		churnTicker := time.NewTicker(time.Duration(float64(time.Second) / *churn))
+		defer churnTicker.Stop()
+		churnTicks = churnTicker.C
+	}
+
+	done := time.After(*duration)
+run:
+	for {
+		select {
+		case <-emitTicker.C:
+			emitAll()
+		case <-churnTicks:
+			emit(table.replaceInstance())
+			results.InstancesMoved++
+		case <-done:
+			break run
+		case <-signals:
+			logger.Info("interrupted")
+			break run
+		}
+	}
+	elapsed := time.Since(started)
+
+	// the subscriber may still be catching up
+	settleDeadline := time.Now().Add(*settleTimeout)
+	for time.Now().Before(settleDeadline) {
+		published, received := timingClient.counts()
+		if received >= published {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	results.Duration = elapsed.String()
+	results.FullEmits = newPercentiles(fullEmits)
+	var fullEmitTime time.Duration
+	for _, took := range fullEmits {
+		fullEmitTime += took
+	}
+	if fullEmitTime > 0 {
+		results.FullEmitMessagesPerSecond = float64(fullEmitMessages) / fullEmitTime.Seconds()
+	}
+
+	results.MessagesPublished, results.MessagesReceived = timingClient.counts()
+	results.MessagesLost = results.MessagesPublished - results.MessagesReceived
+	results.MessagesPerSecond = float64(results.MessagesReceived) / elapsed.Seconds()
+	results.Latency = timingClient.latencyPercentiles()
+	results.SlowConsumerEvents = atomic.LoadInt64(&slowConsumerEvents)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		fatal("failed-to-print-results", err)
+	}
+}
@@ -0,0 +1 @@
+package main // import "code.cloudfoundry.org/route-emitter/cmd/route-emitter-loadgen"
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"github.com/nats-io/nats"
+)
+
+// sequenceField is added to the JSON payload of every published message, for
+// the subscriber to match it with its publishing.
+const sequenceField = "loadgen_sequence"
+
+// timingClient records when each message is published, for the latency of
+// the message to be known once it is received. Each message carries a
+// sequence number, so that a lost message does not throw off the latencies
+// of the identical messages after it.
+type timingClient struct {
+	diegonats.NATSClient
+
+	sequence uint64
+
+	lock      sync.Mutex
+	sent      map[uint64]time.Time
+	published int64
+	received  int64
+	latencies []time.Duration
+}
+
+func newTimingClient(client diegonats.NATSClient) *timingClient {
+	return &timingClient{
+		NATSClient: client,
+		sent:       map[uint64]time.Time{},
+	}
+}
+
+func (c *timingClient) Publish(subject string, data []byte) error {
+	sequence := atomic.AddUint64(&c.sequence, 1)
+	data = withSequence(data, sequence)
+
+	c.lock.Lock()
+	c.sent[sequence] = time.Now()
+	c.lock.Unlock()
+
+	err := c.NATSClient.Publish(subject, data)
+	if err != nil {
+		c.lock.Lock()
+		delete(c.sent, sequence)
+		c.lock.Unlock()
+		return err
+	}
+
+	atomic.AddInt64(&c.published, 1)
+	return nil
+}
+
+func (c *timingClient) receive(msg *nats.Msg) {
+	receivedAt := time.Now()
+
+	var payload struct {
+		Sequence uint64 `json:"loadgen_sequence"`
+	}
+	err := json.Unmarshal(msg.Data, &payload)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.received++
+	if err != nil {
+		return
+	}
+	sentAt, ok := c.sent[payload.Sequence]
+	if !ok {
+		return
+	}
+	c.latencies = append(c.latencies, receivedAt.Sub(sentAt))
+	delete(c.sent, payload.Sequence)
+}
+
+// withSequence adds the sequence number to a JSON object.
+func withSequence(data []byte, sequence uint64) []byte {
+	if len(data) < 2 || data[0] != '{' {
+		return data
+	}
+
+	sequenced := make([]byte, 0, len(data)+len(sequenceField)+24)
+	sequenced = append(sequenced, `{"`+sequenceField+`":`...)
+	sequenced = strconv.AppendUint(sequenced, sequence, 10)
+	if data[1] != '}' {
+		sequenced = append(sequenced, ',')
+	}
+	return append(sequenced, data[1:]...)
+}
+
+func (c *timingClient) counts() (published, received int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return atomic.LoadInt64(&c.published), c.received
+}
+
+func (c *timingClient) latencyPercentiles() Percentiles {
+	c.lock.Lock()
+	latencies := make([]time.Duration, len(c.latencies))
+	copy(latencies, c.latencies)
+	c.lock.Unlock()
+
+	return newPercentiles(latencies)
+}
+
+// Percentiles of a set of durations.
+type Percentiles struct {
+	Count int    `json:"count"`
+	P50   string `json:"p50"`
+	P90   string `json:"p90"`
+	P99   string `json:"p99"`
+	P999  string `json:"p99.9"`
+	Max   string `json:"max"`
+}
+
+func newPercentiles(durations []time.Duration) Percentiles {
+	sort.Sort(byDuration(durations))
+
+	percentile := func(p float64) string {
+		if len(durations) == 0 {
+			return "0s"
+		}
+		i := int(math.Ceil(p*float64(len(durations)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return durations[i].String()
+	}
+
+	return Percentiles{
+		Count: len(durations),
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+		P99:   percentile(0.99),
+		P999:  percentile(0.999),
+		Max:   percentile(1),
+	}
+}
+
+type byDuration []time.Duration
+
+func (d byDuration) Len() int           { return len(d) }
+func (d byDuration) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byDuration) Less(i, j int) bool { return d[i] < d[j] }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+const containerPort = 8080
+
+// syntheticTable is a routing table of apps with the same number of instances
+// and hostnames each, spread over hosts of up to 250 instances.
+type syntheticTable struct {
+	table     routingtable.NATSRoutingTable
+	endpoints [][]routingtable.Endpoint
+	rand      *rand.Rand
+
+	// the number of instances ever placed, for each to get its own guid and
+	// address
+	placed int
+}
+
+func newSyntheticTable(logger lager.Logger, apps, instances, hostnames int) *syntheticTable {
+	t := &syntheticTable{
+		table:     routingtable.NewNATSTable(logger),
+		endpoints: make([][]routingtable.Endpoint, apps),
+		rand:      rand.New(rand.NewSource(1)),
+	}
+
+	for app := 0; app < apps; app++ {
+		routes := make([]routingtable.Route, 0, hostnames)
+		for i := 0; i < hostnames; i++ {
+			routes = append(routes, routingtable.Route{
+				Hostname: fmt.Sprintf("app-%d-%d.loadgen.example.com", app, i),
+				LogGuid:  appGUID(app),
+			})
+		}
+		t.table.SetRoutes(routingKey(app), routes, nil)
+
+		t.endpoints[app] = make([]routingtable.Endpoint, instances)
+		for index := 0; index < instances; index++ {
+			t.endpoints[app][index] = t.place(app, index)
+			t.table.AddEndpoint(routingKey(app), t.endpoints[app][index])
+		}
+	}
+
+	return t
+}
+
+func appGUID(app int) string {
+	return fmt.Sprintf("app-%d", app)
+}
+
+func routingKey(app int) endpoint.RoutingKey {
+	return endpoint.RoutingKey{ProcessGUID: appGUID(app), ContainerPort: containerPort}
+}
+
+func (t *syntheticTable) place(app, index int) routingtable.Endpoint {
+	n := t.placed
+	t.placed++
+
+	return routingtable.Endpoint{
+		InstanceGuid:  fmt.Sprintf("%s-instance-%d", appGUID(app), n),
+		Index:         int32(index),
+		Host:          fmt.Sprintf("10.%d.%d.%d", (n/250/250)%250, (n/250)%250, n%250+1),
+		Port:          uint32(61000 + n%4000),
+		ContainerPort: containerPort,
+	}
+}
+
+// replaceInstance moves an instance picked at random to another host, as a
+// restart would, and returns what the emitter would emit for it.
+func (t *syntheticTable) replaceInstance() routingtable.MessagesToEmit {
+	if len(t.endpoints) == 0 || len(t.endpoints[0]) == 0 {
+		return routingtable.MessagesToEmit{}
+	}
+
+	app := t.rand.Intn(len(t.endpoints))
+	index := t.rand.Intn(len(t.endpoints[app]))
+
+	messages := t.table.RemoveEndpoint(routingKey(app), t.endpoints[app][index])
+	t.endpoints[app][index] = t.place(app, index)
+	return messages.Merge(t.table.AddEndpoint(routingKey(app), t.endpoints[app][index]))
+}
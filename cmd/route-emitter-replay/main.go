@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/recorder"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"github.com/tedsuo/ifrit"
+)
+
+var recordingPath = flag.String(
+	"recording",
+	"",
+	"Path to a recording made with record_events_path",
+)
+
+var emitAtEnd = flag.Bool(
+	"emitAtEnd",
+	true,
+	"Emit all routes once the recording has been replayed",
+)
+
+var tcpRouteTTL = flag.Duration(
+	"tcpRouteTTL",
+	2*time.Minute,
+	"TTL of the printed routing API mappings",
+)
+
+var logLevel = flag.String(
+	"logLevel",
+	"info",
+	"Log level of the replayed route emitter: debug, info, error or fatal",
+)
+
+func main() {
+	flag.Parse()
+
+	logger := lager.NewLogger("route-emitter-replay")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, minLogLevel(*logLevel)))
+
+	if *recordingPath == "" {
+		logger.Fatal("missing-recording", nil)
+	}
+
+	file, err := os.Open(*recordingPath)
+	if err != nil {
+		logger.Fatal("failed-to-open-recording", err, lager.Data{"path": *recordingPath})
+	}
+
+	entries, err := recorder.ReadEntries(file)
+	file.Close()
+	if err != nil {
+		logger.Fatal("failed-to-read-recording", err, lager.Data{"path": *recordingPath})
+	}
+
+	replayer, err := recorder.NewReplayer(logger, entries)
+	if err != nil {
+		logger.Fatal("failed-to-create-replayer", err)
+	}
+
+	printer := &printer{
+		clock:   replayer.Clock(),
+		encoder: json.NewEncoder(os.Stdout),
+		logger:  logger,
+		ttl:     int(tcpRouteTTL.Seconds()),
+	}
+
+	localMode := replayer.CellID() != ""
+	natsHandler := routehandlers.NewNATSHandler(replayer.Clock(), routingtable.NewNATSTable(logger), &natsPrinter{printer}, nil, localMode)
+	routingAPIHandler := routehandlers.NewRoutingAPIHandler(routingtable.NewTCPTable(logger, nil), &routingAPIPrinter{printer}, nil, localMode)
+	handler := routehandlers.NewMultiHandler(replayer.Clock(), 0, logger,
+		routehandlers.NamedHandler{Name: "NATSHandler", Handler: natsHandler},
+		routehandlers.NamedHandler{Name: "RoutingAPIHandler", Handler: routingAPIHandler},
+	)
+	defer handler.Stop()
+
+	logger.Info("replaying", lager.Data{"path": *recordingPath, "entries": len(entries), "cell-id": replayer.CellID()})
+	process := ifrit.Invoke(replayer.NewWatcher(handler, logger))
+
+	err = replayer.Replay()
+	process.Signal(os.Interrupt)
+	<-process.Wait()
+	if err != nil {
+		logger.Fatal("failed-to-replay", err)
+	}
+
+	if *emitAtEnd {
+		handler.Emit(logger.Session("emit"))
+	}
+
+	logger.Info("finished")
+}
+
+func minLogLevel(level string) lager.LogLevel {
+	switch level {
+	case "debug":
+		return lager.DEBUG
+	case "error":
+		return lager.ERROR
+	case "fatal":
+		return lager.FATAL
+	default:
+		return lager.INFO
+	}
+}
+
+// printer writes what the route emitter would have sent to NATS and the
+// routing API as JSON lines, stamped with the replayed time.
+type printer struct {
+	clock  clock.Clock
+	logger lager.Logger
+	ttl    int
+
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+type printedMessage struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Subject   string      `json:"subject"`
+	Message   interface{} `json:"message"`
+}
+
+func (p *printer) print(subject string, message interface{}) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.encoder.Encode(printedMessage{
+		Timestamp: p.clock.Now(),
+		Subject:   subject,
+		Message:   message,
+	})
+}
+
+type natsPrinter struct {
+	*printer
+}
+
+var _ emitter.NATSEmitter = new(natsPrinter)
+
+func (p *natsPrinter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	for _, message := range messagesToEmit.RegistrationMessages {
+		if err := p.print("router.register", message); err != nil {
+			return err
+		}
+	}
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		if err := p.print("router.unregister", message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type routingAPIPrinter struct {
+	*printer
+}
+
+var _ emitter.RoutingAPIEmitter = new(routingAPIPrinter)
+
+func (p *routingAPIPrinter) Emit(routingEvents event.RoutingEvents) (int, int, error) {
+	registrations, unregistrations := routingEvents.ToMappingRequests(p.logger, p.ttl)
+	for _, mapping := range registrations {
+		if err := p.print("routing-api.upsert", mapping); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, mapping := range unregistrations {
+		if err := p.print("routing-api.delete", mapping); err != nil {
+			return 0, 0, err
+		}
+	}
+	return len(registrations), len(unregistrations), nil
+}
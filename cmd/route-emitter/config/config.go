@@ -9,6 +9,17 @@ import (
 	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/lager/lagerflags"
 	"code.cloudfoundry.org/locket"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+	"code.cloudfoundry.org/route-emitter/consulcatalog"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
+	"code.cloudfoundry.org/route-emitter/renderer"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routeplugins"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"code.cloudfoundry.org/route-emitter/verifier"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/webhook"
+	"code.cloudfoundry.org/route-emitter/xds/xdsconfig"
 )
 
 type RoutingAPIConfig struct {
@@ -26,49 +37,85 @@ type OAuthConfig struct {
 }
 
 type RouteEmitterConfig struct {
+	ActualLRPEventFamily               watcher.EventFamily   `json:"actual_lrp_event_family,omitempty"`
 	BBSAddress                         string                `json:"bbs_address"`
+	BBSAddresses                       []string              `json:"bbs_addresses,omitempty"`
 	BBSCACertFile                      string                `json:"bbs_ca_cert_file"`
 	BBSClientCertFile                  string                `json:"bbs_client_cert_file"`
 	BBSClientKeyFile                   string                `json:"bbs_client_key_file"`
 	BBSClientSessionCacheSize          int                   `json:"bbs_client_session_cache_size,omitempty"`
 	BBSMaxIdleConnsPerHost             int                   `json:"bbs_max_idle_conns_per_host,omitempty"`
 	CellID                             string                `json:"cell_id,omitempty"`
+	CellLivenessPollInterval           durationjson.Duration `json:"cell_liveness_poll_interval,omitempty"`
 	CommunicationTimeout               durationjson.Duration `json:"communication_timeout,omitempty"`
 	ConsulCluster                      string                `json:"consul_cluster,omitempty"`
 	ConsulDownModeNotificationInterval durationjson.Duration `json:"consul_down_mode_notification_interval,omitempty"`
 	ConsulSessionName                  string                `json:"consul_session_name,omitempty"`
 	DropsondePort                      int                   `json:"dropsonde_port,omitempty"`
+	EmitLagWarningRatio                float64               `json:"emit_lag_warning_ratio,omitempty"`
+	EventStreamStallTimeout            durationjson.Duration `json:"event_stream_stall_timeout,omitempty"`
+	FallbackEmitInterval               durationjson.Duration `json:"fallback_emit_interval,omitempty"`
+	HandlerTimeout                     durationjson.Duration `json:"handler_timeout,omitempty"`
 	HealthCheckAddress                 string                `json:"healthcheck_address,omitempty"`
 	LockRetryInterval                  durationjson.Duration `json:"lock_retry_interval,omitempty"`
 	LockTTL                            durationjson.Duration `json:"lock_ttl,omitempty"`
+	MaxCachedEvents                    int                   `json:"max_cached_events,omitempty"`
 	NATSAddresses                      string                `json:"nats_addresses,omitempty"`
 	NATSUsername                       string                `json:"nats_username,omitempty"`
 	NATSPassword                       string                `json:"nats_password,omitempty"`
+	RecordEventsPath                   string                `json:"record_events_path,omitempty"`
 	RouteEmittingWorkers               int                   `json:"route_emitting_workers,omitempty"`
+	RouterExpiry                       durationjson.Duration `json:"router_expiry,omitempty"`
+	RouterGreetingTimeout              durationjson.Duration `json:"router_greeting_timeout,omitempty"`
+	RouterStartDebounce                durationjson.Duration `json:"router_start_debounce,omitempty"`
+	ShedLoadOnEmitLag                  bool                  `json:"shed_load_on_emit_lag,omitempty"`
 	SyncInterval                       durationjson.Duration `json:"sync_interval,omitempty"`
+	SyncBatchSize                      int                   `json:"sync_batch_size,omitempty"`
+	SuspectActualLRPRouting            watcher.SuspectPolicy `json:"suspect_actual_lrp_routing,omitempty"`
 	TCPRouteTTL                        durationjson.Duration `json:"tcp_route_ttl,omitempty"`
 	OAuth                              OAuthConfig           `json:"oauth"`
+	Plugins                            []routeplugins.Config `json:"plugins,omitempty"`
 	RoutingAPI                         RoutingAPIConfig      `json:"routing_api"`
+	EnableHTTPEmitter                  bool                  `json:"enable_http_emitter"`
 	EnableTCPEmitter                   bool                  `json:"enable_tcp_emitter"`
+	Webhook                            webhook.Config        `json:"webhook"`
+	XDS                                xdsconfig.Config      `json:"xds"`
+	Renderer                           renderer.Config       `json:"renderer"`
+	DNS                                dnsserver.Config      `json:"dns"`
+	ConsulCatalog                      consulcatalog.Config  `json:"consul_catalog"`
+	AdminAddress                       string                `json:"admin_address,omitempty"`
+	AdminCACertFile                    string                `json:"admin_ca_cert_file,omitempty"`
+	AdminServerCertFile                string                `json:"admin_server_cert_file,omitempty"`
+	AdminServerKeyFile                 string                `json:"admin_server_key_file,omitempty"`
+	Verifier                           verifier.Config       `json:"verifier"`
+	AuditLog                           auditlog.Config       `json:"audit_log"`
 	lagerflags.LagerConfig
 	debugserver.DebugServerConfig
 }
 
 func DefaultRouteEmitterConfig() RouteEmitterConfig {
 	return RouteEmitterConfig{
+		ActualLRPEventFamily:               watcher.GroupEvents,
 		CommunicationTimeout:               durationjson.Duration(30 * time.Second),
 		ConsulDownModeNotificationInterval: durationjson.Duration(time.Minute),
 		ConsulSessionName:                  "route-emitter",
 		DropsondePort:                      3457,
+		EmitLagWarningRatio:                syncer.DefaultEmitLagWarningRatio,
+		FallbackEmitInterval:               durationjson.Duration(syncer.DefaultEmitInterval),
+		HandlerTimeout:                     durationjson.Duration(routehandlers.DefaultHandlerTimeout),
 		LockRetryInterval:                  durationjson.Duration(locket.RetryInterval),
 		LockTTL:                            durationjson.Duration(locket.DefaultSessionTTL),
+		MaxCachedEvents:                    watcher.DefaultMaxCachedEvents,
 		NATSAddresses:                      "nats://127.0.0.1:4222",
 		NATSUsername:                       "nats",
 		NATSPassword:                       "nats",
 		RouteEmittingWorkers:               20,
+		RouterStartDebounce:                durationjson.Duration(syncer.DefaultRouterStartDebounce),
+		SuspectActualLRPRouting:            watcher.RouteSuspect,
 		SyncInterval:                       durationjson.Duration(time.Minute),
 		TCPRouteTTL:                        durationjson.Duration(2 * time.Minute),
 		LagerConfig:                        lagerflags.DefaultLagerConfig(),
+		EnableHTTPEmitter:                  true,
 		EnableTCPEmitter:                   false,
 	}
 }
@@ -1,6 +1,7 @@
 package config_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"time"
@@ -9,7 +10,18 @@ import (
 	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/lager/lagerflags"
 	"code.cloudfoundry.org/locket"
+	"code.cloudfoundry.org/route-emitter/auditlog"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+	"code.cloudfoundry.org/route-emitter/consulcatalog"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
+	"code.cloudfoundry.org/route-emitter/renderer"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routeplugins"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"code.cloudfoundry.org/route-emitter/verifier"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/webhook"
+	"code.cloudfoundry.org/route-emitter/xds/xdsconfig"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -23,12 +35,71 @@ var _ = Describe("Config", func() {
 			"dropsonde_port": 1234,
 			"healthcheck_address": "127.0.0.1:8090",
 			"cell_id": "cellID",
+			"cell_liveness_poll_interval": "10s",
 			"consul_cluster": "consul.example.com",
 			"consul_session_name": "myconsulsession",
 			"communication_timeout":"2s",
 			"consul_down_mode_notification_interval": "2m",
 			"sync_interval": "4s",
+			"sync_batch_size": 500,
+			"event_stream_stall_timeout": "5m",
+			"router_greeting_timeout": "1m",
+			"fallback_emit_interval": "15s",
+			"router_expiry": "2m",
+			"router_start_debounce": "3s",
+			"emit_lag_warning_ratio": 0.75,
+			"shed_load_on_emit_lag": true,
+			"handler_timeout": "20s",
+			"plugins": [
+				{"routes_key": "mesh-router", "config": {"port": 8080}}
+			],
+			"webhook": {
+				"urls": ["https://cmdb.example.com/routes"],
+				"secret": "shared-secret",
+				"batch_size": 100,
+				"full_state_interval": "5m"
+			},
+			"xds": {
+				"listen_address": "127.0.0.1:18000",
+				"http_listener_port": 10080
+			},
+			"renderer": {
+				"debounce": "2s",
+				"templates": [{
+					"source": "/var/vcap/jobs/haproxy/templates/haproxy.cfg.tmpl",
+					"destination": "/var/vcap/data/haproxy/haproxy.cfg",
+					"reload_command": ["/var/vcap/jobs/haproxy/bin/reload"]
+				}]
+			},
+			"dns": {
+				"listen_address": "127.0.0.1:1053",
+				"zones": ["apps.internal"],
+				"ttl": "30s",
+				"debounce": "250ms"
+			},
+			"consul_catalog": {
+				"enable": true,
+				"debounce": "2s"
+			},
+			"admin_address": "127.0.0.1:17019",
+			"admin_ca_cert_file": "/tmp/admin_ca_cert",
+			"admin_server_cert_file": "/tmp/admin_server_cert",
+			"admin_server_key_file": "/tmp/admin_server_key",
+			"verifier": {
+				"listen_address": "127.0.0.1:17020",
+				"source": "bbs",
+				"interval": "15s",
+				"stale_threshold": "1m"
+			},
+			"audit_log": {
+				"path": "/var/vcap/sys/log/route_emitter/audit.log",
+				"max_size_mb": 50,
+				"max_backups": 10
+			},
+			"actual_lrp_event_family": "negotiate",
+			"suspect_actual_lrp_routing": "unroute",
 			"bbs_address": "1.1.1.1:9091",
+			"bbs_addresses": ["https://1.1.1.1:9091", "https://1.1.1.2:9091"],
 			"bbs_ca_cert_file": "/tmp/bbs_ca_cert",
 			"bbs_client_cert_file": "/tmp/bbs_client_cert",
 			"bbs_client_key_file": "/tmp/bbs_client_key",
@@ -38,10 +109,13 @@ var _ = Describe("Config", func() {
 			"nats_addresses": "http://127.0.0.2:4222",
 			"nats_username": "user",
 			"nats_password": "password",
+			"record_events_path": "/var/vcap/data/route-emitter/events.log",
 			"lock_retry_interval": "15s",
 			"lock_ttl": "20s",
+			"max_cached_events": 5000,
 			"log_level": "debug",
 			"debug_address": "127.0.0.1:9999",
+			"enable_http_emitter": true,
 			"enable_tcp_emitter": true,
 			"oauth": {
 				"uaa_url": "https://uaa.cf.service.internal:8443",
@@ -75,15 +149,29 @@ var _ = Describe("Config", func() {
 		routeEmitterConfig, err := config.NewRouteEmitterConfig(configPath)
 		Expect(err).NotTo(HaveOccurred())
 
+		dnsTTL := durationjson.Duration(30 * time.Second)
 		expectedConfig := config.RouteEmitterConfig{
 			DropsondePort:                      1234,
 			HealthCheckAddress:                 "127.0.0.1:8090",
 			ConsulCluster:                      "consul.example.com",
 			CellID:                             "cellID",
+			CellLivenessPollInterval:           durationjson.Duration(10 * time.Second),
 			CommunicationTimeout:               durationjson.Duration(2 * time.Second),
 			SyncInterval:                       durationjson.Duration(4 * time.Second),
+			SyncBatchSize:                      500,
+			EventStreamStallTimeout:            durationjson.Duration(5 * time.Minute),
+			RouterGreetingTimeout:              durationjson.Duration(time.Minute),
+			FallbackEmitInterval:               durationjson.Duration(15 * time.Second),
+			RouterExpiry:                       durationjson.Duration(2 * time.Minute),
+			RouterStartDebounce:                durationjson.Duration(3 * time.Second),
+			EmitLagWarningRatio:                0.75,
+			ShedLoadOnEmitLag:                  true,
+			HandlerTimeout:                     durationjson.Duration(20 * time.Second),
+			ActualLRPEventFamily:               watcher.NegotiateEvents,
+			SuspectActualLRPRouting:            watcher.UnrouteSuspect,
 			ConsulDownModeNotificationInterval: durationjson.Duration(2 * time.Minute),
 			BBSAddress:                         "1.1.1.1:9091",
+			BBSAddresses:                       []string{"https://1.1.1.1:9091", "https://1.1.1.2:9091"},
 			BBSCACertFile:                      "/tmp/bbs_ca_cert",
 			BBSClientCertFile:                  "/tmp/bbs_client_cert",
 			BBSClientKeyFile:                   "/tmp/bbs_client_key",
@@ -92,11 +180,14 @@ var _ = Describe("Config", func() {
 			NATSAddresses:                      "http://127.0.0.2:4222",
 			NATSUsername:                       "user",
 			NATSPassword:                       "password",
+			RecordEventsPath:                   "/var/vcap/data/route-emitter/events.log",
 			LockRetryInterval:                  durationjson.Duration(15 * time.Second),
 			LockTTL:                            durationjson.Duration(20 * time.Second),
+			MaxCachedEvents:                    5000,
 			ConsulSessionName:                  "myconsulsession",
 			RouteEmittingWorkers:               18,
 			TCPRouteTTL:                        durationjson.Duration(2 * time.Minute),
+			EnableHTTPEmitter:                  true,
 			EnableTCPEmitter:                   true,
 			DebugServerConfig: debugserver.DebugServerConfig{
 				DebugAddress: "127.0.0.1:9999",
@@ -111,6 +202,52 @@ var _ = Describe("Config", func() {
 				CACerts:        "some-cert",
 				SkipCertVerify: true,
 			},
+			Plugins: []routeplugins.Config{
+				{RoutesKey: "mesh-router", Config: json.RawMessage(`{"port": 8080}`)},
+			},
+			Webhook: webhook.Config{
+				URLs:              []string{"https://cmdb.example.com/routes"},
+				Secret:            "shared-secret",
+				BatchSize:         100,
+				FullStateInterval: durationjson.Duration(5 * time.Minute),
+			},
+			XDS: xdsconfig.Config{
+				ListenAddress:    "127.0.0.1:18000",
+				HTTPListenerPort: 10080,
+			},
+			Renderer: renderer.Config{
+				Debounce: durationjson.Duration(2 * time.Second),
+				Templates: []renderer.TemplateConfig{{
+					Source:        "/var/vcap/jobs/haproxy/templates/haproxy.cfg.tmpl",
+					Destination:   "/var/vcap/data/haproxy/haproxy.cfg",
+					ReloadCommand: []string{"/var/vcap/jobs/haproxy/bin/reload"},
+				}},
+			},
+			DNS: dnsserver.Config{
+				ListenAddress: "127.0.0.1:1053",
+				Zones:         []string{"apps.internal"},
+				TTL:           &dnsTTL,
+				Debounce:      durationjson.Duration(250 * time.Millisecond),
+			},
+			ConsulCatalog: consulcatalog.Config{
+				Enable:   true,
+				Debounce: durationjson.Duration(2 * time.Second),
+			},
+			AdminAddress:        "127.0.0.1:17019",
+			AdminCACertFile:     "/tmp/admin_ca_cert",
+			AdminServerCertFile: "/tmp/admin_server_cert",
+			AdminServerKeyFile:  "/tmp/admin_server_key",
+			Verifier: verifier.Config{
+				ListenAddress:  "127.0.0.1:17020",
+				Source:         verifier.SourceBBS,
+				Interval:       durationjson.Duration(15 * time.Second),
+				StaleThreshold: durationjson.Duration(time.Minute),
+			},
+			AuditLog: auditlog.Config{
+				Path:       "/var/vcap/sys/log/route_emitter/audit.log",
+				MaxSizeMB:  50,
+				MaxBackups: 10,
+			},
 		}
 
 		Expect(routeEmitterConfig).To(Equal(expectedConfig))
@@ -144,18 +281,26 @@ var _ = Describe("Config", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			config := config.RouteEmitterConfig{
+				ActualLRPEventFamily:               watcher.GroupEvents,
 				CommunicationTimeout:               durationjson.Duration(30 * time.Second),
 				ConsulDownModeNotificationInterval: durationjson.Duration(time.Minute),
 				ConsulSessionName:                  "route-emitter",
 				DropsondePort:                      3457,
+				EmitLagWarningRatio:                syncer.DefaultEmitLagWarningRatio,
+				FallbackEmitInterval:               durationjson.Duration(syncer.DefaultEmitInterval),
+				HandlerTimeout:                     durationjson.Duration(routehandlers.DefaultHandlerTimeout),
 				LockRetryInterval:                  durationjson.Duration(locket.RetryInterval),
 				LockTTL:                            durationjson.Duration(locket.DefaultSessionTTL),
+				MaxCachedEvents:                    watcher.DefaultMaxCachedEvents,
 				NATSAddresses:                      "nats://127.0.0.1:4222",
 				NATSUsername:                       "nats",
 				NATSPassword:                       "nats",
 				RouteEmittingWorkers:               20,
+				RouterStartDebounce:                durationjson.Duration(syncer.DefaultRouterStartDebounce),
+				SuspectActualLRPRouting:            watcher.RouteSuspect,
 				SyncInterval:                       durationjson.Duration(time.Minute),
 				TCPRouteTTL:                        durationjson.Duration(2 * time.Minute),
+				EnableHTTPEmitter:                  true,
 				EnableTCPEmitter:                   false,
 				LagerConfig: lagerflags.LagerConfig{
 					LogLevel: "info",
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,15 +18,27 @@ import (
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagerflags"
 	route_emitter "code.cloudfoundry.org/route-emitter"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+	"code.cloudfoundry.org/route-emitter/bbsfailover"
+	"code.cloudfoundry.org/route-emitter/cellwatcher"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+	"code.cloudfoundry.org/route-emitter/consulcatalog"
 	"code.cloudfoundry.org/route-emitter/consuldownchecker"
 	"code.cloudfoundry.org/route-emitter/consuldownmodenotifier"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/recorder"
+	"code.cloudfoundry.org/route-emitter/renderer"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routeplugins"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/syncer"
+	"code.cloudfoundry.org/route-emitter/verifier"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/webhook"
+	"code.cloudfoundry.org/route-emitter/xds"
 	"code.cloudfoundry.org/routing-api"
 	uaaclient "code.cloudfoundry.org/uaa-go-client"
 	uaaconfig "code.cloudfoundry.org/uaa-go-client/config"
@@ -60,26 +73,181 @@ func main() {
 	cfhttp.Initialize(time.Duration(cfg.CommunicationTimeout))
 
 	logger, reconfigurableSink := lagerflags.NewFromConfig(cfg.ConsulSessionName, cfg.LagerConfig)
-	natsClient := diegonats.NewClient()
 
-	natsPingDuration := 20 * time.Second
-	logger.Info("setting-nats-ping-interval", lager.Data{"duration-in-seconds": natsPingDuration.Seconds()})
-	natsClient.SetPingInterval(natsPingDuration)
+	if !cfg.EnableHTTPEmitter && !cfg.EnableTCPEmitter && len(cfg.Plugins) == 0 {
+		logger.Fatal("no-emitter-enabled", errors.New("at least one of the HTTP and TCP emitters or a plugin must be enabled"))
+	}
 
 	clock := clock.NewClock()
-	syncer := syncer.NewSyncer(clock, time.Duration(cfg.SyncInterval), natsClient, logger)
 
 	initializeDropsonde(logger, cfg.DropsondePort)
 
-	natsClientRunner := diegonats.NewClientRunner(cfg.NATSAddresses, cfg.NATSUsername, cfg.NATSPassword, logger, natsClient)
-
-	bbsClient := initializeBBSClient(logger, cfg)
+	bbsClient, bbsFailover := initializeBBSClient(logger, clock, cfg)
 
 	localMode := cfg.CellID != ""
-	table := initializeRoutingTable(logger)
-	natsEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers)
-	natsHandler := routehandlers.NewNATSHandler(table, natsEmitter, localMode)
-	handlers := []watcher.RouteHandler{natsHandler}
+	handlers := []routehandlers.NamedHandler{}
+	emitMonitor := syncer.NewEmitMonitor(clock, cfg.EmitLagWarningRatio, cfg.ShedLoadOnEmitLag)
+
+	// the admin API serves the routing tables and the state of the emitter
+	var adminAPI *adminapi.API
+	var adminAddress string
+	var adminTLSConfig *tls.Config
+	if cfg.AdminAddress != "" {
+		adminAPI = adminapi.NewAPI(logger, clock, cfg.CellID, emitMonitor)
+
+		// without mutual TLS, the admin API is only reachable from the VM
+		adminAddress = adminapi.ListenAddress(cfg.AdminAddress)
+		if cfg.AdminServerCertFile != "" {
+			var err error
+			adminTLSConfig, err = cfhttp.NewTLSConfig(cfg.AdminServerCertFile, cfg.AdminServerKeyFile, cfg.AdminCACertFile)
+			if err != nil {
+				logger.Fatal("failed-to-load-admin-tls-config", err)
+			}
+		} else if !adminapi.IsLoopback(adminAddress) {
+			logger.Fatal("admin-address-requires-mutual-tls", errors.New("the admin API only listens on a loopback address without mutual TLS"), lager.Data{"admin-address": adminAddress})
+		}
+	}
+
+	// the webhook emitter sees everything the HTTP and TCP emitters emit. In
+	// local mode that is only the routes of this cell, which its full pushes
+	// would hand to the webhooks as the full state.
+	var webhookEmitter *webhook.Emitter
+	if cfg.Webhook.Enabled() {
+		if cfg.CellID != "" {
+			logger.Fatal("webhook-requires-global-mode", errors.New("webhooks are only available without a cell id"))
+		}
+		httpClient := cfhttp.NewClient()
+		if cfg.Webhook.RequestTimeout > 0 {
+			httpClient = cfhttp.NewCustomTimeoutClient(time.Duration(cfg.Webhook.RequestTimeout))
+		}
+		webhookEmitter = webhook.NewEmitter(logger, clock, httpClient, cfg.Webhook)
+	}
+
+	// the audit log records every registration and unregistration the HTTP
+	// and TCP emitters emit, and why
+	var auditLog *auditlog.Log
+	if cfg.AuditLog.Enabled() {
+		auditLog, err = auditlog.NewLog(logger, clock, cfg.AuditLog)
+		if err != nil {
+			logger.Fatal("failed-to-open-audit-log", err, lager.Data{"path": cfg.AuditLog.Path})
+		}
+		defer auditLog.Close()
+	}
+
+	// the xDS server serves the routing tables to Envoy
+	var xdsServer *xds.Server
+	if cfg.XDS.Enabled() {
+		xdsServer = xds.NewServer(logger, cfg.XDS)
+	}
+
+	// the DNS server answers for the hostnames in the HTTP routing table
+	var dnsServer *dnsserver.Server
+	if cfg.DNS.Enabled() {
+		dnsServer = dnsserver.NewServer(logger, clock, cfg.DNS)
+	}
+
+	// only global mode talks to Consul
+	var consulClient consuladapter.Client
+	if cfg.CellID == "" {
+		consulClient = initializeConsulClient(logger, cfg.ConsulCluster)
+	}
+
+	// the Consul registrar registers the hostnames in the HTTP routing table
+	// as Consul services
+	var consulRegistrar *consulcatalog.Registrar
+	if cfg.ConsulCatalog.Enabled() {
+		if consulClient == nil {
+			logger.Fatal("consul-catalog-requires-global-mode", errors.New("consul catalog registration is only available without a cell id"))
+		}
+		consulRegistrar = consulcatalog.NewRegistrar(logger, clock, consulClient, cfg.ConsulCatalog)
+	}
+
+	// the renderer renders the routing tables into proxy configurations
+	var tableRenderer *renderer.Renderer
+	if cfg.Renderer.Enabled() {
+		tableRenderer, err = renderer.NewRenderer(logger, clock, cfg.Renderer)
+		if err != nil {
+			logger.Fatal("failed-to-create-renderer", err)
+		}
+	}
+
+	// the HTTP emitter registers routes with the gorouters over NATS, and
+	// emits at the interval the gorouters ask for. Without it, nothing needs
+	// NATS and the syncer runs on timers alone.
+	var routeSyncer syncer.Syncer
+	var natsClientRunner ifrit.Runner
+	var natsVerifier *verifier.Verifier
+	if cfg.Verifier.Enabled() && !cfg.EnableHTTPEmitter {
+		logger.Fatal("verifier-requires-http-emitter", errors.New("the verifier listens to the registrations of the HTTP emitter"))
+	}
+	if cfg.EnableHTTPEmitter {
+		natsClient := diegonats.NewClient()
+
+		natsPingDuration := 20 * time.Second
+		logger.Info("setting-nats-ping-interval", lager.Data{"duration-in-seconds": natsPingDuration.Seconds()})
+		natsClient.SetPingInterval(natsPingDuration)
+
+		routeSyncer = syncer.NewSyncer(
+			clock,
+			time.Duration(cfg.SyncInterval),
+			time.Duration(cfg.RouterGreetingTimeout),
+			time.Duration(cfg.FallbackEmitInterval),
+			time.Duration(cfg.RouterExpiry),
+			time.Duration(cfg.RouterStartDebounce),
+			emitMonitor,
+			natsClient,
+			logger,
+		)
+		natsClientRunner = diegonats.NewClientRunner(cfg.NATSAddresses, cfg.NATSUsername, cfg.NATSPassword, logger, natsClient)
+
+		// the verifier compares the registrations on NATS with the routes
+		if cfg.Verifier.Enabled() {
+			natsVerifier, err = verifier.NewVerifier(logger, clock, natsClient, bbsClient, cfg.CellID, cfg.Verifier)
+			if err != nil {
+				logger.Fatal("failed-to-create-verifier", err)
+			}
+		}
+
+		table := initializeRoutingTable(logger)
+		if xdsServer != nil {
+			table = xdsServer.NATSTable(table)
+		}
+		if tableRenderer != nil {
+			table = tableRenderer.NATSTable(table)
+		}
+		if dnsServer != nil {
+			table = dnsServer.NATSTable(table)
+		}
+		if consulRegistrar != nil {
+			table = consulRegistrar.NATSTable(table)
+		}
+		if adminAPI != nil {
+			table = adminAPI.NATSTable(table)
+		}
+		if natsVerifier != nil {
+			table = natsVerifier.NATSTable(table)
+		}
+		natsEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers)
+		if natsVerifier != nil {
+			natsEmitter = natsVerifier.NATSEmitter(natsEmitter)
+		}
+		if webhookEmitter != nil {
+			natsEmitter = webhookEmitter.NATSEmitter(natsEmitter)
+		}
+		if auditLog != nil {
+			natsEmitter = auditLog.NATSEmitter(natsEmitter)
+		}
+		natsHandler := routehandlers.NewNATSHandler(clock, table, natsEmitter, emitMonitor, localMode)
+		handlers = append(handlers, routehandlers.NamedHandler{Name: "NATSHandler", Handler: natsHandler})
+	} else {
+		logger.Info("http-emitter-disabled")
+		routeSyncer = syncer.NewTimerSyncer(
+			clock,
+			time.Duration(cfg.SyncInterval),
+			time.Duration(cfg.FallbackEmitInterval),
+			logger,
+		)
+	}
 
 	routeTTL := time.Duration(cfg.TCPRouteTTL)
 	if routeTTL.Seconds() > 65535 {
@@ -93,18 +261,73 @@ func main() {
 		logger.Debug("creating-routing-api-client", lager.Data{"api-location": routingAPIAddress})
 		routingAPIClient := routing_api.NewClient(routingAPIAddress, false)
 		routingAPIEmitter := emitter.NewRoutingAPIEmitter(tcpLogger, routingAPIClient, uaaClient, int(routeTTL.Seconds()))
+		if webhookEmitter != nil {
+			routingAPIEmitter = webhookEmitter.RoutingAPIEmitter(routingAPIEmitter)
+		}
+		if auditLog != nil {
+			routingAPIEmitter = auditLog.RoutingAPIEmitter(routingAPIEmitter)
+		}
 		tcpTable := routingtable.NewTCPTable(tcpLogger, nil)
-		routingAPIHandler := routehandlers.NewRoutingAPIHandler(tcpTable, routingAPIEmitter, localMode)
-		handlers = append(handlers, routingAPIHandler)
+		if xdsServer != nil {
+			tcpTable = xdsServer.TCPTable(tcpTable)
+		}
+		if tableRenderer != nil {
+			tcpTable = tableRenderer.TCPTable(tcpTable)
+		}
+		if adminAPI != nil {
+			tcpTable = adminAPI.TCPTable(tcpTable)
+		}
+		routingAPIHandler := routehandlers.NewRoutingAPIHandler(tcpTable, routingAPIEmitter, emitMonitor, localMode)
+		handlers = append(handlers, routehandlers.NamedHandler{Name: "RoutingAPIHandler", Handler: routingAPIHandler})
 	}
 
-	handler := routehandlers.NewMultiHandler(handlers...)
+	pluginHandler, err := routeplugins.DefaultRegistry.Build(logger, clock, localMode, time.Duration(cfg.HandlerTimeout), cfg.Plugins)
+	if err != nil {
+		logger.Fatal("failed-to-build-plugins", err, lager.Data{"registered-routes-keys": routeplugins.DefaultRegistry.RoutesKeys()})
+	}
+	if pluginHandler != nil {
+		handlers = append(handlers, routehandlers.NamedHandler{Name: "PluginHandler", Handler: pluginHandler})
+	}
+
+	var eventRecorder watcher.Recorder
+	if cfg.RecordEventsPath != "" {
+		fileRecorder, err := recorder.NewFileRecorder(logger, clock, cfg.RecordEventsPath, cfg.CellID)
+		if err != nil {
+			logger.Fatal("failed-to-create-event-recorder", err, lager.Data{"path": cfg.RecordEventsPath})
+		}
+		defer fileRecorder.Close()
+		logger.Info("recording-events", lager.Data{"path": cfg.RecordEventsPath})
+		eventRecorder = fileRecorder
+	}
+
+	lrpEvents := watcher.ActualLRPEvents{
+		Family:        cfg.ActualLRPEventFamily,
+		SuspectPolicy: cfg.SuspectActualLRPRouting,
+	}
+	if err := lrpEvents.Validate(); err != nil {
+		logger.Fatal("invalid-actual-lrp-events", err)
+	}
+
+	var cellWatcher *cellwatcher.CellWatcher
+	var cellChanges <-chan cellwatcher.Change
+	if cfg.CellLivenessPollInterval > 0 {
+		cellWatcher = cellwatcher.NewCellWatcher(bbsClient, clock, time.Duration(cfg.CellLivenessPollInterval), logger)
+		cellChanges = cellWatcher.Changes()
+	}
+
+	handler := routehandlers.NewMultiHandler(clock, time.Duration(cfg.HandlerTimeout), logger, handlers...)
 	watcher := watcher.NewWatcher(
 		cfg.CellID,
 		bbsClient,
 		clock,
 		handler,
-		syncer.Events(),
+		routeSyncer.Events(),
+		cellChanges,
+		cfg.SyncBatchSize,
+		cfg.MaxCachedEvents,
+		time.Duration(cfg.EventStreamStallTimeout),
+		lrpEvents,
+		eventRecorder,
 		logger,
 	)
 
@@ -112,16 +335,20 @@ func main() {
 		resp.WriteHeader(http.StatusOK)
 	}
 	healthCheckServer := http_server.New(cfg.HealthCheckAddress, http.HandlerFunc(healthHandler))
-	members := grouper.Members{
-		{"nats-client", natsClientRunner},
-		{"healthcheck", healthCheckServer},
+	members := grouper.Members{}
+	if natsClientRunner != nil {
+		members = append(members, grouper.Member{"nats-client", natsClientRunner})
+	}
+	members = append(members, grouper.Member{"healthcheck", healthCheckServer})
+	if bbsFailover != nil {
+		members = append(members, grouper.Member{"bbs-failover", bbsFailover})
+	}
+	if adminAPI != nil {
+		members = append(members, grouper.Member{"admin-server", newAdminServer(adminAddress, adminTLSConfig, adminAPI.Handler(routeSyncer.Events()))})
 	}
 
-	var consulClient consuladapter.Client
 	var consulDownModeNotifier *consuldownmodenotifier.ConsulDownModeNotifier
 	if cfg.CellID == "" {
-		consulClient = initializeConsulClient(logger, cfg.ConsulCluster)
-
 		lockMaintainer := initializeLockMaintainer(
 			logger,
 			consulClient,
@@ -140,14 +367,48 @@ func main() {
 
 		// we are running in global mode
 		members = append(members, grouper.Member{"lock-maintainer", lockMaintainer})
+		if adminAPI != nil {
+			members = append(members, grouper.Member{"admin-lock-status", adminAPI.LockHeld()})
+		}
 		members = append(members, grouper.Member{"consul-down-mode-notifier", consulDownModeNotifier})
 	}
 
 	members = append(members,
 		grouper.Member{"watcher", watcher},
-		grouper.Member{"syncer", syncer},
+		grouper.Member{"syncer", routeSyncer},
 	)
 
+	if cellWatcher != nil {
+		members = append(members, grouper.Member{"cell-watcher", cellWatcher})
+	}
+
+	if webhookEmitter != nil {
+		members = append(members, grouper.Member{"webhook-emitter", webhookEmitter})
+	}
+
+	if xdsServer != nil {
+		members = append(members, grouper.Member{"xds-server", xdsServer})
+	}
+
+	if tableRenderer != nil {
+		members = append(members, grouper.Member{"renderer", tableRenderer})
+	}
+
+	if dnsServer != nil {
+		members = append(members, grouper.Member{"dns-server", dnsServer})
+	}
+
+	if consulRegistrar != nil {
+		members = append(members, grouper.Member{"consul-catalog", consulRegistrar})
+	}
+
+	if natsVerifier != nil {
+		members = append(members,
+			grouper.Member{"verifier", natsVerifier},
+			grouper.Member{"verifier-server", http_server.New(cfg.Verifier.ListenAddress, natsVerifier.Handler())},
+		)
+	}
+
 	if cfg.DebugAddress != "" {
 		members = append(grouper.Members{
 			{"debug-server", debugserver.Runner(cfg.DebugAddress, reconfigurableSink)},
@@ -185,12 +446,44 @@ func main() {
 			time.Duration(cfg.ConsulDownModeNotificationInterval),
 		)
 		// we are running in global mode
-		members = grouper.Members{
-			{"nats-client", natsClientRunner},
-			{"consul-down-checker", consulDownChecker},
-			{"consul-down-mode-notifier", consulDownModeNotifier},
-			{"watcher", watcher},
-			{"syncer", syncer},
+		members = grouper.Members{}
+		if natsClientRunner != nil {
+			members = append(members, grouper.Member{"nats-client", natsClientRunner})
+		}
+		if bbsFailover != nil {
+			members = append(members, grouper.Member{"bbs-failover", bbsFailover})
+		}
+		if adminAPI != nil {
+			members = append(members, grouper.Member{"admin-server", newAdminServer(adminAddress, adminTLSConfig, adminAPI.Handler(routeSyncer.Events()))})
+		}
+		members = append(members, grouper.Member{"consul-down-checker", consulDownChecker})
+		if adminAPI != nil {
+			members = append(members, grouper.Member{"admin-consul-down-mode", adminAPI.ConsulDownMode()})
+		}
+		members = append(members,
+			grouper.Member{"consul-down-mode-notifier", consulDownModeNotifier},
+			grouper.Member{"watcher", watcher},
+			grouper.Member{"syncer", routeSyncer},
+		)
+		if webhookEmitter != nil {
+			members = append(members, grouper.Member{"webhook-emitter", webhookEmitter})
+		}
+		if xdsServer != nil {
+			members = append(members, grouper.Member{"xds-server", xdsServer})
+		}
+		if tableRenderer != nil {
+			members = append(members, grouper.Member{"renderer", tableRenderer})
+		}
+		if dnsServer != nil {
+			members = append(members, grouper.Member{"dns-server", dnsServer})
+		}
+		// the Consul registrar stays out, as Consul is down and its
+		// registrations were removed when the lock was lost
+		if natsVerifier != nil {
+			members = append(members,
+				grouper.Member{"verifier", natsVerifier},
+				grouper.Member{"verifier-server", http_server.New(cfg.Verifier.ListenAddress, natsVerifier.Handler())},
+			)
 		}
 
 		group = grouper.NewOrdered(os.Interrupt, members)
@@ -207,6 +500,7 @@ func main() {
 		}
 	}
 
+	handler.Stop()
 	logger.Info("exited")
 }
 
@@ -263,6 +557,13 @@ func initializeRoutingTable(logger lager.Logger) routingtable.NATSRoutingTable {
 	return routingtable.NewNATSTable(logger)
 }
 
+func newAdminServer(address string, tlsConfig *tls.Config, handler http.Handler) ifrit.Runner {
+	if tlsConfig != nil {
+		return http_server.NewTLSServer(address, handler, tlsConfig)
+	}
+	return http_server.New(address, handler)
+}
+
 func initializeConsulClient(logger lager.Logger, consulCluster string) consuladapter.Client {
 	consulClient, err := consuladapter.NewClientFromUrl(consulCluster)
 	if err != nil {
@@ -288,21 +589,51 @@ func initializeLockMaintainer(
 	return serviceClient.NewRouteEmitterLockRunner(logger, uuid.String(), lockRetryInterval, lockTTL)
 }
 
+// initializeBBSClient also returns the failover client when several BBS
+// addresses are configured, to probe the addresses that failed, and nil
+// otherwise.
 func initializeBBSClient(
+	logger lager.Logger,
+	clock clock.Clock,
+	cfg config.RouteEmitterConfig,
+) (bbs.Client, *bbsfailover.Client) {
+	addresses := cfg.BBSAddresses
+	if len(addresses) == 0 {
+		addresses = []string{cfg.BBSAddress}
+	}
+
+	clients := make([]bbs.Client, 0, len(addresses))
+	for _, address := range addresses {
+		clients = append(clients, newBBSClient(logger, cfg, address))
+	}
+
+	if len(clients) == 1 {
+		return clients[0], nil
+	}
+
+	bbsClient, err := bbsfailover.NewClient(logger, clock, bbsfailover.DefaultProbeInterval, addresses, clients)
+	if err != nil {
+		logger.Fatal("Failed to configure BBS failover", err)
+	}
+	return bbsClient, bbsClient
+}
+
+func newBBSClient(
 	logger lager.Logger,
 	cfg config.RouteEmitterConfig,
+	address string,
 ) bbs.Client {
-	bbsURL, err := url.Parse(cfg.BBSAddress)
+	bbsURL, err := url.Parse(address)
 	if err != nil {
-		logger.Fatal("Invalid BBS URL", err)
+		logger.Fatal("Invalid BBS URL", err, lager.Data{"address": address})
 	}
 
 	if bbsURL.Scheme != "https" {
-		return bbs.NewClient(cfg.BBSAddress)
+		return bbs.NewClient(address)
 	}
 
 	bbsClient, err := bbs.NewSecureClient(
-		cfg.BBSAddress,
+		address,
 		cfg.BBSCACertFile,
 		cfg.BBSClientCertFile,
 		cfg.BBSClientKeyFile,
@@ -310,7 +641,7 @@ func initializeBBSClient(
 		cfg.BBSMaxIdleConnsPerHost,
 	)
 	if err != nil {
-		logger.Fatal("Failed to configure secure BBS client", err)
+		logger.Fatal("Failed to configure secure BBS client", err, lager.Data{"address": address})
 	}
 	return bbsClient
 }
@@ -258,6 +258,28 @@ var _ = Describe("Route Emitter", func() {
 		})
 	})
 
+	Context("when the webhook emitter is enabled in local mode", func() {
+		var (
+			runner  *ginkgomon.Runner
+			emitter ifrit.Process
+		)
+
+		BeforeEach(func() {
+			cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+				cfg.Webhook.URLs = []string{"http://127.0.0.1:0/routes"}
+			})
+			runner = createEmitterRunner("emitter1", "cell-local", cfgs...)
+			emitter = ifrit.Invoke(runner)
+		})
+
+		It("logs an error and exits, as the full state of a cell is only part of the routes", func() {
+			var err error
+			Eventually(emitter.Wait()).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(runner.Buffer()).To(gbytes.Say("webhook-requires-global-mode"))
+		})
+	})
+
 	Context("when the tcp route emitter is enabled", func() {
 		var (
 			expectedTcpRouteMapping    apimodels.TcpRouteMapping
@@ -1320,6 +1342,37 @@ var _ = Describe("Route Emitter", func() {
 			fakeBBS.Close()
 		})
 	})
+
+	Context("when several bbs addresses are configured and the first one is down", func() {
+		var (
+			runner  *ginkgomon.Runner
+			emitter ifrit.Process
+		)
+
+		BeforeEach(func() {
+			cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+				cfg.BBSAddresses = []string{"http://127.0.0.1:1", bbsURL.String()}
+			})
+
+			runner = createEmitterRunner("route-emitter", "", cfgs...)
+			Expect(bbsClient.UpsertDomain(logger, domain, time.Hour)).To(Succeed())
+			Expect(bbsClient.DesireLRP(logger, desiredLRP)).To(Succeed())
+			Expect(bbsClient.StartActualLRP(logger, &lrpKey, &instanceKey, &netInfo)).To(Succeed())
+		})
+
+		It("fails over to the next address and emits the routes", func() {
+			emitter = ginkgomon.Invoke(runner)
+			Expect(runner).To(gbytes.Say("failed-over"))
+
+			var msg routingtable.RegistryMessage
+			Eventually(registeredRoutes).Should(Receive(&msg))
+			Expect(msg.PrivateInstanceId).To(Equal(instanceKey.GetInstanceGuid()))
+		})
+
+		AfterEach(func() {
+			ginkgomon.Interrupt(emitter, emitterInterruptTimeout)
+		})
+	})
 })
 
 func newRoutes(hosts []string, port uint32, routeServiceUrl string) *models.Routes {
@@ -0,0 +1,8 @@
+package main
+
+// Route handler plugins register their factory with the default
+// routeplugins registry when their package is imported, and are enabled by
+// routes key in the plugins section of the configuration. To build a plugin
+// into the route emitter, import it here for its side effects:
+//
+//	import _ "example.com/mesh/routeemitterplugin"
@@ -0,0 +1,33 @@
+package consulcatalog_test
+
+import (
+	"testing"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var consulRunner *consulrunner.ClusterRunner
+
+func TestConsulCatalog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Consul Catalog Suite")
+}
+
+var _ = BeforeSuite(func() {
+	consulRunner = consulrunner.NewClusterRunner(
+		consulrunner.ClusterRunnerConfig{
+			StartingPort: 19001 + GinkgoParallelNode()*consulrunner.PortOffsetLength,
+			NumNodes:     1,
+			Scheme:       "http",
+		},
+	)
+	consulRunner.Start()
+	consulRunner.WaitUntilReady()
+})
+
+var _ = AfterSuite(func() {
+	consulRunner.Stop()
+})
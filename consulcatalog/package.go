@@ -0,0 +1 @@
+package consulcatalog // import "code.cloudfoundry.org/route-emitter/consulcatalog"
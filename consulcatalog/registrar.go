@@ -0,0 +1,230 @@
+package consulcatalog
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+const DefaultDebounce = time.Second
+
+var (
+	reconcileFailures = metric.Counter("ConsulCatalogReconcileFailures")
+	serviceInstances  = metric.Metric("ConsulCatalogServiceInstances")
+)
+
+// Config configures the registration of the hostnames of the HTTP routing
+// table as Consul services. It needs the Consul client of global mode.
+type Config struct {
+	Enable   bool                  `json:"enable,omitempty"`
+	Debounce durationjson.Duration `json:"debounce,omitempty"`
+}
+
+func (c Config) Enabled() bool {
+	return c.Enable
+}
+
+// Registrar registers every hostname of the HTTP routing table with the local
+// Consul agent, as a service with one instance per endpoint, tagged with the
+// instance index, process guid and isolation segment of the endpoint.
+//
+// It reconciles the agent with the table when it starts, and then at most
+// once per debounce interval while the table keeps changing, which includes
+// the swap of every sync. Each reconciliation registers what is missing or
+// out of date and deregisters what is no longer routed, so that instances
+// changed behind its back are repaired by the next sync. It deregisters every
+// instance it owns when it is signalled, which includes the emitter losing
+// the lock, for the agent not to keep advertising them once nobody keeps
+// them up to date.
+type Registrar struct {
+	logger   lager.Logger
+	clock    clock.Clock
+	debounce time.Duration
+	agent    consuladapter.Agent
+	changed  chan struct{}
+
+	lock  sync.Mutex
+	table routingtable.NATSRoutingTable
+}
+
+func NewRegistrar(logger lager.Logger, clock clock.Clock, consulClient consuladapter.Client, config Config) *Registrar {
+	debounce := time.Duration(config.Debounce)
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	return &Registrar{
+		logger:   logger.Session("consul-catalog"),
+		clock:    clock,
+		debounce: debounce,
+		agent:    consulClient.Agent(),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// NATSTable registers the hostnames of the table, and returns the table the
+// emitter has to mutate for the registrar to notice.
+func (r *Registrar) NATSTable(table routingtable.NATSRoutingTable) routingtable.NATSRoutingTable {
+	r.lock.Lock()
+	r.table = table
+	r.lock.Unlock()
+
+	r.tableChanged()
+	return routingtable.NewObservedNATSTable(table, r.tableChanged)
+}
+
+func (r *Registrar) tableChanged() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Registrar) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := r.logger.Session("run")
+	logger.Info("starting", lager.Data{"debounce": r.debounce.String()})
+
+	// the first reconciliation covers whatever changed until now
+	select {
+	case <-r.changed:
+	default:
+	}
+	r.reconcileOrRetry(logger)
+
+	close(ready)
+	logger.Info("started")
+
+	var debounce clock.Timer
+	var debounced <-chan time.Time
+	for {
+		select {
+		case <-r.changed:
+			if debounce == nil {
+				debounce = r.clock.NewTimer(r.debounce)
+				debounced = debounce.C()
+			}
+		case <-debounced:
+			debounce, debounced = nil, nil
+			r.reconcileOrRetry(logger)
+		case <-signals:
+			logger.Info("stopping")
+			if debounce != nil {
+				debounce.Stop()
+			}
+			r.deregisterAll(logger)
+			return nil
+		}
+	}
+}
+
+// deregisterAll deregisters every service instance the registrar owns.
+func (r *Registrar) deregisterAll(logger lager.Logger) {
+	logger = logger.Session("deregister-all")
+
+	services, err := r.agent.Services()
+	if err != nil {
+		logger.Error("failed-to-list-services", err)
+		return
+	}
+
+	deregistered := 0
+	for id := range services {
+		if !strings.HasPrefix(id, ServiceIDPrefix) {
+			continue
+		}
+
+		err := r.agent.ServiceDeregister(id)
+		if err != nil {
+			logger.Error("failed-to-deregister-service-instance", err, lager.Data{"id": id})
+			continue
+		}
+		deregistered++
+	}
+
+	err = serviceInstances.Send(0)
+	if err != nil {
+		logger.Error("failed-to-send-service-instances-metric", err)
+	}
+	logger.Info("deregistered", lager.Data{"deregistered": deregistered})
+}
+
+// reconcileOrRetry reconciles, and tries again after the debounce interval if
+// that fails.
+func (r *Registrar) reconcileOrRetry(logger lager.Logger) {
+	err := r.reconcile(logger)
+	if err != nil {
+		reconcileFailures.Increment()
+		r.tableChanged()
+	}
+}
+
+func (r *Registrar) reconcile(logger lager.Logger) error {
+	logger = logger.Session("reconcile")
+
+	r.lock.Lock()
+	table := r.table
+	r.lock.Unlock()
+
+	var entries []routingtable.NATSTableEntry
+	if table != nil {
+		entries = table.Snapshot()
+	}
+	instances := buildServiceInstances(entries)
+
+	services, err := r.agent.Services()
+	if err != nil {
+		logger.Error("failed-to-list-services", err)
+		return err
+	}
+
+	var reconcileErr error
+	deregistered := 0
+	for id := range services {
+		if !strings.HasPrefix(id, ServiceIDPrefix) {
+			continue
+		}
+		if _, ok := instances[id]; ok {
+			continue
+		}
+
+		err := r.agent.ServiceDeregister(id)
+		if err != nil {
+			logger.Error("failed-to-deregister-service-instance", err, lager.Data{"id": id})
+			reconcileErr = err
+			continue
+		}
+		deregistered++
+	}
+
+	registered := 0
+	for id, instance := range instances {
+		if service, ok := services[id]; ok && sameInstance(service, instance) {
+			continue
+		}
+
+		err := r.agent.ServiceRegister(instance)
+		if err != nil {
+			logger.Error("failed-to-register-service-instance", err, lager.Data{"id": id})
+			reconcileErr = err
+			continue
+		}
+		registered++
+	}
+
+	err = serviceInstances.Send(len(instances))
+	if err != nil {
+		logger.Error("failed-to-send-service-instances-metric", err)
+	}
+	if registered > 0 || deregistered > 0 {
+		logger.Info("reconciled", lager.Data{"instances": len(instances), "registered": registered, "deregistered": deregistered})
+	}
+	return reconcileErr
+}
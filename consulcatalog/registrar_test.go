@@ -0,0 +1,151 @@
+package consulcatalog_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/consulcatalog"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registrar", func() {
+	var (
+		logger       *lagertest.TestLogger
+		clock        *fakeclock.FakeClock
+		consulClient consuladapter.Client
+		config       consulcatalog.Config
+		table        routingtable.NATSRoutingTable
+		process      ifrit.Process
+	)
+
+	key := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+	endpoint1 := routingtable.Endpoint{InstanceGuid: "ig-1", Index: 0, Host: "10.0.0.1", Port: 61001, ContainerPort: 8080}
+	endpoint2 := routingtable.Endpoint{InstanceGuid: "ig-2", Index: 1, Host: "10.0.0.2", Port: 61002, ContainerPort: 8080}
+	routes := []routingtable.Route{
+		{Hostname: "app.example.com", IsolationSegment: "isolation-segment"},
+		{Hostname: "app.example.com/api", IsolationSegment: "isolation-segment"},
+	}
+
+	services := func() map[string]*api.AgentService {
+		services, err := consulClient.Agent().Services()
+		Expect(err).NotTo(HaveOccurred())
+		delete(services, "consul")
+		return services
+	}
+
+	serviceIDs := func() []string {
+		ids := []string{}
+		for id := range services() {
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		consulClient = consulRunner.NewClient()
+		config = consulcatalog.Config{
+			Enable:   true,
+			Debounce: durationjson.Duration(time.Second),
+		}
+	})
+
+	JustBeforeEach(func() {
+		registrar := consulcatalog.NewRegistrar(logger, clock, consulClient, config)
+		table = registrar.NATSTable(routingtable.NewNATSTable(logger))
+		table.SetRoutes(key, routes, nil)
+		table.AddEndpoint(key, endpoint1)
+
+		process = ifrit.Invoke(registrar)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+
+		for id := range services() {
+			Expect(consulClient.Agent().ServiceDeregister(id)).To(Succeed())
+		}
+	})
+
+	It("registers an instance of the hostname service per endpoint when it starts", func() {
+		Expect(services()).To(HaveLen(1))
+
+		service := services()["route-emitter:app.example.com:10.0.0.1:61001"]
+		Expect(service).NotTo(BeNil())
+		Expect(service.Service).To(Equal("app.example.com"))
+		Expect(service.Address).To(Equal("10.0.0.1"))
+		Expect(service.Port).To(Equal(61001))
+		Expect(service.Tags).To(ConsistOf(
+			"route-emitter",
+			"instance-index:0",
+			"process-guid:process-guid",
+			"isolation-segment:isolation-segment",
+		))
+	})
+
+	It("registers and deregisters instances once the changes settle", func() {
+		table.AddEndpoint(key, endpoint2)
+		table.RemoveEndpoint(key, endpoint1)
+		Consistently(serviceIDs).Should(ConsistOf("route-emitter:app.example.com:10.0.0.1:61001"))
+
+		clock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(serviceIDs).Should(ConsistOf("route-emitter:app.example.com:10.0.0.2:61002"))
+		Expect(services()["route-emitter:app.example.com:10.0.0.2:61002"].Tags).To(ContainElement("instance-index:1"))
+
+		table.RemoveEndpoint(key, endpoint2)
+		clock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(serviceIDs).Should(BeEmpty())
+	})
+
+	It("repairs the registrations on every sync", func() {
+		Expect(consulClient.Agent().ServiceDeregister("route-emitter:app.example.com:10.0.0.1:61001")).To(Succeed())
+
+		syncTable := routingtable.NewNATSTable(logger)
+		syncTable.SetRoutes(key, routes, nil)
+		syncTable.AddEndpoint(key, endpoint1)
+		table.Swap(syncTable, models.NewDomainSet([]string{"cf-apps"}))
+
+		clock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(serviceIDs).Should(ConsistOf("route-emitter:app.example.com:10.0.0.1:61001"))
+	})
+
+	Context("when the agent has other services registered", func() {
+		BeforeEach(func() {
+			Expect(consulClient.Agent().ServiceRegister(&api.AgentServiceRegistration{
+				ID:   "route-emitter:gone.example.com:10.0.0.9:61009",
+				Name: "gone.example.com",
+			})).To(Succeed())
+			Expect(consulClient.Agent().ServiceRegister(&api.AgentServiceRegistration{
+				ID:   "other-service",
+				Name: "other-service",
+			})).To(Succeed())
+		})
+
+		It("only deregisters its own instances that are no longer routed", func() {
+			Expect(serviceIDs()).To(ConsistOf(
+				"route-emitter:app.example.com:10.0.0.1:61001",
+				"other-service",
+			))
+		})
+
+		It("deregisters its own instances when it is signalled", func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			Expect(serviceIDs()).To(ConsistOf("other-service"))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package consulcatalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// ServiceIDPrefix starts the IDs of the service instances the registrar
+	// registers. It leaves every other service of the agent alone.
+	ServiceIDPrefix = "route-emitter:"
+
+	ServiceTag = "route-emitter"
+)
+
+// buildServiceInstances returns the service instances of the entries, keyed
+// by ID: one service per hostname, with one instance per endpoint behind it.
+// Paths are dropped, as services have no use for them.
+func buildServiceInstances(entries []routingtable.NATSTableEntry) map[string]*api.AgentServiceRegistration {
+	instances := map[string]*api.AgentServiceRegistration{}
+	for _, entry := range entries {
+		for _, route := range entry.Routes {
+			hostname := routeHostname(route.Hostname)
+			for _, endpoint := range entry.Endpoints {
+				id := fmt.Sprintf("%s%s:%s:%d", ServiceIDPrefix, hostname, endpoint.Host, endpoint.Port)
+				if _, ok := instances[id]; ok {
+					continue
+				}
+
+				isolationSegment := route.IsolationSegment
+				if isolationSegment == "" {
+					isolationSegment = endpoint.IsolationSegment
+				}
+
+				tags := []string{
+					ServiceTag,
+					fmt.Sprintf("instance-index:%d", endpoint.Index),
+					"process-guid:" + entry.Key.ProcessGUID,
+				}
+				if isolationSegment != "" {
+					tags = append(tags, "isolation-segment:"+isolationSegment)
+				}
+
+				instances[id] = &api.AgentServiceRegistration{
+					ID:      id,
+					Name:    hostname,
+					Tags:    tags,
+					Address: endpoint.Host,
+					Port:    int(endpoint.Port),
+				}
+			}
+		}
+	}
+	return instances
+}
+
+// routeHostname returns the lower case hostname of a route.
+func routeHostname(route string) string {
+	if i := strings.Index(route, "/"); i >= 0 {
+		route = route[:i]
+	}
+	return strings.ToLower(route)
+}
+
+// sameInstance returns whether the agent already has the instance registered
+// as it is.
+func sameInstance(service *api.AgentService, instance *api.AgentServiceRegistration) bool {
+	if service.Service != instance.Name || service.Address != instance.Address || service.Port != instance.Port {
+		return false
+	}
+	if len(service.Tags) != len(instance.Tags) {
+		return false
+	}
+
+	registeredTags := append([]string{}, service.Tags...)
+	tags := append([]string{}, instance.Tags...)
+	sort.Strings(registeredTags)
+	sort.Strings(tags)
+	for i := range tags {
+		if registeredTags[i] != tags[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/miekg/dns"
+)
+
+type backend struct {
+	ip   net.IP
+	port uint32
+
+	// target is the name SRV records point at, which resolves to ip
+	target string
+}
+
+// records is what the server answers with: the backends of every hostname,
+// and the addresses of the SRV targets.
+type records struct {
+	backends map[string][]backend
+	targets  map[string]net.IP
+}
+
+// buildRecords indexes the entries by hostname. Paths are dropped, as DNS has
+// no use for them, and so are endpoints whose host is not an IP address.
+func buildRecords(entries []routingtable.NATSTableEntry) *records {
+	r := &records{
+		backends: map[string][]backend{},
+		targets:  map[string]net.IP{},
+	}
+
+	seen := map[string]map[string]bool{}
+	for _, entry := range entries {
+		for _, route := range entry.Routes {
+			hostname := routeHostname(route.Hostname)
+			if seen[hostname] == nil {
+				seen[hostname] = map[string]bool{}
+			}
+
+			for _, endpoint := range entry.Endpoints {
+				ip := net.ParseIP(endpoint.Host)
+				if ip == nil {
+					continue
+				}
+
+				address := fmt.Sprintf("%s:%d", ip, endpoint.Port)
+				if seen[hostname][address] {
+					continue
+				}
+				seen[hostname][address] = true
+
+				target := dns.Fqdn(strings.NewReplacer(".", "-", ":", "-").Replace(ip.String()) + "." + strings.TrimSuffix(hostname, "."))
+				r.backends[hostname] = append(r.backends[hostname], backend{ip: ip, port: endpoint.Port, target: target})
+				r.targets[target] = ip
+			}
+		}
+	}
+
+	for _, backends := range r.backends {
+		sort.Sort(byAddress(backends))
+	}
+	return r
+}
+
+// routeHostname returns the fully qualified, lower case hostname of a route.
+func routeHostname(route string) string {
+	if i := strings.Index(route, "/"); i >= 0 {
+		route = route[:i]
+	}
+	return dns.Fqdn(strings.ToLower(route))
+}
+
+type byAddress []backend
+
+func (s byAddress) Len() int      { return len(s) }
+func (s byAddress) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byAddress) Less(i, j int) bool {
+	if c := strings.Compare(s[i].ip.String(), s[j].ip.String()); c != 0 {
+		return c < 0
+	}
+	return s[i].port < s[j].port
+}
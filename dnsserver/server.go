@@ -0,0 +1,321 @@
+package dnsserver
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/miekg/dns"
+)
+
+const (
+	DefaultTTL      = 5 * time.Second
+	DefaultDebounce = 100 * time.Millisecond
+)
+
+// Config configures the DNS server. It is disabled without a listen address.
+// Without zones, it answers for every registered hostname. Without a TTL, the
+// answers carry DefaultTTL; a TTL of 0 tells resolvers not to cache them.
+type Config struct {
+	ListenAddress string                 `json:"listen_address,omitempty"`
+	Zones         []string               `json:"zones,omitempty"`
+	TTL           *durationjson.Duration `json:"ttl,omitempty"`
+	Debounce      durationjson.Duration  `json:"debounce,omitempty"`
+}
+
+func (c Config) Enabled() bool {
+	return c.ListenAddress != ""
+}
+
+// Server answers DNS queries, over UDP and TCP, for the hostnames registered
+// in the HTTP routing table: A and AAAA queries with the hosts of their
+// endpoints, and SRV queries with their hosts and ports. SRV queries may be
+// prefixed with service and protocol labels, such as _http._tcp.
+//
+// The answers are indexed off the query path: once when the server starts,
+// and then at most once per debounce interval while the table keeps
+// changing. Queries read whichever index was swapped in last.
+type Server struct {
+	logger        lager.Logger
+	clock         clock.Clock
+	listenAddress string
+	zones         []string
+	ttl           uint32
+	debounce      time.Duration
+	changed       chan struct{}
+
+	// records holds the *records queries are answered from
+	records atomic.Value
+
+	lock  sync.Mutex
+	table routingtable.NATSRoutingTable
+}
+
+func NewServer(logger lager.Logger, clock clock.Clock, config Config) *Server {
+	ttl := DefaultTTL
+	if config.TTL != nil && *config.TTL >= 0 {
+		ttl = time.Duration(*config.TTL)
+	}
+	debounce := time.Duration(config.Debounce)
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	zones := make([]string, 0, len(config.Zones))
+	for _, zone := range config.Zones {
+		zones = append(zones, dns.Fqdn(strings.ToLower(zone)))
+	}
+
+	s := &Server{
+		logger:        logger.Session("dns-server"),
+		clock:         clock,
+		listenAddress: config.ListenAddress,
+		zones:         zones,
+		ttl:           uint32(ttl.Seconds()),
+		debounce:      debounce,
+		changed:       make(chan struct{}, 1),
+	}
+	s.records.Store(buildRecords(nil))
+	return s
+}
+
+// NATSTable answers for the hostnames of the table, and returns the table
+// the emitter has to mutate for the answers to change with it.
+func (s *Server) NATSTable(table routingtable.NATSRoutingTable) routingtable.NATSRoutingTable {
+	s.lock.Lock()
+	s.table = table
+	s.lock.Unlock()
+
+	s.tableChanged()
+	return routingtable.NewObservedNATSTable(table, s.tableChanged)
+}
+
+func (s *Server) tableChanged() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// index rebuilds the records from the table as it is, and swaps them in.
+func (s *Server) index() {
+	s.lock.Lock()
+	table := s.table
+	s.lock.Unlock()
+
+	var entries []routingtable.NATSTableEntry
+	if table != nil {
+		entries = table.Snapshot()
+	}
+	s.records.Store(buildRecords(entries))
+}
+
+func (s *Server) currentRecords() *records {
+	return s.records.Load().(*records)
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := s.logger.Session("run", lager.Data{"listen-address": s.listenAddress, "zones": s.zones})
+	logger.Info("starting", lager.Data{"debounce": s.debounce.String()})
+
+	// the first index covers whatever changed until now
+	select {
+	case <-s.changed:
+	default:
+	}
+	s.index()
+
+	handler := dns.HandlerFunc(s.serveDNS)
+	started := make(chan struct{}, 2)
+	servers := []*dns.Server{
+		{Addr: s.listenAddress, Net: "udp", Handler: handler, NotifyStartedFunc: func() { started <- struct{}{} }},
+		{Addr: s.listenAddress, Net: "tcp", Handler: handler, NotifyStartedFunc: func() { started <- struct{}{} }},
+	}
+
+	errChan := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			errChan <- server.ListenAndServe()
+		}(server)
+	}
+
+	shutdown := func() {
+		for _, server := range servers {
+			server.Shutdown()
+		}
+	}
+
+	for range servers {
+		select {
+		case <-started:
+		case err := <-errChan:
+			logger.Error("failed-to-start", err)
+			shutdown()
+			return err
+		}
+	}
+
+	close(ready)
+	logger.Info("started")
+
+	var debounce clock.Timer
+	var debounced <-chan time.Time
+	stopDebounce := func() {
+		if debounce != nil {
+			debounce.Stop()
+		}
+	}
+
+	for {
+		select {
+		case <-s.changed:
+			if debounce == nil {
+				debounce = s.clock.NewTimer(s.debounce)
+				debounced = debounce.C()
+			}
+		case <-debounced:
+			debounce, debounced = nil, nil
+			s.index()
+		case err := <-errChan:
+			logger.Error("failed-to-serve", err)
+			stopDebounce()
+			shutdown()
+			return err
+		case <-signals:
+			logger.Info("stopping")
+			stopDebounce()
+			shutdown()
+			return nil
+		}
+	}
+}
+
+func (s *Server) serveDNS(w dns.ResponseWriter, request *dns.Msg) {
+	response := &dns.Msg{}
+	response.SetReply(request)
+	response.Authoritative = true
+
+	records := s.currentRecords()
+	for _, question := range request.Question {
+		name := strings.ToLower(question.Name)
+		if !s.inZones(name) {
+			response.Rcode = dns.RcodeRefused
+			break
+		}
+
+		answers, extra, found := s.answer(records, question.Qtype, question.Qclass, name, question.Name)
+		if !found {
+			response.Rcode = dns.RcodeNameError
+			continue
+		}
+		response.Answer = append(response.Answer, answers...)
+		response.Extra = append(response.Extra, extra...)
+	}
+
+	err := w.WriteMsg(response)
+	if err != nil {
+		s.logger.Error("failed-to-write-response", err)
+	}
+}
+
+func (s *Server) inZones(name string) bool {
+	if len(s.zones) == 0 {
+		return true
+	}
+	for _, zone := range s.zones {
+		if dns.IsSubDomain(zone, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// answer returns the records for a question, and false when the name does
+// not exist at all.
+func (s *Server) answer(r *records, qtype, qclass uint16, name, questionName string) ([]dns.RR, []dns.RR, bool) {
+	if qclass != dns.ClassINET && qclass != dns.ClassANY {
+		return nil, nil, true
+	}
+
+	header := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: questionName, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.ttl}
+	}
+
+	if ip, ok := r.targets[name]; ok {
+		return addressRecords(qtype, []backend{{ip: ip}}, header), nil, true
+	}
+
+	if qtype == dns.TypeSRV {
+		name = withoutServiceLabels(name)
+	}
+	backends, ok := r.backends[name]
+	if !ok {
+		return nil, nil, false
+	}
+
+	if qtype != dns.TypeSRV {
+		return addressRecords(qtype, backends, header), nil, true
+	}
+
+	answers := make([]dns.RR, 0, len(backends))
+	extra := make([]dns.RR, 0, len(backends))
+	for _, b := range backends {
+		answers = append(answers, &dns.SRV{
+			Hdr:      header(dns.TypeSRV),
+			Priority: 0,
+			Weight:   10,
+			Port:     uint16(b.port),
+			Target:   b.target,
+		})
+
+		targetHeader := func(rrtype uint16) dns.RR_Header {
+			return dns.RR_Header{Name: b.target, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.ttl}
+		}
+		rrtype := dns.TypeA
+		if b.ip.To4() == nil {
+			rrtype = dns.TypeAAAA
+		}
+		extra = append(extra, addressRecords(rrtype, []backend{b}, targetHeader)...)
+	}
+	return answers, extra, true
+}
+
+// addressRecords returns the A or AAAA records of the distinct IP addresses
+// of the backends.
+func addressRecords(qtype uint16, backends []backend, header func(uint16) dns.RR_Header) []dns.RR {
+	answers := []dns.RR{}
+	seen := map[string]bool{}
+	for _, b := range backends {
+		if seen[b.ip.String()] {
+			continue
+		}
+		seen[b.ip.String()] = true
+
+		ipv4 := b.ip.To4()
+		switch {
+		case (qtype == dns.TypeA || qtype == dns.TypeANY) && ipv4 != nil:
+			answers = append(answers, &dns.A{Hdr: header(dns.TypeA), A: ipv4})
+		case (qtype == dns.TypeAAAA || qtype == dns.TypeANY) && ipv4 == nil:
+			answers = append(answers, &dns.AAAA{Hdr: header(dns.TypeAAAA), AAAA: b.ip})
+		}
+	}
+	return answers
+}
+
+// withoutServiceLabels strips the leading underscored labels of SRV names.
+func withoutServiceLabels(name string) string {
+	for strings.HasPrefix(name, "_") {
+		i := strings.Index(name, ".")
+		if i < 0 {
+			return name
+		}
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package dnsserver_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"github.com/miekg/dns"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		logger        *lagertest.TestLogger
+		clock         *fakeclock.FakeClock
+		listenAddress string
+		config        dnsserver.Config
+		table         routingtable.NATSRoutingTable
+		process       ifrit.Process
+	)
+
+	key := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+
+	query := func(network, name string, qtype uint16) *dns.Msg {
+		request := &dns.Msg{}
+		request.SetQuestion(dns.Fqdn(name), qtype)
+
+		client := &dns.Client{Net: network}
+		response, _, err := client.Exchange(request, listenAddress)
+		Expect(err).NotTo(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		listenAddress = fmt.Sprintf("127.0.0.1:%d", 15353+GinkgoParallelNode())
+		ttl := durationjson.Duration(30 * time.Second)
+		config = dnsserver.Config{
+			ListenAddress: listenAddress,
+			Zones:         []string{"apps.internal"},
+			TTL:           &ttl,
+			Debounce:      durationjson.Duration(time.Second),
+		}
+	})
+
+	JustBeforeEach(func() {
+		server := dnsserver.NewServer(logger, clock, config)
+		table = server.NATSTable(routingtable.NewNATSTable(logger))
+		table.SetRoutes(key, []routingtable.Route{{Hostname: "app.apps.internal"}, {Hostname: "app.apps.internal/api"}}, nil)
+		table.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "10.0.0.1", Port: 61001, ContainerPort: 8080})
+		table.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-2", Host: "fd00::2", Port: 61002, ContainerPort: 8080})
+
+		process = ifrit.Invoke(server)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("answers A queries with the IPv4 endpoint hosts", func() {
+		response := query("udp", "app.apps.internal", dns.TypeA)
+		Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(response.Authoritative).To(BeTrue())
+		Expect(response.Answer).To(HaveLen(1))
+
+		a := response.Answer[0].(*dns.A)
+		Expect(a.A.String()).To(Equal("10.0.0.1"))
+		Expect(a.Hdr.Ttl).To(BeEquivalentTo(30))
+	})
+
+	It("answers AAAA queries with the IPv6 endpoint hosts", func() {
+		response := query("udp", "app.apps.internal", dns.TypeAAAA)
+		Expect(response.Answer).To(HaveLen(1))
+		Expect(response.Answer[0].(*dns.AAAA).AAAA.String()).To(Equal("fd00::2"))
+	})
+
+	It("answers SRV queries with the endpoint hosts and ports", func() {
+		response := query("tcp", "_http._tcp.app.apps.internal", dns.TypeSRV)
+		Expect(response.Answer).To(HaveLen(2))
+
+		srv := response.Answer[0].(*dns.SRV)
+		Expect(srv.Port).To(BeEquivalentTo(61001))
+		Expect(response.Extra).To(HaveLen(2))
+		Expect(response.Extra[0].Header().Name).To(Equal(srv.Target))
+		Expect(response.Extra[0].(*dns.A).A.String()).To(Equal("10.0.0.1"))
+
+		targetResponse := query("udp", srv.Target, dns.TypeA)
+		Expect(targetResponse.Answer).To(HaveLen(1))
+		Expect(targetResponse.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.1"))
+	})
+
+	It("updates the answers once the table stops changing for the debounce interval", func() {
+		table.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-3", Host: "10.0.0.3", Port: 61003, ContainerPort: 8080})
+		clock.WaitForWatcherAndIncrement(500 * time.Millisecond)
+		Consistently(func() []dns.RR { return query("udp", "app.apps.internal", dns.TypeA).Answer }).Should(HaveLen(1))
+
+		clock.Increment(500 * time.Millisecond)
+		Eventually(func() []dns.RR { return query("udp", "app.apps.internal", dns.TypeA).Answer }).Should(HaveLen(2))
+
+		table.RemoveEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "10.0.0.1", Port: 61001, ContainerPort: 8080})
+		table.RemoveEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-3", Host: "10.0.0.3", Port: 61003, ContainerPort: 8080})
+		other := endpoint.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+		table.SetRoutes(other, []routingtable.Route{{Hostname: "other.apps.internal"}}, nil)
+		table.AddEndpoint(other, routingtable.Endpoint{InstanceGuid: "ig-4", Host: "10.0.0.4", Port: 61004, ContainerPort: 8080})
+		clock.WaitForWatcherAndIncrement(time.Second)
+
+		Eventually(func() []dns.RR { return query("udp", "app.apps.internal", dns.TypeA).Answer }).Should(BeEmpty())
+		Expect(query("udp", "other.apps.internal", dns.TypeA).Answer).To(HaveLen(1))
+	})
+
+	Context("when the TTL is 0", func() {
+		BeforeEach(func() {
+			ttl := durationjson.Duration(0)
+			config.TTL = &ttl
+		})
+
+		It("answers with a TTL of 0", func() {
+			response := query("udp", "app.apps.internal", dns.TypeA)
+			Expect(response.Answer).To(HaveLen(1))
+			Expect(response.Answer[0].Header().Ttl).To(BeZero())
+		})
+	})
+
+	Context("when there is no TTL", func() {
+		BeforeEach(func() {
+			config.TTL = nil
+		})
+
+		It("answers with the default TTL", func() {
+			response := query("udp", "app.apps.internal", dns.TypeA)
+			Expect(response.Answer).To(HaveLen(1))
+			Expect(response.Answer[0].Header().Ttl).To(BeEquivalentTo(dnsserver.DefaultTTL.Seconds()))
+		})
+	})
+
+	It("answers NXDOMAIN for unknown hostnames in its zones", func() {
+		Expect(query("udp", "missing.apps.internal", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+	})
+
+	It("refuses to answer for names outside its zones", func() {
+		Expect(query("udp", "example.com", dns.TypeA).Rcode).To(Equal(dns.RcodeRefused))
+	})
+})
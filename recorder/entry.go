@@ -0,0 +1,67 @@
+package recorder
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+type EntryType string
+
+const (
+	HeaderEntry         EntryType = "header"
+	EventEntry          EntryType = "event"
+	SyncStartEntry      EntryType = "sync-start"
+	SyncBatchEntry      EntryType = "sync-batch"
+	SyncEntry           EntryType = "sync"
+	DesiredRefreshEntry EntryType = "desired-refresh"
+)
+
+var ErrMissingHeader = errors.New("recording does not start with a header")
+
+// Entry is a single line of a recording. Which fields are set depends on the
+// type of the entry.
+type Entry struct {
+	Timestamp     time.Time                          `json:"timestamp"`
+	Type          EntryType                          `json:"type"`
+	CellID        string                             `json:"cell_id,omitempty"`
+	Event         *RecordedEvent                     `json:"event,omitempty"`
+	Desired       []*models.DesiredLRPSchedulingInfo `json:"desired,omitempty"`
+	RunningActual []*endpoint.ActualLRPRoutingInfo   `json:"running_actual,omitempty"`
+	Domains       []string                           `json:"domains,omitempty"`
+	Error         string                             `json:"error,omitempty"`
+}
+
+// RecordedEvent is a BBS event in the same encoding the BBS uses on the event
+// stream: the event type and the base64 encoded protobuf payload.
+type RecordedEvent struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+func ReadEntries(reader io.Reader) ([]Entry, error) {
+	decoder := json.NewDecoder(reader)
+
+	entries := []Entry{}
+	for {
+		var entry Entry
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 || entries[0].Type != HeaderEntry {
+		return nil, ErrMissingHeader
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,135 @@
+package recorder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"github.com/gogo/protobuf/proto"
+)
+
+// FileRecorder writes everything the watcher reads from the BBS to a file,
+// one JSON encoded Entry per line.
+type FileRecorder struct {
+	logger lager.Logger
+	clock  clock.Clock
+
+	lock    sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+var _ watcher.Recorder = new(FileRecorder)
+
+func NewFileRecorder(logger lager.Logger, clock clock.Clock, path, cellID string) (*FileRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &FileRecorder{
+		logger:  logger.Session("recorder"),
+		clock:   clock,
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}
+
+	err = recorder.encoder.Encode(Entry{
+		Timestamp: clock.Now(),
+		Type:      HeaderEntry,
+		CellID:    cellID,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+func (r *FileRecorder) RecordEvent(event models.Event) {
+	data, err := proto.Marshal(event)
+	if err != nil {
+		r.logger.Error("failed-to-marshal-event", err, lager.Data{"type": event.EventType()})
+		return
+	}
+
+	r.write(Entry{
+		Type: EventEntry,
+		Event: &RecordedEvent{
+			Name: event.EventType(),
+			Data: base64.StdEncoding.EncodeToString(data),
+		},
+	})
+}
+
+func (r *FileRecorder) RecordSyncStart() {
+	r.write(Entry{Type: SyncStartEntry})
+}
+
+func (r *FileRecorder) RecordSyncBatch(desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo) {
+	r.write(Entry{
+		Type:          SyncBatchEntry,
+		Desired:       desired,
+		RunningActual: runningActual,
+	})
+}
+
+func (r *FileRecorder) RecordSync(
+	desired []*models.DesiredLRPSchedulingInfo,
+	runningActual []*endpoint.ActualLRPRoutingInfo,
+	domains models.DomainSet,
+	err error,
+) {
+	domainNames := make([]string, 0, len(domains))
+	for domain := range domains {
+		domainNames = append(domainNames, domain)
+	}
+
+	r.write(Entry{
+		Type:          SyncEntry,
+		Desired:       desired,
+		RunningActual: runningActual,
+		Domains:       domainNames,
+		Error:         errorString(err),
+	})
+}
+
+func (r *FileRecorder) RecordDesiredRefresh(desired []*models.DesiredLRPSchedulingInfo, err error) {
+	r.write(Entry{
+		Type:    DesiredRefreshEntry,
+		Desired: desired,
+		Error:   errorString(err),
+	})
+}
+
+func (r *FileRecorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.file.Close()
+}
+
+func (r *FileRecorder) write(entry Entry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entry.Timestamp = r.clock.Now()
+	err := r.encoder.Encode(entry)
+	if err != nil {
+		r.logger.Error("failed-to-record-entry", err, lager.Data{"type": entry.Type})
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,139 @@
+package recorder_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/recorder"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"github.com/gogo/protobuf/proto"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileRecorder", func() {
+	var (
+		logger       *lagertest.TestLogger
+		clock        *fakeclock.FakeClock
+		path         string
+		fileRecorder *recorder.FileRecorder
+	)
+
+	readEntries := func() []recorder.Entry {
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		entries, err := recorder.ReadEntries(file)
+		Expect(err).NotTo(HaveOccurred())
+		return entries
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Unix(1000, 0))
+
+		file, err := ioutil.TempFile("", "route-emitter-recording")
+		Expect(err).NotTo(HaveOccurred())
+		path = file.Name()
+		Expect(file.Close()).To(Succeed())
+
+		fileRecorder, err = recorder.NewFileRecorder(logger, clock, path, "cell-id")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(fileRecorder.Close()).To(Succeed())
+		Expect(os.Remove(path)).To(Succeed())
+	})
+
+	It("starts the recording with a header", func() {
+		entries := readEntries()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Type).To(Equal(recorder.HeaderEntry))
+		Expect(entries[0].CellID).To(Equal("cell-id"))
+		Expect(entries[0].Timestamp.Equal(time.Unix(1000, 0))).To(BeTrue())
+	})
+
+	It("records events in the encoding of the bbs event stream", func() {
+		event := models.NewActualLRPRemovedEvent(&models.ActualLRPGroup{
+			Instance: &models.ActualLRP{
+				ActualLRPKey: models.NewActualLRPKey("pg-1", 0, "domain"),
+				State:        models.ActualLRPStateRunning,
+			},
+		})
+
+		clock.Increment(time.Second)
+		fileRecorder.RecordEvent(event)
+
+		entries := readEntries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[1].Type).To(Equal(recorder.EventEntry))
+		Expect(entries[1].Timestamp.Equal(time.Unix(1001, 0))).To(BeTrue())
+		Expect(entries[1].Event.Name).To(Equal(models.EventTypeActualLRPRemoved))
+
+		data, err := base64.StdEncoding.DecodeString(entries[1].Event.Data)
+		Expect(err).NotTo(HaveOccurred())
+		decoded := &models.ActualLRPRemovedEvent{}
+		Expect(proto.Unmarshal(data, decoded)).To(Succeed())
+		Expect(decoded.ActualLrpGroup.Instance.ProcessGuid).To(Equal("pg-1"))
+	})
+
+	It("records syncs and desired lrp refreshes", func() {
+		desired := []*models.DesiredLRPSchedulingInfo{
+			{DesiredLRPKey: models.NewDesiredLRPKey("pg-1", "domain", "lg-1"), Instances: 1},
+		}
+		actuals := []*endpoint.ActualLRPRoutingInfo{
+			{
+				ActualLRP: &models.ActualLRP{
+					ActualLRPKey: models.NewActualLRPKey("pg-1", 0, "domain"),
+					State:        models.ActualLRPStateRunning,
+				},
+				Evacuating: true,
+			},
+		}
+
+		fileRecorder.RecordSyncStart()
+		fileRecorder.RecordSyncBatch(desired, actuals)
+		fileRecorder.RecordSync(desired, actuals, models.NewDomainSet([]string{"domain"}), nil)
+		fileRecorder.RecordDesiredRefresh(nil, errors.New("boom"))
+
+		entries := readEntries()
+		Expect(entries).To(HaveLen(5))
+		Expect(entries[1].Type).To(Equal(recorder.SyncStartEntry))
+
+		Expect(entries[2].Type).To(Equal(recorder.SyncBatchEntry))
+		Expect(entries[2].Desired).To(HaveLen(1))
+		Expect(entries[2].RunningActual).To(HaveLen(1))
+
+		Expect(entries[3].Type).To(Equal(recorder.SyncEntry))
+		Expect(entries[3].Desired[0].ProcessGuid).To(Equal("pg-1"))
+		Expect(entries[3].RunningActual[0].ActualLRP.ProcessGuid).To(Equal("pg-1"))
+		Expect(entries[3].RunningActual[0].Evacuating).To(BeTrue())
+		Expect(entries[3].Domains).To(ConsistOf("domain"))
+		Expect(entries[3].Error).To(BeEmpty())
+
+		Expect(entries[4].Type).To(Equal(recorder.DesiredRefreshEntry))
+		Expect(entries[4].Error).To(Equal("boom"))
+	})
+
+	Describe("ReadEntries", func() {
+		It("fails when the recording has no header", func() {
+			_, err := recorder.ReadEntries(strings.NewReader(`{"type":"sync-start"}`))
+			Expect(err).To(Equal(recorder.ErrMissingHeader))
+		})
+
+		It("fails on malformed entries", func() {
+			_, err := recorder.ReadEntries(strings.NewReader(`{"type":`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
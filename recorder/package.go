@@ -0,0 +1 @@
+package recorder // import "code.cloudfoundry.org/route-emitter/recorder"
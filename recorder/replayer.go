@@ -0,0 +1,315 @@
+package recorder
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"github.com/vito/go-sse/sse"
+)
+
+// Replayer feeds a recording through a Watcher. It stands in for the BBS,
+// answering the watcher's requests with the recorded responses, and for the
+// syncer, starting a sync wherever one was started in the recording. Time is
+// driven by a fake clock that follows the recorded timestamps.
+//
+// The replayer waits for the watcher to take each step before moving on, so
+// the route handler sees the same sequence of calls on every replay. Syncs
+// that failed in the recording are skipped, as they did not touch the route
+// handler.
+type Replayer struct {
+	logger  lager.Logger
+	entries []Entry
+	cellID  string
+	clock   *fakeclock.FakeClock
+
+	client     *replayClient
+	syncEvents syncer.Events
+	synced     chan struct{}
+
+	eventRequested bool
+	syncInFlight   bool
+}
+
+func NewReplayer(logger lager.Logger, entries []Entry) (*Replayer, error) {
+	if len(entries) == 0 || entries[0].Type != HeaderEntry {
+		return nil, ErrMissingHeader
+	}
+
+	return &Replayer{
+		logger:  logger.Session("replayer"),
+		entries: entries[1:],
+		cellID:  entries[0].CellID,
+		clock:   fakeclock.NewFakeClock(entries[0].Timestamp),
+		client: &replayClient{
+			source: &rawEventSource{
+				requested: make(chan struct{}),
+				events:    make(chan sse.Event),
+				closed:    make(chan struct{}),
+			},
+			actuals: make(chan actualsResponse),
+			desired: make(chan desiredResponse),
+			domains: make(chan domainsResponse),
+		},
+		syncEvents: syncer.Events{
+			Sync: make(chan struct{}),
+			Emit: make(chan struct{}),
+		},
+		synced: make(chan struct{}, 1),
+	}, nil
+}
+
+// CellID is the cell id the recording was made with, empty for a recording
+// made in global mode.
+func (r *Replayer) CellID() string {
+	return r.cellID
+}
+
+func (r *Replayer) Clock() clock.Clock {
+	return r.clock
+}
+
+// NewWatcher returns a watcher that reads from the recording and hands what it
+// reads to the given route handler. The watcher caches every event received
+// during a sync: a sync that was forced because the recorded watcher dropped
+// events is part of the recording, so the replaying watcher must not force
+// one of its own.
+func (r *Replayer) NewWatcher(routeHandler watcher.RouteHandler, logger lager.Logger) *watcher.Watcher {
+	return watcher.NewWatcher(
+		r.cellID,
+		r.client,
+		r.clock,
+		&replayHandler{RouteHandler: routeHandler, synced: r.synced},
+		r.syncEvents,
+		nil,
+		0,
+		math.MaxInt32,
+		0,
+		watcher.ActualLRPEvents{},
+		nil,
+		logger,
+	)
+}
+
+// Replay walks the recording and returns once the watcher has handled all of
+// it. The watcher returned by NewWatcher must be running.
+func (r *Replayer) Replay() error {
+	var pending *Entry
+	skipRefreshes := false
+
+	for i, entry := range r.entries {
+		r.advanceClock(entry.Timestamp)
+
+		if entry.Type != DesiredRefreshEntry {
+			skipRefreshes = false
+		}
+
+		switch entry.Type {
+		case EventEntry:
+			r.awaitSync()
+			r.deliverEvent(entry.Event)
+		case SyncStartEntry:
+			r.awaitSync()
+			if !syncSucceeded(r.entries[i+1:]) {
+				r.logger.Info("skipping-failed-sync", lager.Data{"timestamp": entry.Timestamp})
+				continue
+			}
+			r.syncEvents.Sync <- struct{}{}
+			pending = &Entry{}
+		case SyncBatchEntry:
+			if pending != nil {
+				pending.Desired = append(pending.Desired, entry.Desired...)
+				pending.RunningActual = append(pending.RunningActual, entry.RunningActual...)
+			}
+		case SyncEntry:
+			if pending == nil {
+				skipRefreshes = true
+				continue
+			}
+			pending.Desired = append(pending.Desired, entry.Desired...)
+			pending.RunningActual = append(pending.RunningActual, entry.RunningActual...)
+			pending.Domains = entry.Domains
+			r.deliverSync(pending)
+			pending = nil
+		case DesiredRefreshEntry:
+			if skipRefreshes {
+				continue
+			}
+			r.client.desired <- desiredResponse{
+				desired: entry.Desired,
+				err:     entryError(entry),
+			}
+		case HeaderEntry:
+			return errors.New("unexpected header in the middle of the recording")
+		}
+	}
+
+	r.awaitSync()
+	return nil
+}
+
+func (r *Replayer) advanceClock(timestamp time.Time) {
+	if d := timestamp.Sub(r.clock.Now()); d > 0 {
+		r.clock.Increment(d)
+	}
+}
+
+// deliverEvent hands the event to the watcher and returns once the watcher
+// has received it. The watcher asks for the next event only after it has
+// taken the previous one off the stream.
+func (r *Replayer) deliverEvent(event *RecordedEvent) {
+	source := r.client.source
+	if !r.eventRequested {
+		<-source.requested
+	}
+
+	source.events <- sse.Event{
+		Name: event.Name,
+		Data: []byte(event.Data),
+	}
+
+	<-source.requested
+	r.eventRequested = true
+}
+
+func (r *Replayer) deliverSync(entry *Entry) {
+	groups := make([]*models.ActualLRPGroup, 0, len(entry.RunningActual))
+	for _, info := range entry.RunningActual {
+		groups = append(groups, actualLRPGroup(info))
+	}
+
+	r.client.actuals <- actualsResponse{groups: groups}
+	// in local mode the watcher only asks for the desired lrps of the actual
+	// lrps running on the cell
+	if r.cellID == "" || len(groups) > 0 {
+		r.client.desired <- desiredResponse{desired: entry.Desired}
+	}
+	r.client.domains <- domainsResponse{domains: entry.Domains}
+
+	r.syncInFlight = true
+}
+
+func (r *Replayer) awaitSync() {
+	if r.syncInFlight {
+		<-r.synced
+		r.syncInFlight = false
+	}
+}
+
+func syncSucceeded(entries []Entry) bool {
+	for _, entry := range entries {
+		if entry.Type == SyncEntry {
+			return entry.Error == ""
+		}
+	}
+	return false
+}
+
+func actualLRPGroup(info *endpoint.ActualLRPRoutingInfo) *models.ActualLRPGroup {
+	if info.Evacuating {
+		return &models.ActualLRPGroup{Evacuating: info.ActualLRP}
+	}
+	return &models.ActualLRPGroup{Instance: info.ActualLRP}
+}
+
+func entryError(entry Entry) error {
+	if entry.Error == "" {
+		return nil
+	}
+	return errors.New(entry.Error)
+}
+
+type replayHandler struct {
+	watcher.RouteHandler
+	synced chan<- struct{}
+}
+
+func (h *replayHandler) Sync(
+	logger lager.Logger,
+	desired []*models.DesiredLRPSchedulingInfo,
+	runningActual []*endpoint.ActualLRPRoutingInfo,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	h.RouteHandler.Sync(logger, desired, runningActual, domains, cachedEvents)
+	h.synced <- struct{}{}
+}
+
+type actualsResponse struct {
+	groups []*models.ActualLRPGroup
+}
+
+type desiredResponse struct {
+	desired []*models.DesiredLRPSchedulingInfo
+	err     error
+}
+
+type domainsResponse struct {
+	domains []string
+}
+
+// replayClient implements the parts of the BBS client used by the watcher.
+// Any other call panics on the nil embedded client.
+type replayClient struct {
+	bbs.Client
+
+	source  *rawEventSource
+	actuals chan actualsResponse
+	desired chan desiredResponse
+	domains chan domainsResponse
+}
+
+func (c *replayClient) SubscribeToEvents(logger lager.Logger) (events.EventSource, error) {
+	return events.NewEventSource(c.source), nil
+}
+
+func (c *replayClient) ActualLRPGroups(logger lager.Logger, filter models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+	response := <-c.actuals
+	return response.groups, nil
+}
+
+func (c *replayClient) DesiredLRPSchedulingInfos(logger lager.Logger, filter models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+	response := <-c.desired
+	return response.desired, response.err
+}
+
+func (c *replayClient) Domains(logger lager.Logger) ([]string, error) {
+	response := <-c.domains
+	return response.domains, nil
+}
+
+type rawEventSource struct {
+	requested chan struct{}
+	events    chan sse.Event
+	closed    chan struct{}
+}
+
+func (s *rawEventSource) Next() (sse.Event, error) {
+	select {
+	case s.requested <- struct{}{}:
+	case <-s.closed:
+		return sse.Event{}, io.EOF
+	}
+
+	select {
+	case event := <-s.events:
+		return event, nil
+	case <-s.closed:
+		return sse.Event{}, io.EOF
+	}
+}
+
+func (s *rawEventSource) Close() error {
+	close(s.closed)
+	return nil
+}
@@ -0,0 +1,182 @@
+package recorder_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/recorder"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher/fakes"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replayer", func() {
+	var (
+		logger       *lagertest.TestLogger
+		clock        *fakeclock.FakeClock
+		path         string
+		fileRecorder *recorder.FileRecorder
+		routeHandler *fakes.FakeRouteHandler
+		process      ifrit.Process
+
+		desired      []*models.DesiredLRPSchedulingInfo
+		actuals      []*endpoint.ActualLRPRoutingInfo
+		removedEvent models.Event
+	)
+
+	replay := func() *recorder.Replayer {
+		Expect(fileRecorder.Close()).To(Succeed())
+
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		entries, err := recorder.ReadEntries(file)
+		Expect(err).NotTo(HaveOccurred())
+
+		replayer, err := recorder.NewReplayer(logger, entries)
+		Expect(err).NotTo(HaveOccurred())
+
+		process = ifrit.Invoke(replayer.NewWatcher(routeHandler, logger))
+		Expect(replayer.Replay()).To(Succeed())
+		return replayer
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Unix(1000, 0))
+		routeHandler = new(fakes.FakeRouteHandler)
+
+		file, err := ioutil.TempFile("", "route-emitter-recording")
+		Expect(err).NotTo(HaveOccurred())
+		path = file.Name()
+		Expect(file.Close()).To(Succeed())
+
+		fileRecorder, err = recorder.NewFileRecorder(logger, clock, path, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		desired = []*models.DesiredLRPSchedulingInfo{
+			{DesiredLRPKey: models.NewDesiredLRPKey("pg-1", "domain", "lg-1"), Instances: 1},
+		}
+		actualLRPGroup := &models.ActualLRPGroup{
+			Instance: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey("pg-1", 0, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey("ig-1", "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.NewPortMapping(61000, 8080)),
+				State:                models.ActualLRPStateRunning,
+			},
+		}
+		actuals = []*endpoint.ActualLRPRoutingInfo{endpoint.NewActualLRPRoutingInfo(actualLRPGroup)}
+		removedEvent = models.NewActualLRPRemovedEvent(actualLRPGroup)
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			process = nil
+		}
+		Expect(os.Remove(path)).To(Succeed())
+	})
+
+	It("fails without a header", func() {
+		Expect(fileRecorder.Close()).To(Succeed())
+		_, err := recorder.NewReplayer(logger, []recorder.Entry{{Type: recorder.SyncStartEntry}})
+		Expect(err).To(Equal(recorder.ErrMissingHeader))
+	})
+
+	It("syncs the route handler with the recorded state", func() {
+		fileRecorder.RecordSyncStart()
+		fileRecorder.RecordSync(desired, actuals, models.NewDomainSet([]string{"domain"}), nil)
+
+		replay()
+
+		Expect(routeHandler.SyncCallCount()).To(Equal(1))
+		_, syncedDesired, syncedActuals, domains, cachedEvents := routeHandler.SyncArgsForCall(0)
+		Expect(syncedDesired).To(HaveLen(1))
+		Expect(syncedDesired[0].ProcessGuid).To(Equal("pg-1"))
+		Expect(syncedActuals).To(HaveLen(1))
+		Expect(syncedActuals[0].ActualLRP.InstanceGuid).To(Equal("ig-1"))
+		Expect(domains).To(Equal(models.NewDomainSet([]string{"domain"})))
+		Expect(cachedEvents).To(BeEmpty())
+	})
+
+	It("hands recorded events to the route handler in order", func() {
+		fileRecorder.RecordSyncStart()
+		fileRecorder.RecordSync(desired, actuals, nil, nil)
+		clock.Increment(time.Minute)
+		fileRecorder.RecordEvent(removedEvent)
+
+		replayer := replay()
+
+		Expect(routeHandler.SyncCallCount()).To(Equal(1))
+		Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+		_, event := routeHandler.HandleEventArgsForCall(0)
+		Expect(event.EventType()).To(Equal(models.EventTypeActualLRPRemoved))
+		Expect(event.Key()).To(Equal(removedEvent.Key()))
+
+		Expect(replayer.Clock().Now().Equal(time.Unix(1060, 0))).To(BeTrue())
+	})
+
+	It("caches events that were received during a sync", func() {
+		fileRecorder.RecordSyncStart()
+		fileRecorder.RecordEvent(removedEvent)
+		fileRecorder.RecordSync(desired, actuals, nil, nil)
+
+		replay()
+
+		Expect(routeHandler.SyncCallCount()).To(Equal(1))
+		_, _, _, _, cachedEvents := routeHandler.SyncArgsForCall(0)
+		Expect(cachedEvents).To(HaveLen(1))
+		Expect(cachedEvents[0].Key()).To(Equal(removedEvent.Key()))
+		Expect(routeHandler.HandleEventCallCount()).To(Equal(0))
+	})
+
+	It("replays batched syncs as a single sync", func() {
+		fileRecorder.RecordSyncStart()
+		fileRecorder.RecordSyncBatch(desired, actuals)
+		fileRecorder.RecordSyncBatch(desired, nil)
+		fileRecorder.RecordSync(nil, nil, nil, nil)
+
+		replay()
+
+		Expect(routeHandler.SyncCallCount()).To(Equal(1))
+		_, syncedDesired, syncedActuals, _, _ := routeHandler.SyncArgsForCall(0)
+		Expect(syncedDesired).To(HaveLen(2))
+		Expect(syncedActuals).To(HaveLen(1))
+	})
+
+	It("skips syncs that failed", func() {
+		fileRecorder.RecordSyncStart()
+		fileRecorder.RecordSync(nil, nil, nil, errors.New("boom"))
+		fileRecorder.RecordEvent(removedEvent)
+
+		replay()
+
+		Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+		Expect(routeHandler.SyncCallCount()).To(Equal(0))
+	})
+
+	It("answers desired lrp refreshes with the recorded response", func() {
+		routeHandler.ShouldRefreshDesiredReturns(true)
+		changedEvent := models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: actuals[0].ActualLRP})
+
+		fileRecorder.RecordEvent(changedEvent)
+		fileRecorder.RecordDesiredRefresh(desired, nil)
+
+		replay()
+
+		Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+		Expect(routeHandler.RefreshDesiredCallCount()).To(Equal(1))
+		_, refreshed := routeHandler.RefreshDesiredArgsForCall(0)
+		Expect(refreshed[0].ProcessGuid).To(Equal("pg-1"))
+	})
+})
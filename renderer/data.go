@@ -0,0 +1,131 @@
+package renderer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+// Data is what the templates are executed with: a consistent snapshot of
+// the HTTP and TCP routing tables, holding only the entries that have both
+// routes and endpoints.
+type Data struct {
+	HTTP []routingtable.NATSTableEntry
+	TCP  []routingtable.TCPTableEntry
+}
+
+// Hostname is an HTTP hostname and its routes, for proxies that configure
+// virtual hosts and cannot have two of them for the same name.
+type Hostname struct {
+	Name   string
+	Routes []HostnameRoute
+}
+
+// HostnameRoute is a path of a hostname, with the endpoints of every routing
+// key that routes it.
+type HostnameRoute struct {
+	// Name is unique to the hostname and path, and safe to use as an
+	// identifier in proxy configurations.
+	Name            string
+	Path            string
+	RouteServiceUrl string
+	Endpoints       []routingtable.Endpoint
+}
+
+// Hostnames groups the HTTP routes by hostname. The routes of a hostname are
+// ordered by path, longest first.
+func (d Data) Hostnames() []Hostname {
+	routes := map[string]map[string]*HostnameRoute{}
+	for _, entry := range d.HTTP {
+		for _, route := range entry.Routes {
+			hostname, path := routeHost(route.Hostname), routePath(route.Hostname)
+			if routes[hostname] == nil {
+				routes[hostname] = map[string]*HostnameRoute{}
+			}
+
+			hostnameRoute, ok := routes[hostname][path]
+			if !ok {
+				hostnameRoute = &HostnameRoute{
+					Name: safeName(route.Hostname),
+					Path: path,
+				}
+				routes[hostname][path] = hostnameRoute
+			}
+			if hostnameRoute.RouteServiceUrl == "" {
+				hostnameRoute.RouteServiceUrl = route.RouteServiceUrl
+			}
+			hostnameRoute.Endpoints = append(hostnameRoute.Endpoints, entry.Endpoints...)
+		}
+	}
+
+	hostnames := make([]Hostname, 0, len(routes))
+	for name, paths := range routes {
+		hostname := Hostname{Name: name}
+		for _, route := range paths {
+			hostname.Routes = append(hostname.Routes, *route)
+		}
+		sort.Sort(byPathLength(hostname.Routes))
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Sort(byName(hostnames))
+	return hostnames
+}
+
+var funcs = template.FuncMap{
+	"backendName": backendName,
+	"routeHost":   routeHost,
+	"routePath":   routePath,
+	"safeName":    safeName,
+}
+
+// routeHost returns the hostname of a route, without its path.
+func routeHost(route string) string {
+	i := strings.Index(route, "/")
+	if i < 0 {
+		return route
+	}
+	return route[:i]
+}
+
+// routePath returns the path of a route, or / when it has none.
+func routePath(route string) string {
+	i := strings.Index(route, "/")
+	if i < 0 {
+		return "/"
+	}
+	return route[i:]
+}
+
+var unsafeCharacters = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// safeName makes any string usable as an identifier in proxy configurations.
+func safeName(s string) string {
+	return unsafeCharacters.ReplaceAllString(s, "_")
+}
+
+// backendName names the backend of a routing key.
+func backendName(key endpoint.RoutingKey) string {
+	return fmt.Sprintf("%s_%d", safeName(key.ProcessGUID), key.ContainerPort)
+}
+
+type byName []Hostname
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+type byPathLength []HostnameRoute
+
+func (s byPathLength) Len() int      { return len(s) }
+func (s byPathLength) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPathLength) Less(i, j int) bool {
+	if len(s[i].Path) != len(s[j].Path) {
+		return len(s[i].Path) > len(s[j].Path)
+	}
+	return s[i].Path < s[j].Path
+}
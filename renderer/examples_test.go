@@ -0,0 +1,96 @@
+package renderer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/renderer"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Examples", func() {
+	var (
+		logger *lagertest.TestLogger
+		dir    string
+	)
+
+	render := func(example string) string {
+		destination := filepath.Join(dir, example)
+		r, err := renderer.NewRenderer(logger, fakeclock.NewFakeClock(time.Now()), renderer.Config{
+			Templates: []renderer.TemplateConfig{{
+				Source:      filepath.Join("examples", example+".tmpl"),
+				Destination: destination,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		httpKey := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+		natsTable := r.NATSTable(routingtable.NewNATSTable(logger))
+		natsTable.SetRoutes(httpKey, []routingtable.Route{
+			{Hostname: "a.example.com"},
+			{Hostname: "a.example.com/api"},
+			{Hostname: "b.example.com", RouteServiceUrl: "https://rs.example.com"},
+		}, nil)
+		natsTable.AddEndpoint(httpKey, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "1.1.1.1", Port: 61001, ContainerPort: 8080})
+
+		tcpKey := endpoint.RoutingKey{ProcessGUID: "tcp-process-guid", ContainerPort: 5222}
+		r.TCPTable(routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
+			tcpKey: {
+				ExternalEndpoints: endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 6000)},
+				Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+					endpoint.NewEndpointKey("ig-2", false): endpoint.NewEndpoint("ig-2", false, "1.1.1.2", 61002, 5222, nil),
+				},
+			},
+		}))
+
+		process := ifrit.Invoke(r)
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+
+		contents, err := ioutil.ReadFile(destination)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+
+		var err error
+		dir, err = ioutil.TempDir("", "examples")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("renders the HAProxy example", func() {
+		config := render("haproxy.cfg")
+		Expect(config).To(ContainSubstring("use_backend process-guid_8080 if { hdr(host) -i a.example.com } { path_beg /api }"))
+		Expect(config).To(ContainSubstring("# b.example.com is bound to the route service https://rs.example.com"))
+		Expect(config).To(ContainSubstring("server ig-1 1.1.1.1:61001 check"))
+		Expect(config).To(ContainSubstring("bind *:6000"))
+		Expect(config).To(ContainSubstring("server ig-2 1.1.1.2:61002 check"))
+	})
+
+	It("renders the nginx examples", func() {
+		config := render("nginx.conf")
+		Expect(config).To(ContainSubstring("upstream a_example_com_api {\n    server 1.1.1.1:61001;\n}"))
+		Expect(config).To(ContainSubstring("server_name a.example.com;"))
+		Expect(config).To(ContainSubstring("location /api {"))
+		Expect(config).To(ContainSubstring("proxy_pass https://rs.example.com;"))
+
+		config = render("nginx-stream.conf")
+		Expect(config).To(ContainSubstring("listen 6000;"))
+		Expect(config).To(ContainSubstring("server 1.1.1.2:61002;"))
+	})
+})
@@ -0,0 +1 @@
+package renderer // import "code.cloudfoundry.org/route-emitter/renderer"
@@ -0,0 +1,279 @@
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+const DefaultDebounce = time.Second
+
+var (
+	renderFailures = metric.Counter("RouteEmitterRenderFailures")
+	reloadFailures = metric.Counter("RouteEmitterReloadFailures")
+)
+
+// Config configures the templates the routing tables are rendered through.
+type Config struct {
+	Templates []TemplateConfig      `json:"templates,omitempty"`
+	Debounce  durationjson.Duration `json:"debounce,omitempty"`
+}
+
+// TemplateConfig renders the Go template at Source into Destination, and
+// runs ReloadCommand whenever that changes the file.
+type TemplateConfig struct {
+	Source        string   `json:"source"`
+	Destination   string   `json:"destination"`
+	ReloadCommand []string `json:"reload_command,omitempty"`
+}
+
+func (c Config) Enabled() bool {
+	return len(c.Templates) > 0
+}
+
+type target struct {
+	template      *template.Template
+	destination   string
+	reloadCommand []string
+
+	// the contents of the destination, once read or written
+	rendered []byte
+}
+
+// Renderer renders the routing tables into files. It renders once when it
+// starts, and then at most once per debounce interval while the tables keep
+// changing. Files are replaced atomically and only when their contents
+// change, and each distinct reload command runs once per render that changed
+// any of its files.
+type Renderer struct {
+	logger   lager.Logger
+	clock    clock.Clock
+	debounce time.Duration
+	targets  []*target
+	changed  chan struct{}
+
+	lock      sync.Mutex
+	natsTable routingtable.NATSRoutingTable
+	tcpTable  routingtable.TCPRoutingTable
+}
+
+func NewRenderer(logger lager.Logger, clock clock.Clock, config Config) (*Renderer, error) {
+	debounce := time.Duration(config.Debounce)
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	targets := make([]*target, 0, len(config.Templates))
+	for _, templateConfig := range config.Templates {
+		if templateConfig.Destination == "" {
+			return nil, fmt.Errorf("template %q has no destination", templateConfig.Source)
+		}
+
+		t, err := template.New(filepath.Base(templateConfig.Source)).Funcs(funcs).ParseFiles(templateConfig.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, &target{
+			template:      t,
+			destination:   templateConfig.Destination,
+			reloadCommand: templateConfig.ReloadCommand,
+		})
+	}
+
+	return &Renderer{
+		logger:   logger.Session("renderer"),
+		clock:    clock,
+		debounce: debounce,
+		targets:  targets,
+		changed:  make(chan struct{}, 1),
+	}, nil
+}
+
+// NATSTable renders the HTTP routes of the table, and returns the table the
+// emitter has to mutate for the renderer to notice.
+func (r *Renderer) NATSTable(table routingtable.NATSRoutingTable) routingtable.NATSRoutingTable {
+	r.lock.Lock()
+	r.natsTable = table
+	r.lock.Unlock()
+
+	r.tableChanged()
+	return routingtable.NewObservedNATSTable(table, r.tableChanged)
+}
+
+// TCPTable renders the TCP routes of the table, and returns the table the
+// emitter has to mutate for the renderer to notice.
+func (r *Renderer) TCPTable(table routingtable.TCPRoutingTable) routingtable.TCPRoutingTable {
+	r.lock.Lock()
+	r.tcpTable = table
+	r.lock.Unlock()
+
+	r.tableChanged()
+	return routingtable.NewObservedTCPTable(table, r.tableChanged)
+}
+
+func (r *Renderer) tableChanged() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Renderer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := r.logger.Session("run")
+	logger.Info("starting", lager.Data{"debounce": r.debounce.String()})
+
+	// the first render covers whatever changed until now
+	select {
+	case <-r.changed:
+	default:
+	}
+	r.render(logger)
+
+	close(ready)
+	logger.Info("started")
+
+	var debounce clock.Timer
+	var debounced <-chan time.Time
+	for {
+		select {
+		case <-r.changed:
+			if debounce == nil {
+				debounce = r.clock.NewTimer(r.debounce)
+				debounced = debounce.C()
+			}
+		case <-debounced:
+			debounce, debounced = nil, nil
+			r.render(logger)
+		case <-signals:
+			logger.Info("stopping")
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return nil
+		}
+	}
+}
+
+func (r *Renderer) snapshot() Data {
+	r.lock.Lock()
+	natsTable, tcpTable := r.natsTable, r.tcpTable
+	r.lock.Unlock()
+
+	data := Data{}
+	if natsTable != nil {
+		data.HTTP = natsTable.Snapshot()
+	}
+	if tcpTable != nil {
+		data.TCP = tcpTable.Snapshot()
+	}
+	return data
+}
+
+func (r *Renderer) render(logger lager.Logger) {
+	data := r.snapshot()
+
+	reloads := [][]string{}
+	reloadsSeen := map[string]bool{}
+	for _, t := range r.targets {
+		logger := logger.WithData(lager.Data{"destination": t.destination})
+
+		changed, err := t.render(data)
+		if err != nil {
+			renderFailures.Increment()
+			logger.Error("failed-to-render", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		logger.Info("rendered", lager.Data{"http-entries": len(data.HTTP), "tcp-entries": len(data.TCP)})
+
+		key := strings.Join(t.reloadCommand, "\x00")
+		if len(t.reloadCommand) > 0 && !reloadsSeen[key] {
+			reloadsSeen[key] = true
+			reloads = append(reloads, t.reloadCommand)
+		}
+	}
+
+	for _, command := range reloads {
+		output, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			reloadFailures.Increment()
+			logger.Error("failed-to-reload", err, lager.Data{"command": command, "output": string(output)})
+			continue
+		}
+		logger.Info("reloaded", lager.Data{"command": command})
+	}
+}
+
+// render writes the destination when the template renders anything new, and
+// returns whether it did.
+func (t *target) render(data Data) (bool, error) {
+	buffer := &bytes.Buffer{}
+	err := t.template.Execute(buffer, data)
+	if err != nil {
+		return false, err
+	}
+
+	if t.rendered == nil {
+		// do not reload for a file left by a previous run
+		existing, err := ioutil.ReadFile(t.destination)
+		if err == nil {
+			t.rendered = existing
+		}
+	}
+	if t.rendered != nil && bytes.Equal(buffer.Bytes(), t.rendered) {
+		return false, nil
+	}
+
+	err = writeFileAtomically(t.destination, buffer.Bytes())
+	if err != nil {
+		return false, err
+	}
+	t.rendered = buffer.Bytes()
+	return true, nil
+}
+
+// writeFileAtomically writes to a temporary file next to the destination and
+// renames it over the destination, so that readers only ever see either file
+// in full.
+func writeFileAtomically(destination string, contents []byte) error {
+	temp, err := ioutil.TempFile(filepath.Dir(destination), "."+filepath.Base(destination)+".")
+	if err != nil {
+		return err
+	}
+
+	_, err = temp.Write(contents)
+	if err == nil {
+		err = temp.Sync()
+	}
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), destination)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package renderer_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRenderer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Renderer Suite")
+}
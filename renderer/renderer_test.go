@@ -0,0 +1,150 @@
+package renderer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/renderer"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Renderer", func() {
+	var (
+		logger      *lagertest.TestLogger
+		clock       *fakeclock.FakeClock
+		dir         string
+		source      string
+		destination string
+		reloads     string
+		config      renderer.Config
+		r           *renderer.Renderer
+		natsTable   routingtable.NATSRoutingTable
+		process     ifrit.Process
+	)
+
+	key := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+
+	rendered := func() string {
+		contents, err := ioutil.ReadFile(destination)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	reloadCount := func() int {
+		contents, err := ioutil.ReadFile(reloads)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return len(contents)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+
+		var err error
+		dir, err = ioutil.TempDir("", "renderer")
+		Expect(err).NotTo(HaveOccurred())
+
+		source = filepath.Join(dir, "routes.tmpl")
+		destination = filepath.Join(dir, "routes.conf")
+		reloads = filepath.Join(dir, "reloads")
+		Expect(ioutil.WriteFile(source, []byte(
+			`{{range .HTTP}}{{range .Routes}}{{.Hostname}}{{end}}{{range .Endpoints}} {{.Host}}:{{.Port}}{{end}};{{end}}`,
+		), 0644)).To(Succeed())
+
+		config = renderer.Config{
+			Debounce: durationjson.Duration(time.Second),
+			Templates: []renderer.TemplateConfig{{
+				Source:        source,
+				Destination:   destination,
+				ReloadCommand: []string{"sh", "-c", "printf x >> " + reloads},
+			}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		r, err = renderer.NewRenderer(logger, clock, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		natsTable = r.NATSTable(routingtable.NewNATSTable(logger))
+		natsTable.SetRoutes(key, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+		natsTable.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "1.1.1.1", Port: 61001, ContainerPort: 8080})
+
+		process = ifrit.Invoke(r)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		os.RemoveAll(dir)
+	})
+
+	It("renders the tables and reloads when it starts", func() {
+		Expect(rendered()).To(Equal("a.example.com 1.1.1.1:61001;"))
+		Expect(reloadCount()).To(Equal(1))
+	})
+
+	It("renders the tables again once the changes settle", func() {
+		natsTable.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-2", Host: "1.1.1.2", Port: 61002, ContainerPort: 8080})
+		clock.WaitForWatcherAndIncrement(500 * time.Millisecond)
+		natsTable.RemoveEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "1.1.1.1", Port: 61001, ContainerPort: 8080})
+		Consistently(rendered).Should(Equal("a.example.com 1.1.1.1:61001;"))
+
+		clock.Increment(500 * time.Millisecond)
+		Eventually(rendered).Should(Equal("a.example.com 1.1.1.2:61002;"))
+		Eventually(reloadCount).Should(Equal(2))
+
+		matches, err := filepath.Glob(filepath.Join(dir, ".routes.conf.*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeEmpty())
+	})
+
+	It("does not reload when nothing rendered changes", func() {
+		natsTable.SetRoutes(key, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+		clock.WaitForWatcherAndIncrement(time.Second)
+
+		Consistently(reloadCount).Should(Equal(1))
+	})
+
+	Context("when the destination is already up to date", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(destination, []byte("a.example.com 1.1.1.1:61001;"), 0644)).To(Succeed())
+		})
+
+		It("does not reload when it starts", func() {
+			Expect(reloadCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the reload command fails", func() {
+		BeforeEach(func() {
+			config.Templates[0].ReloadCommand = []string{"sh", "-c", "echo broken; exit 1"}
+		})
+
+		It("logs the output", func() {
+			Expect(logger).To(gbytes.Say("failed-to-reload.*broken"))
+		})
+	})
+
+	Context("when the template cannot be parsed", func() {
+		It("fails to build the renderer", func() {
+			Expect(ioutil.WriteFile(source, []byte("{{range}}"), 0644)).To(Succeed())
+			_, err := renderer.NewRenderer(logger, clock, config)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
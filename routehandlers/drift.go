@@ -0,0 +1,56 @@
+package routehandlers
+
+import (
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+var (
+	httpDriftMetrics = driftMetrics{
+		routesAdded:      metric.Metric("HTTPRouteDriftAdded"),
+		routesRemoved:    metric.Metric("HTTPRouteDriftRemoved"),
+		endpointsAdded:   metric.Metric("HTTPEndpointDriftAdded"),
+		endpointsRemoved: metric.Metric("HTTPEndpointDriftRemoved"),
+	}
+
+	tcpDriftMetrics = driftMetrics{
+		routesAdded:      metric.Metric("TCPRouteDriftAdded"),
+		routesRemoved:    metric.Metric("TCPRouteDriftRemoved"),
+		endpointsAdded:   metric.Metric("TCPEndpointDriftAdded"),
+		endpointsRemoved: metric.Metric("TCPEndpointDriftRemoved"),
+	}
+)
+
+type driftMetrics struct {
+	routesAdded      metric.Metric
+	routesRemoved    metric.Metric
+	endpointsAdded   metric.Metric
+	endpointsRemoved metric.Metric
+}
+
+// reportDrift sends the drift found by a sync as metrics, on every sync so
+// that the metrics drop back to zero, and logs a sample of the drifted
+// routing keys.
+func reportDrift(logger lager.Logger, drift routingtable.Drift, metrics driftMetrics) {
+	if !drift.Empty() {
+		logger.Info("sync-drift-detected", lager.Data{
+			"routes-added":      drift.RoutesAdded,
+			"routes-removed":    drift.RoutesRemoved,
+			"endpoints-added":   drift.EndpointsAdded,
+			"endpoints-removed": drift.EndpointsRemoved,
+			"routing-keys":      drift.RoutingKeys,
+		})
+	}
+
+	for m, value := range map[metric.Metric]int{
+		metrics.routesAdded:      drift.RoutesAdded,
+		metrics.routesRemoved:    drift.RoutesRemoved,
+		metrics.endpointsAdded:   drift.EndpointsAdded,
+		metrics.endpointsRemoved: drift.EndpointsRemoved,
+	} {
+		if err := m.Send(value); err != nil {
+			logger.Error("failed-to-send-drift-metric", err, lager.Data{"metric": string(m)})
+		}
+	}
+}
@@ -1,28 +1,155 @@
 package routehandlers
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+// DefaultHandlerTimeout is how long the MultiHandler waits for a sub handler
+// to handle an event or emit before it moves on without it.
+const DefaultHandlerTimeout = 10 * time.Second
+
+// handlerQueueSize bounds the calls queued for a sub handler that has fallen
+// behind. Further calls to it are dropped until it catches up, and a sync is
+// requested to repair its table.
+const handlerQueueSize = 1024
+
+var (
+	handlerTimeouts     = metric.Counter("RouteEmitterHandlerTimeouts")
+	handlerPanics       = metric.Counter("RouteEmitterHandlerPanics")
+	handlerCallsDropped = metric.Counter("RouteEmitterHandlerCallsDropped")
 )
 
+// NamedHandler is a sub handler of a MultiHandler. The name tells the sub
+// handlers apart in the logs and in the name of the duration metric of the
+// sub handler, RouteEmitter<name>Duration.
+type NamedHandler struct {
+	Name    string
+	Handler watcher.RouteHandler
+}
+
+// callMode tells how the MultiHandler waits for a call, and whether it may
+// drop it.
+type callMode int
+
+const (
+	// waited for at most the handler timeout, and dropped when the sub
+	// handler has fallen behind, as the next sync repairs what it missed
+	callTimed callMode = iota
+	// waited for at most the handler timeout, and queued however far behind
+	// the sub handler is, as a sync does not repair what it changes
+	callTimedUndroppable
+	// waited for until the sub handler finishes it
+	callUntimed
+	// waited for until the sub handler finishes it, and repairs whatever the
+	// sub handler missed
+	callSync
+)
+
+// MultiHandler fans calls out to several route handlers. Each sub handler runs
+// on its own goroutine, so that a slow one does not hold up the others, and
+// handles its calls one at a time and in order, as if it were called by the
+// watcher directly.
+//
+// Events, emits and refreshes wait at most the handler timeout for each sub
+// handler, and not at all for one that is still busy with an earlier call.
+// Syncs wait for every sub handler to finish. A sub handler that panics is
+// logged and keeps receiving calls.
+//
+// A sub handler whose queue is full is marked dirty: the MultiHandler
+// requests a sync, and skips the calls that a sync repairs until the sub
+// handler is handed one.
 type MultiHandler struct {
-	handlers []watcher.RouteHandler
+	clock   clock.Clock
+	timeout time.Duration
+	workers []*handlerWorker
+	logger  lager.Logger
+
+	syncRequests chan struct{}
+	stopped      chan struct{}
+	stopOnce     sync.Once
 }
 
-var _ watcher.RouteHandler = new(MultiHandler)
+var _ watcher.BatchRouteHandler = new(MultiHandler)
+var _ watcher.HostSuppressor = new(MultiHandler)
+var _ watcher.SyncRequester = new(MultiHandler)
+var _ watcher.EventHandlingTimer = new(MultiHandler)
 
-func NewMultiHandler(handlers ...watcher.RouteHandler) *MultiHandler {
-	return &MultiHandler{
-		handlers: handlers,
+// NewMultiHandler returns a MultiHandler for the given handlers, and starts a
+// goroutine for each of them until Stop is called. A timeout of 0 waits for
+// every sub handler to finish every call. The logger is used for calls that
+// do not come with one.
+func NewMultiHandler(clock clock.Clock, timeout time.Duration, logger lager.Logger, handlers ...NamedHandler) *MultiHandler {
+	h := &MultiHandler{
+		clock:        clock,
+		timeout:      timeout,
+		workers:      make([]*handlerWorker, 0, len(handlers)),
+		logger:       logger.Session("multi-handler"),
+		syncRequests: make(chan struct{}, 1),
+		stopped:      make(chan struct{}),
 	}
+
+	for _, handler := range handlers {
+		worker := newHandlerWorker(handler)
+		go worker.run(h.stopped)
+		h.workers = append(h.workers, worker)
+
+		// sub handlers that are MultiHandlers themselves request syncs too
+		if requester, ok := handler.Handler.(watcher.SyncRequester); ok {
+			go h.forwardSyncRequests(requester.SyncRequests())
+		}
+	}
+
+	return h
+}
+
+// Stop stops the goroutines of the sub handlers once they finish the call
+// they are busy with, and stops the sub handlers that can be stopped. Calls
+// made after Stop are ignored.
+func (h *MultiHandler) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopped)
+		for _, w := range h.workers {
+			if s, ok := w.handler.(interface {
+				Stop()
+			}); ok {
+				s.Stop()
+			}
+		}
+	})
 }
 
+// SyncRequests receives when a sub handler missed calls and needs a sync.
+func (h *MultiHandler) SyncRequests() <-chan struct{} {
+	return h.syncRequests
+}
+
+// TimesEventHandling marks the MultiHandler as sending the event handling
+// durations itself. Each sub handler is timed on its own goroutine, so that a
+// call that times out does not report the timeout.
+func (h *MultiHandler) TimesEventHandling() {}
+
 func (h *MultiHandler) HandleEvent(logger lager.Logger, event models.Event) {
-	for _, rh := range h.handlers {
+	h.each(logger, "handle-event", callTimed, func(rh watcher.RouteHandler) {
+		if _, ok := rh.(watcher.EventHandlingTimer); ok {
+			rh.HandleEvent(logger, event)
+			return
+		}
+
+		started := h.clock.Now()
 		rh.HandleEvent(logger, event)
-	}
+		watcher.SendEventHandlingDuration(logger, event, h.clock.Since(started))
+	})
 }
 
 func (h *MultiHandler) Sync(
@@ -30,30 +157,296 @@ func (h *MultiHandler) Sync(
 	desired []*models.DesiredLRPSchedulingInfo,
 	runningActual []*endpoint.ActualLRPRoutingInfo,
 	domains models.DomainSet,
-	cachedEvents map[string]models.Event,
+	cachedEvents []models.Event,
 ) {
-	for _, rh := range h.handlers {
+	h.each(logger, "sync", callSync, func(rh watcher.RouteHandler) {
 		rh.Sync(logger, desired, runningActual, domains, cachedEvents)
+	})
+}
+
+// CanSyncInBatches reports whether every sub handler can sync in batches. The
+// watcher syncs the MultiHandler all at once otherwise, rather than have it
+// hold every batch for the sub handlers that cannot.
+func (h *MultiHandler) CanSyncInBatches() bool {
+	for _, w := range h.workers {
+		bh, ok := w.handler.(watcher.BatchRouteHandler)
+		if !ok {
+			return false
+		}
+		if checker, ok := bh.(interface {
+			CanSyncInBatches() bool
+		}); ok && !checker.CanSyncInBatches() {
+			return false
+		}
 	}
+	return true
+}
+
+func (h *MultiHandler) SyncBatch(
+	logger lager.Logger,
+	desired []*models.DesiredLRPSchedulingInfo,
+	runningActual []*endpoint.ActualLRPRoutingInfo,
+) {
+	h.each(logger, "sync-batch", callUntimed, func(rh watcher.RouteHandler) {
+		if bh, ok := rh.(watcher.BatchRouteHandler); ok {
+			bh.SyncBatch(logger, desired, runningActual)
+		}
+	})
+}
+
+func (h *MultiHandler) CompleteBatchSync(
+	logger lager.Logger,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	h.each(logger, "complete-batch-sync", callSync, func(rh watcher.RouteHandler) {
+		if bh, ok := rh.(watcher.BatchRouteHandler); ok {
+			bh.CompleteBatchSync(logger, domains, cachedEvents)
+		}
+	})
+}
+
+func (h *MultiHandler) AbortBatchSync(logger lager.Logger) {
+	h.each(logger, "abort-batch-sync", callUntimed, func(rh watcher.RouteHandler) {
+		if bh, ok := rh.(watcher.BatchRouteHandler); ok {
+			bh.AbortBatchSync(logger)
+		}
+	})
 }
 
 func (h *MultiHandler) Emit(logger lager.Logger) {
-	for _, rh := range h.handlers {
+	h.each(logger, "emit", callTimed, func(rh watcher.RouteHandler) {
 		rh.Emit(logger)
-	}
+	})
 }
 
+func (h *MultiHandler) SuppressHost(logger lager.Logger, host string) {
+	h.each(logger, "suppress-host", callTimedUndroppable, func(rh watcher.RouteHandler) {
+		if s, ok := rh.(watcher.HostSuppressor); ok {
+			s.SuppressHost(logger, host)
+		}
+	})
+}
+
+func (h *MultiHandler) UnsuppressHost(logger lager.Logger, host string) {
+	h.each(logger, "unsuppress-host", callTimedUndroppable, func(rh watcher.RouteHandler) {
+		if s, ok := rh.(watcher.HostSuppressor); ok {
+			s.UnsuppressHost(logger, host)
+		}
+	})
+}
+
+// ShouldRefreshDesired also refreshes when a sub handler did not answer in
+// time, as a needless refresh only costs a request to the BBS.
 func (h *MultiHandler) ShouldRefreshDesired(a *endpoint.ActualLRPRoutingInfo) bool {
-	var refresh bool
-	for _, rh := range h.handlers {
-		refresh = refresh || rh.ShouldRefreshDesired(a)
+	answers := make(chan bool, len(h.workers))
+	unfinished := h.each(h.logger, "should-refresh-desired", callTimed, func(rh watcher.RouteHandler) {
+		answers <- rh.ShouldRefreshDesired(a)
+	})
+
+	refresh := unfinished > 0
+	for len(answers) > 0 {
+		refresh = <-answers || refresh
 	}
 
 	return refresh
 }
 
 func (h *MultiHandler) RefreshDesired(logger lager.Logger, d []*models.DesiredLRPSchedulingInfo) {
-	for _, rh := range h.handlers {
+	h.each(logger, "refresh-desired", callTimed, func(rh watcher.RouteHandler) {
 		rh.RefreshDesired(logger, d)
+	})
+}
+
+// each queues the call for every sub handler and waits for them to finish it,
+// at most for the handler timeout unless the call is untimed. It returns how
+// many sub handlers have not finished the call.
+func (h *MultiHandler) each(logger lager.Logger, call string, mode callMode, f func(watcher.RouteHandler)) int {
+	select {
+	case <-h.stopped:
+		return len(h.workers)
+	default:
+	}
+
+	timed := mode == callTimed || mode == callTimedUndroppable
+
+	pending := map[*handlerWorker]<-chan struct{}{}
+	for _, w := range h.workers {
+		// a dirty handler skips the calls a sync repairs until it gets one
+		if mode == callTimed && w.isDirty() {
+			h.requestSync()
+			continue
+		}
+
+		busy := w.busy()
+		done, ok := w.enqueue(h.clock, logger, call, f, mode != callTimed, h.stopped)
+		if !ok {
+			handlerCallsDropped.Increment()
+			logger.Error("handler-fell-behind", errCallDropped, lager.Data{"handler": w.name, "call": call})
+			w.setDirty(true)
+			h.requestSync()
+			continue
+		}
+		if mode == callSync {
+			w.setDirty(false)
+		}
+		// a handler still busy with calls it timed out on is not waited for
+		// again, so that it does not slow down every call until it catches up
+		if timed && busy {
+			continue
+		}
+		pending[w] = done
+	}
+
+	var timeout <-chan time.Time
+	if timed && h.timeout > 0 {
+		timer := h.clock.NewTimer(h.timeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	unfinished := len(h.workers) - len(pending)
+	expired := false
+	for w, done := range pending {
+		if !expired {
+			select {
+			case <-done:
+				continue
+			case <-timeout:
+				expired = true
+			case <-h.stopped:
+				return len(h.workers)
+			}
+		} else {
+			select {
+			case <-done:
+				continue
+			default:
+			}
+		}
+
+		handlerTimeouts.Increment()
+		logger.Error("handler-timed-out", errHandlerTimedOut, lager.Data{
+			"handler": w.name,
+			"call":    call,
+			"timeout": h.timeout.String(),
+		})
+		unfinished++
+	}
+
+	return unfinished
+}
+
+// requestSync asks the watcher for a sync, unless a request is already
+// pending.
+func (h *MultiHandler) requestSync() {
+	select {
+	case h.syncRequests <- struct{}{}:
+	default:
+	}
+}
+
+func (h *MultiHandler) forwardSyncRequests(requests <-chan struct{}) {
+	for {
+		select {
+		case <-requests:
+			h.requestSync()
+		case <-h.stopped:
+			return
+		}
+	}
+}
+
+var (
+	errCallDropped     = errors.New("handler queue is full, dropping call")
+	errHandlerTimedOut = errors.New("handler did not finish in time")
+)
+
+type handlerWorker struct {
+	name     string
+	handler  watcher.RouteHandler
+	duration metric.Duration
+
+	calls    chan func()
+	inFlight int32
+	dirty    int32
+}
+
+func newHandlerWorker(handler NamedHandler) *handlerWorker {
+	return &handlerWorker{
+		name:     handler.Name,
+		handler:  handler.Handler,
+		duration: metric.Duration(fmt.Sprintf("RouteEmitter%sDuration", handler.Name)),
+		calls:    make(chan func(), handlerQueueSize),
+	}
+}
+
+func (w *handlerWorker) run(stopped <-chan struct{}) {
+	for {
+		select {
+		case call := <-w.calls:
+			call()
+		case <-stopped:
+			return
+		}
+	}
+}
+
+func (w *handlerWorker) busy() bool {
+	return atomic.LoadInt32(&w.inFlight) > 0
+}
+
+func (w *handlerWorker) isDirty() bool {
+	return atomic.LoadInt32(&w.dirty) == 1
+}
+
+func (w *handlerWorker) setDirty(dirty bool) {
+	var value int32
+	if dirty {
+		value = 1
+	}
+	atomic.StoreInt32(&w.dirty, value)
+}
+
+// enqueue queues the call and returns a channel that is closed once the
+// handler has finished it. Unless block is set, it gives up when the queue is
+// full. It also gives up once the MultiHandler is stopped.
+func (w *handlerWorker) enqueue(clock clock.Clock, logger lager.Logger, call string, f func(watcher.RouteHandler), block bool, stopped <-chan struct{}) (<-chan struct{}, bool) {
+	done := make(chan struct{})
+	task := func() {
+		defer close(done)
+		defer atomic.AddInt32(&w.inFlight, -1)
+		defer func() {
+			if r := recover(); r != nil {
+				handlerPanics.Increment()
+				logger.Error("handler-panicked", fmt.Errorf("%v", r), lager.Data{"handler": w.name, "call": call})
+			}
+		}()
+
+		started := clock.Now()
+		f(w.handler)
+		err := w.duration.Send(clock.Since(started))
+		if err != nil {
+			logger.Error("failed-to-send-handler-duration-metric", err, lager.Data{"handler": w.name})
+		}
+	}
+
+	atomic.AddInt32(&w.inFlight, 1)
+	if block {
+		select {
+		case w.calls <- task:
+			return done, true
+		case <-stopped:
+			atomic.AddInt32(&w.inFlight, -1)
+			return nil, false
+		}
+	}
+
+	select {
+	case w.calls <- task:
+		return done, true
+	default:
+		atomic.AddInt32(&w.inFlight, -1)
+		return nil, false
 	}
 }
@@ -1,34 +1,59 @@
 package routehandlers_test
 
 import (
+	"fmt"
+	"time"
+
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/watcher"
 	"code.cloudfoundry.org/route-emitter/watcher/fakes"
+	fake_metrics_sender "github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	"github.com/cloudfoundry/dropsonde/metrics"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("MultiHandler", func() {
 	var (
-		fakeHandlers []*fakes.FakeRouteHandler
-		multiHandler *routehandlers.MultiHandler
-		logger       *lagertest.TestLogger
+		fakeHandlers     []*fakes.FakeRouteHandler
+		multiHandler     *routehandlers.MultiHandler
+		logger           *lagertest.TestLogger
+		clock            *fakeclock.FakeClock
+		timeout          time.Duration
+		fakeMetricSender *fake_metrics_sender.FakeMetricSender
 	)
 
+	named := func(handlers ...watcher.RouteHandler) []routehandlers.NamedHandler {
+		namedHandlers := []routehandlers.NamedHandler{}
+		for i, h := range handlers {
+			namedHandlers = append(namedHandlers, routehandlers.NamedHandler{Name: fmt.Sprintf("handler-%d", i), Handler: h})
+		}
+		return namedHandlers
+	}
+
 	BeforeEach(func() {
 		fakeHandlers = []*fakes.FakeRouteHandler{
 			&fakes.FakeRouteHandler{},
 			&fakes.FakeRouteHandler{},
 		}
 
-		var hs []watcher.RouteHandler
-		for _, h := range fakeHandlers {
-			hs = append(hs, h)
-		}
-		multiHandler = routehandlers.NewMultiHandler(hs...)
+		clock = fakeclock.NewFakeClock(time.Now())
+		timeout = 5 * time.Second
 		logger = lagertest.NewTestLogger("multihandler")
+		multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, named(fakeHandlers[0], fakeHandlers[1])...)
+
+		fakeMetricSender = fake_metrics_sender.NewFakeMetricSender()
+		metrics.Initialize(fakeMetricSender, nil)
+	})
+
+	AfterEach(func() {
+		multiHandler.Stop()
 	})
 
 	Describe("HandleEvent", func() {
@@ -79,8 +104,7 @@ var _ = Describe("MultiHandler", func() {
 					LogGuid:     "log",
 				}
 				event := models.NewDesiredLRPRemovedEvent(desiredLRP)
-				cachedEvents := make(map[string]models.Event)
-				cachedEvents[event.Key()] = event
+				cachedEvents := []models.Event{event}
 
 				multiHandler.Sync(logger, nil, nil, nil, cachedEvents)
 
@@ -93,6 +117,60 @@ var _ = Describe("MultiHandler", func() {
 		})
 	})
 
+	Describe("batched sync", func() {
+		var batchHandler *fakes.FakeBatchRouteHandler
+
+		BeforeEach(func() {
+			batchHandler = &fakes.FakeBatchRouteHandler{}
+			multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, named(fakeHandlers[0], batchHandler)...)
+		})
+
+		desired := func(guid string) *models.DesiredLRPSchedulingInfo {
+			return &models.DesiredLRPSchedulingInfo{
+				DesiredLRPKey: models.NewDesiredLRPKey(guid, "tests", "log"),
+			}
+		}
+
+		It("passes batches to sub handlers that support batching", func() {
+			multiHandler.SyncBatch(logger, []*models.DesiredLRPSchedulingInfo{desired("pg-1")}, nil)
+			multiHandler.SyncBatch(logger, []*models.DesiredLRPSchedulingInfo{desired("pg-2")}, nil)
+			multiHandler.CompleteBatchSync(logger, nil, nil)
+
+			Expect(batchHandler.SyncBatchCallCount()).To(Equal(2))
+			Expect(batchHandler.CompleteBatchSyncCallCount()).To(Equal(1))
+			Expect(batchHandler.SyncCallCount()).To(Equal(0))
+		})
+
+		It("does not hold the batches for the other sub handlers", func() {
+			multiHandler.SyncBatch(logger, []*models.DesiredLRPSchedulingInfo{desired("pg-1")}, nil)
+			multiHandler.CompleteBatchSync(logger, nil, nil)
+			Expect(fakeHandlers[0].SyncCallCount()).To(Equal(0))
+		})
+
+		It("aborts the sub handlers that support batching", func() {
+			multiHandler.SyncBatch(logger, []*models.DesiredLRPSchedulingInfo{desired("pg-1")}, nil)
+			multiHandler.AbortBatchSync(logger)
+			Expect(batchHandler.AbortBatchSyncCallCount()).To(Equal(1))
+		})
+
+		Describe("CanSyncInBatches", func() {
+			It("is false when a sub handler does not support batching", func() {
+				Expect(multiHandler.CanSyncInBatches()).To(BeFalse())
+			})
+
+			It("is true when every sub handler supports batching", func() {
+				multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, named(batchHandler, &fakes.FakeBatchRouteHandler{})...)
+				Expect(multiHandler.CanSyncInBatches()).To(BeTrue())
+			})
+
+			It("is false when a nested multi handler cannot sync in batches", func() {
+				nested := routehandlers.NewMultiHandler(clock, timeout, logger, named(fakeHandlers[0])...)
+				multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, named(batchHandler, nested)...)
+				Expect(multiHandler.CanSyncInBatches()).To(BeFalse())
+			})
+		})
+	})
+
 	Describe("Emit", func() {
 		It("calls Emit on sub handlers", func() {
 			multiHandler.Emit(logger)
@@ -127,4 +205,193 @@ var _ = Describe("MultiHandler", func() {
 			}
 		})
 	})
+
+	Context("when a sub handler is slow", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			fakeHandlers[0].EmitStub = func(lager.Logger) {
+				<-release
+			}
+			fakeHandlers[0].SyncStub = func(lager.Logger, []*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, []models.Event) {
+				<-release
+			}
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("does not hold up the other sub handlers", func() {
+			go multiHandler.Emit(logger)
+			Eventually(fakeHandlers[1].EmitCallCount).Should(Equal(1))
+		})
+
+		It("gives up on it after the timeout", func() {
+			emitted := make(chan struct{})
+			go func() {
+				multiHandler.Emit(logger)
+				close(emitted)
+			}()
+
+			Consistently(emitted).ShouldNot(BeClosed())
+			clock.WaitForWatcherAndIncrement(timeout)
+			Eventually(emitted).Should(BeClosed())
+
+			Expect(logger).To(gbytes.Say("handler-timed-out"))
+			Expect(fakeMetricSender.GetCounter("RouteEmitterHandlerTimeouts")).To(BeEquivalentTo(1))
+		})
+
+		It("does not wait for it again until it catches up", func() {
+			go multiHandler.Emit(logger)
+			clock.WaitForWatcherAndIncrement(timeout)
+			Eventually(logger).Should(gbytes.Say("handler-timed-out"))
+
+			multiHandler.Emit(logger)
+			Expect(fakeHandlers[1].EmitCallCount()).To(Equal(2))
+		})
+
+		It("calls it in order once it catches up", func() {
+			go multiHandler.Emit(logger)
+			clock.WaitForWatcherAndIncrement(timeout)
+			Eventually(logger).Should(gbytes.Say("handler-timed-out"))
+
+			multiHandler.RefreshDesired(logger, nil)
+			Expect(fakeHandlers[0].RefreshDesiredCallCount()).To(Equal(0))
+
+			release <- struct{}{}
+			Eventually(fakeHandlers[0].RefreshDesiredCallCount).Should(Equal(1))
+		})
+
+		It("refreshes desired when it does not answer in time", func() {
+			go multiHandler.Emit(logger)
+			clock.WaitForWatcherAndIncrement(timeout)
+			Eventually(logger).Should(gbytes.Say("handler-timed-out"))
+
+			Expect(multiHandler.ShouldRefreshDesired(nil)).To(BeTrue())
+		})
+
+		It("waits for it to finish a sync", func() {
+			synced := make(chan struct{})
+			go func() {
+				multiHandler.Sync(logger, nil, nil, nil, nil)
+				close(synced)
+			}()
+
+			Eventually(fakeHandlers[1].SyncCallCount).Should(Equal(1))
+			Consistently(synced).ShouldNot(BeClosed())
+
+			release <- struct{}{}
+			Eventually(synced).Should(BeClosed())
+		})
+	})
+
+	Context("when a sub handler falls behind", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			fakeHandlers[0].EmitStub = func(lager.Logger) {
+				<-release
+			}
+
+			go multiHandler.Emit(logger)
+			clock.WaitForWatcherAndIncrement(timeout)
+			Eventually(logger).Should(gbytes.Say("handler-timed-out"))
+
+			// one call is in flight, the queue holds 1024 more
+			for i := 0; i < 1025; i++ {
+				multiHandler.Emit(logger)
+			}
+		})
+
+		AfterEach(func() {
+			if release != nil {
+				close(release)
+			}
+		})
+
+		It("drops the call and requests a sync", func() {
+			Expect(logger).To(gbytes.Say("handler-fell-behind.*handler-0"))
+			Expect(fakeMetricSender.GetCounter("RouteEmitterHandlerCallsDropped")).To(BeEquivalentTo(1))
+			Expect(multiHandler.SyncRequests()).To(Receive())
+		})
+
+		It("skips the calls a sync repairs until it is handed a sync", func() {
+			close(release)
+			release = nil
+			Eventually(fakeHandlers[0].EmitCallCount).Should(Equal(1025))
+
+			multiHandler.Emit(logger)
+			Consistently(fakeHandlers[0].EmitCallCount).Should(Equal(1025))
+			Expect(fakeHandlers[1].EmitCallCount()).To(Equal(1027))
+
+			multiHandler.Sync(logger, nil, nil, nil, nil)
+			Expect(fakeHandlers[0].SyncCallCount()).To(Equal(1))
+
+			multiHandler.Emit(logger)
+			Expect(fakeHandlers[0].EmitCallCount()).To(Equal(1026))
+		})
+	})
+
+	Describe("Stop", func() {
+		It("stops calling the sub handlers", func() {
+			multiHandler.Stop()
+			multiHandler.Emit(logger)
+			multiHandler.Sync(logger, nil, nil, nil, nil)
+
+			Consistently(fakeHandlers[0].EmitCallCount).Should(Equal(0))
+			Expect(fakeHandlers[1].SyncCallCount()).To(Equal(0))
+		})
+
+		It("stops the sub handlers that are multi handlers", func() {
+			nested := routehandlers.NewMultiHandler(clock, timeout, logger, named(fakeHandlers[0])...)
+			multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, named(nested)...)
+			multiHandler.Stop()
+
+			nested.Emit(logger)
+			Consistently(fakeHandlers[0].EmitCallCount).Should(Equal(0))
+		})
+	})
+
+	Context("when a sub handler panics", func() {
+		BeforeEach(func() {
+			fakeHandlers[0].EmitStub = func(lager.Logger) {
+				panic("boom")
+			}
+		})
+
+		It("recovers and keeps calling it", func() {
+			multiHandler.Emit(logger)
+			Expect(logger).To(gbytes.Say("handler-panicked"))
+			Expect(fakeMetricSender.GetCounter("RouteEmitterHandlerPanics")).To(BeEquivalentTo(1))
+			Expect(fakeHandlers[1].EmitCallCount()).To(Equal(1))
+
+			multiHandler.Emit(logger)
+			Expect(fakeHandlers[0].EmitCallCount()).To(Equal(2))
+		})
+	})
+
+	It("sends how long each sub handler took to handle an event", func() {
+		fakeHandler := &fakes.FakeRouteHandler{}
+		fakeHandler.HandleEventStub = func(lager.Logger, models.Event) {
+			clock.Increment(time.Second)
+		}
+		multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, routehandlers.NamedHandler{Name: "TimedHandler", Handler: fakeHandler})
+
+		multiHandler.HandleEvent(logger, models.NewDesiredLRPRemovedEvent(&models.DesiredLRP{ProcessGuid: "guid"}))
+		Expect(fakeMetricSender.GetValue("RouteEmitterDesiredLRPRemovedHandlingDuration").Value).To(BeEquivalentTo(time.Second))
+	})
+
+	It("sends the duration of each sub handler", func() {
+		fakeHandler := &fakes.FakeRouteHandler{}
+		fakeHandler.EmitStub = func(lager.Logger) {
+			clock.Increment(time.Second)
+		}
+		multiHandler = routehandlers.NewMultiHandler(clock, timeout, logger, routehandlers.NamedHandler{Name: "TimedHandler", Handler: fakeHandler})
+
+		multiHandler.Emit(logger)
+		Expect(fakeMetricSender.GetValue("RouteEmitterTimedHandlerDuration").Value).To(BeEquivalentTo(time.Second))
+	})
 })
@@ -2,13 +2,16 @@ package routehandlers
 
 import (
 	"errors"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/routingtable/util"
+	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/route-emitter/watcher"
 	"code.cloudfoundry.org/routing-info/cfroutes"
 	"code.cloudfoundry.org/runtimeschema/metric"
@@ -21,20 +24,34 @@ var (
 	routesRegistered   = metric.Counter("RoutesRegistered")
 	routesUnregistered = metric.Counter("RoutesUnregistered")
 	httpRouteCount     = metric.Metric("HTTPRouteCount")
+
+	actualLRPEmitLatency = metric.Duration("RouteEmitterActualLRPEmitLatency")
 )
 
 type NATSHandler struct {
+	clock        clock.Clock
 	routingTable routingtable.NATSRoutingTable
 	emitter      emitter.NATSEmitter
+	emitMonitor  *syncer.EmitMonitor
 	localMode    bool
+
+	// table being built by a batched sync, nil when no batched sync is in
+	// progress
+	batchTable routingtable.NATSRoutingTable
+
+	// the table is only expected to match the BBS after the first sync
+	synced bool
 }
 
-var _ watcher.RouteHandler = new(NATSHandler)
+var _ watcher.BatchRouteHandler = new(NATSHandler)
+var _ watcher.HostSuppressor = new(NATSHandler)
 
-func NewNATSHandler(routingTable routingtable.NATSRoutingTable, natsEmitter emitter.NATSEmitter, localMode bool) *NATSHandler {
+func NewNATSHandler(clock clock.Clock, routingTable routingtable.NATSRoutingTable, natsEmitter emitter.NATSEmitter, emitMonitor *syncer.EmitMonitor, localMode bool) *NATSHandler {
 	return &NATSHandler{
+		clock:        clock,
 		routingTable: routingTable,
 		emitter:      natsEmitter,
+		emitMonitor:  emitMonitor,
 		localMode:    localMode,
 	}
 }
@@ -67,13 +84,18 @@ func (handler *NATSHandler) HandleEvent(logger lager.Logger, event models.Event)
 }
 
 func (handler *NATSHandler) Emit(logger lager.Logger) {
+	started := handler.emitMonitor.EmitStarted()
 	messagesToEmit := handler.routingTable.MessagesToEmit()
 
-	logger.Debug("emitting-messages", lager.Data{"messages": messagesToEmit})
+	// logging the whole table is the first thing to go when emits lag
+	if !handler.emitMonitor.ShedLoad() {
+		logger.Debug("emitting-messages", lager.Data{"messages": messagesToEmit})
+	}
 	err := handler.emitter.Emit(messagesToEmit)
 	if err != nil {
 		logger.Error("failed-to-emit-routes", err)
 	}
+	handler.emitMonitor.EmitCompleted(logger, started, err)
 
 	routesSynced.Add(messagesToEmit.RouteRegistrationCount())
 	err = routesTotal.Send(handler.routingTable.RouteCount())
@@ -87,21 +109,71 @@ func (handler *NATSHandler) Sync(
 	desired []*models.DesiredLRPSchedulingInfo,
 	actuals []*endpoint.ActualLRPRoutingInfo,
 	domains models.DomainSet,
-	cachedEvents map[string]models.Event,
+	cachedEvents []models.Event,
 ) {
 	logger = logger.Session("nats-sync")
 	logger.Debug("starting")
 	defer logger.Debug("completed")
 
-	schedInfoMap := make(map[string]*models.DesiredLRPSchedulingInfo)
-	for _, schedInfo := range desired {
-		schedInfoMap[schedInfo.ProcessGuid] = schedInfo
+	newTable := routingtable.NewTempTable(
+		routingtable.RoutesByRoutingKeyFromSchedulingInfos(desired),
+		routingtable.EndpointsByRoutingKeyFromActuals(actuals, schedulingInfosByProcessGuid(desired)),
+	)
+
+	handler.swapTable(logger, newTable, domains, cachedEvents)
+}
+
+func (handler *NATSHandler) SyncBatch(
+	logger lager.Logger,
+	desired []*models.DesiredLRPSchedulingInfo,
+	actuals []*endpoint.ActualLRPRoutingInfo,
+) {
+	if handler.batchTable == nil {
+		handler.batchTable = routingtable.NewTempTable(nil, nil)
 	}
 
-	newTable := routingtable.NewTempTable(
+	routingtable.AppendToTempTable(
+		handler.batchTable,
 		routingtable.RoutesByRoutingKeyFromSchedulingInfos(desired),
-		routingtable.EndpointsByRoutingKeyFromActuals(actuals, schedInfoMap),
+		routingtable.EndpointsByRoutingKeyFromActuals(actuals, schedulingInfosByProcessGuid(desired)),
 	)
+}
+
+func (handler *NATSHandler) CompleteBatchSync(
+	logger lager.Logger,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	logger = logger.Session("nats-batch-sync")
+	logger.Debug("starting")
+	defer logger.Debug("completed")
+
+	newTable := handler.batchTable
+	handler.batchTable = nil
+	if newTable == nil {
+		newTable = routingtable.NewTempTable(nil, nil)
+	}
+
+	handler.swapTable(logger, newTable, domains, cachedEvents)
+}
+
+func (handler *NATSHandler) AbortBatchSync(logger lager.Logger) {
+	logger.Debug("nats-batch-sync-aborted")
+	handler.batchTable = nil
+}
+
+func (handler *NATSHandler) swapTable(
+	logger lager.Logger,
+	newTable routingtable.NATSRoutingTable,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	// the drift is measured before the cached events are applied, as the
+	// current table has not seen them yet
+	var drift routingtable.Drift
+	if handler.synced {
+		drift = handler.routingTable.Drift(newTable, domains)
+	}
 
 	/////////
 
@@ -120,6 +192,11 @@ func (handler *NATSHandler) Sync(
 
 	//////////
 
+	if handler.synced {
+		reportDrift(logger, drift, httpDriftMetrics)
+	}
+	handler.synced = true
+
 	messages := handler.routingTable.Swap(newTable, domains)
 	logger.Debug("start-emitting-messages", lager.Data{
 		"num-registration-messages":   len(messages.RegistrationMessages),
@@ -155,6 +232,18 @@ func (handler *NATSHandler) ShouldRefreshDesired(actual *endpoint.ActualLRPRouti
 	return true
 }
 
+func (handler *NATSHandler) SuppressHost(logger lager.Logger, host string) {
+	logger = logger.Session("nats-suppress-host", lager.Data{"host": host})
+	messagesToEmit := handler.routingTable.SuppressHost(host)
+	logger.Info("unregistering-endpoints", lager.Data{"num-unregistration-messages": len(messagesToEmit.UnregistrationMessages)})
+	handler.emitMessages(logger, messagesToEmit)
+}
+
+func (handler *NATSHandler) UnsuppressHost(logger lager.Logger, host string) {
+	logger.Info("nats-unsuppress-host", lager.Data{"host": host})
+	handler.routingTable.UnsuppressHost(host)
+}
+
 func (handler *NATSHandler) handleDesiredCreate(logger lager.Logger, desiredLRP *models.DesiredLRPSchedulingInfo) {
 	logger = logger.Session("handle-desired-create", util.DesiredLRPData(desiredLRP))
 	logger.Debug("starting")
@@ -248,6 +337,14 @@ func (handler *NATSHandler) handleActualDelete(logger lager.Logger, actualLRPInf
 	}
 }
 
+func schedulingInfosByProcessGuid(desired []*models.DesiredLRPSchedulingInfo) map[string]*models.DesiredLRPSchedulingInfo {
+	schedInfoMap := make(map[string]*models.DesiredLRPSchedulingInfo)
+	for _, schedInfo := range desired {
+		schedInfoMap[schedInfo.ProcessGuid] = schedInfo
+	}
+	return schedInfoMap
+}
+
 type set map[interface{}]struct{}
 
 func (set set) contains(value interface{}) bool {
@@ -287,12 +384,37 @@ func (handler *NATSHandler) setRoutesForDesired(logger lager.Logger, schedulingI
 	return routingKeySet
 }
 
-func (handler *NATSHandler) emitMessages(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit) {
-	if handler.emitter != nil {
-		logger.Debug("emit-messages", lager.Data{"messages": messagesToEmit})
-		handler.emitter.Emit(messagesToEmit)
-		routesRegistered.Add(messagesToEmit.RouteRegistrationCount())
-		routesUnregistered.Add(messagesToEmit.RouteUnregistrationCount())
+// emitMessages returns true if any messages were handed to the emitter
+func (handler *NATSHandler) emitMessages(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit) bool {
+	if handler.emitter == nil {
+		return false
+	}
+
+	logger.Debug("emit-messages", lager.Data{"messages": messagesToEmit})
+	handler.emitter.Emit(messagesToEmit)
+	routesRegistered.Add(messagesToEmit.RouteRegistrationCount())
+	routesUnregistered.Add(messagesToEmit.RouteUnregistrationCount())
+
+	return len(messagesToEmit.RegistrationMessages) > 0 || len(messagesToEmit.UnregistrationMessages) > 0
+}
+
+// sendEmitLatency reports the time between the last state change of the
+// actual lrp, as recorded by the BBS, and the route emitter publishing the
+// resulting messages. The BBS and the route emitter clocks are not
+// synchronized, so this is only an estimate.
+func (handler *NATSHandler) sendEmitLatency(logger lager.Logger, actualLRPInfo *endpoint.ActualLRPRoutingInfo) {
+	if actualLRPInfo.ActualLRP.Since <= 0 {
+		return
+	}
+
+	latency := handler.clock.Since(time.Unix(0, actualLRPInfo.ActualLRP.Since))
+	if latency < 0 {
+		return
+	}
+
+	err := actualLRPEmitLatency.Send(latency)
+	if err != nil {
+		logger.Error("failed-to-send-actual-lrp-emit-latency-metric", err)
 	}
 }
 
@@ -303,10 +425,15 @@ func (handler *NATSHandler) addAndEmit(logger lager.Logger, actualLRPInfo *endpo
 		logger.Error("failed-to-extract-endpoint-from-actual", err)
 		return
 	}
+	emitted := false
 	for _, routingEndpoint := range endpoints {
 		key := endpoint.RoutingKey{ProcessGUID: actualLRPInfo.ActualLRP.ProcessGuid, ContainerPort: uint32(routingEndpoint.ContainerPort)}
 		messagesToEmit := handler.routingTable.AddEndpoint(key, routingEndpoint)
-		handler.emitMessages(logger, messagesToEmit)
+		emitted = handler.emitMessages(logger, messagesToEmit) || emitted
+	}
+
+	if emitted {
+		handler.sendEmitLatency(logger, actualLRPInfo)
 	}
 }
 
@@ -318,12 +445,17 @@ func (handler *NATSHandler) removeAndEmit(logger lager.Logger, actualLRPInfo *en
 		return
 	}
 
+	emitted := false
 	for _, key := range routingtable.RoutingKeysFromActual(actualLRPInfo.ActualLRP) {
 		for _, endpoint := range endpoints {
 			if key.ContainerPort == endpoint.ContainerPort {
 				messagesToEmit := handler.routingTable.RemoveEndpoint(key, endpoint)
-				handler.emitMessages(logger, messagesToEmit)
+				emitted = handler.emitMessages(logger, messagesToEmit) || emitted
 			}
 		}
 	}
+
+	if emitted {
+		handler.sendEmitLatency(logger, actualLRPInfo)
+	}
 }
@@ -3,14 +3,17 @@ package routehandlers_test
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/route-emitter/emitter/fakes"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/routing-info/cfroutes"
 	fake_metrics_sender "github.com/cloudfoundry/dropsonde/metric_sender/fake"
 	"github.com/gogo/protobuf/proto"
@@ -65,6 +68,7 @@ var _ = Describe("NATSHandler", func() {
 		fakeMetricSender    *fake_metrics_sender.FakeMetricSender
 
 		logger *lagertest.TestLogger
+		clock  *fakeclock.FakeClock
 
 		routeHandler *routehandlers.NATSHandler
 	)
@@ -73,6 +77,7 @@ var _ = Describe("NATSHandler", func() {
 		fakeTable = &fakeroutingtable.FakeNATSRoutingTable{}
 		natsEmitter = &fakes.FakeNATSEmitter{}
 		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
 
 		dummyEndpoint := routingtable.Endpoint{InstanceGuid: expectedInstanceGuid, Index: expectedIndex, Host: expectedHost, Port: expectedContainerPort}
 		dummyMessageFoo := routingtable.RegistryMessageFor(dummyEndpoint, routingtable.Route{Hostname: "foo.com", LogGuid: logGuid})
@@ -97,7 +102,7 @@ var _ = Describe("NATSHandler", func() {
 		fakeMetricSender = fake_metrics_sender.NewFakeMetricSender()
 		metrics.Initialize(fakeMetricSender, nil)
 
-		routeHandler = routehandlers.NewNATSHandler(fakeTable, natsEmitter, false)
+		routeHandler = routehandlers.NewNATSHandler(clock, fakeTable, natsEmitter, nil, false)
 	})
 
 	Context("when an unrecoginzed event is received", func() {
@@ -107,6 +112,27 @@ var _ = Describe("NATSHandler", func() {
 		})
 	})
 
+	Describe("SuppressHost", func() {
+		BeforeEach(func() {
+			fakeTable.SuppressHostReturns(dummyMessagesToEmit)
+			routeHandler.SuppressHost(logger, expectedHost)
+		})
+
+		It("suppresses the host in the table and emits the unregistrations", func() {
+			Expect(fakeTable.SuppressHostCallCount()).To(Equal(1))
+			Expect(fakeTable.SuppressHostArgsForCall(0)).To(Equal(expectedHost))
+
+			Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+			Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(dummyMessagesToEmit))
+		})
+
+		It("lifts the suppression from the table", func() {
+			routeHandler.UnsuppressHost(logger, expectedHost)
+			Expect(fakeTable.UnsuppressHostCallCount()).To(Equal(1))
+			Expect(fakeTable.UnsuppressHostArgsForCall(0)).To(Equal(expectedHost))
+		})
+	})
+
 	Describe("DesiredLRP Event", func() {
 		Context("DesiredLRPCreated Event", func() {
 			var desiredLRP *models.DesiredLRP
@@ -830,6 +856,43 @@ var _ = Describe("NATSHandler", func() {
 				It("sends a 'routes unregistered' metric", func() {
 					Expect(fakeMetricSender.GetCounter("RoutesUnregistered")).To(BeEquivalentTo(0))
 				})
+
+				It("does not send the emit latency without a since timestamp", func() {
+					Expect(fakeMetricSender.GetValue("RouteEmitterActualLRPEmitLatency").Value).To(BeZero())
+				})
+
+				Context("when the actual lrp has a since timestamp", func() {
+					BeforeEach(func() {
+						actualLRP.Since = clock.Now().Add(-time.Minute).UnixNano()
+					})
+
+					It("sends the latency between the actual lrp change and emitting its routes", func() {
+						latency := fakeMetricSender.GetValue("RouteEmitterActualLRPEmitLatency")
+						Expect(latency.Value).To(BeEquivalentTo(time.Minute))
+						Expect(latency.Unit).To(Equal("nanos"))
+					})
+
+					Context("when the since timestamp is ahead of the clock", func() {
+						BeforeEach(func() {
+							actualLRP.Since = clock.Now().Add(time.Minute).UnixNano()
+						})
+
+						It("does not send the emit latency", func() {
+							Expect(fakeMetricSender.GetValue("RouteEmitterActualLRPEmitLatency").Value).To(BeZero())
+						})
+					})
+				})
+
+				Context("when the table has nothing to emit", func() {
+					BeforeEach(func() {
+						actualLRP.Since = time.Now().Add(-time.Minute).UnixNano()
+						fakeTable.AddEndpointReturns(routingtable.MessagesToEmit{})
+					})
+
+					It("does not send the emit latency", func() {
+						Expect(fakeMetricSender.GetValue("RouteEmitterActualLRPEmitLatency").Value).To(BeZero())
+					})
+				})
 			})
 
 			Context("when the resulting LRP is not in the RUNNING state", func() {
@@ -1376,9 +1439,50 @@ var _ = Describe("NATSHandler", func() {
 				Expect(natsEmitter.EmitCallCount()).Should(Equal(1))
 			})
 
+			Context("when syncing in batches", func() {
+				It("swaps in a table built from all batches", func() {
+					routeHandler.SyncBatch(logger, desiredInfo[:2], actualInfo[:2])
+					routeHandler.SyncBatch(logger, desiredInfo[2:], actualInfo[2:])
+					Expect(fakeTable.SwapCallCount()).Should(Equal(0))
+
+					routeHandler.CompleteBatchSync(logger, domains, nil)
+					Expect(fakeTable.SwapCallCount()).Should(Equal(1))
+					tempRoutingTable, swapDomains := fakeTable.SwapArgsForCall(0)
+					Expect(tempRoutingTable.RouteCount()).To(Equal(3))
+					Expect(swapDomains).To(Equal(domains))
+
+					Expect(natsEmitter.EmitCallCount()).Should(Equal(1))
+				})
+
+				It("starts from an empty table after completing", func() {
+					routeHandler.SyncBatch(logger, desiredInfo, actualInfo)
+					routeHandler.CompleteBatchSync(logger, domains, nil)
+
+					routeHandler.SyncBatch(logger, desiredInfo[:1], actualInfo[:1])
+					routeHandler.CompleteBatchSync(logger, domains, nil)
+
+					Expect(fakeTable.SwapCallCount()).Should(Equal(2))
+					tempRoutingTable, _ := fakeTable.SwapArgsForCall(1)
+					Expect(tempRoutingTable.RouteCount()).To(Equal(1))
+				})
+
+				Context("when the batched sync is aborted", func() {
+					It("discards the batches received so far", func() {
+						routeHandler.SyncBatch(logger, desiredInfo, actualInfo)
+						routeHandler.AbortBatchSync(logger)
+						Expect(fakeTable.SwapCallCount()).Should(Equal(0))
+
+						routeHandler.CompleteBatchSync(logger, domains, nil)
+						Expect(fakeTable.SwapCallCount()).Should(Equal(1))
+						tempRoutingTable, _ := fakeTable.SwapArgsForCall(0)
+						Expect(tempRoutingTable.RouteCount()).To(Equal(0))
+					})
+				})
+			})
+
 			Context("when emitting metrics in localMode", func() {
 				BeforeEach(func() {
-					routeHandler = routehandlers.NewNATSHandler(fakeTable, natsEmitter, true)
+					routeHandler = routehandlers.NewNATSHandler(clock, fakeTable, natsEmitter, nil, true)
 					fakeTable.RouteCountReturns(5)
 				})
 
@@ -1388,6 +1492,88 @@ var _ = Describe("NATSHandler", func() {
 				})
 			})
 
+			Context("when the routing table drifted from the BBS", func() {
+				BeforeEach(func() {
+					fakeTable.DriftReturns(routingtable.Drift{
+						RoutesAdded:      1,
+						RoutesRemoved:    2,
+						EndpointsAdded:   3,
+						EndpointsRemoved: 4,
+						RoutingKeys:      []endpoint.RoutingKey{{ProcessGUID: "pg-1", ContainerPort: 8080}},
+					})
+				})
+
+				It("does not check for drift on the first sync", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					Expect(fakeTable.DriftCallCount()).To(Equal(0))
+					Expect(logger).NotTo(gbytes.Say("sync-drift-detected"))
+				})
+
+				Context("on later syncs", func() {
+					BeforeEach(func() {
+						routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+						routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					})
+
+					It("compares the table with the synced table before swapping", func() {
+						Expect(fakeTable.DriftCallCount()).To(Equal(1))
+						driftTable, driftDomains := fakeTable.DriftArgsForCall(0)
+						swapTable, _ := fakeTable.SwapArgsForCall(1)
+						Expect(driftTable).To(BeIdenticalTo(swapTable))
+						Expect(driftDomains).To(Equal(domains))
+					})
+
+					It("emits the drift metrics", func() {
+						Expect(fakeMetricSender.GetValue("HTTPRouteDriftAdded").Value).To(BeEquivalentTo(1))
+						Expect(fakeMetricSender.GetValue("HTTPRouteDriftRemoved").Value).To(BeEquivalentTo(2))
+						Expect(fakeMetricSender.GetValue("HTTPEndpointDriftAdded").Value).To(BeEquivalentTo(3))
+						Expect(fakeMetricSender.GetValue("HTTPEndpointDriftRemoved").Value).To(BeEquivalentTo(4))
+					})
+
+					It("logs the drift", func() {
+						Expect(logger).To(gbytes.Say("sync-drift-detected.*pg-1"))
+					})
+				})
+
+				Context("when there is no drift", func() {
+					BeforeEach(func() {
+						fakeTable.DriftReturns(routingtable.Drift{})
+					})
+
+					It("emits zero drift metrics and does not log", func() {
+						routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+						routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+						Expect(fakeMetricSender.GetValue("HTTPRouteDriftAdded").Value).To(BeZero())
+						Expect(fakeMetricSender.GetValue("HTTPEndpointDriftRemoved").Unit).To(Equal("Metric"))
+						Expect(logger).NotTo(gbytes.Say("sync-drift-detected"))
+					})
+				})
+
+				Context("when an event was cached during a later sync", func() {
+					BeforeEach(func() {
+						routeHandler = routehandlers.NewNATSHandler(clock, routingtable.NewNATSTable(logger), natsEmitter, nil, false)
+						routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					})
+
+					It("does not count it as drift", func() {
+						routeHandler.Sync(logger, desiredInfo, actualInfo, domains, []models.Event{
+							models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{
+								Instance: &models.ActualLRP{
+									ActualLRPKey:         models.NewActualLRPKey("pg-1", 1, "domain"),
+									ActualLRPInstanceKey: models.NewActualLRPInstanceKey("ig-5", "cell-id"),
+									ActualLRPNetInfo:     models.NewActualLRPNetInfo("5.5.5.5", "container-ip-5", models.NewPortMapping(55, 8080)),
+									State:                models.ActualLRPStateRunning,
+								},
+							}),
+						})
+
+						Expect(fakeMetricSender.GetValue("HTTPEndpointDriftAdded").Unit).To(Equal("Metric"))
+						Expect(fakeMetricSender.GetValue("HTTPEndpointDriftAdded").Value).To(BeZero())
+						Expect(logger).NotTo(gbytes.Say("sync-drift-detected"))
+					})
+				})
+			})
+
 			Context("when NATS events are cached", func() {
 				BeforeEach(func() {
 					routes := cfroutes.CFRoutes{
@@ -1420,10 +1606,7 @@ var _ = Describe("NATSHandler", func() {
 						},
 					})
 
-					cachedEvents := map[string]models.Event{
-						desiredLRPEvent.Key(): desiredLRPEvent,
-						actualLRPEvent.Key():  actualLRPEvent,
-					}
+					cachedEvents := []models.Event{desiredLRPEvent, actualLRPEvent}
 					routeHandler.Sync(
 						logger,
 						desiredInfo,
@@ -1440,6 +1623,28 @@ var _ = Describe("NATSHandler", func() {
 					Expect(natsEmitter.EmitCallCount()).Should(Equal(1))
 				})
 			})
+
+			Context("when several events for the same instance are cached", func() {
+				It("applies them in the order they were received", func() {
+					group := &models.ActualLRPGroup{
+						Instance: &models.ActualLRP{
+							ActualLRPKey:         models.NewActualLRPKey("pg-1", 0, "domain"),
+							ActualLRPInstanceKey: models.NewActualLRPInstanceKey(endpoint1.InstanceGuid, "cell-id"),
+							ActualLRPNetInfo:     models.NewActualLRPNetInfo(endpoint1.Host, "container-ip-1", models.NewPortMapping(endpoint1.Port, endpoint1.ContainerPort)),
+							State:                models.ActualLRPStateRunning,
+						},
+					}
+
+					routeHandler.Sync(logger, desiredInfo, actualInfo, domains, []models.Event{
+						models.NewActualLRPRemovedEvent(group),
+						models.NewActualLRPCreatedEvent(group),
+					})
+
+					Expect(fakeTable.SwapCallCount()).Should(Equal(1))
+					tempRoutingTable, _ := fakeTable.SwapArgsForCall(0)
+					Expect(tempRoutingTable.RouteCount()).Should(Equal(3))
+				})
+			})
 		})
 	})
 
@@ -1478,6 +1683,35 @@ var _ = Describe("NATSHandler", func() {
 			routeHandler.Emit(logger)
 			Expect(fakeMetricSender.GetCounter("RoutesSynced")).To(BeEquivalentTo(3))
 		})
+
+		Context("when emits lag behind the prune threshold", func() {
+			var emitMonitor *syncer.EmitMonitor
+
+			BeforeEach(func() {
+				emitMonitor = syncer.NewEmitMonitor(clock, 0.5, true)
+				emitMonitor.SetPruneThreshold(time.Second)
+				emitMonitor.EmitCompleted(logger, clock.Now().Add(-time.Second), nil)
+				Expect(emitMonitor.ShedLoad()).To(BeTrue())
+
+				routeHandler = routehandlers.NewNATSHandler(clock, fakeTable, natsEmitter, emitMonitor, false)
+			})
+
+			It("still emits all registration events", func() {
+				routeHandler.Emit(logger)
+				Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+				Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(registrationMsgs))
+			})
+
+			It("does not log the messages", func() {
+				routeHandler.Emit(logger)
+				Expect(logger).NotTo(gbytes.Say("emitting-messages"))
+			})
+
+			It("reports the emit to the monitor", func() {
+				routeHandler.Emit(logger)
+				Expect(emitMonitor.ShedLoad()).To(BeFalse())
+			})
+		})
 	})
 
 	Describe("RefreshDesired", func() {
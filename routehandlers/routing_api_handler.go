@@ -10,6 +10,7 @@ import (
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
 	"code.cloudfoundry.org/route-emitter/routingtable/util"
+	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/route-emitter/watcher"
 	"code.cloudfoundry.org/runtimeschema/metric"
 )
@@ -21,15 +22,28 @@ var (
 type RoutingAPIHandler struct {
 	routingTable routingtable.TCPRoutingTable
 	emitter      emitter.RoutingAPIEmitter
+	emitMonitor  *syncer.EmitMonitor
 	localMode    bool
+
+	// whether the last refresh of the TCP routes was skipped to shed load
+	skippedRefresh bool
+
+	// table being built by a batched sync, nil when no batched sync is in
+	// progress
+	batchTable routingtable.TCPRoutingTable
+
+	// the table is only expected to match the BBS after the first sync
+	synced bool
 }
 
-var _ watcher.RouteHandler = new(RoutingAPIHandler)
+var _ watcher.BatchRouteHandler = new(RoutingAPIHandler)
+var _ watcher.HostSuppressor = new(RoutingAPIHandler)
 
-func NewRoutingAPIHandler(routingTable routingtable.TCPRoutingTable, emitter emitter.RoutingAPIEmitter, localMode bool) *RoutingAPIHandler {
+func NewRoutingAPIHandler(routingTable routingtable.TCPRoutingTable, emitter emitter.RoutingAPIEmitter, emitMonitor *syncer.EmitMonitor, localMode bool) *RoutingAPIHandler {
 	return &RoutingAPIHandler{
 		routingTable: routingTable,
 		emitter:      emitter,
+		emitMonitor:  emitMonitor,
 		localMode:    localMode,
 	}
 }
@@ -67,7 +81,7 @@ func (handler *RoutingAPIHandler) Sync(
 	desired []*models.DesiredLRPSchedulingInfo,
 	actuals []*endpoint.ActualLRPRoutingInfo,
 	domains models.DomainSet,
-	cachedEvents map[string]models.Event,
+	cachedEvents []models.Event,
 ) {
 	logger = logger.Session("routing-api-sync")
 	logger.Debug("starting")
@@ -77,16 +91,90 @@ func (handler *RoutingAPIHandler) Sync(
 
 	tempRoutingTable = routingtable.NewTCPTable(logger, nil)
 	logger.Debug("construct-routing-table")
+	addToTable(tempRoutingTable, desired, actuals)
+
+	handler.swapTable(logger, tempRoutingTable, cachedEvents)
+}
+
+func (handler *RoutingAPIHandler) SyncBatch(
+	logger lager.Logger,
+	desired []*models.DesiredLRPSchedulingInfo,
+	actuals []*endpoint.ActualLRPRoutingInfo,
+) {
+	if handler.batchTable == nil {
+		handler.batchTable = routingtable.NewTCPTable(logger.Session("routing-api-batch-sync"), nil)
+	}
+
+	addToTable(handler.batchTable, desired, actuals)
+}
+
+func (handler *RoutingAPIHandler) CompleteBatchSync(
+	logger lager.Logger,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	logger = logger.Session("routing-api-batch-sync")
+	logger.Debug("starting")
+	defer logger.Debug("completed")
+
+	tempRoutingTable := handler.batchTable
+	handler.batchTable = nil
+	if tempRoutingTable == nil {
+		tempRoutingTable = routingtable.NewTCPTable(logger, nil)
+	}
+
+	handler.swapTable(logger, tempRoutingTable, cachedEvents)
+}
+
+func (handler *RoutingAPIHandler) AbortBatchSync(logger lager.Logger) {
+	logger.Debug("routing-api-batch-sync-aborted")
+	handler.batchTable = nil
+}
+
+func addToTable(table routingtable.TCPRoutingTable, desired []*models.DesiredLRPSchedulingInfo, actuals []*endpoint.ActualLRPRoutingInfo) {
 	for _, desireLrp := range desired {
-		tempRoutingTable.AddRoutes(desireLrp)
+		table.AddRoutes(desireLrp)
 	}
 
 	for _, actualLrp := range actuals {
-		tempRoutingTable.AddEndpoint(actualLrp)
+		table.AddEndpoint(actualLrp)
+	}
+}
+
+func (handler *RoutingAPIHandler) swapTable(
+	logger lager.Logger,
+	tempRoutingTable routingtable.TCPRoutingTable,
+	cachedEvents []models.Event,
+) {
+	// the drift is measured before the cached events are applied, as the
+	// current table has not seen them yet
+	var drift routingtable.Drift
+	if handler.synced {
+		drift = handler.routingTable.Drift(tempRoutingTable)
+	}
+
+	// apply the events received during the sync to the new table, in the order
+	// they were received, without emitting anything
+	emitter := handler.emitter
+	handler.emitter = nil
+
+	table := handler.routingTable
+	handler.routingTable = tempRoutingTable
+
+	for _, event := range cachedEvents {
+		handler.HandleEvent(logger, event)
 	}
 
+	handler.routingTable = table
+	handler.emitter = emitter
+
 	numRoutes := 0
 	if tempRoutingTable.RouteCount() != 0 {
+		if handler.synced {
+			reportDrift(logger, drift, tcpDriftMetrics)
+		}
+		handler.synced = true
+
 		routingEvents := handler.routingTable.Swap(tempRoutingTable)
 		logger.Debug("swap-complete", lager.Data{"events": len(routingEvents)})
 		numRoutes = handler.emit(routingEvents)
@@ -102,6 +190,16 @@ func (handler *RoutingAPIHandler) Sync(
 
 func (handler *RoutingAPIHandler) Emit(logger lager.Logger) {
 	logger = logger.Session("routing-api-emit")
+
+	// refreshing the TCP routes can wait while the HTTP routes are about to be
+	// pruned, but never twice in a row, so that they outlive their TTL
+	if handler.emitMonitor.ShedLoad() && !handler.skippedRefresh {
+		logger.Info("skipping-refresh-to-shed-load")
+		handler.skippedRefresh = true
+		return
+	}
+	handler.skippedRefresh = false
+
 	events := handler.routingTable.GetRoutingEvents()
 	logger.Debug("emitting-messages", lager.Data{"messages": events})
 	_, _, err := handler.emitter.Emit(events)
@@ -128,6 +226,18 @@ func (handler *RoutingAPIHandler) ShouldRefreshDesired(actual *endpoint.ActualLR
 	return true
 }
 
+func (handler *RoutingAPIHandler) SuppressHost(logger lager.Logger, host string) {
+	logger = logger.Session("tcp-suppress-host", lager.Data{"host": host})
+	routingEvents := handler.routingTable.SuppressHost(host)
+	logger.Info("unregistering-endpoints", lager.Data{"num-routing-events": len(routingEvents)})
+	handler.emit(routingEvents)
+}
+
+func (handler *RoutingAPIHandler) UnsuppressHost(logger lager.Logger, host string) {
+	logger.Info("tcp-unsuppress-host", lager.Data{"host": host})
+	handler.routingTable.UnsuppressHost(host)
+}
+
 func (handler *RoutingAPIHandler) handleDesiredCreate(logger lager.Logger, desiredLRP *models.DesiredLRPSchedulingInfo) {
 	logger = logger.Session("handle-desired-create", util.DesiredLRPData(desiredLRP))
 	logger.Debug("starting")
@@ -1,7 +1,10 @@
 package routehandlers_test
 
 import (
+	"time"
+
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	emitterfakes "code.cloudfoundry.org/route-emitter/emitter/fakes"
@@ -10,11 +13,13 @@ import (
 	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 	fake_metrics_sender "github.com/cloudfoundry/dropsonde/metric_sender/fake"
 	"github.com/cloudfoundry/dropsonde/metrics"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("RoutingAPIHandler", func() {
@@ -30,12 +35,42 @@ var _ = Describe("RoutingAPIHandler", func() {
 		logger = lagertest.NewTestLogger("test")
 		fakeRoutingTable = new(fakeroutingtable.FakeTCPRoutingTable)
 		fakeEmitter = new(emitterfakes.FakeRoutingAPIEmitter)
-		routeHandler = routehandlers.NewRoutingAPIHandler(fakeRoutingTable, fakeEmitter, false)
+		routeHandler = routehandlers.NewRoutingAPIHandler(fakeRoutingTable, fakeEmitter, nil, false)
 
 		fakeMetricSender = fake_metrics_sender.NewFakeMetricSender()
 		metrics.Initialize(fakeMetricSender, nil)
 	})
 
+	Describe("SuppressHost", func() {
+		var routingEvents event.RoutingEvents
+
+		BeforeEach(func() {
+			routingEvents = event.RoutingEvents{
+				event.RoutingEvent{
+					EventType: event.RouteUnregistrationEvent,
+					Key:       endpoint.RoutingKey{},
+					Entry:     endpoint.RoutableEndpoints{},
+				},
+			}
+			fakeRoutingTable.SuppressHostReturns(routingEvents)
+			routeHandler.SuppressHost(logger, "some-ip-1")
+		})
+
+		It("suppresses the host in the table and emits the unregistrations", func() {
+			Expect(fakeRoutingTable.SuppressHostCallCount()).To(Equal(1))
+			Expect(fakeRoutingTable.SuppressHostArgsForCall(0)).To(Equal("some-ip-1"))
+
+			Expect(fakeEmitter.EmitCallCount()).To(Equal(1))
+			Expect(fakeEmitter.EmitArgsForCall(0)).To(Equal(routingEvents))
+		})
+
+		It("lifts the suppression from the table", func() {
+			routeHandler.UnsuppressHost(logger, "some-ip-1")
+			Expect(fakeRoutingTable.UnsuppressHostCallCount()).To(Equal(1))
+			Expect(fakeRoutingTable.UnsuppressHostArgsForCall(0)).To(Equal("some-ip-1"))
+		})
+	})
+
 	Describe("DesiredLRP Event", func() {
 		var (
 			desiredLRP    *models.DesiredLRP
@@ -692,7 +727,7 @@ var _ = Describe("RoutingAPIHandler", func() {
 
 			Context("when emitting metrics in localMode", func() {
 				BeforeEach(func() {
-					routeHandler = routehandlers.NewRoutingAPIHandler(fakeRoutingTable, fakeEmitter, true)
+					routeHandler = routehandlers.NewRoutingAPIHandler(fakeRoutingTable, fakeEmitter, nil, true)
 
 					fakeEmitter.EmitReturns(1, 0, nil)
 				})
@@ -703,6 +738,102 @@ var _ = Describe("RoutingAPIHandler", func() {
 				})
 			})
 
+			Context("when events were cached during the sync", func() {
+				It("applies them to the new table in the order they were received without emitting them", func() {
+					group := &models.ActualLRPGroup{Instance: actualInfo[0].ActualLRP}
+					routeHandler.Sync(logger, desiredInfo, actualInfo, nil, []models.Event{
+						models.NewActualLRPRemovedEvent(group),
+						models.NewActualLRPCreatedEvent(group),
+					})
+
+					Expect(fakeRoutingTable.SwapCallCount()).Should(Equal(1))
+					routingEvents := fakeRoutingTable.SwapArgsForCall(0).GetRoutingEvents()
+					Expect(routingEvents).To(HaveLen(1))
+					Expect(routingEvents[0].Entry.Endpoints).To(HaveLen(1))
+					Expect(fakeEmitter.EmitCallCount()).Should(Equal(1))
+				})
+			})
+
+			Context("when the routing table drifted from the BBS", func() {
+				BeforeEach(func() {
+					fakeRoutingTable.DriftReturns(routingtable.Drift{
+						RoutesAdded:      1,
+						RoutesRemoved:    2,
+						EndpointsAdded:   3,
+						EndpointsRemoved: 4,
+						RoutingKeys:      []endpoint.RoutingKey{endpoint.NewRoutingKey("process-guid-1", 5222)},
+					})
+				})
+
+				It("does not check for drift on the first sync", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, nil, nil)
+					Expect(fakeRoutingTable.DriftCallCount()).To(Equal(0))
+				})
+
+				It("compares the table with the synced table and reports the drift on later syncs", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, nil, nil)
+					routeHandler.Sync(logger, desiredInfo, actualInfo, nil, nil)
+
+					Expect(fakeRoutingTable.DriftCallCount()).To(Equal(1))
+					Expect(fakeRoutingTable.DriftArgsForCall(0)).To(BeIdenticalTo(fakeRoutingTable.SwapArgsForCall(1)))
+
+					Expect(fakeMetricSender.GetValue("TCPRouteDriftAdded").Value).To(BeEquivalentTo(1))
+					Expect(fakeMetricSender.GetValue("TCPRouteDriftRemoved").Value).To(BeEquivalentTo(2))
+					Expect(fakeMetricSender.GetValue("TCPEndpointDriftAdded").Value).To(BeEquivalentTo(3))
+					Expect(fakeMetricSender.GetValue("TCPEndpointDriftRemoved").Value).To(BeEquivalentTo(4))
+					Expect(logger.(*lagertest.TestLogger)).To(gbytes.Say("sync-drift-detected.*process-guid-1"))
+				})
+			})
+
+			Context("when an event was cached during a later sync", func() {
+				BeforeEach(func() {
+					routeHandler = routehandlers.NewRoutingAPIHandler(routingtable.NewTCPTable(logger, nil), fakeEmitter, nil, false)
+					routeHandler.Sync(logger, desiredInfo, actualInfo, nil, nil)
+				})
+
+				It("does not count it as drift", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, nil, []models.Event{
+						models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{
+							Instance: &models.ActualLRP{
+								ActualLRPKey:         models.NewActualLRPKey("process-guid-1", 1, "domain"),
+								ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid-2", "cell-id"),
+								ActualLRPNetInfo:     models.NewActualLRPNetInfo("other-ip", "container-ip-2", models.NewPortMapping(61007, 5222)),
+								State:                models.ActualLRPStateRunning,
+								ModificationTag:      modificationTag,
+							},
+						}),
+					})
+
+					Expect(fakeMetricSender.GetValue("TCPEndpointDriftAdded").Unit).To(Equal("Metric"))
+					Expect(fakeMetricSender.GetValue("TCPEndpointDriftAdded").Value).To(BeZero())
+					Expect(logger.(*lagertest.TestLogger)).NotTo(gbytes.Say("sync-drift-detected"))
+				})
+			})
+
+			Context("when syncing in batches", func() {
+				It("swaps in a table built from all batches", func() {
+					routeHandler.SyncBatch(logger, desiredInfo, nil)
+					routeHandler.SyncBatch(logger, nil, actualInfo)
+					Expect(fakeRoutingTable.SwapCallCount()).Should(Equal(0))
+
+					routeHandler.CompleteBatchSync(logger, nil, nil)
+					Expect(fakeRoutingTable.SwapCallCount()).Should(Equal(1))
+					tempRoutingTable := fakeRoutingTable.SwapArgsForCall(0)
+					Expect(tempRoutingTable.RouteCount()).To(Equal(1))
+					Expect(tempRoutingTable.GetRoutingEvents()).To(HaveLen(1))
+					Expect(fakeEmitter.EmitCallCount()).Should(Equal(1))
+				})
+
+				Context("when the batched sync is aborted", func() {
+					It("does not update the routing table", func() {
+						routeHandler.SyncBatch(logger, desiredInfo, actualInfo)
+						routeHandler.AbortBatchSync(logger)
+						routeHandler.CompleteBatchSync(logger, nil, nil)
+						Expect(fakeRoutingTable.SwapCallCount()).Should(Equal(0))
+					})
+				})
+			})
+
 			It("updates the routing table", func() {
 				routeHandler.Sync(logger, desiredInfo, actualInfo, nil, nil)
 				Expect(fakeRoutingTable.SwapCallCount()).Should(Equal(1))
@@ -753,5 +884,31 @@ var _ = Describe("RoutingAPIHandler", func() {
 			Expect(fakeEmitter.EmitCallCount()).To(Equal(1))
 			Expect(fakeEmitter.EmitArgsForCall(0)).To(Equal(events))
 		})
+
+		Context("when the emit monitor sheds load", func() {
+			BeforeEach(func() {
+				clock := fakeclock.NewFakeClock(time.Now())
+				emitMonitor := syncer.NewEmitMonitor(clock, 0.5, true)
+				emitMonitor.SetPruneThreshold(time.Second)
+				emitMonitor.EmitCompleted(logger, clock.Now().Add(-time.Second), nil)
+
+				routeHandler = routehandlers.NewRoutingAPIHandler(fakeRoutingTable, fakeEmitter, emitMonitor, false)
+			})
+
+			It("skips refreshing the routes", func() {
+				routeHandler.Emit(logger)
+				Expect(fakeEmitter.EmitCallCount()).To(Equal(0))
+				Expect(logger).To(gbytes.Say("skipping-refresh-to-shed-load"))
+			})
+
+			It("never skips two refreshes in a row", func() {
+				routeHandler.Emit(logger)
+				routeHandler.Emit(logger)
+				Expect(fakeEmitter.EmitCallCount()).To(Equal(1))
+
+				routeHandler.Emit(logger)
+				Expect(fakeEmitter.EmitCallCount()).To(Equal(1))
+			})
+		})
 	})
 })
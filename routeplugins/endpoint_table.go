@@ -0,0 +1,120 @@
+package routeplugins
+
+import (
+	"sort"
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+// EndpointReader gives plugins read access to the running endpoints of every
+// LRP, as derived from the BBS.
+type EndpointReader interface {
+	// Endpoints returns the running endpoints behind the container port of a
+	// process, ordered by index.
+	Endpoints(key endpoint.RoutingKey) []routingtable.Endpoint
+}
+
+// EndpointTable holds the running endpoints of every LRP. It is shared by all
+// plugins and kept up to date from the same events and syncs they handle.
+type EndpointTable struct {
+	lock      sync.RWMutex
+	endpoints map[endpoint.RoutingKey]map[routingtable.EndpointKey]routingtable.Endpoint
+}
+
+var _ EndpointReader = new(EndpointTable)
+
+func NewEndpointTable() *EndpointTable {
+	return &EndpointTable{
+		endpoints: map[endpoint.RoutingKey]map[routingtable.EndpointKey]routingtable.Endpoint{},
+	}
+}
+
+func (t *EndpointTable) Endpoints(key endpoint.RoutingKey) []routingtable.Endpoint {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	endpoints := make([]routingtable.Endpoint, 0, len(t.endpoints[key]))
+	for _, e := range t.endpoints[key] {
+		endpoints = append(endpoints, e)
+	}
+	sort.Sort(byIndex(endpoints))
+	return endpoints
+}
+
+type byIndex []routingtable.Endpoint
+
+func (s byIndex) Len() int      { return len(s) }
+func (s byIndex) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byIndex) Less(i, j int) bool {
+	if s[i].Index != s[j].Index {
+		return s[i].Index < s[j].Index
+	}
+	return !s[i].Evacuating && s[j].Evacuating
+}
+
+// HandleEvent applies the actual LRP events to the table and ignores all
+// others.
+func (t *EndpointTable) HandleEvent(event models.Event) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch event := event.(type) {
+	case *models.ActualLRPCreatedEvent:
+		t.add(endpoint.NewActualLRPRoutingInfo(event.ActualLrpGroup))
+	case *models.ActualLRPChangedEvent:
+		t.remove(endpoint.NewActualLRPRoutingInfo(event.Before))
+		t.add(endpoint.NewActualLRPRoutingInfo(event.After))
+	case *models.ActualLRPRemovedEvent:
+		t.remove(endpoint.NewActualLRPRoutingInfo(event.ActualLrpGroup))
+	}
+}
+
+// Sync replaces the contents of the table with the given running actual LRPs.
+func (t *EndpointTable) Sync(runningActuals []*endpoint.ActualLRPRoutingInfo) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.endpoints = map[endpoint.RoutingKey]map[routingtable.EndpointKey]routingtable.Endpoint{}
+	for _, actual := range runningActuals {
+		t.add(actual)
+	}
+}
+
+func (t *EndpointTable) add(actualLRPInfo *endpoint.ActualLRPRoutingInfo) {
+	if actualLRPInfo.ActualLRP == nil || actualLRPInfo.ActualLRP.State != models.ActualLRPStateRunning {
+		return
+	}
+
+	endpoints, err := routingtable.EndpointsFromActual(actualLRPInfo)
+	if err != nil {
+		return
+	}
+
+	for containerPort, e := range endpoints {
+		key := endpoint.RoutingKey{ProcessGUID: actualLRPInfo.ActualLRP.ProcessGuid, ContainerPort: containerPort}
+		if t.endpoints[key] == nil {
+			t.endpoints[key] = map[routingtable.EndpointKey]routingtable.Endpoint{}
+		}
+		t.endpoints[key][routingtable.EndpointKey{InstanceGuid: e.InstanceGuid, Evacuating: e.Evacuating}] = e
+	}
+}
+
+func (t *EndpointTable) remove(actualLRPInfo *endpoint.ActualLRPRoutingInfo) {
+	if actualLRPInfo.ActualLRP == nil {
+		return
+	}
+
+	for _, key := range routingtable.RoutingKeysFromActual(actualLRPInfo.ActualLRP) {
+		endpointKey := routingtable.EndpointKey{
+			InstanceGuid: actualLRPInfo.ActualLRP.InstanceGuid,
+			Evacuating:   actualLRPInfo.Evacuating,
+		}
+		delete(t.endpoints[key], endpointKey)
+		if len(t.endpoints[key]) == 0 {
+			delete(t.endpoints, key)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package routeplugins_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routeplugins"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EndpointTable", func() {
+	var (
+		table *routeplugins.EndpointTable
+		key   endpoint.RoutingKey
+	)
+
+	actualLRP := func(instanceGuid string, index int32, state string) *models.ActualLRP {
+		return &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", index, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.NewPortMapping(61000+uint32(index), 8080)),
+			State:                state,
+		}
+	}
+
+	BeforeEach(func() {
+		table = routeplugins.NewEndpointTable()
+		key = endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+	})
+
+	It("adds running actual LRPs", func() {
+		table.HandleEvent(models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: actualLRP("ig-1", 0, models.ActualLRPStateRunning)}))
+		table.HandleEvent(models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: actualLRP("ig-2", 1, models.ActualLRPStateClaimed)}))
+
+		endpoints := table.Endpoints(key)
+		Expect(endpoints).To(HaveLen(1))
+		Expect(endpoints[0].InstanceGuid).To(Equal("ig-1"))
+	})
+
+	It("tracks actual LRPs that change state", func() {
+		claimed := actualLRP("ig-1", 0, models.ActualLRPStateClaimed)
+		running := actualLRP("ig-1", 0, models.ActualLRPStateRunning)
+
+		table.HandleEvent(models.NewActualLRPChangedEvent(&models.ActualLRPGroup{Instance: claimed}, &models.ActualLRPGroup{Instance: running}))
+		Expect(table.Endpoints(key)).To(HaveLen(1))
+
+		table.HandleEvent(models.NewActualLRPChangedEvent(&models.ActualLRPGroup{Instance: running}, &models.ActualLRPGroup{Instance: claimed}))
+		Expect(table.Endpoints(key)).To(BeEmpty())
+	})
+
+	It("removes actual LRPs", func() {
+		running := actualLRP("ig-1", 0, models.ActualLRPStateRunning)
+		table.HandleEvent(models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: running}))
+		table.HandleEvent(models.NewActualLRPRemovedEvent(&models.ActualLRPGroup{Instance: running}))
+		Expect(table.Endpoints(key)).To(BeEmpty())
+	})
+
+	It("replaces its contents on sync and orders endpoints by index", func() {
+		table.HandleEvent(models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: actualLRP("ig-stale", 5, models.ActualLRPStateRunning)}))
+
+		table.Sync([]*endpoint.ActualLRPRoutingInfo{
+			{ActualLRP: actualLRP("ig-2", 1, models.ActualLRPStateRunning)},
+			{ActualLRP: actualLRP("ig-1", 0, models.ActualLRPStateRunning)},
+		})
+
+		endpoints := table.Endpoints(key)
+		Expect(endpoints).To(HaveLen(2))
+		Expect(endpoints[0].InstanceGuid).To(Equal("ig-1"))
+		Expect(endpoints[1].InstanceGuid).To(Equal("ig-2"))
+	})
+})
@@ -0,0 +1,71 @@
+package routeplugins
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher"
+)
+
+// pluginHandler updates the shared endpoint table before handing events and
+// syncs to the handlers of the plugins.
+type pluginHandler struct {
+	*routehandlers.MultiHandler
+	endpoints *EndpointTable
+
+	// running actual LRPs accumulated during a batched sync
+	batchActuals []*endpoint.ActualLRPRoutingInfo
+}
+
+var _ watcher.BatchRouteHandler = new(pluginHandler)
+var _ watcher.HostSuppressor = new(pluginHandler)
+
+func (h *pluginHandler) HandleEvent(logger lager.Logger, event models.Event) {
+	h.endpoints.HandleEvent(event)
+	h.MultiHandler.HandleEvent(logger, event)
+}
+
+func (h *pluginHandler) Sync(
+	logger lager.Logger,
+	desired []*models.DesiredLRPSchedulingInfo,
+	runningActual []*endpoint.ActualLRPRoutingInfo,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	h.syncEndpoints(runningActual, cachedEvents)
+	h.MultiHandler.Sync(logger, desired, runningActual, domains, cachedEvents)
+}
+
+func (h *pluginHandler) SyncBatch(
+	logger lager.Logger,
+	desired []*models.DesiredLRPSchedulingInfo,
+	runningActual []*endpoint.ActualLRPRoutingInfo,
+) {
+	h.batchActuals = append(h.batchActuals, runningActual...)
+	h.MultiHandler.SyncBatch(logger, desired, runningActual)
+}
+
+func (h *pluginHandler) CompleteBatchSync(
+	logger lager.Logger,
+	domains models.DomainSet,
+	cachedEvents []models.Event,
+) {
+	h.syncEndpoints(h.batchActuals, cachedEvents)
+	h.batchActuals = nil
+	h.MultiHandler.CompleteBatchSync(logger, domains, cachedEvents)
+}
+
+func (h *pluginHandler) AbortBatchSync(logger lager.Logger) {
+	h.batchActuals = nil
+	h.MultiHandler.AbortBatchSync(logger)
+}
+
+// syncEndpoints replaces the endpoint table, and applies the events that
+// arrived during the sync on top, as the plugins do.
+func (h *pluginHandler) syncEndpoints(runningActual []*endpoint.ActualLRPRoutingInfo, cachedEvents []models.Event) {
+	h.endpoints.Sync(runningActual)
+	for _, event := range cachedEvents {
+		h.endpoints.HandleEvent(event)
+	}
+}
@@ -0,0 +1 @@
+package routeplugins // import "code.cloudfoundry.org/route-emitter/routeplugins"
@@ -0,0 +1,158 @@
+package routeplugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+)
+
+// Environment is what a plugin gets to build its route handler with.
+type Environment struct {
+	Clock clock.Clock
+
+	// LocalMode is set when the emitter only emits the routes of the LRPs
+	// running on its own cell.
+	LocalMode bool
+
+	// Endpoints is updated with every event and sync before any plugin
+	// handles it.
+	Endpoints EndpointReader
+}
+
+// Factory builds the route handler for the routes under one key of the
+// DesiredLRP routes, from the plugin's own section of the configuration.
+type Factory func(logger lager.Logger, env Environment, config json.RawMessage) (watcher.RouteHandler, error)
+
+// Config enables the plugin registered for a routes key.
+type Config struct {
+	RoutesKey string          `json:"routes_key"`
+	Config    json.RawMessage `json:"config,omitempty"`
+}
+
+// Registry maps routes keys to the factories of their route handlers.
+type Registry struct {
+	lock      sync.Mutex
+	factories map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: map[string]Factory{},
+	}
+}
+
+// Register attaches the factory to a routes key. The keys understood by the
+// emitter itself cannot be registered.
+func (r *Registry) Register(routesKey string, factory Factory) error {
+	if routesKey == cfroutes.CF_ROUTER || routesKey == tcp_routes.TCP_ROUTER {
+		return fmt.Errorf("routes key %q is reserved", routesKey)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.factories[routesKey]; ok {
+		return fmt.Errorf("routes key %q is already registered", routesKey)
+	}
+	r.factories[routesKey] = factory
+	return nil
+}
+
+// RoutesKeys returns the registered routes keys in order.
+func (r *Registry) RoutesKeys() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys := make([]string, 0, len(r.factories))
+	for key := range r.factories {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Build returns a single route handler for the enabled plugins, or nil when
+// none are enabled. It keeps the shared endpoint table up to date, and calls
+// the handlers of the plugins concurrently, giving up on any of them after
+// handlerTimeout.
+func (r *Registry) Build(
+	logger lager.Logger,
+	clock clock.Clock,
+	localMode bool,
+	handlerTimeout time.Duration,
+	configs []Config,
+) (watcher.RouteHandler, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	endpoints := NewEndpointTable()
+	env := Environment{
+		Clock:     clock,
+		LocalMode: localMode,
+		Endpoints: endpoints,
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	handlers := make([]routehandlers.NamedHandler, 0, len(configs))
+	enabled := map[string]bool{}
+	for _, config := range configs {
+		factory, ok := r.factories[config.RoutesKey]
+		if !ok {
+			return nil, fmt.Errorf("no plugin registered for routes key %q", config.RoutesKey)
+		}
+		if enabled[config.RoutesKey] {
+			return nil, fmt.Errorf("plugin for routes key %q is enabled twice", config.RoutesKey)
+		}
+		enabled[config.RoutesKey] = true
+
+		handler, err := factory(logger.Session("plugin", lager.Data{"routes-key": config.RoutesKey}), env, config.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build plugin for routes key %q: %s", config.RoutesKey, err)
+		}
+		handlers = append(handlers, routehandlers.NamedHandler{Name: config.RoutesKey, Handler: handler})
+	}
+
+	return &pluginHandler{
+		endpoints:    endpoints,
+		MultiHandler: routehandlers.NewMultiHandler(clock, handlerTimeout, logger, handlers...),
+	}, nil
+}
+
+// DefaultRegistry is the registry plugins register with from their init
+// functions.
+var DefaultRegistry = NewRegistry()
+
+// Register attaches the factory to a routes key in the default registry, and
+// panics if it cannot, as plugins register when their package is imported.
+func Register(routesKey string, factory Factory) {
+	err := DefaultRegistry.Register(routesKey, factory)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RoutesFor returns the routes under the given key of a desired LRP.
+func RoutesFor(schedulingInfo *models.DesiredLRPSchedulingInfo, routesKey string) (json.RawMessage, bool) {
+	if schedulingInfo.Routes == nil {
+		return nil, false
+	}
+
+	routes, ok := schedulingInfo.Routes[routesKey]
+	if !ok || routes == nil {
+		return nil, false
+	}
+	return *routes, true
+}
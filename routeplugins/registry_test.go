@@ -0,0 +1,189 @@
+package routeplugins_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/routeplugins"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/watcher/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		logger      *lagertest.TestLogger
+		clock       *fakeclock.FakeClock
+		registry    *routeplugins.Registry
+		fakeHandler *fakes.FakeRouteHandler
+
+		env    routeplugins.Environment
+		config json.RawMessage
+	)
+
+	factory := func(logger lager.Logger, e routeplugins.Environment, c json.RawMessage) (watcher.RouteHandler, error) {
+		env, config = e, c
+		return fakeHandler, nil
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		registry = routeplugins.NewRegistry()
+		fakeHandler = &fakes.FakeRouteHandler{}
+	})
+
+	Describe("Register", func() {
+		It("registers the factory for the routes key", func() {
+			Expect(registry.Register("mesh-router", factory)).To(Succeed())
+			Expect(registry.RoutesKeys()).To(Equal([]string{"mesh-router"}))
+		})
+
+		It("refuses to register a routes key twice", func() {
+			Expect(registry.Register("mesh-router", factory)).To(Succeed())
+			Expect(registry.Register("mesh-router", factory)).NotTo(Succeed())
+		})
+
+		It("refuses to register the routes keys of the built in handlers", func() {
+			Expect(registry.Register("cf-router", factory)).NotTo(Succeed())
+			Expect(registry.Register("tcp-router", factory)).NotTo(Succeed())
+		})
+	})
+
+	Describe("Build", func() {
+		BeforeEach(func() {
+			Expect(registry.Register("mesh-router", factory)).To(Succeed())
+		})
+
+		It("builds the enabled plugins with their config", func() {
+			handler, err := registry.Build(logger, clock, true, 0, []routeplugins.Config{
+				{RoutesKey: "mesh-router", Config: json.RawMessage(`{"port":8080}`)},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handler).NotTo(BeNil())
+
+			Expect(config).To(MatchJSON(`{"port":8080}`))
+			Expect(env.Clock).To(Equal(clock))
+			Expect(env.LocalMode).To(BeTrue())
+			Expect(env.Endpoints).NotTo(BeNil())
+
+			handler.Emit(logger)
+			Expect(fakeHandler.EmitCallCount()).To(Equal(1))
+		})
+
+		It("returns no handler when no plugin is enabled", func() {
+			handler, err := registry.Build(logger, clock, false, 0, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handler).To(BeNil())
+		})
+
+		It("fails for routes keys without a plugin", func() {
+			_, err := registry.Build(logger, clock, false, 0, []routeplugins.Config{{RoutesKey: "other-router"}})
+			Expect(err).To(MatchError(ContainSubstring("other-router")))
+		})
+
+		It("fails when a plugin is enabled twice", func() {
+			_, err := registry.Build(logger, clock, false, 0, []routeplugins.Config{
+				{RoutesKey: "mesh-router"},
+				{RoutesKey: "mesh-router"},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when a factory fails", func() {
+			Expect(registry.Register("broken-router", func(lager.Logger, routeplugins.Environment, json.RawMessage) (watcher.RouteHandler, error) {
+				return nil, errors.New("boom")
+			})).To(Succeed())
+
+			_, err := registry.Build(logger, clock, false, 0, []routeplugins.Config{{RoutesKey: "broken-router"}})
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+		})
+
+		Context("when the plugins handle events and syncs", func() {
+			var (
+				handler  watcher.RouteHandler
+				actual   *models.ActualLRP
+				key      endpoint.RoutingKey
+				observed []routingtable.Endpoint
+			)
+
+			BeforeEach(func() {
+				actual = &models.ActualLRP{
+					ActualLRPKey:         models.NewActualLRPKey("process-guid", 0, "domain"),
+					ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+					ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.NewPortMapping(61000, 8080)),
+					State:                models.ActualLRPStateRunning,
+				}
+				key = endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+
+				fakeHandler.HandleEventStub = func(lager.Logger, models.Event) {
+					observed = env.Endpoints.Endpoints(key)
+				}
+				fakeHandler.SyncStub = func(lager.Logger, []*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, []models.Event) {
+					observed = env.Endpoints.Endpoints(key)
+				}
+
+				var err error
+				handler, err = registry.Build(logger, clock, false, 0, []routeplugins.Config{{RoutesKey: "mesh-router"}})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("updates the shared endpoints before an event reaches the plugins", func() {
+				handler.HandleEvent(logger, models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: actual}))
+				Expect(observed).To(HaveLen(1))
+				Expect(observed[0].Host).To(Equal("1.1.1.1"))
+				Expect(observed[0].Port).To(BeEquivalentTo(61000))
+			})
+
+			It("updates the shared endpoints before a sync reaches the plugins", func() {
+				handler.Sync(logger, nil, []*endpoint.ActualLRPRoutingInfo{{ActualLRP: actual}}, nil, nil)
+				Expect(observed).To(HaveLen(1))
+			})
+
+			It("updates the shared endpoints before a batched sync completes", func() {
+				fakeBatchHandler := &fakes.FakeBatchRouteHandler{}
+				fakeBatchHandler.CompleteBatchSyncStub = func(lager.Logger, models.DomainSet, []models.Event) {
+					observed = env.Endpoints.Endpoints(key)
+				}
+				Expect(registry.Register("batch-router", func(logger lager.Logger, e routeplugins.Environment, c json.RawMessage) (watcher.RouteHandler, error) {
+					env = e
+					return fakeBatchHandler, nil
+				})).To(Succeed())
+
+				handler, err := registry.Build(logger, clock, false, 0, []routeplugins.Config{{RoutesKey: "batch-router"}})
+				Expect(err).NotTo(HaveOccurred())
+				batchHandler, ok := handler.(watcher.BatchRouteHandler)
+				Expect(ok).To(BeTrue())
+
+				batchHandler.SyncBatch(logger, nil, []*endpoint.ActualLRPRoutingInfo{{ActualLRP: actual}})
+				batchHandler.CompleteBatchSync(logger, nil, nil)
+				Expect(observed).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("RoutesFor", func() {
+		It("returns the routes under the key", func() {
+			routes := json.RawMessage(`{"hosts":["a.mesh"]}`)
+			schedulingInfo := &models.DesiredLRPSchedulingInfo{
+				Routes: models.Routes{"mesh-router": &routes},
+			}
+
+			found, ok := routeplugins.RoutesFor(schedulingInfo, "mesh-router")
+			Expect(ok).To(BeTrue())
+			Expect(found).To(MatchJSON(`{"hosts":["a.mesh"]}`))
+
+			_, ok = routeplugins.RoutesFor(schedulingInfo, "other-router")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package routeplugins_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRoutePlugins(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RoutePlugins Suite")
+}
@@ -2,6 +2,7 @@ package benchmarks_test
 
 import (
 	"fmt"
+	"runtime"
 	"strconv"
 	"time"
 
@@ -207,6 +208,73 @@ var _ = Describe("Benchmarks", func() {
 		}, numSamples())
 	})
 
+	Context("building the sync table", func() {
+		const batchSize = 5000
+
+		routesAndEndpoints := func(startIndex, endIndex int) (map[endpoint.RoutingKey][]routingtable.Route, map[endpoint.RoutingKey][]routingtable.Endpoint) {
+			routesMap := map[endpoint.RoutingKey][]routingtable.Route{}
+			endpointsMap := map[endpoint.RoutingKey][]routingtable.Endpoint{}
+			for i := startIndex; i < endIndex; i++ {
+				guid := "app-" + strconv.Itoa(i)
+				key := endpoint.RoutingKey{
+					ProcessGUID:   guid,
+					ContainerPort: 8080,
+				}
+				routesMap[key] = []routingtable.Route{
+					{
+						Hostname: guid,
+						LogGuid:  guid,
+					},
+				}
+				endpointsMap[key] = []routingtable.Endpoint{
+					{
+						InstanceGuid:  guid,
+						Host:          guid,
+						Domain:        "test.domain",
+						Port:          1024 + uint32(i),
+						ContainerPort: key.ContainerPort,
+					},
+				}
+			}
+			return routesMap, endpointsMap
+		}
+
+		heapInUse := func() float64 {
+			runtime.GC()
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			return float64(stats.HeapInuse) / (1024 * 1024)
+		}
+
+		BeforeEach(func() {
+			// the sync table does not emit, keep the AfterEach assertion happy
+			registrationMessages = MaxRoutes
+		})
+
+		Measure("building the table in one go", func(b Benchmarker) {
+			before := heapInUse()
+			routesMap, endpointsMap := routesAndEndpoints(0, MaxRoutes)
+			table := routingtable.NewTempTable(routesMap, endpointsMap)
+			b.RecordValueWithPrecision("heap growth", heapInUse()-before, "MB", 1)
+			Expect(table.RouteCount()).To(Equal(MaxRoutes))
+		}, numSamples())
+
+		Measure("building the table in batches", func(b Benchmarker) {
+			before := heapInUse()
+			peak := 0.0
+			table := routingtable.NewTempTable(nil, nil)
+			for i := 0; i < MaxRoutes; i += batchSize {
+				routesMap, endpointsMap := routesAndEndpoints(i, i+batchSize)
+				routingtable.AppendToTempTable(table, routesMap, endpointsMap)
+				if growth := heapInUse() - before; growth > peak {
+					peak = growth
+				}
+			}
+			b.RecordValueWithPrecision("heap growth", peak, "MB", 1)
+			Expect(table.RouteCount()).To(Equal(MaxRoutes))
+		}, numSamples())
+	})
+
 	Context("get endpoint from index", func() {
 		BeforeEach(func() {
 			for i := 0; i < 5; i++ {
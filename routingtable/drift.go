@@ -0,0 +1,121 @@
+package routingtable
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+// MaxDriftSample is the number of drifted routing keys kept in a Drift.
+const MaxDriftSample = 10
+
+// Drift counts the differences between a table that was kept up to date by
+// events and the table built from the BBS during a sync. Drift means that the
+// route emitter missed or mishandled events.
+type Drift struct {
+	RoutesAdded      int
+	RoutesRemoved    int
+	EndpointsAdded   int
+	EndpointsRemoved int
+
+	// a sample of at most MaxDriftSample routing keys that drifted
+	RoutingKeys []endpoint.RoutingKey
+}
+
+func (d Drift) Empty() bool {
+	return d.RoutesAdded == 0 && d.RoutesRemoved == 0 &&
+		d.EndpointsAdded == 0 && d.EndpointsRemoved == 0
+}
+
+// add adds the drift of the entry with the given routing key
+func (d *Drift) add(key endpoint.RoutingKey, entryDrift Drift) {
+	if entryDrift.Empty() {
+		return
+	}
+
+	d.RoutesAdded += entryDrift.RoutesAdded
+	d.RoutesRemoved += entryDrift.RoutesRemoved
+	d.EndpointsAdded += entryDrift.EndpointsAdded
+	d.EndpointsRemoved += entryDrift.EndpointsRemoved
+
+	if len(d.RoutingKeys) < MaxDriftSample {
+		d.RoutingKeys = append(d.RoutingKeys, key)
+	}
+}
+
+// natsEntryDrift compares two entries of a NATS routing table. Endpoints in
+// domains that are not fresh are kept by Swap, so they do not count as
+// removed, and neither do the routes of their entry.
+func natsEntryDrift(existing, updated RoutableEndpoints, domains models.DomainSet) Drift {
+	drift := Drift{}
+
+	unfresh := false
+	for key, existingEndpoint := range existing.Endpoints {
+		if domains != nil && !domains.Contains(existingEndpoint.Domain) {
+			unfresh = true
+			continue
+		}
+		if _, ok := updated.Endpoints[key]; !ok {
+			drift.EndpointsRemoved++
+		}
+	}
+	for key := range updated.Endpoints {
+		if _, ok := existing.Endpoints[key]; !ok {
+			drift.EndpointsAdded++
+		}
+	}
+
+	existingRoutes := map[Route]struct{}{}
+	for _, route := range existing.Routes {
+		existingRoutes[route] = struct{}{}
+	}
+	updatedRoutes := map[Route]struct{}{}
+	for _, route := range updated.Routes {
+		updatedRoutes[route] = struct{}{}
+		if _, ok := existingRoutes[route]; !ok {
+			drift.RoutesAdded++
+		}
+	}
+	if !unfresh {
+		for route := range existingRoutes {
+			if _, ok := updatedRoutes[route]; !ok {
+				drift.RoutesRemoved++
+			}
+		}
+	}
+
+	return drift
+}
+
+func tcpEntryDrift(existing, updated endpoint.RoutableEndpoints) Drift {
+	drift := Drift{}
+
+	for key := range existing.Endpoints {
+		if _, ok := updated.Endpoints[key]; !ok {
+			drift.EndpointsRemoved++
+		}
+	}
+	for key := range updated.Endpoints {
+		if _, ok := existing.Endpoints[key]; !ok {
+			drift.EndpointsAdded++
+		}
+	}
+
+	existingRoutes := map[endpoint.ExternalEndpointInfo]struct{}{}
+	for _, route := range existing.ExternalEndpoints {
+		existingRoutes[route] = struct{}{}
+	}
+	updatedRoutes := map[endpoint.ExternalEndpointInfo]struct{}{}
+	for _, route := range updated.ExternalEndpoints {
+		updatedRoutes[route] = struct{}{}
+		if _, ok := existingRoutes[route]; !ok {
+			drift.RoutesAdded++
+		}
+	}
+	for route := range existingRoutes {
+		if _, ok := updatedRoutes[route]; !ok {
+			drift.RoutesRemoved++
+		}
+	}
+
+	return drift
+}
@@ -25,6 +25,15 @@ type FakeNATSRoutingTable struct {
 	swapReturns struct {
 		result1 routingtable.MessagesToEmit
 	}
+	DriftStub        func(newTable routingtable.NATSRoutingTable, domains models.DomainSet) routingtable.Drift
+	driftMutex       sync.RWMutex
+	driftArgsForCall []struct {
+		newTable routingtable.NATSRoutingTable
+		domains  models.DomainSet
+	}
+	driftReturns struct {
+		result1 routingtable.Drift
+	}
 	SetRoutesStub        func(key endpoint.RoutingKey, routes []routingtable.Route, modTag *models.ModificationTag) routingtable.MessagesToEmit
 	setRoutesMutex       sync.RWMutex
 	setRoutesArgsForCall []struct {
@@ -79,12 +88,37 @@ type FakeNATSRoutingTable struct {
 	endpointsForIndexReturns struct {
 		result1 []routingtable.Endpoint
 	}
+	SuppressHostStub        func(host string) routingtable.MessagesToEmit
+	suppressHostMutex       sync.RWMutex
+	suppressHostArgsForCall []struct {
+		host string
+	}
+	suppressHostReturns struct {
+		result1 routingtable.MessagesToEmit
+	}
+	UnsuppressHostStub        func(host string)
+	unsuppressHostMutex       sync.RWMutex
+	unsuppressHostArgsForCall []struct {
+		host string
+	}
 	MessagesToEmitStub        func() routingtable.MessagesToEmit
 	messagesToEmitMutex       sync.RWMutex
 	messagesToEmitArgsForCall []struct{}
 	messagesToEmitReturns     struct {
 		result1 routingtable.MessagesToEmit
 	}
+	SnapshotStub        func() []routingtable.NATSTableEntry
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct{}
+	snapshotReturns     struct {
+		result1 []routingtable.NATSTableEntry
+	}
+	EntryCountStub        func() int
+	entryCountMutex       sync.RWMutex
+	entryCountArgsForCall []struct{}
+	entryCountReturns     struct {
+		result1 int
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -148,6 +182,40 @@ func (fake *FakeNATSRoutingTable) SwapReturns(result1 routingtable.MessagesToEmi
 	}{result1}
 }
 
+func (fake *FakeNATSRoutingTable) Drift(newTable routingtable.NATSRoutingTable, domains models.DomainSet) routingtable.Drift {
+	fake.driftMutex.Lock()
+	fake.driftArgsForCall = append(fake.driftArgsForCall, struct {
+		newTable routingtable.NATSRoutingTable
+		domains  models.DomainSet
+	}{newTable, domains})
+	fake.recordInvocation("Drift", []interface{}{newTable, domains})
+	fake.driftMutex.Unlock()
+	if fake.DriftStub != nil {
+		return fake.DriftStub(newTable, domains)
+	} else {
+		return fake.driftReturns.result1
+	}
+}
+
+func (fake *FakeNATSRoutingTable) DriftCallCount() int {
+	fake.driftMutex.RLock()
+	defer fake.driftMutex.RUnlock()
+	return len(fake.driftArgsForCall)
+}
+
+func (fake *FakeNATSRoutingTable) DriftArgsForCall(i int) (routingtable.NATSRoutingTable, models.DomainSet) {
+	fake.driftMutex.RLock()
+	defer fake.driftMutex.RUnlock()
+	return fake.driftArgsForCall[i].newTable, fake.driftArgsForCall[i].domains
+}
+
+func (fake *FakeNATSRoutingTable) DriftReturns(result1 routingtable.Drift) {
+	fake.DriftStub = nil
+	fake.driftReturns = struct {
+		result1 routingtable.Drift
+	}{result1}
+}
+
 func (fake *FakeNATSRoutingTable) SetRoutes(key endpoint.RoutingKey, routes []routingtable.Route, modTag *models.ModificationTag) routingtable.MessagesToEmit {
 	var routesCopy []routingtable.Route
 	if routes != nil {
@@ -357,6 +425,63 @@ func (fake *FakeNATSRoutingTable) EndpointsForIndexReturns(result1 []routingtabl
 	}{result1}
 }
 
+func (fake *FakeNATSRoutingTable) SuppressHost(host string) routingtable.MessagesToEmit {
+	fake.suppressHostMutex.Lock()
+	fake.suppressHostArgsForCall = append(fake.suppressHostArgsForCall, struct {
+		host string
+	}{host})
+	fake.recordInvocation("SuppressHost", []interface{}{host})
+	fake.suppressHostMutex.Unlock()
+	if fake.SuppressHostStub != nil {
+		return fake.SuppressHostStub(host)
+	} else {
+		return fake.suppressHostReturns.result1
+	}
+}
+
+func (fake *FakeNATSRoutingTable) SuppressHostCallCount() int {
+	fake.suppressHostMutex.RLock()
+	defer fake.suppressHostMutex.RUnlock()
+	return len(fake.suppressHostArgsForCall)
+}
+
+func (fake *FakeNATSRoutingTable) SuppressHostArgsForCall(i int) string {
+	fake.suppressHostMutex.RLock()
+	defer fake.suppressHostMutex.RUnlock()
+	return fake.suppressHostArgsForCall[i].host
+}
+
+func (fake *FakeNATSRoutingTable) SuppressHostReturns(result1 routingtable.MessagesToEmit) {
+	fake.SuppressHostStub = nil
+	fake.suppressHostReturns = struct {
+		result1 routingtable.MessagesToEmit
+	}{result1}
+}
+
+func (fake *FakeNATSRoutingTable) UnsuppressHost(host string) {
+	fake.unsuppressHostMutex.Lock()
+	fake.unsuppressHostArgsForCall = append(fake.unsuppressHostArgsForCall, struct {
+		host string
+	}{host})
+	fake.recordInvocation("UnsuppressHost", []interface{}{host})
+	fake.unsuppressHostMutex.Unlock()
+	if fake.UnsuppressHostStub != nil {
+		fake.UnsuppressHostStub(host)
+	}
+}
+
+func (fake *FakeNATSRoutingTable) UnsuppressHostCallCount() int {
+	fake.unsuppressHostMutex.RLock()
+	defer fake.unsuppressHostMutex.RUnlock()
+	return len(fake.unsuppressHostArgsForCall)
+}
+
+func (fake *FakeNATSRoutingTable) UnsuppressHostArgsForCall(i int) string {
+	fake.unsuppressHostMutex.RLock()
+	defer fake.unsuppressHostMutex.RUnlock()
+	return fake.unsuppressHostArgsForCall[i].host
+}
+
 func (fake *FakeNATSRoutingTable) MessagesToEmit() routingtable.MessagesToEmit {
 	fake.messagesToEmitMutex.Lock()
 	fake.messagesToEmitArgsForCall = append(fake.messagesToEmitArgsForCall, struct{}{})
@@ -382,6 +507,56 @@ func (fake *FakeNATSRoutingTable) MessagesToEmitReturns(result1 routingtable.Mes
 	}{result1}
 }
 
+func (fake *FakeNATSRoutingTable) Snapshot() []routingtable.NATSTableEntry {
+	fake.snapshotMutex.Lock()
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct{}{})
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if fake.SnapshotStub != nil {
+		return fake.SnapshotStub()
+	} else {
+		return fake.snapshotReturns.result1
+	}
+}
+
+func (fake *FakeNATSRoutingTable) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeNATSRoutingTable) SnapshotReturns(result1 []routingtable.NATSTableEntry) {
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 []routingtable.NATSTableEntry
+	}{result1}
+}
+
+func (fake *FakeNATSRoutingTable) EntryCount() int {
+	fake.entryCountMutex.Lock()
+	fake.entryCountArgsForCall = append(fake.entryCountArgsForCall, struct{}{})
+	fake.recordInvocation("EntryCount", []interface{}{})
+	fake.entryCountMutex.Unlock()
+	if fake.EntryCountStub != nil {
+		return fake.EntryCountStub()
+	} else {
+		return fake.entryCountReturns.result1
+	}
+}
+
+func (fake *FakeNATSRoutingTable) EntryCountCallCount() int {
+	fake.entryCountMutex.RLock()
+	defer fake.entryCountMutex.RUnlock()
+	return len(fake.entryCountArgsForCall)
+}
+
+func (fake *FakeNATSRoutingTable) EntryCountReturns(result1 int) {
+	fake.EntryCountStub = nil
+	fake.entryCountReturns = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *FakeNATSRoutingTable) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -389,6 +564,8 @@ func (fake *FakeNATSRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.routeCountMutex.RUnlock()
 	fake.swapMutex.RLock()
 	defer fake.swapMutex.RUnlock()
+	fake.driftMutex.RLock()
+	defer fake.driftMutex.RUnlock()
 	fake.setRoutesMutex.RLock()
 	defer fake.setRoutesMutex.RUnlock()
 	fake.getRoutesMutex.RLock()
@@ -401,8 +578,16 @@ func (fake *FakeNATSRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.removeEndpointMutex.RUnlock()
 	fake.endpointsForIndexMutex.RLock()
 	defer fake.endpointsForIndexMutex.RUnlock()
+	fake.suppressHostMutex.RLock()
+	defer fake.suppressHostMutex.RUnlock()
+	fake.unsuppressHostMutex.RLock()
+	defer fake.unsuppressHostMutex.RUnlock()
 	fake.messagesToEmitMutex.RLock()
 	defer fake.messagesToEmitMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	fake.entryCountMutex.RLock()
+	defer fake.entryCountMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -74,12 +74,45 @@ type FakeTCPRoutingTable struct {
 	swapReturns struct {
 		result1 event.RoutingEvents
 	}
+	DriftStub        func(t routingtable.TCPRoutingTable) routingtable.Drift
+	driftMutex       sync.RWMutex
+	driftArgsForCall []struct {
+		t routingtable.TCPRoutingTable
+	}
+	driftReturns struct {
+		result1 routingtable.Drift
+	}
+	SuppressHostStub        func(host string) event.RoutingEvents
+	suppressHostMutex       sync.RWMutex
+	suppressHostArgsForCall []struct {
+		host string
+	}
+	suppressHostReturns struct {
+		result1 event.RoutingEvents
+	}
+	UnsuppressHostStub        func(host string)
+	unsuppressHostMutex       sync.RWMutex
+	unsuppressHostArgsForCall []struct {
+		host string
+	}
 	GetRoutingEventsStub        func() event.RoutingEvents
 	getRoutingEventsMutex       sync.RWMutex
 	getRoutingEventsArgsForCall []struct{}
 	getRoutingEventsReturns     struct {
 		result1 event.RoutingEvents
 	}
+	SnapshotStub        func() []routingtable.TCPTableEntry
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct{}
+	snapshotReturns     struct {
+		result1 []routingtable.TCPTableEntry
+	}
+	EntryCountStub        func() int
+	entryCountMutex       sync.RWMutex
+	entryCountArgsForCall []struct{}
+	entryCountReturns     struct {
+		result1 int
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -341,6 +374,96 @@ func (fake *FakeTCPRoutingTable) SwapReturns(result1 event.RoutingEvents) {
 	}{result1}
 }
 
+func (fake *FakeTCPRoutingTable) Drift(t routingtable.TCPRoutingTable) routingtable.Drift {
+	fake.driftMutex.Lock()
+	fake.driftArgsForCall = append(fake.driftArgsForCall, struct {
+		t routingtable.TCPRoutingTable
+	}{t})
+	fake.recordInvocation("Drift", []interface{}{t})
+	fake.driftMutex.Unlock()
+	if fake.DriftStub != nil {
+		return fake.DriftStub(t)
+	} else {
+		return fake.driftReturns.result1
+	}
+}
+
+func (fake *FakeTCPRoutingTable) DriftCallCount() int {
+	fake.driftMutex.RLock()
+	defer fake.driftMutex.RUnlock()
+	return len(fake.driftArgsForCall)
+}
+
+func (fake *FakeTCPRoutingTable) DriftArgsForCall(i int) routingtable.TCPRoutingTable {
+	fake.driftMutex.RLock()
+	defer fake.driftMutex.RUnlock()
+	return fake.driftArgsForCall[i].t
+}
+
+func (fake *FakeTCPRoutingTable) DriftReturns(result1 routingtable.Drift) {
+	fake.DriftStub = nil
+	fake.driftReturns = struct {
+		result1 routingtable.Drift
+	}{result1}
+}
+
+func (fake *FakeTCPRoutingTable) SuppressHost(host string) event.RoutingEvents {
+	fake.suppressHostMutex.Lock()
+	fake.suppressHostArgsForCall = append(fake.suppressHostArgsForCall, struct {
+		host string
+	}{host})
+	fake.recordInvocation("SuppressHost", []interface{}{host})
+	fake.suppressHostMutex.Unlock()
+	if fake.SuppressHostStub != nil {
+		return fake.SuppressHostStub(host)
+	} else {
+		return fake.suppressHostReturns.result1
+	}
+}
+
+func (fake *FakeTCPRoutingTable) SuppressHostCallCount() int {
+	fake.suppressHostMutex.RLock()
+	defer fake.suppressHostMutex.RUnlock()
+	return len(fake.suppressHostArgsForCall)
+}
+
+func (fake *FakeTCPRoutingTable) SuppressHostArgsForCall(i int) string {
+	fake.suppressHostMutex.RLock()
+	defer fake.suppressHostMutex.RUnlock()
+	return fake.suppressHostArgsForCall[i].host
+}
+
+func (fake *FakeTCPRoutingTable) SuppressHostReturns(result1 event.RoutingEvents) {
+	fake.SuppressHostStub = nil
+	fake.suppressHostReturns = struct {
+		result1 event.RoutingEvents
+	}{result1}
+}
+
+func (fake *FakeTCPRoutingTable) UnsuppressHost(host string) {
+	fake.unsuppressHostMutex.Lock()
+	fake.unsuppressHostArgsForCall = append(fake.unsuppressHostArgsForCall, struct {
+		host string
+	}{host})
+	fake.recordInvocation("UnsuppressHost", []interface{}{host})
+	fake.unsuppressHostMutex.Unlock()
+	if fake.UnsuppressHostStub != nil {
+		fake.UnsuppressHostStub(host)
+	}
+}
+
+func (fake *FakeTCPRoutingTable) UnsuppressHostCallCount() int {
+	fake.unsuppressHostMutex.RLock()
+	defer fake.unsuppressHostMutex.RUnlock()
+	return len(fake.unsuppressHostArgsForCall)
+}
+
+func (fake *FakeTCPRoutingTable) UnsuppressHostArgsForCall(i int) string {
+	fake.unsuppressHostMutex.RLock()
+	defer fake.unsuppressHostMutex.RUnlock()
+	return fake.unsuppressHostArgsForCall[i].host
+}
+
 func (fake *FakeTCPRoutingTable) GetRoutingEvents() event.RoutingEvents {
 	fake.getRoutingEventsMutex.Lock()
 	fake.getRoutingEventsArgsForCall = append(fake.getRoutingEventsArgsForCall, struct{}{})
@@ -366,6 +489,56 @@ func (fake *FakeTCPRoutingTable) GetRoutingEventsReturns(result1 event.RoutingEv
 	}{result1}
 }
 
+func (fake *FakeTCPRoutingTable) Snapshot() []routingtable.TCPTableEntry {
+	fake.snapshotMutex.Lock()
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct{}{})
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if fake.SnapshotStub != nil {
+		return fake.SnapshotStub()
+	} else {
+		return fake.snapshotReturns.result1
+	}
+}
+
+func (fake *FakeTCPRoutingTable) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeTCPRoutingTable) SnapshotReturns(result1 []routingtable.TCPTableEntry) {
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 []routingtable.TCPTableEntry
+	}{result1}
+}
+
+func (fake *FakeTCPRoutingTable) EntryCount() int {
+	fake.entryCountMutex.Lock()
+	fake.entryCountArgsForCall = append(fake.entryCountArgsForCall, struct{}{})
+	fake.recordInvocation("EntryCount", []interface{}{})
+	fake.entryCountMutex.Unlock()
+	if fake.EntryCountStub != nil {
+		return fake.EntryCountStub()
+	} else {
+		return fake.entryCountReturns.result1
+	}
+}
+
+func (fake *FakeTCPRoutingTable) EntryCountCallCount() int {
+	fake.entryCountMutex.RLock()
+	defer fake.entryCountMutex.RUnlock()
+	return len(fake.entryCountArgsForCall)
+}
+
+func (fake *FakeTCPRoutingTable) EntryCountReturns(result1 int) {
+	fake.EntryCountStub = nil
+	fake.entryCountReturns = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *FakeTCPRoutingTable) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -385,8 +558,18 @@ func (fake *FakeTCPRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.removeEndpointMutex.RUnlock()
 	fake.swapMutex.RLock()
 	defer fake.swapMutex.RUnlock()
+	fake.driftMutex.RLock()
+	defer fake.driftMutex.RUnlock()
+	fake.suppressHostMutex.RLock()
+	defer fake.suppressHostMutex.RUnlock()
+	fake.unsuppressHostMutex.RLock()
+	defer fake.unsuppressHostMutex.RUnlock()
 	fake.getRoutingEventsMutex.RLock()
 	defer fake.getRoutingEventsMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	fake.entryCountMutex.RLock()
+	defer fake.entryCountMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -16,8 +16,18 @@ func MatchMessagesToEmit(messages routingtable.MessagesToEmit) *messagesToEmitMa
 	}
 }
 
+// MatchWireMessagesToEmit only compares what the routers receive, leaving out
+// the process guids and reasons of the messages.
+func MatchWireMessagesToEmit(messages routingtable.MessagesToEmit) *messagesToEmitMatcher {
+	return &messagesToEmitMatcher{
+		expected: messages,
+		wire:     true,
+	}
+}
+
 type messagesToEmitMatcher struct {
 	expected routingtable.MessagesToEmit
+	wire     bool
 }
 
 func (m *messagesToEmitMatcher) Match(a interface{}) (success bool, err error) {
@@ -49,11 +59,17 @@ func (m *messagesToEmitMatcher) matchArrs(actual, expected []routingtable.Regist
 
 	for _, message := range actual {
 		sort.Sort(sort.StringSlice(message.URIs))
+		if m.wire {
+			message = wireMessage(message)
+		}
 		fixedActual = append(fixedActual, message)
 	}
 
 	for _, message := range expected {
 		sort.Sort(sort.StringSlice(message.URIs))
+		if m.wire {
+			message = wireMessage(message)
+		}
 		fixedExpected = append(fixedExpected, message)
 	}
 
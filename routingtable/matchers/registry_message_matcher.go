@@ -15,8 +15,18 @@ func MatchRegistryMessage(message routingtable.RegistryMessage) *registryMessage
 	}
 }
 
+// MatchWireRegistryMessage only compares what the routers receive, leaving
+// out the process guid and reason.
+func MatchWireRegistryMessage(message routingtable.RegistryMessage) *registryMessageMatcher {
+	return &registryMessageMatcher{
+		expected: message,
+		wire:     true,
+	}
+}
+
 type registryMessageMatcher struct {
 	expected routingtable.RegistryMessage
+	wire     bool
 }
 
 func (m *registryMessageMatcher) Match(a interface{}) (success bool, err error) {
@@ -25,9 +35,14 @@ func (m *registryMessageMatcher) Match(a interface{}) (success bool, err error)
 		return false, fmt.Errorf("%s is not a routingtable.RegistryMessage", format.Object(actual, 1))
 	}
 
-	sort.Sort(sort.StringSlice(m.expected.URIs))
+	expected := m.expected
+	if m.wire {
+		actual, expected = wireMessage(actual), wireMessage(expected)
+	}
+
+	sort.Sort(sort.StringSlice(expected.URIs))
 	sort.Sort(sort.StringSlice(actual.URIs))
-	return reflect.DeepEqual(actual, m.expected), nil
+	return reflect.DeepEqual(actual, expected), nil
 }
 
 func (m *registryMessageMatcher) FailureMessage(actual interface{}) (message string) {
@@ -37,3 +52,11 @@ func (m *registryMessageMatcher) FailureMessage(actual interface{}) (message str
 func (m *registryMessageMatcher) NegatedFailureMessage(actual interface{}) (message string) {
 	return format.Message(actual, "not to match", m.expected)
 }
+
+// wireMessage is the message as the routers receive it, without the process
+// guid and reason that are only there for the audit log.
+func wireMessage(message routingtable.RegistryMessage) routingtable.RegistryMessage {
+	message.ProcessGUID = ""
+	message.Reason = ""
+	return message
+}
@@ -1,48 +1,55 @@
 package routingtable
 
-import "code.cloudfoundry.org/bbs/models"
-
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+)
+
+// MessageBuilder builds the messages for the entries of a routing key. The
+// messages carry the process guid of the key and the reason they are built
+// for, which are not sent to the routers.
 type MessageBuilder interface {
-	RegistrationsFor(existingEntry, newEntry *RoutableEndpoints) MessagesToEmit
-	UnfreshRegistrations(existingEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit
-	MergedRegistrations(existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit
-	UnregistrationsFor(existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit
+	RegistrationsFor(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, reason event.Reason) MessagesToEmit
+	UnfreshRegistrations(key endpoint.RoutingKey, existingEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit
+	MergedRegistrations(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit
+	UnregistrationsFor(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit
 }
 
 type NoopMessageBuilder struct {
 }
 
-func (NoopMessageBuilder) RegistrationsFor(existingEntry, newEntry *RoutableEndpoints) MessagesToEmit {
+func (NoopMessageBuilder) RegistrationsFor(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, reason event.Reason) MessagesToEmit {
 	return MessagesToEmit{}
 }
 
-func (NoopMessageBuilder) UnfreshRegistrations(existingEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit {
+func (NoopMessageBuilder) UnfreshRegistrations(key endpoint.RoutingKey, existingEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit {
 	return MessagesToEmit{}
 }
 
-func (NoopMessageBuilder) MergedRegistrations(existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit {
+func (NoopMessageBuilder) MergedRegistrations(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit {
 	return MessagesToEmit{}
 }
 
-func (NoopMessageBuilder) UnregistrationsFor(existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit {
+func (NoopMessageBuilder) UnregistrationsFor(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit {
 	return MessagesToEmit{}
 }
 
 type MessagesToEmitBuilder struct {
 }
 
-func (MessagesToEmitBuilder) UnfreshRegistrations(existingEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit {
+func (MessagesToEmitBuilder) UnfreshRegistrations(key endpoint.RoutingKey, existingEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit {
 	messagesToEmit := MessagesToEmit{}
 	for _, endpoint := range existingEntry.Endpoints {
 		if domains != nil && !domains.Contains(endpoint.Domain) {
-			createAndAddMessages(endpoint, existingEntry.Routes, &messagesToEmit.RegistrationMessages)
+			createAndAddMessages(endpoint, existingEntry.Routes, &messagesToEmit.RegistrationMessages, key.ProcessGUID, reason)
 		}
 	}
 
 	return messagesToEmit
 }
 
-func (MessagesToEmitBuilder) MergedRegistrations(existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit {
+func (MessagesToEmitBuilder) MergedRegistrations(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit {
 	messagesToEmit := MessagesToEmit{}
 
 	for _, endpoint := range newEntry.Endpoints {
@@ -68,12 +75,12 @@ func (MessagesToEmitBuilder) MergedRegistrations(existingEntry, newEntry *Routab
 			continue
 		}
 
-		createAndAddMessages(endpoint, routeList, &messagesToEmit.RegistrationMessages)
+		createAndAddMessages(endpoint, routeList, &messagesToEmit.RegistrationMessages, key.ProcessGUID, reason)
 	}
 	return messagesToEmit
 }
 
-func (MessagesToEmitBuilder) RegistrationsFor(existingEntry, newEntry *RoutableEndpoints) MessagesToEmit {
+func (MessagesToEmitBuilder) RegistrationsFor(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, reason event.Reason) MessagesToEmit {
 	messagesToEmit := MessagesToEmit{}
 	if len(newEntry.Routes) == 0 {
 		//no hostnames, so nothing could possibly be registered
@@ -83,7 +90,7 @@ func (MessagesToEmitBuilder) RegistrationsFor(existingEntry, newEntry *RoutableE
 	// only new entry OR something changed between existing and new entry
 	if existingEntry == nil || hostnamesHaveChanged(existingEntry, newEntry) || routeServiceUrlHasChanged(existingEntry, newEntry) {
 		for _, endpoint := range newEntry.Endpoints {
-			createAndAddMessages(endpoint, newEntry.Routes, &messagesToEmit.RegistrationMessages)
+			createAndAddMessages(endpoint, newEntry.Routes, &messagesToEmit.RegistrationMessages, key.ProcessGUID, reason)
 		}
 		return messagesToEmit
 	}
@@ -91,14 +98,14 @@ func (MessagesToEmitBuilder) RegistrationsFor(existingEntry, newEntry *RoutableE
 	//otherwise only register *new* endpoints
 	for _, endpoint := range newEntry.Endpoints {
 		if !existingEntry.hasEndpoint(endpoint) {
-			createAndAddMessages(endpoint, newEntry.Routes, &messagesToEmit.RegistrationMessages)
+			createAndAddMessages(endpoint, newEntry.Routes, &messagesToEmit.RegistrationMessages, key.ProcessGUID, reason)
 		}
 	}
 
 	return messagesToEmit
 }
 
-func (MessagesToEmitBuilder) UnregistrationsFor(existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet) MessagesToEmit {
+func (MessagesToEmitBuilder) UnregistrationsFor(key endpoint.RoutingKey, existingEntry, newEntry *RoutableEndpoints, domains models.DomainSet, reason event.Reason) MessagesToEmit {
 	messagesToEmit := MessagesToEmit{}
 
 	if len(existingEntry.Routes) == 0 {
@@ -114,7 +121,7 @@ func (MessagesToEmitBuilder) UnregistrationsFor(existingEntry, newEntry *Routabl
 			// only unregister if domain is fresh or preforming event processing
 			if domains == nil || domains.Contains(endpoint.Domain) {
 				//if the endpoint has disappeared unregister all its previous hostnames
-				createAndAddMessages(endpoint, existingEntry.Routes, &messagesToEmit.UnregistrationMessages)
+				createAndAddMessages(endpoint, existingEntry.Routes, &messagesToEmit.UnregistrationMessages, key.ProcessGUID, reason)
 			}
 		}
 	}
@@ -131,7 +138,7 @@ func (MessagesToEmitBuilder) UnregistrationsFor(existingEntry, newEntry *Routabl
 			// only unregister if domain is fresh or preforming event processing
 			if domains == nil || domains.Contains(endpoint.Domain) {
 				//if a endpoint is still present, and hostnames have disappeared, unregister those hostnames
-				createAndAddMessages(endpoint, routesThatDisappeared, &messagesToEmit.UnregistrationMessages)
+				createAndAddMessages(endpoint, routesThatDisappeared, &messagesToEmit.UnregistrationMessages, key.ProcessGUID, reason)
 			}
 		}
 	}
@@ -166,9 +173,11 @@ func routeServiceUrlHasChanged(existingEntry, newEntry *RoutableEndpoints) bool
 	return false
 }
 
-func createAndAddMessages(endpoint Endpoint, routes []Route, messages *[]RegistryMessage) {
+func createAndAddMessages(endpoint Endpoint, routes []Route, messages *[]RegistryMessage, processGUID string, reason event.Reason) {
 	for _, route := range routes {
 		message := RegistryMessageFor(endpoint, route)
+		message.ProcessGUID = processGUID
+		message.Reason = reason
 		*messages = append(*messages, message)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,6 +22,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 	hostname2 := "bar.example.com"
 	hostname3 := "baz.example.com"
 	domain := "tests"
+	key := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
 
 	currentTag := &models.ModificationTag{Epoch: "abc", Index: 1}
 	endpoint1 := routingtable.Endpoint{InstanceGuid: "ig-1", Host: "1.1.1.1", Index: 0, Domain: domain, Port: 11, ContainerPort: 8080, Evacuating: false, ModificationTag: currentTag}
@@ -27,6 +30,13 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 	freshDomains := models.NewDomainSet([]string{"tests"})
 	noFreshDomains := models.NewDomainSet([]string{"foo"})
 
+	messageFor := func(e routingtable.Endpoint, hostname string, reason event.Reason) routingtable.RegistryMessage {
+		message := routingtable.RegistryMessageFor(e, routingtable.Route{Hostname: hostname})
+		message.ProcessGUID = key.ProcessGUID
+		message.Reason = reason
+		return message
+	}
+
 	BeforeEach(func() {
 		builder = routingtable.MessagesToEmitBuilder{}
 	})
@@ -43,7 +53,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 		})
 
 		JustBeforeEach(func() {
-			messages = builder.UnfreshRegistrations(existingEntry, domains)
+			messages = builder.UnfreshRegistrations(key, existingEntry, domains, event.ReasonSync)
 		})
 
 		Context("when domain is fresh", func() {
@@ -64,8 +74,8 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 			It("does emits a registration", func() {
 				expected := routingtable.MessagesToEmit{
 					RegistrationMessages: []routingtable.RegistryMessage{
-						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
+						messageFor(endpoint1, hostname1, event.ReasonSync),
+						messageFor(endpoint1, hostname2, event.ReasonSync),
 					},
 				}
 				Expect(messages).To(MatchMessagesToEmit(expected))
@@ -85,7 +95,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 		})
 
 		JustBeforeEach(func() {
-			messages = builder.MergedRegistrations(existingEntry, newEntry, domains)
+			messages = builder.MergedRegistrations(key, existingEntry, newEntry, domains, event.ReasonSync)
 		})
 
 		Context("when domain is fresh", func() {
@@ -107,8 +117,8 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				It("does emits a registration", func() {
 					expected := routingtable.MessagesToEmit{
 						RegistrationMessages: []routingtable.RegistryMessage{
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname3}),
+							messageFor(endpoint1, hostname1, event.ReasonSync),
+							messageFor(endpoint1, hostname3, event.ReasonSync),
 						},
 					}
 					Expect(messages).To(MatchMessagesToEmit(expected))
@@ -136,8 +146,8 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				It("emits a registration", func() {
 					expected := routingtable.MessagesToEmit{
 						RegistrationMessages: []routingtable.RegistryMessage{
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname3}),
+							messageFor(endpoint1, hostname1, event.ReasonSync),
+							messageFor(endpoint1, hostname3, event.ReasonSync),
 						},
 					}
 					Expect(messages).To(MatchMessagesToEmit(expected))
@@ -171,8 +181,8 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				It("does emits an registration", func() {
 					expected := routingtable.MessagesToEmit{
 						RegistrationMessages: []routingtable.RegistryMessage{
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
+							messageFor(endpoint1, hostname1, event.ReasonSync),
+							messageFor(endpoint1, hostname2, event.ReasonSync),
 						},
 					}
 					Expect(messages).To(MatchMessagesToEmit(expected))
@@ -195,8 +205,8 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 					It("emits the registration", func() {
 						expected := routingtable.MessagesToEmit{
 							RegistrationMessages: []routingtable.RegistryMessage{
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
+								messageFor(endpoint1, hostname1, event.ReasonSync),
+								messageFor(endpoint1, hostname2, event.ReasonSync),
 							},
 						}
 						Expect(messages).To(MatchMessagesToEmit(expected))
@@ -218,9 +228,9 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				It("emits a merged registration", func() {
 					expected := routingtable.MessagesToEmit{
 						RegistrationMessages: []routingtable.RegistryMessage{
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname3}),
+							messageFor(endpoint1, hostname1, event.ReasonSync),
+							messageFor(endpoint1, hostname2, event.ReasonSync),
+							messageFor(endpoint1, hostname3, event.ReasonSync),
 						},
 					}
 					Expect(messages).To(MatchMessagesToEmit(expected))
@@ -250,18 +260,24 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 		})
 
 		JustBeforeEach(func() {
-			messages = builder.RegistrationsFor(existingEntry, newEntry)
+			messages = builder.RegistrationsFor(key, existingEntry, newEntry, event.ReasonDesiredChange)
 		})
 
 		Context("when no existing entry", func() {
 			It("emits a registration", func() {
 				expected := routingtable.MessagesToEmit{
 					RegistrationMessages: []routingtable.RegistryMessage{
-						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
+						messageFor(endpoint1, hostname1, event.ReasonDesiredChange),
 					},
 				}
 				Expect(messages).To(MatchMessagesToEmit(expected))
 			})
+
+			It("tags the registration with the process guid and the reason", func() {
+				Expect(messages.RegistrationMessages).To(HaveLen(1))
+				Expect(messages.RegistrationMessages[0].ProcessGUID).To(Equal("process-guid"))
+				Expect(messages.RegistrationMessages[0].Reason).To(Equal(event.ReasonDesiredChange))
+			})
 		})
 
 		Context("when new entry has no hostnames", func() {
@@ -301,7 +317,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				It("emits a registration", func() {
 					expected := routingtable.MessagesToEmit{
 						RegistrationMessages: []routingtable.RegistryMessage{
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
+							messageFor(endpoint1, hostname1, event.ReasonDesiredChange),
 						},
 					}
 					Expect(messages).To(MatchMessagesToEmit(expected))
@@ -321,7 +337,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				It("emits a registration", func() {
 					expected := routingtable.MessagesToEmit{
 						RegistrationMessages: []routingtable.RegistryMessage{
-							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
+							messageFor(endpoint1, hostname1, event.ReasonDesiredChange),
 						},
 					}
 					Expect(messages).To(MatchMessagesToEmit(expected))
@@ -344,7 +360,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 					It("emits a registration", func() {
 						expected := routingtable.MessagesToEmit{
 							RegistrationMessages: []routingtable.RegistryMessage{
-								routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname1}),
+								messageFor(endpoint2, hostname1, event.ReasonDesiredChange),
 							},
 						}
 						Expect(messages).To(MatchMessagesToEmit(expected))
@@ -396,15 +412,15 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				BeforeEach(func() {
 					domains := models.NewDomainSet([]string{"tests"})
 
-					messages = builder.UnregistrationsFor(existingEntry, newEntry, domains)
+					messages = builder.UnregistrationsFor(key, existingEntry, newEntry, domains, event.ReasonSync)
 				})
 
 				Context("when an endpoint is removed", func() {
 					It("emits an unregistration", func() {
 						expected := routingtable.MessagesToEmit{
 							UnregistrationMessages: []routingtable.RegistryMessage{
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
+								messageFor(endpoint1, hostname1, event.ReasonSync),
+								messageFor(endpoint1, hostname2, event.ReasonSync),
 							},
 						}
 						Expect(messages).To(MatchMessagesToEmit(expected))
@@ -416,7 +432,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 				BeforeEach(func() {
 					domains := models.NewDomainSet([]string{"foo"})
 
-					messages = builder.UnregistrationsFor(existingEntry, newEntry, domains)
+					messages = builder.UnregistrationsFor(key, existingEntry, newEntry, domains, event.ReasonSync)
 				})
 
 				Context("when an endpoint is removed", func() {
@@ -431,7 +447,7 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 		Context("when doing event processing", func() {
 
 			JustBeforeEach(func() {
-				messages = builder.UnregistrationsFor(existingEntry, newEntry, nil)
+				messages = builder.UnregistrationsFor(key, existingEntry, newEntry, nil, event.ReasonActualChange)
 			})
 
 			Context("when there are no hostnames in the existing", func() {
@@ -474,8 +490,8 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 					It("emits an unregistration", func() {
 						expected := routingtable.MessagesToEmit{
 							UnregistrationMessages: []routingtable.RegistryMessage{
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
+								messageFor(endpoint1, hostname1, event.ReasonActualChange),
+								messageFor(endpoint1, hostname2, event.ReasonActualChange),
 							},
 						}
 						Expect(messages).To(MatchMessagesToEmit(expected))
@@ -546,12 +562,19 @@ var _ = Describe("MessagesToEmitBuilder", func() {
 					It("emits an unregistration", func() {
 						expected := routingtable.MessagesToEmit{
 							UnregistrationMessages: []routingtable.RegistryMessage{
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1}),
-								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2}),
+								messageFor(endpoint1, hostname1, event.ReasonActualChange),
+								messageFor(endpoint1, hostname2, event.ReasonActualChange),
 							},
 						}
 						Expect(messages).To(MatchMessagesToEmit(expected))
 					})
+
+					It("tags the unregistrations with the process guid and the reason", func() {
+						for _, message := range messages.UnregistrationMessages {
+							Expect(message.ProcessGUID).To(Equal("process-guid"))
+							Expect(message.Reason).To(Equal(event.ReasonActualChange))
+						}
+					})
 				})
 
 				Context("when an endpoint has been added", func() {
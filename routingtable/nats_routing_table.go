@@ -6,6 +6,7 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
 	"code.cloudfoundry.org/runtimeschema/metric"
 )
 
@@ -17,6 +18,7 @@ type NATSRoutingTable interface {
 	RouteCount() int
 
 	Swap(newTable NATSRoutingTable, domains models.DomainSet) MessagesToEmit
+	Drift(newTable NATSRoutingTable, domains models.DomainSet) Drift
 
 	SetRoutes(key endpoint.RoutingKey, routes []Route, modTag *models.ModificationTag) MessagesToEmit
 	GetRoutes(key endpoint.RoutingKey) []Route
@@ -25,7 +27,12 @@ type NATSRoutingTable interface {
 	RemoveEndpoint(key endpoint.RoutingKey, routingEndpoint Endpoint) MessagesToEmit
 	EndpointsForIndex(key endpoint.RoutingKey, index int32) []Endpoint
 
+	SuppressHost(host string) MessagesToEmit
+	UnsuppressHost(host string)
+
 	MessagesToEmit() MessagesToEmit
+	Snapshot() []NATSTableEntry
+	EntryCount() int
 }
 
 type noopLocker struct{}
@@ -36,48 +43,66 @@ func (noopLocker) Unlock() {}
 type natsRoutingTable struct {
 	entries        map[endpoint.RoutingKey]RoutableEndpoints
 	addressEntries map[Address]EndpointKey // for collision detection
+	// endpoints on these hosts are kept out of the table
+	suppressedHosts map[string]struct{}
 	sync.Locker
 	messageBuilder MessageBuilder
 	logger         lager.Logger
 }
 
 func NewTempTable(routesMap RoutesByRoutingKey, endpointsByKey EndpointsByRoutingKey) NATSRoutingTable {
-	entries := make(map[endpoint.RoutingKey]RoutableEndpoints)
-	addressEntries := make(map[Address]EndpointKey)
+	table := &natsRoutingTable{
+		entries:        make(map[endpoint.RoutingKey]RoutableEndpoints),
+		addressEntries: make(map[Address]EndpointKey),
+		Locker:         noopLocker{},
+		messageBuilder: NoopMessageBuilder{},
+	}
+	table.addTempEntries(routesMap, endpointsByKey)
+
+	return table
+}
+
+// AppendToTempTable adds routes and endpoints to a table created with
+// NewTempTable, so that a sync table can be built up one batch at a time.
+func AppendToTempTable(t NATSRoutingTable, routesMap RoutesByRoutingKey, endpointsByKey EndpointsByRoutingKey) {
+	table, ok := t.(*natsRoutingTable)
+	if !ok {
+		return
+	}
+	table.addTempEntries(routesMap, endpointsByKey)
+}
 
+func (table *natsRoutingTable) addTempEntries(routesMap RoutesByRoutingKey, endpointsByKey EndpointsByRoutingKey) {
 	for key, routes := range routesMap {
-		entries[key] = RoutableEndpoints{
-			Routes: routes,
-		}
+		entry := table.entries[key]
+		entry.Routes = append(entry.Routes, routes...)
+		table.entries[key] = entry
 	}
 
 	for key, endpoints := range endpointsByKey {
-		entry, ok := entries[key]
-		if !ok {
-			entry = RoutableEndpoints{}
+		entry := table.entries[key]
+		if entry.Endpoints == nil {
+			entry.Endpoints = EndpointsAsMap(endpoints)
+		} else {
+			for _, endpoint := range endpoints {
+				entry.Endpoints[endpoint.key()] = endpoint
+			}
 		}
-		entry.Endpoints = EndpointsAsMap(endpoints)
-		entries[key] = entry
+		table.entries[key] = entry
 		for _, endpoint := range endpoints {
-			addressEntries[endpoint.address()] = endpoint.key()
+			table.addressEntries[endpoint.address()] = endpoint.key()
 		}
 	}
-
-	return &natsRoutingTable{
-		entries:        entries,
-		addressEntries: addressEntries,
-		Locker:         noopLocker{},
-		messageBuilder: NoopMessageBuilder{},
-	}
 }
 
 func NewNATSTable(logger lager.Logger) NATSRoutingTable {
 	return &natsRoutingTable{
-		entries:        make(map[endpoint.RoutingKey]RoutableEndpoints),
-		addressEntries: make(map[Address]EndpointKey),
-		Locker:         &sync.Mutex{},
-		messageBuilder: MessagesToEmitBuilder{},
-		logger:         logger,
+		entries:         make(map[endpoint.RoutingKey]RoutableEndpoints),
+		addressEntries:  make(map[Address]EndpointKey),
+		suppressedHosts: make(map[string]struct{}),
+		Locker:          &sync.Mutex{},
+		messageBuilder:  MessagesToEmitBuilder{},
+		logger:          logger,
 	}
 }
 
@@ -122,11 +147,15 @@ func (table *natsRoutingTable) Swap(t NATSRoutingTable, domains models.DomainSet
 
 	table.Lock()
 	for key, newEntry := range newEntries {
+		if filtered, ok := table.withoutSuppressedHosts(newEntry); ok {
+			newEntry = filtered
+		}
+
 		// See if we have a match
 		existingEntry, _ := table.entries[key]
 
 		//always register everything on sync  NOTE if a merge does occur we may return an altered newEntry
-		messagesToEmit = messagesToEmit.Merge(table.messageBuilder.MergedRegistrations(&existingEntry, &newEntry, domains))
+		messagesToEmit = messagesToEmit.Merge(table.messageBuilder.MergedRegistrations(key, &existingEntry, &newEntry, domains, event.ReasonSync))
 		updatedEntries[key] = newEntry
 		for _, endpoint := range newEntry.Endpoints {
 			updatedAddressEntries[endpoint.address()] = endpoint.key()
@@ -135,11 +164,11 @@ func (table *natsRoutingTable) Swap(t NATSRoutingTable, domains models.DomainSet
 
 	for key, existingEntry := range table.entries {
 		newEntry, ok := newEntries[key]
-		messagesToEmit = messagesToEmit.Merge(table.messageBuilder.UnregistrationsFor(&existingEntry, &newEntry, domains))
+		messagesToEmit = messagesToEmit.Merge(table.messageBuilder.UnregistrationsFor(key, &existingEntry, &newEntry, domains, event.ReasonSync))
 
 		// maybe reemit old ones no longer found in the new table
 		if !ok {
-			unfreshRegistrations := table.messageBuilder.UnfreshRegistrations(&existingEntry, domains)
+			unfreshRegistrations := table.messageBuilder.UnfreshRegistrations(key, &existingEntry, domains, event.ReasonSync)
 			if len(unfreshRegistrations.RegistrationMessages) > 0 {
 				updatedEntries[key] = existingEntry
 				for _, endpoint := range existingEntry.Endpoints {
@@ -157,12 +186,41 @@ func (table *natsRoutingTable) Swap(t NATSRoutingTable, domains models.DomainSet
 	return messagesToEmit
 }
 
+// Drift returns how the table differs from a table built during a sync,
+// without modifying either table. Endpoints on suppressed hosts are left out,
+// as Swap keeps them out of the table.
+func (table *natsRoutingTable) Drift(t NATSRoutingTable, domains models.DomainSet) Drift {
+	drift := Drift{}
+
+	newTable, ok := t.(*natsRoutingTable)
+	if !ok {
+		return drift
+	}
+
+	table.Lock()
+	defer table.Unlock()
+
+	for key, newEntry := range newTable.entries {
+		if filtered, ok := table.withoutSuppressedHosts(newEntry); ok {
+			newEntry = filtered
+		}
+		drift.add(key, natsEntryDrift(table.entries[key], newEntry, domains))
+	}
+	for key, existingEntry := range table.entries {
+		if _, ok := newTable.entries[key]; !ok {
+			drift.add(key, natsEntryDrift(existingEntry, RoutableEndpoints{}, domains))
+		}
+	}
+
+	return drift
+}
+
 func (table *natsRoutingTable) MessagesToEmit() MessagesToEmit {
 	table.Lock()
 
 	messagesToEmit := MessagesToEmit{}
-	for _, entry := range table.entries {
-		messagesToEmit = messagesToEmit.Merge(table.messageBuilder.RegistrationsFor(nil, &entry))
+	for key, entry := range table.entries {
+		messagesToEmit = messagesToEmit.Merge(table.messageBuilder.RegistrationsFor(key, nil, &entry, event.ReasonEmit))
 	}
 
 	table.Unlock()
@@ -183,7 +241,7 @@ func (table *natsRoutingTable) SetRoutes(key endpoint.RoutingKey, routes []Route
 	newEntry.ModificationTag = modTag
 	table.entries[key] = newEntry
 
-	return table.emit(key, currentEntry, newEntry)
+	return table.emit(key, currentEntry, newEntry, event.ReasonDesiredChange)
 }
 
 func (table *natsRoutingTable) GetRoutes(key endpoint.RoutingKey) []Route {
@@ -209,13 +267,17 @@ func (table *natsRoutingTable) RemoveRoutes(key endpoint.RoutingKey, modTag *mod
 
 	table.entries[key] = newEntry
 
-	return table.emit(key, currentEntry, newEntry)
+	return table.emit(key, currentEntry, newEntry, event.ReasonDesiredChange)
 }
 
 func (table *natsRoutingTable) AddEndpoint(key endpoint.RoutingKey, routingEndpoint Endpoint) MessagesToEmit {
 	table.Lock()
 	defer table.Unlock()
 
+	if _, ok := table.suppressedHosts[routingEndpoint.Host]; ok {
+		return MessagesToEmit{}
+	}
+
 	currentEntry := table.entries[key]
 	newEntry := currentEntry.copy()
 	newEntry.Endpoints[routingEndpoint.key()] = routingEndpoint
@@ -223,6 +285,7 @@ func (table *natsRoutingTable) AddEndpoint(key endpoint.RoutingKey, routingEndpo
 
 	address := routingEndpoint.address()
 
+	reason := event.ReasonActualChange
 	if existingEndpointKey, ok := table.addressEntries[address]; ok {
 		if existingEndpointKey.InstanceGuid != routingEndpoint.InstanceGuid {
 			addressCollisions.Add(1)
@@ -232,12 +295,13 @@ func (table *natsRoutingTable) AddEndpoint(key endpoint.RoutingKey, routingEndpo
 				"instance_guid_b": routingEndpoint.InstanceGuid,
 				"Address":         routingEndpoint.address(),
 			})
+			reason = event.ReasonCollision
 		}
 	}
 
 	table.addressEntries[address] = routingEndpoint.key()
 
-	return table.emit(key, currentEntry, newEntry)
+	return table.emit(key, currentEntry, newEntry, reason)
 }
 
 func (table *natsRoutingTable) RemoveEndpoint(key endpoint.RoutingKey, routingEndpoint Endpoint) MessagesToEmit {
@@ -258,12 +322,65 @@ func (table *natsRoutingTable) RemoveEndpoint(key endpoint.RoutingKey, routingEn
 
 	delete(table.addressEntries, routingEndpoint.address())
 
-	return table.emit(key, currentEntry, newEntry)
+	return table.emit(key, currentEntry, newEntry, event.ReasonActualChange)
+}
+
+// SuppressHost removes every endpoint on the host, and keeps endpoints on it
+// out of the table until UnsuppressHost is called, whether they are added by
+// events or by a sync.
+func (table *natsRoutingTable) SuppressHost(host string) MessagesToEmit {
+	table.Lock()
+	defer table.Unlock()
+
+	table.suppressedHosts[host] = struct{}{}
+
+	messagesToEmit := MessagesToEmit{}
+	for key, currentEntry := range table.entries {
+		newEntry, ok := table.withoutSuppressedHosts(currentEntry)
+		if !ok {
+			continue
+		}
+
+		for endpointKey, endpoint := range currentEntry.Endpoints {
+			if _, ok := newEntry.Endpoints[endpointKey]; !ok {
+				delete(table.addressEntries, endpoint.address())
+			}
+		}
+		table.entries[key] = newEntry
+		messagesToEmit = messagesToEmit.Merge(table.emit(key, currentEntry, newEntry, event.ReasonActualChange))
+	}
+
+	return messagesToEmit
+}
+
+func (table *natsRoutingTable) UnsuppressHost(host string) {
+	table.Lock()
+	defer table.Unlock()
+
+	delete(table.suppressedHosts, host)
+}
+
+// withoutSuppressedHosts returns a copy of the entry without the endpoints on
+// suppressed hosts, and false if it has none.
+func (table *natsRoutingTable) withoutSuppressedHosts(entry RoutableEndpoints) (RoutableEndpoints, bool) {
+	var filtered RoutableEndpoints
+	found := false
+	for endpointKey, endpoint := range entry.Endpoints {
+		if _, ok := table.suppressedHosts[endpoint.Host]; !ok {
+			continue
+		}
+		if !found {
+			filtered = entry.copy()
+			found = true
+		}
+		delete(filtered.Endpoints, endpointKey)
+	}
+	return filtered, found
 }
 
-func (table *natsRoutingTable) emit(key endpoint.RoutingKey, oldEntry, newEntry RoutableEndpoints) MessagesToEmit {
-	messagesToEmit := table.messageBuilder.RegistrationsFor(&oldEntry, &newEntry)
-	messagesToEmit = messagesToEmit.Merge(table.messageBuilder.UnregistrationsFor(&oldEntry, &newEntry, nil))
+func (table *natsRoutingTable) emit(key endpoint.RoutingKey, oldEntry, newEntry RoutableEndpoints, reason event.Reason) MessagesToEmit {
+	messagesToEmit := table.messageBuilder.RegistrationsFor(key, &oldEntry, &newEntry, reason)
+	messagesToEmit = messagesToEmit.Merge(table.messageBuilder.UnregistrationsFor(key, &oldEntry, &newEntry, nil, reason))
 
 	return messagesToEmit
 }
@@ -9,6 +9,7 @@ import (
 
 	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
@@ -71,7 +72,7 @@ var _ = Describe("RoutingTable", func() {
 					routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
 				},
 			}
-			Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+			Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 
 			messagesToEmit = table.AddEndpoint(key, evacuating1)
 			Expect(messagesToEmit).To(BeZero())
@@ -92,7 +93,7 @@ var _ = Describe("RoutingTable", func() {
 						routingtable.RegistryMessageFor(newInstanceEndpointAfterEvacuation, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
 					},
 				}
-				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+				Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 
 				messagesToEmit = table.RemoveEndpoint(key, evacuating1)
 				expected = routingtable.MessagesToEmit{
@@ -100,7 +101,62 @@ var _ = Describe("RoutingTable", func() {
 						routingtable.RegistryMessageFor(evacuating1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
 					},
 				}
-				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+				Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
+			})
+		})
+	})
+
+	Describe("SuppressHost", func() {
+		BeforeEach(func() {
+			table.SetRoutes(key, []routingtable.Route{routingtable.Route{Hostname: hostname1, LogGuid: logGuid}}, currentTag)
+			table.AddEndpoint(key, endpoint1)
+			table.AddEndpoint(key, endpoint2)
+
+			messagesToEmit = table.SuppressHost("1.1.1.1")
+		})
+
+		It("unregisters the endpoints on the host", func() {
+			expected := routingtable.MessagesToEmit{
+				UnregistrationMessages: []routingtable.RegistryMessage{
+					routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
+				},
+			}
+			Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
+			Expect(table.RouteCount()).To(Equal(1))
+		})
+
+		It("keeps endpoints on the host from being added", func() {
+			Expect(table.AddEndpoint(key, collisionEndpoint)).To(BeZero())
+			Expect(table.RouteCount()).To(Equal(1))
+		})
+
+		It("keeps endpoints on the host out of a sync", func() {
+			tempTable := routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{routingtable.Route{Hostname: hostname1, LogGuid: logGuid}}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1, endpoint2}},
+			)
+
+			messagesToEmit = table.Swap(tempTable, domains)
+			expected := routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
+				},
+			}
+			Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
+		})
+
+		Context("when the host is unsuppressed", func() {
+			BeforeEach(func() {
+				table.UnsuppressHost("1.1.1.1")
+			})
+
+			It("adds endpoints on the host again", func() {
+				expected := routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
+					},
+				}
+				Expect(table.AddEndpoint(key, endpoint1)).To(MatchWireMessagesToEmit(expected))
 			})
 		})
 	})
@@ -138,7 +194,7 @@ var _ = Describe("RoutingTable", func() {
 						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname3, LogGuid: logGuid}),
 					},
 				}
-				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+				Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 			})
 
 			Context("when an endpoint is added that is a collision", func() {
@@ -177,7 +233,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname3, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -204,7 +260,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 		})
@@ -232,7 +288,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -268,7 +324,7 @@ var _ = Describe("RoutingTable", func() {
 								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
 							},
 						}
-						Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+						Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 					})
 				})
 
@@ -317,7 +373,7 @@ var _ = Describe("RoutingTable", func() {
 								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
 							},
 						}
-						Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+						Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 					})
 				})
 
@@ -370,7 +426,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid, RouteServiceUrl: "https://rs.new.example.com"}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 			})
@@ -409,7 +465,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -437,7 +493,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname3, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -462,7 +518,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid, RouteServiceUrl: "https://rs.example.com"}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 			})
@@ -490,7 +546,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint3, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -517,7 +573,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(evacuating1, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -551,7 +607,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(evacuating1, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -582,7 +638,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint3, routingtable.Route{Hostname: hostname3, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -608,7 +664,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -635,7 +691,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -661,7 +717,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -690,7 +746,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -717,7 +773,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -750,7 +806,7 @@ var _ = Describe("RoutingTable", func() {
 								routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 							},
 						}
-						Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+						Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 					})
 				})
 
@@ -768,7 +824,7 @@ var _ = Describe("RoutingTable", func() {
 								routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 							},
 						}
-						Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+						Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 					})
 
 					Context("when the domain is repeatedly not fresh", func() {
@@ -806,7 +862,7 @@ var _ = Describe("RoutingTable", func() {
 									routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 								},
 							}
-							Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+							Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 						})
 					})
 				})
@@ -863,6 +919,164 @@ var _ = Describe("RoutingTable", func() {
 		})
 	})
 
+	Describe("AppendToTempTable", func() {
+		var tempTable routingtable.NATSRoutingTable
+
+		otherKey := endpoint.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+
+		BeforeEach(func() {
+			tempTable = routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1}},
+			)
+		})
+
+		It("adds the routes and endpoints of another batch", func() {
+			routingtable.AppendToTempTable(
+				tempTable,
+				routingtable.RoutesByRoutingKey{otherKey: []routingtable.Route{
+					routingtable.Route{Hostname: hostname2, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{otherKey: {endpoint2}, key: {endpoint3}},
+			)
+			Expect(tempTable.RouteCount()).To(Equal(3))
+
+			messagesToEmit = table.Swap(tempTable, domains)
+			expected := routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
+					routingtable.RegistryMessageFor(endpoint3, routingtable.Route{Hostname: hostname1, LogGuid: logGuid}),
+					routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
+				},
+			}
+			Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
+		})
+
+		It("builds the same table as a single NewTempTable call", func() {
+			batchedTable := routingtable.NewTempTable(nil, nil)
+			routingtable.AppendToTempTable(
+				batchedTable,
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1}},
+			)
+
+			Expect(batchedTable.RouteCount()).To(Equal(tempTable.RouteCount()))
+			Expect(table.Swap(batchedTable, domains)).To(MatchMessagesToEmit(table.Swap(tempTable, domains)))
+		})
+	})
+
+	Describe("Drift", func() {
+		otherKey := endpoint.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+		otherDomainEndpoint := routingtable.Endpoint{InstanceGuid: "ig-6", Host: "6.6.6.6", Index: 0, Domain: "other-domain", Port: 66, ContainerPort: 8080, ModificationTag: currentTag}
+
+		BeforeEach(func() {
+			table.Swap(routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+					routingtable.Route{Hostname: hostname2, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1, endpoint2}},
+			), domains)
+		})
+
+		It("is empty when the tables match", func() {
+			tempTable := routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{
+					routingtable.Route{Hostname: hostname2, LogGuid: logGuid},
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint2, endpoint1}},
+			)
+
+			Expect(table.Drift(tempTable, domains).Empty()).To(BeTrue())
+		})
+
+		It("counts the routes and endpoints that were added and removed", func() {
+			tempTable := routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{
+					key: []routingtable.Route{
+						routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+						routingtable.Route{Hostname: hostname3, LogGuid: logGuid},
+					},
+					otherKey: []routingtable.Route{
+						routingtable.Route{Hostname: hostname3, LogGuid: logGuid},
+					},
+				},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1, endpoint3}},
+			)
+
+			drift := table.Drift(tempTable, domains)
+			Expect(drift.RoutesAdded).To(Equal(2))
+			Expect(drift.RoutesRemoved).To(Equal(1))
+			Expect(drift.EndpointsAdded).To(Equal(1))
+			Expect(drift.EndpointsRemoved).To(Equal(1))
+			Expect(drift.RoutingKeys).To(ConsistOf(key, otherKey))
+		})
+
+		It("counts the entries that are missing from the new table", func() {
+			drift := table.Drift(routingtable.NewTempTable(nil, nil), domains)
+			Expect(drift.RoutesRemoved).To(Equal(2))
+			Expect(drift.EndpointsRemoved).To(Equal(2))
+			Expect(drift.RoutingKeys).To(ConsistOf(key))
+		})
+
+		It("does not count endpoints in domains that are not fresh as removed", func() {
+			table.AddEndpoint(otherKey, otherDomainEndpoint)
+			table.SetRoutes(otherKey, []routingtable.Route{
+				routingtable.Route{Hostname: hostname3, LogGuid: logGuid},
+			}, nil)
+
+			tempTable := routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+					routingtable.Route{Hostname: hostname2, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1, endpoint2}},
+			)
+
+			Expect(table.Drift(tempTable, domains).Empty()).To(BeTrue())
+		})
+
+		It("does not count endpoints on suppressed hosts as added", func() {
+			table.SuppressHost(endpoint2.Host)
+
+			tempTable := routingtable.NewTempTable(
+				routingtable.RoutesByRoutingKey{key: []routingtable.Route{
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+					routingtable.Route{Hostname: hostname2, LogGuid: logGuid},
+				}},
+				routingtable.EndpointsByRoutingKey{key: {endpoint1, endpoint2}},
+			)
+
+			Expect(table.Drift(tempTable, domains).Empty()).To(BeTrue())
+		})
+
+		It("samples at most MaxDriftSample routing keys", func() {
+			routes := routingtable.RoutesByRoutingKey{}
+			for i := 0; i < routingtable.MaxDriftSample+5; i++ {
+				routes[endpoint.RoutingKey{ProcessGUID: fmt.Sprintf("pg-%d", i), ContainerPort: 8080}] = []routingtable.Route{
+					routingtable.Route{Hostname: hostname1, LogGuid: logGuid},
+				}
+			}
+
+			drift := table.Drift(routingtable.NewTempTable(routes, nil), domains)
+			Expect(drift.RoutesAdded).To(Equal(routingtable.MaxDriftSample + 5))
+			Expect(drift.RoutingKeys).To(HaveLen(routingtable.MaxDriftSample))
+		})
+
+		It("does not modify either table", func() {
+			tempTable := routingtable.NewTempTable(nil, nil)
+			table.Drift(tempTable, domains)
+
+			Expect(table.RouteCount()).To(Equal(4))
+			Expect(tempTable.RouteCount()).To(Equal(0))
+		})
+	})
+
 	Describe("Processing deltas", func() {
 		Context("when the table is empty", func() {
 			Context("When setting routes", func() {
@@ -930,7 +1144,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid, RouteServiceUrl: "https://rs.example.com"}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("emits nothing when a hostname is added to a route with an older tag", func() {
@@ -959,7 +1173,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname3, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("emits nothing when a hostname is removed from a route with an older tag", func() {
@@ -984,7 +1198,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("emits nothing when hostnames are added and removed from a route with an older tag", func() {
@@ -1013,7 +1227,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -1029,7 +1243,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("updates routing table with a newer tag", func() {
@@ -1048,7 +1262,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("updates routing table with a same tag", func() {
@@ -1099,7 +1313,17 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint3, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
+				})
+
+				It("emits the registrations for an actual change", func() {
+					messagesToEmit = table.AddEndpoint(key, endpoint3)
+
+					Expect(messagesToEmit.RegistrationMessages).NotTo(BeEmpty())
+					for _, message := range messagesToEmit.RegistrationMessages {
+						Expect(message.ProcessGUID).To(Equal(key.ProcessGUID))
+						Expect(message.Reason).To(Equal(event.ReasonActualChange))
+					}
 				})
 
 				It("does not log a collision", func() {
@@ -1120,6 +1344,15 @@ var _ = Describe("RoutingTable", func() {
 							),
 						))
 					})
+
+					It("emits the registrations for the collision", func() {
+						messagesToEmit = table.AddEndpoint(key, collisionEndpoint)
+
+						Expect(messagesToEmit.RegistrationMessages).NotTo(BeEmpty())
+						for _, message := range messagesToEmit.RegistrationMessages {
+							Expect(message.Reason).To(Equal(event.ReasonCollision))
+						}
+					})
 				})
 
 				Context("when an evacuating endpoint is added for an instance that already exists", func() {
@@ -1151,7 +1384,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("emits unregistrations when the tag is newer", func() {
@@ -1165,7 +1398,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 
 				It("emits nothing when the tag is older", func() {
@@ -1242,7 +1475,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname2, LogGuid: logGuid, RouteServiceUrl: "https://rs.example.com"}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 		})
@@ -1268,7 +1501,7 @@ var _ = Describe("RoutingTable", func() {
 							routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid, RouteServiceUrl: "https://rs.example.com"}),
 						},
 					}
-					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+					Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
 				})
 			})
 
@@ -1350,7 +1583,16 @@ var _ = Describe("RoutingTable", func() {
 						routingtable.RegistryMessageFor(endpoint2, routingtable.Route{Hostname: hostname2, LogGuid: logGuid}),
 					},
 				}
-				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+				Expect(messagesToEmit).To(MatchWireMessagesToEmit(expected))
+			})
+
+			It("emits the registrations for the periodic emit", func() {
+				messagesToEmit = table.MessagesToEmit()
+
+				for _, message := range messagesToEmit.RegistrationMessages {
+					Expect(message.ProcessGUID).To(Equal(key.ProcessGUID))
+					Expect(message.Reason).To(Equal(event.ReasonEmit))
+				}
 			})
 		})
 	})
@@ -0,0 +1,105 @@
+package routingtable
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+)
+
+// NewObservedNATSTable returns a table that calls changed after every
+// mutation of the given table, so that consumers of its contents, other than
+// the emitter, can tell when to read them again.
+func NewObservedNATSTable(table NATSRoutingTable, changed func()) NATSRoutingTable {
+	return &observedNATSTable{NATSRoutingTable: table, changed: changed}
+}
+
+type observedNATSTable struct {
+	NATSRoutingTable
+	changed func()
+}
+
+func (t *observedNATSTable) Swap(newTable NATSRoutingTable, domains models.DomainSet) MessagesToEmit {
+	defer t.changed()
+	return t.NATSRoutingTable.Swap(newTable, domains)
+}
+
+func (t *observedNATSTable) SetRoutes(key endpoint.RoutingKey, routes []Route, modTag *models.ModificationTag) MessagesToEmit {
+	defer t.changed()
+	return t.NATSRoutingTable.SetRoutes(key, routes, modTag)
+}
+
+func (t *observedNATSTable) RemoveRoutes(key endpoint.RoutingKey, modTag *models.ModificationTag) MessagesToEmit {
+	defer t.changed()
+	return t.NATSRoutingTable.RemoveRoutes(key, modTag)
+}
+
+func (t *observedNATSTable) AddEndpoint(key endpoint.RoutingKey, routingEndpoint Endpoint) MessagesToEmit {
+	defer t.changed()
+	return t.NATSRoutingTable.AddEndpoint(key, routingEndpoint)
+}
+
+func (t *observedNATSTable) RemoveEndpoint(key endpoint.RoutingKey, routingEndpoint Endpoint) MessagesToEmit {
+	defer t.changed()
+	return t.NATSRoutingTable.RemoveEndpoint(key, routingEndpoint)
+}
+
+func (t *observedNATSTable) SuppressHost(host string) MessagesToEmit {
+	defer t.changed()
+	return t.NATSRoutingTable.SuppressHost(host)
+}
+
+func (t *observedNATSTable) UnsuppressHost(host string) {
+	defer t.changed()
+	t.NATSRoutingTable.UnsuppressHost(host)
+}
+
+// NewObservedTCPTable returns a table that calls changed after every
+// mutation of the given table.
+func NewObservedTCPTable(table TCPRoutingTable, changed func()) TCPRoutingTable {
+	return &observedTCPTable{TCPRoutingTable: table, changed: changed}
+}
+
+type observedTCPTable struct {
+	TCPRoutingTable
+	changed func()
+}
+
+func (t *observedTCPTable) Swap(newTable TCPRoutingTable) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.Swap(newTable)
+}
+
+func (t *observedTCPTable) AddRoutes(desiredLRP *models.DesiredLRPSchedulingInfo) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.AddRoutes(desiredLRP)
+}
+
+func (t *observedTCPTable) UpdateRoutes(beforeLRP, afterLRP *models.DesiredLRPSchedulingInfo) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.UpdateRoutes(beforeLRP, afterLRP)
+}
+
+func (t *observedTCPTable) RemoveRoutes(desiredLRP *models.DesiredLRPSchedulingInfo) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.RemoveRoutes(desiredLRP)
+}
+
+func (t *observedTCPTable) AddEndpoint(actualLRP *endpoint.ActualLRPRoutingInfo) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.AddEndpoint(actualLRP)
+}
+
+func (t *observedTCPTable) RemoveEndpoint(actualLRP *endpoint.ActualLRPRoutingInfo) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.RemoveEndpoint(actualLRP)
+}
+
+func (t *observedTCPTable) SuppressHost(host string) event.RoutingEvents {
+	defer t.changed()
+	return t.TCPRoutingTable.SuppressHost(host)
+}
+
+func (t *observedTCPTable) UnsuppressHost(host string) {
+	defer t.changed()
+	t.TCPRoutingTable.UnsuppressHost(host)
+}
@@ -0,0 +1,129 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Observed routing tables", func() {
+	var changes int
+
+	changed := func() {
+		changes++
+	}
+
+	BeforeEach(func() {
+		changes = 0
+	})
+
+	Describe("NewObservedNATSTable", func() {
+		var (
+			fakeTable *fakeroutingtable.FakeNATSRoutingTable
+			table     routingtable.NATSRoutingTable
+			key       endpoint.RoutingKey
+		)
+
+		BeforeEach(func() {
+			fakeTable = &fakeroutingtable.FakeNATSRoutingTable{}
+			table = routingtable.NewObservedNATSTable(fakeTable, changed)
+			key = endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+		})
+
+		It("reports every mutation once the table is mutated", func() {
+			fakeTable.SetRoutesStub = func(endpoint.RoutingKey, []routingtable.Route, *models.ModificationTag) routingtable.MessagesToEmit {
+				Expect(changes).To(Equal(0))
+				return routingtable.MessagesToEmit{}
+			}
+
+			table.SetRoutes(key, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+			table.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1"})
+			table.RemoveEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1"})
+			table.RemoveRoutes(key, nil)
+			table.Swap(routingtable.NewTempTable(nil, nil), nil)
+			table.SuppressHost("1.1.1.1")
+			table.UnsuppressHost("1.1.1.1")
+
+			Expect(fakeTable.SetRoutesCallCount()).To(Equal(1))
+			Expect(fakeTable.SwapCallCount()).To(Equal(1))
+			Expect(changes).To(Equal(7))
+		})
+
+		It("does not report reads", func() {
+			table.GetRoutes(key)
+			table.EndpointsForIndex(key, 0)
+			table.MessagesToEmit()
+			table.Snapshot()
+			table.RouteCount()
+
+			Expect(changes).To(Equal(0))
+		})
+
+		It("reports the mutations of a table observed by several consumers to all of them", func() {
+			otherChanges := 0
+			table = routingtable.NewObservedNATSTable(table, func() { otherChanges++ })
+
+			table.SetRoutes(key, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+
+			Expect(fakeTable.SetRoutesCallCount()).To(Equal(1))
+			Expect(changes).To(Equal(1))
+			Expect(otherChanges).To(Equal(1))
+		})
+
+		It("swaps in the table it is handed, which the wrapped table can read", func() {
+			logger := lagertest.NewTestLogger("test")
+			table = routingtable.NewObservedNATSTable(routingtable.NewNATSTable(logger), changed)
+
+			newTable := routingtable.NewNATSTable(logger)
+			newTable.SetRoutes(key, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+			table.Swap(newTable, nil)
+
+			Expect(table.GetRoutes(key)).To(Equal([]routingtable.Route{{Hostname: "a.example.com"}}))
+			Expect(changes).To(Equal(1))
+		})
+	})
+
+	Describe("NewObservedTCPTable", func() {
+		var (
+			fakeTable *fakeroutingtable.FakeTCPRoutingTable
+			table     routingtable.TCPRoutingTable
+		)
+
+		BeforeEach(func() {
+			fakeTable = &fakeroutingtable.FakeTCPRoutingTable{}
+			table = routingtable.NewObservedTCPTable(fakeTable, changed)
+		})
+
+		It("reports every mutation once the table is mutated", func() {
+			schedulingInfo := &models.DesiredLRPSchedulingInfo{}
+			routingInfo := &endpoint.ActualLRPRoutingInfo{}
+
+			table.AddRoutes(schedulingInfo)
+			table.UpdateRoutes(schedulingInfo, schedulingInfo)
+			table.RemoveRoutes(schedulingInfo)
+			table.AddEndpoint(routingInfo)
+			table.RemoveEndpoint(routingInfo)
+			table.Swap(routingtable.NewTCPTable(lagertest.NewTestLogger("test"), nil))
+			table.SuppressHost("1.1.1.1")
+			table.UnsuppressHost("1.1.1.1")
+
+			Expect(fakeTable.AddRoutesCallCount()).To(Equal(1))
+			Expect(fakeTable.SwapCallCount()).To(Equal(1))
+			Expect(changes).To(Equal(8))
+		})
+
+		It("does not report reads", func() {
+			table.GetRoutes(endpoint.RoutingKey{})
+			table.GetRoutingEvents()
+			table.Snapshot()
+			table.RouteCount()
+
+			Expect(changes).To(Equal(0))
+		})
+	})
+})
@@ -1,6 +1,11 @@
 package routingtable
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+)
 
 type RegistryMessage struct {
 	Host                 string            `json:"host"`
@@ -12,6 +17,11 @@ type RegistryMessage struct {
 	PrivateInstanceIndex string            `json:"private_instance_index,omitempty"`
 	IsolationSegment     string            `json:"isolation_segment,omitempty"`
 	Tags                 map[string]string `json:"tags,omitempty"`
+
+	// set by the MessagesToEmitBuilder for the audit log, not sent to the
+	// routers
+	ProcessGUID string       `json:"-"`
+	Reason      event.Reason `json:"-"`
 }
 
 func RegistryMessageFor(endpoint Endpoint, route Route) RegistryMessage {
@@ -34,6 +44,17 @@ func RegistryMessageFor(endpoint Endpoint, route Route) RegistryMessage {
 }
 
 type RouterGreetingMessage struct {
-	MinimumRegisterInterval int `json:"minimumRegisterIntervalInSeconds"`
-	PruneThresholdInSeconds int `json:"pruneThresholdInSeconds"`
+	ID                      string   `json:"id,omitempty"`
+	Hosts                   []string `json:"hosts,omitempty"`
+	MinimumRegisterInterval int      `json:"minimumRegisterIntervalInSeconds"`
+	PruneThresholdInSeconds int      `json:"pruneThresholdInSeconds"`
+}
+
+// RouterID identifies the router that sent the greeting, by its id or else by
+// its hosts. Greetings from routers that send neither share the empty id.
+func (m RouterGreetingMessage) RouterID() string {
+	if m.ID != "" {
+		return m.ID
+	}
+	return strings.Join(m.Hosts, ",")
 }
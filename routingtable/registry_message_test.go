@@ -96,3 +96,38 @@ var _ = Describe("RegistryMessage", func() {
 		})
 	})
 })
+
+var _ = Describe("RouterGreetingMessage", func() {
+	Describe("RouterID", func() {
+		It("is the id of the router", func() {
+			greeting := routingtable.RouterGreetingMessage{ID: "router-1", Hosts: []string{"10.0.0.1"}}
+			Expect(greeting.RouterID()).To(Equal("router-1"))
+		})
+
+		It("falls back to the hosts of the router", func() {
+			greeting := routingtable.RouterGreetingMessage{Hosts: []string{"10.0.0.1", "10.0.0.2"}}
+			Expect(greeting.RouterID()).To(Equal("10.0.0.1,10.0.0.2"))
+		})
+
+		It("is empty when the router sends neither", func() {
+			Expect(routingtable.RouterGreetingMessage{}.RouterID()).To(BeEmpty())
+		})
+	})
+
+	It("deserializes the id and hosts of gorouter greetings", func() {
+		var greeting routingtable.RouterGreetingMessage
+		err := json.Unmarshal([]byte(`{
+			"id": "router-1",
+			"hosts": ["10.0.0.1"],
+			"minimumRegisterIntervalInSeconds": 20,
+			"pruneThresholdInSeconds": 120
+		}`), &greeting)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(greeting).To(Equal(routingtable.RouterGreetingMessage{
+			ID:                      "router-1",
+			Hosts:                   []string{"10.0.0.1"},
+			MinimumRegisterInterval: 20,
+			PruneThresholdInSeconds: 120,
+		}))
+	})
+})
@@ -5,6 +5,7 @@ import "code.cloudfoundry.org/bbs/models"
 type ActualLRPRoutingInfo struct {
 	ActualLRP  *models.ActualLRP
 	Evacuating bool
+	Suspect    bool
 }
 
 func NewActualLRPRoutingInfo(actualLRPGroup *models.ActualLRPGroup) *ActualLRPRoutingInfo {
@@ -12,5 +13,30 @@ func NewActualLRPRoutingInfo(actualLRPGroup *models.ActualLRPGroup) *ActualLRPRo
 	return &ActualLRPRoutingInfo{
 		ActualLRP:  lrp,
 		Evacuating: evacuating,
+		Suspect:    lrp != nil && lrp.Presence == models.ActualLRP_Suspect,
+	}
+}
+
+// RunningActualLRPRoutingInfos returns the routing infos of the groups whose
+// resolved actual LRP is running.
+func RunningActualLRPRoutingInfos(actualLRPGroups []*models.ActualLRPGroup) []*ActualLRPRoutingInfo {
+	runningActualLRPs := make([]*ActualLRPRoutingInfo, 0, len(actualLRPGroups))
+	for _, actualLRPGroup := range actualLRPGroups {
+		routingInfo := NewActualLRPRoutingInfo(actualLRPGroup)
+		if routingInfo.ActualLRP.State == models.ActualLRPStateRunning {
+			runningActualLRPs = append(runningActualLRPs, routingInfo)
+		}
+	}
+	return runningActualLRPs
+}
+
+// NewActualLRPRoutingInfoFromInstance returns the routing info of an actual
+// LRP as returned by the instance based BBS endpoints, where the presence of
+// the instance takes the place of its position in an actual LRP group.
+func NewActualLRPRoutingInfoFromInstance(lrp *models.ActualLRP) *ActualLRPRoutingInfo {
+	return &ActualLRPRoutingInfo{
+		ActualLRP:  lrp,
+		Evacuating: lrp.Presence == models.ActualLRP_Evacuating,
+		Suspect:    lrp.Presence == models.ActualLRP_Suspect,
 	}
 }
@@ -0,0 +1,36 @@
+package endpoint_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RunningActualLRPRoutingInfos", func() {
+	It("returns the resolved actual LRPs that are running", func() {
+		running := &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("running", 0, "domain"), State: models.ActualLRPStateRunning}
+		claimed := &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("claimed", 0, "domain"), State: models.ActualLRPStateClaimed}
+		evacuating := &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("evacuating", 0, "domain"), State: models.ActualLRPStateRunning}
+
+		routingInfos := endpoint.RunningActualLRPRoutingInfos([]*models.ActualLRPGroup{
+			{Instance: running},
+			{Instance: claimed},
+			{Evacuating: evacuating},
+		})
+		Expect(routingInfos).To(Equal([]*endpoint.ActualLRPRoutingInfo{
+			{ActualLRP: running},
+			{ActualLRP: evacuating, Evacuating: true},
+		}))
+	})
+})
+
+var _ = Describe("NewActualLRPRoutingInfo", func() {
+	It("marks a suspect actual LRP as suspect", func() {
+		suspect := &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("suspect", 0, "domain"), Presence: models.ActualLRP_Suspect}
+
+		routingInfo := endpoint.NewActualLRPRoutingInfo(&models.ActualLRPGroup{Instance: suspect})
+		Expect(routingInfo).To(Equal(&endpoint.ActualLRPRoutingInfo{ActualLRP: suspect, Suspect: true}))
+	})
+})
@@ -20,6 +20,7 @@ func NewEndpointKey(instanceGUID string, evacuating bool) EndpointKey {
 
 type Endpoint struct {
 	InstanceGUID    string
+	Index           int32
 	Host            string
 	Port            uint32
 	ContainerPort   uint32
@@ -93,6 +94,7 @@ func NewEndpointsFromActual(actualInfo *ActualLRPRoutingInfo) map[uint32]Endpoin
 			portMapping.ContainerPort,
 			&actual.ModificationTag,
 		)
+		endpoint.Index = actual.Index
 		endpoints[portMapping.ContainerPort] = endpoint
 	}
 
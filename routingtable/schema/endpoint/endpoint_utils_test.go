@@ -37,6 +37,20 @@ var _ = Describe("LRP Utils", func() {
 					endpoint.NewEndpoint("instance-guid", false, "1.1.1.1", 66, 99, &tag),
 				}))
 			})
+
+			It("carries the index of the actual", func() {
+				actualInfo := &endpoint.ActualLRPRoutingInfo{
+					ActualLRP: &models.ActualLRP{
+						ActualLRPKey:         models.NewActualLRPKey("process-guid", 3, "domain"),
+						ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+						ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.NewPortMapping(11, 44)),
+						State:                models.ActualLRPStateRunning,
+					},
+				}
+
+				endpoints := endpoint.NewEndpointsFromActual(actualInfo)
+				Expect(endpoints[44].Index).To(BeEquivalentTo(3))
+			})
 		})
 
 		Context("when actual is evacuating", func() {
@@ -15,10 +15,22 @@ const (
 	RouteUnregistrationEvent RoutingEventType = "RouteUnregistrationEvent"
 )
 
+// Reason is why routes are registered or unregistered.
+type Reason string
+
+const (
+	ReasonDesiredChange Reason = "desired-change"
+	ReasonActualChange  Reason = "actual-change"
+	ReasonSync          Reason = "sync"
+	ReasonEmit          Reason = "emit"
+	ReasonCollision     Reason = "collision"
+)
+
 type RoutingEvent struct {
 	EventType RoutingEventType
 	Key       endpoint.RoutingKey
 	Entry     endpoint.RoutableEndpoints
+	Reason    Reason
 }
 
 type RoutingEvents []RoutingEvent
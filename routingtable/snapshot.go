@@ -0,0 +1,148 @@
+package routingtable
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+// NATSTableEntry is a copy of the routes of a routing key and the endpoints
+// they route to.
+type NATSTableEntry struct {
+	Key       endpoint.RoutingKey
+	Routes    []Route
+	Endpoints []Endpoint
+}
+
+// TCPTableEntry is a copy of the external endpoints of a routing key and the
+// endpoints they route to.
+type TCPTableEntry struct {
+	Key               endpoint.RoutingKey
+	ExternalEndpoints endpoint.ExternalEndpointInfos
+	Endpoints         []endpoint.Endpoint
+}
+
+func (table *natsRoutingTable) Snapshot() []NATSTableEntry {
+	table.Lock()
+	defer table.Unlock()
+
+	entries := make([]NATSTableEntry, 0, len(table.entries))
+	for key, entry := range table.entries {
+		if len(entry.Routes) == 0 || len(entry.Endpoints) == 0 {
+			continue
+		}
+
+		snapshotEntry := NATSTableEntry{
+			Key:       key,
+			Routes:    make([]Route, len(entry.Routes)),
+			Endpoints: make([]Endpoint, 0, len(entry.Endpoints)),
+		}
+		copy(snapshotEntry.Routes, entry.Routes)
+		for _, e := range entry.Endpoints {
+			snapshotEntry.Endpoints = append(snapshotEntry.Endpoints, e)
+		}
+		sort.Sort(natsEndpointsByKey(snapshotEntry.Endpoints))
+		entries = append(entries, snapshotEntry)
+	}
+
+	sort.Sort(natsEntriesByKey(entries))
+	return entries
+}
+
+func (table *tcpRoutingTable) Snapshot() []TCPTableEntry {
+	table.Lock()
+	defer table.Unlock()
+
+	entries := make([]TCPTableEntry, 0, len(table.entries))
+	for key, entry := range table.entries {
+		if len(entry.ExternalEndpoints) == 0 || len(entry.Endpoints) == 0 {
+			continue
+		}
+
+		snapshotEntry := TCPTableEntry{
+			Key:               key,
+			ExternalEndpoints: make(endpoint.ExternalEndpointInfos, len(entry.ExternalEndpoints)),
+			Endpoints:         make([]endpoint.Endpoint, 0, len(entry.Endpoints)),
+		}
+		copy(snapshotEntry.ExternalEndpoints, entry.ExternalEndpoints)
+		for _, e := range entry.Endpoints {
+			snapshotEntry.Endpoints = append(snapshotEntry.Endpoints, e)
+		}
+		sort.Sort(tcpEndpointsByKey(snapshotEntry.Endpoints))
+		entries = append(entries, snapshotEntry)
+	}
+
+	sort.Sort(tcpEntriesByKey(entries))
+	return entries
+}
+
+// EntryCount returns the number of entries a Snapshot would return, without
+// copying them.
+func (table *natsRoutingTable) EntryCount() int {
+	table.Lock()
+	defer table.Unlock()
+
+	count := 0
+	for _, entry := range table.entries {
+		if len(entry.Routes) > 0 && len(entry.Endpoints) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// EntryCount returns the number of entries a Snapshot would return, without
+// copying them.
+func (table *tcpRoutingTable) EntryCount() int {
+	table.Lock()
+	defer table.Unlock()
+
+	count := 0
+	for _, entry := range table.entries {
+		if len(entry.ExternalEndpoints) > 0 && len(entry.Endpoints) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func lessRoutingKey(a, b endpoint.RoutingKey) bool {
+	if a.ProcessGUID != b.ProcessGUID {
+		return a.ProcessGUID < b.ProcessGUID
+	}
+	return a.ContainerPort < b.ContainerPort
+}
+
+type natsEntriesByKey []NATSTableEntry
+
+func (s natsEntriesByKey) Len() int           { return len(s) }
+func (s natsEntriesByKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s natsEntriesByKey) Less(i, j int) bool { return lessRoutingKey(s[i].Key, s[j].Key) }
+
+type tcpEntriesByKey []TCPTableEntry
+
+func (s tcpEntriesByKey) Len() int           { return len(s) }
+func (s tcpEntriesByKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s tcpEntriesByKey) Less(i, j int) bool { return lessRoutingKey(s[i].Key, s[j].Key) }
+
+type natsEndpointsByKey []Endpoint
+
+func (s natsEndpointsByKey) Len() int      { return len(s) }
+func (s natsEndpointsByKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s natsEndpointsByKey) Less(i, j int) bool {
+	if s[i].InstanceGuid != s[j].InstanceGuid {
+		return s[i].InstanceGuid < s[j].InstanceGuid
+	}
+	return !s[i].Evacuating && s[j].Evacuating
+}
+
+type tcpEndpointsByKey []endpoint.Endpoint
+
+func (s tcpEndpointsByKey) Len() int      { return len(s) }
+func (s tcpEndpointsByKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s tcpEndpointsByKey) Less(i, j int) bool {
+	if s[i].InstanceGUID != s[j].InstanceGUID {
+		return s[i].InstanceGUID < s[j].InstanceGUID
+	}
+	return !s[i].Evacuating && s[j].Evacuating
+}
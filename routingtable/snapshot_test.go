@@ -0,0 +1,86 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	var logger *lagertest.TestLogger
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	Describe("NATSRoutingTable", func() {
+		var table routingtable.NATSRoutingTable
+
+		BeforeEach(func() {
+			table = routingtable.NewNATSTable(logger)
+
+			keyB := endpoint.RoutingKey{ProcessGUID: "process-guid-b", ContainerPort: 8080}
+			table.SetRoutes(keyB, []routingtable.Route{{Hostname: "b.example.com", RouteServiceUrl: "https://rs.example.com"}}, nil)
+			table.AddEndpoint(keyB, routingtable.Endpoint{InstanceGuid: "ig-2", Host: "1.1.1.2", Port: 61002, ContainerPort: 8080})
+			table.AddEndpoint(keyB, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "1.1.1.1", Port: 61001, ContainerPort: 8080})
+
+			keyA := endpoint.RoutingKey{ProcessGUID: "process-guid-a", ContainerPort: 8080}
+			table.SetRoutes(keyA, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+			table.AddEndpoint(keyA, routingtable.Endpoint{InstanceGuid: "ig-3", Host: "1.1.1.3", Port: 61003, ContainerPort: 8080})
+
+			unrouted := endpoint.RoutingKey{ProcessGUID: "process-guid-c", ContainerPort: 8080}
+			table.AddEndpoint(unrouted, routingtable.Endpoint{InstanceGuid: "ig-4", Host: "1.1.1.4", Port: 61004, ContainerPort: 8080})
+		})
+
+		It("returns the routed entries and their endpoints in order", func() {
+			entries := table.Snapshot()
+			Expect(entries).To(HaveLen(2))
+
+			Expect(entries[0].Key.ProcessGUID).To(Equal("process-guid-a"))
+			Expect(entries[0].Routes).To(Equal([]routingtable.Route{{Hostname: "a.example.com"}}))
+
+			Expect(entries[1].Key.ProcessGUID).To(Equal("process-guid-b"))
+			Expect(entries[1].Routes[0].RouteServiceUrl).To(Equal("https://rs.example.com"))
+			Expect(entries[1].Endpoints).To(HaveLen(2))
+			Expect(entries[1].Endpoints[0].Host).To(Equal("1.1.1.1"))
+			Expect(entries[1].Endpoints[1].Host).To(Equal("1.1.1.2"))
+		})
+
+		It("is not affected by later changes to the table", func() {
+			entries := table.Snapshot()
+			table.RemoveRoutes(entries[0].Key, nil)
+
+			Expect(entries[0].Routes).To(HaveLen(1))
+			Expect(table.Snapshot()).To(HaveLen(1))
+		})
+	})
+
+	Describe("TCPRoutingTable", func() {
+		It("returns the routed entries and their endpoints in order", func() {
+			external := endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 5222)}
+			table := routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
+				{ProcessGUID: "process-guid-b", ContainerPort: 5222}: {
+					ExternalEndpoints: external,
+					Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+						endpoint.NewEndpointKey("ig-2", false): endpoint.NewEndpoint("ig-2", false, "1.1.1.2", 61002, 5222, nil),
+						endpoint.NewEndpointKey("ig-1", false): endpoint.NewEndpoint("ig-1", false, "1.1.1.1", 61001, 5222, nil),
+					},
+				},
+				{ProcessGUID: "process-guid-a", ContainerPort: 5222}: {
+					ExternalEndpoints: external,
+				},
+			})
+
+			entries := table.Snapshot()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Key.ProcessGUID).To(Equal("process-guid-b"))
+			Expect(entries[0].ExternalEndpoints).To(Equal(external))
+			Expect(entries[0].Endpoints).To(HaveLen(2))
+			Expect(entries[0].Endpoints[0].InstanceGUID).To(Equal("ig-1"))
+			Expect(entries[0].Endpoints[1].InstanceGUID).To(Equal("ig-2"))
+		})
+	})
+})
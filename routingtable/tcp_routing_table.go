@@ -30,12 +30,20 @@ type TCPRoutingTable interface {
 	RemoveEndpoint(actualLRP *endpoint.ActualLRPRoutingInfo) event.RoutingEvents
 
 	Swap(t TCPRoutingTable) event.RoutingEvents
+	Drift(t TCPRoutingTable) Drift
+
+	SuppressHost(host string) event.RoutingEvents
+	UnsuppressHost(host string)
 
 	GetRoutingEvents() event.RoutingEvents
+	Snapshot() []TCPTableEntry
+	EntryCount() int
 }
 
 type tcpRoutingTable struct {
 	entries map[endpoint.RoutingKey]endpoint.RoutableEndpoints
+	// endpoints on these hosts are kept out of the table
+	suppressedHosts map[string]struct{}
 	sync.Locker
 	logger lager.Logger
 }
@@ -45,9 +53,10 @@ func NewTCPTable(logger lager.Logger, entries map[endpoint.RoutingKey]endpoint.R
 		entries = make(map[endpoint.RoutingKey]endpoint.RoutableEndpoints)
 	}
 	return &tcpRoutingTable{
-		entries: entries,
-		Locker:  &sync.Mutex{},
-		logger:  logger,
+		entries:         entries,
+		suppressedHosts: make(map[string]struct{}),
+		Locker:          &sync.Mutex{},
+		logger:          logger,
 	}
 }
 
@@ -68,7 +77,7 @@ func (table *tcpRoutingTable) GetRoutingEvents() event.RoutingEvents {
 
 	for key, entry := range table.entries {
 		//always register everything on sync
-		routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, entry, event.RouteRegistrationEvent)...)
+		routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, entry, event.RouteRegistrationEvent, event.ReasonEmit)...)
 	}
 
 	return routingEvents
@@ -88,19 +97,24 @@ func (table *tcpRoutingTable) Swap(t TCPRoutingTable) event.RoutingEvents {
 
 	newEntries := newTable.entries
 	for key, newEntry := range newEntries {
+		if filtered, ok := table.withoutSuppressedHosts(newEntry); ok {
+			newEntry = filtered
+			newEntries[key] = newEntry
+		}
+
 		//always register everything on sync
-		routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, newEntry, event.RouteRegistrationEvent)...)
+		routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, newEntry, event.RouteRegistrationEvent, event.ReasonSync)...)
 
 		newExternalEndpoints := newEntry.ExternalEndpoints
 		existingEntry := table.entries[key]
 
 		unregistrationEntry := existingEntry.RemoveExternalEndpoints(newExternalEndpoints)
-		routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, unregistrationEntry, event.RouteUnregistrationEvent)...)
+		routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, unregistrationEntry, event.RouteUnregistrationEvent, event.ReasonSync)...)
 	}
 
 	for key, existingEntry := range table.entries {
 		if _, ok := newEntries[key]; !ok {
-			routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, existingEntry, event.RouteUnregistrationEvent)...)
+			routingEvents = append(routingEvents, table.createRoutingEvent(table.logger, key, existingEntry, event.RouteUnregistrationEvent, event.ReasonSync)...)
 		}
 	}
 
@@ -109,6 +123,35 @@ func (table *tcpRoutingTable) Swap(t TCPRoutingTable) event.RoutingEvents {
 	return routingEvents
 }
 
+// Drift returns how the table differs from a table built during a sync,
+// without modifying either table. Endpoints on suppressed hosts are left out,
+// as Swap keeps them out of the table.
+func (table *tcpRoutingTable) Drift(t TCPRoutingTable) Drift {
+	drift := Drift{}
+
+	newTable, ok := t.(*tcpRoutingTable)
+	if !ok {
+		return drift
+	}
+
+	table.Lock()
+	defer table.Unlock()
+
+	for key, newEntry := range newTable.entries {
+		if filtered, ok := table.withoutSuppressedHosts(newEntry); ok {
+			newEntry = filtered
+		}
+		drift.add(key, tcpEntryDrift(table.entries[key], newEntry))
+	}
+	for key, existingEntry := range table.entries {
+		if _, ok := newTable.entries[key]; !ok {
+			drift.add(key, tcpEntryDrift(existingEntry, endpoint.RoutableEndpoints{}))
+		}
+	}
+
+	return drift
+}
+
 func (table *tcpRoutingTable) RouteCount() int {
 	table.Lock()
 	defer table.Unlock()
@@ -194,6 +237,7 @@ func (table *tcpRoutingTable) removeRoutingKeys(
 					EventType: event.RouteUnregistrationEvent,
 					Key:       key,
 					Entry:     existingEntry,
+					Reason:    event.ReasonDesiredChange,
 				})
 			}
 
@@ -236,12 +280,12 @@ func (table *tcpRoutingTable) mergeRoutes(
 		updatedEntry.LogGUID = logGUID
 		updatedEntry.ModificationTag = modificationTag
 		table.entries[key] = updatedEntry
-		routingEvents = append(routingEvents, table.createRoutingEvent(logger, key, updatedEntry, event.RouteRegistrationEvent)...)
+		routingEvents = append(routingEvents, table.createRoutingEvent(logger, key, updatedEntry, event.RouteRegistrationEvent, event.ReasonDesiredChange)...)
 		logger.Debug("routing-table-entry-updated", lager.Data{"key": key})
 	}
 
 	unregistrationEntry := existingEntry.RemoveExternalEndpoints(newExternalEndpoints)
-	routingEvents = append(routingEvents, table.createRoutingEvent(logger, key, unregistrationEntry, event.RouteUnregistrationEvent)...)
+	routingEvents = append(routingEvents, table.createRoutingEvent(logger, key, unregistrationEntry, event.RouteUnregistrationEvent, event.ReasonDesiredChange)...)
 
 	return routingEvents
 }
@@ -269,6 +313,11 @@ func (table *tcpRoutingTable) addEndpoint(logger lager.Logger, key endpoint.Rout
 	table.Lock()
 	defer table.Unlock()
 
+	if _, ok := table.suppressedHosts[endpoint.Host]; ok {
+		logger.Debug("skipping-endpoint-on-suppressed-host", lager.Data{"host": endpoint.Host})
+		return event.RoutingEvents{}
+	}
+
 	currentEntry := table.entries[key]
 
 	if existingEndpoint, ok := currentEntry.Endpoints[endpoint.Key()]; ok {
@@ -327,7 +376,58 @@ func (table *tcpRoutingTable) removeEndpoint(logger lager.Logger, key endpoint.R
 
 	deletedEntry := table.getDeletedEntry(currentEntry, newEntry)
 
-	return table.createRoutingEvent(logger, key, deletedEntry, event.RouteUnregistrationEvent)
+	return table.createRoutingEvent(logger, key, deletedEntry, event.RouteUnregistrationEvent, event.ReasonActualChange)
+}
+
+// SuppressHost removes every endpoint on the host, and keeps endpoints on it
+// out of the table until UnsuppressHost is called, whether they are added by
+// events or by a sync.
+func (table *tcpRoutingTable) SuppressHost(host string) event.RoutingEvents {
+	logger := table.logger.Session("suppress-host", lager.Data{"host": host})
+
+	table.Lock()
+	defer table.Unlock()
+
+	table.suppressedHosts[host] = struct{}{}
+
+	routingEvents := event.RoutingEvents{}
+	for key, currentEntry := range table.entries {
+		newEntry, ok := table.withoutSuppressedHosts(currentEntry)
+		if !ok {
+			continue
+		}
+		table.entries[key] = newEntry
+
+		deletedEntry := table.getDeletedEntry(currentEntry, newEntry)
+		routingEvents = append(routingEvents, table.createRoutingEvent(logger, key, deletedEntry, event.RouteUnregistrationEvent, event.ReasonActualChange)...)
+	}
+
+	return routingEvents
+}
+
+func (table *tcpRoutingTable) UnsuppressHost(host string) {
+	table.Lock()
+	defer table.Unlock()
+
+	delete(table.suppressedHosts, host)
+}
+
+// withoutSuppressedHosts returns a copy of the entry without the endpoints on
+// suppressed hosts, and false if it has none.
+func (table *tcpRoutingTable) withoutSuppressedHosts(entry endpoint.RoutableEndpoints) (endpoint.RoutableEndpoints, bool) {
+	var filtered endpoint.RoutableEndpoints
+	found := false
+	for endpointKey, e := range entry.Endpoints {
+		if _, ok := table.suppressedHosts[e.Host]; !ok {
+			continue
+		}
+		if !found {
+			filtered = entry.Copy()
+			found = true
+		}
+		delete(filtered.Endpoints, endpointKey)
+	}
+	return filtered, found
 }
 
 func (table *tcpRoutingTable) getRegistrationEvents(
@@ -353,12 +453,13 @@ func (table *tcpRoutingTable) getRegistrationEvents(
 			EventType: event.RouteRegistrationEvent,
 			Key:       key,
 			Entry:     newEntry,
+			Reason:    event.ReasonActualChange,
 		})
 	}
 	return routingEvents
 }
 
-func (table *tcpRoutingTable) createRoutingEvent(logger lager.Logger, key endpoint.RoutingKey, entry endpoint.RoutableEndpoints, eventType event.RoutingEventType) event.RoutingEvents {
+func (table *tcpRoutingTable) createRoutingEvent(logger lager.Logger, key endpoint.RoutingKey, entry endpoint.RoutableEndpoints, eventType event.RoutingEventType, reason event.Reason) event.RoutingEvents {
 	logger.Debug("create-routing-events")
 	// in which case does a entry end up with no external endpoints ?
 	if entry.ExternalEndpoints.HasNoExternalPorts(logger) {
@@ -372,6 +473,7 @@ func (table *tcpRoutingTable) createRoutingEvent(logger lager.Logger, key endpoi
 				EventType: eventType,
 				Key:       key,
 				Entry:     entry,
+				Reason:    reason,
 			},
 		}
 	}
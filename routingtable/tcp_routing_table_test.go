@@ -261,6 +261,7 @@ var _ = Describe("TCPRoutingTable", func() {
 				routingEvent := routingEvents[0]
 				Expect(routingEvent.Key).Should(Equal(key))
 				Expect(routingEvent.EventType).Should(Equal(event.RouteRegistrationEvent))
+				Expect(routingEvent.Reason).Should(Equal(event.ReasonSync))
 				externalInfo := endpoint.ExternalEndpointInfos{
 					endpoint.NewExternalEndpointInfo("router-group-guid", 61000),
 				}
@@ -311,6 +312,46 @@ var _ = Describe("TCPRoutingTable", func() {
 			}
 		})
 
+		Describe("SuppressHost", func() {
+			var routingEvents event.RoutingEvents
+
+			BeforeEach(func() {
+				routingTable = routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
+					key: endpoint.NewRoutableEndpoints(externalEndpoints, endpoints, logGuid, modificationTag),
+				})
+				routingEvents = routingTable.SuppressHost("some-ip-1")
+			})
+
+			It("unregisters the endpoints on the host", func() {
+				Expect(routingEvents).To(HaveLen(1))
+				Expect(routingEvents[0].EventType).To(Equal(event.RouteUnregistrationEvent))
+				Expect(routingEvents[0].Entry.Endpoints).To(ConsistOf(endpoints[endpoint.NewEndpointKey("instance-guid-1", false)]))
+			})
+
+			It("keeps endpoints on the host from being added", func() {
+				actualLRP := getActualLRP("process-guid-1", "instance-guid-3", "some-ip-1", "container-ip-3", 62005, 5222, false, modificationTag)
+				Expect(routingTable.AddEndpoint(actualLRP)).To(BeEmpty())
+			})
+
+			It("keeps endpoints on the host out of a sync", func() {
+				tempRoutingTable := routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
+					key: endpoint.NewRoutableEndpoints(externalEndpoints, endpoints, logGuid, modificationTag),
+				})
+				routingEvents = routingTable.Swap(tempRoutingTable)
+				Expect(routingEvents).To(HaveLen(1))
+				Expect(routingEvents[0].EventType).To(Equal(event.RouteRegistrationEvent))
+				Expect(routingEvents[0].Entry.Endpoints).To(ConsistOf(endpoints[endpoint.NewEndpointKey("instance-guid-2", false)]))
+			})
+
+			Context("when the host is unsuppressed", func() {
+				It("adds endpoints on the host again", func() {
+					routingTable.UnsuppressHost("some-ip-1")
+					actualLRP := getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag)
+					Expect(routingTable.AddEndpoint(actualLRP)).To(HaveLen(1))
+				})
+			})
+		})
+
 		Describe("GetRoutes", func() {
 			It("returns the associated desired state", func() {
 				routingTable = routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
@@ -532,14 +573,17 @@ var _ = Describe("TCPRoutingTable", func() {
 							EventType: event.RouteRegistrationEvent,
 							Key:       key2,
 							Entry:     expectedEntry2,
+							Reason:    event.ReasonDesiredChange,
 						}, event.RoutingEvent{
 							EventType: event.RouteRegistrationEvent,
 							Key:       key,
 							Entry:     expectedEntry1,
+							Reason:    event.ReasonDesiredChange,
 						}, event.RoutingEvent{
 							EventType: event.RouteUnregistrationEvent,
 							Key:       key,
 							Entry:     expectedEntry3,
+							Reason:    event.ReasonDesiredChange,
 						},
 					}
 				}
@@ -1073,6 +1117,7 @@ var _ = Describe("TCPRoutingTable", func() {
 					routingEvent := routingEvents[0]
 					Expect(routingEvent.Key).Should(Equal(key))
 					Expect(routingEvent.EventType).Should(Equal(event.RouteRegistrationEvent))
+					Expect(routingEvent.Reason).Should(Equal(event.ReasonActualChange))
 
 					expectedEndpoints := map[endpoint.EndpointKey]endpoint.Endpoint{
 						endpoint.NewEndpointKey("instance-guid-1", false): endpoint.NewEndpoint(
@@ -1261,6 +1306,7 @@ var _ = Describe("TCPRoutingTable", func() {
 				routingEvent := routingEvents[0]
 				Expect(routingEvent.Key).Should(Equal(key))
 				Expect(routingEvent.EventType).Should(Equal(event.RouteRegistrationEvent))
+				Expect(routingEvent.Reason).Should(Equal(event.ReasonEmit))
 				externalInfo := []endpoint.ExternalEndpointInfo{
 					endpoint.NewExternalEndpointInfo("router-group-guid", 61000),
 				}
@@ -1399,4 +1445,72 @@ var _ = Describe("TCPRoutingTable", func() {
 			})
 		})
 	})
+
+	Describe("Drift", func() {
+		var (
+			key       endpoint.RoutingKey
+			endpoints map[endpoint.EndpointKey]endpoint.Endpoint
+		)
+
+		newTable := func(externalEndpoints endpoint.ExternalEndpointInfos, endpoints map[endpoint.EndpointKey]endpoint.Endpoint) routingtable.TCPRoutingTable {
+			return routingtable.NewTCPTable(logger, map[endpoint.RoutingKey]endpoint.RoutableEndpoints{
+				key: endpoint.NewRoutableEndpoints(externalEndpoints, endpoints, "log-guid-1", modificationTag),
+			})
+		}
+
+		BeforeEach(func() {
+			key = endpoint.NewRoutingKey("process-guid-1", 5222)
+			modificationTag = &models.ModificationTag{Epoch: "abc", Index: 1}
+			endpoints = map[endpoint.EndpointKey]endpoint.Endpoint{
+				endpoint.NewEndpointKey("instance-guid-1", false): endpoint.NewEndpoint(
+					"instance-guid-1", false, "some-ip-1", 62004, 5222, modificationTag),
+			}
+			routingTable = newTable(endpoint.ExternalEndpointInfos{
+				endpoint.NewExternalEndpointInfo("router-group-guid", 61000),
+			}, endpoints)
+		})
+
+		It("is empty when the tables match", func() {
+			tempRoutingTable := newTable(endpoint.ExternalEndpointInfos{
+				endpoint.NewExternalEndpointInfo("router-group-guid", 61000),
+			}, endpoints)
+
+			Expect(routingTable.Drift(tempRoutingTable).Empty()).To(BeTrue())
+		})
+
+		It("counts the routes and endpoints that were added and removed", func() {
+			tempRoutingTable := newTable(endpoint.ExternalEndpointInfos{
+				endpoint.NewExternalEndpointInfo("router-group-guid", 62000),
+			}, map[endpoint.EndpointKey]endpoint.Endpoint{
+				endpoint.NewEndpointKey("instance-guid-2", false): endpoint.NewEndpoint(
+					"instance-guid-2", false, "some-ip-2", 62004, 5222, modificationTag),
+			})
+
+			drift := routingTable.Drift(tempRoutingTable)
+			Expect(drift).To(Equal(routingtable.Drift{
+				RoutesAdded:      1,
+				RoutesRemoved:    1,
+				EndpointsAdded:   1,
+				EndpointsRemoved: 1,
+				RoutingKeys:      []endpoint.RoutingKey{key},
+			}))
+		})
+
+		It("does not count endpoints on suppressed hosts as added", func() {
+			routingTable.SuppressHost("some-ip-1")
+			tempRoutingTable := newTable(endpoint.ExternalEndpointInfos{
+				endpoint.NewExternalEndpointInfo("router-group-guid", 61000),
+			}, endpoints)
+
+			Expect(routingTable.Drift(tempRoutingTable).Empty()).To(BeTrue())
+		})
+
+		It("counts the entries that are missing from the new table", func() {
+			drift := routingTable.Drift(routingtable.NewTCPTable(logger, nil))
+			Expect(drift.RoutesRemoved).To(Equal(1))
+			Expect(drift.EndpointsRemoved).To(Equal(1))
+			Expect(drift.RoutingKeys).To(ConsistOf(key))
+			Expect(routingTable.RouteCount()).To(Equal(1))
+		})
+	})
 })
@@ -32,6 +32,7 @@ func ActualLRPData(info *endpoint.ActualLRPRoutingInfo) lager.Data {
 		"address":       lrp.Address,
 		"ports":         lrp.Ports,
 		"evacuating":    info.Evacuating,
+		"suspect":       info.Suspect,
 		"state":         lrp.State,
 	}
 }
@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+var (
+	emitDuration    = metric.Duration("RouteEmitterEmitDuration")
+	emitSpacing     = metric.Duration("RouteEmitterEmitSpacing")
+	emitLagWarnings = metric.Counter("RouteEmitterEmitLagWarnings")
+	emitsDropped    = metric.Counter("RouteEmitterEmitsDropped")
+)
+
+// DefaultEmitLagWarningRatio is the share of the prune threshold an emit may
+// take before the EmitMonitor warns about it.
+const DefaultEmitLagWarningRatio = 0.5
+
+// EmitMonitor measures how long full emits take and how far apart the
+// successful ones are, and warns when either gets close to the prune
+// threshold of the routers, past which they drop the routes of every app.
+//
+// The syncer tells it the prune threshold, the route handlers tell it about
+// their emits. A nil EmitMonitor measures nothing and never sheds load.
+type EmitMonitor struct {
+	clock        clock.Clock
+	warningRatio float64
+	shedLoad     bool
+
+	lock           sync.Mutex
+	pruneThreshold time.Duration
+	lastEmit       time.Time
+	lagging        bool
+}
+
+// NewEmitMonitor returns a monitor that warns once an emit, or the time since
+// the last successful one, takes warningRatio of the prune threshold. If
+// shedLoad is set, ShedLoad reports true for as long as emits lag.
+func NewEmitMonitor(clock clock.Clock, warningRatio float64, shedLoad bool) *EmitMonitor {
+	if warningRatio <= 0 || warningRatio > 1 {
+		warningRatio = DefaultEmitLagWarningRatio
+	}
+
+	return &EmitMonitor{
+		clock:        clock,
+		warningRatio: warningRatio,
+		shedLoad:     shedLoad,
+	}
+}
+
+// SetPruneThreshold sets the shortest prune threshold of the known routers.
+func (m *EmitMonitor) SetPruneThreshold(threshold time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pruneThreshold = threshold
+}
+
+// EmitStarted returns the time a full emit starts at, to be handed back to
+// EmitCompleted.
+func (m *EmitMonitor) EmitStarted() time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+	return m.clock.Now()
+}
+
+// EmitCompleted records a full emit that started at the given time. Failed
+// emits count towards the duration of emits, but not as successful emits.
+func (m *EmitMonitor) EmitCompleted(logger lager.Logger, started time.Time, emitErr error) {
+	if m == nil {
+		return
+	}
+
+	now := m.clock.Now()
+	duration := now.Sub(started)
+	err := emitDuration.Send(duration)
+	if err != nil {
+		logger.Error("failed-to-send-emit-duration-metric", err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var spacing time.Duration
+	if !m.lastEmit.IsZero() {
+		spacing = now.Sub(m.lastEmit)
+	}
+	if emitErr == nil {
+		if spacing > 0 {
+			err := emitSpacing.Send(spacing)
+			if err != nil {
+				logger.Error("failed-to-send-emit-spacing-metric", err)
+			}
+		}
+		m.lastEmit = now
+	}
+
+	if m.pruneThreshold <= 0 {
+		return
+	}
+
+	limit := time.Duration(float64(m.pruneThreshold) * m.warningRatio)
+	if duration < limit && spacing < limit {
+		if m.lagging {
+			logger.Info("emit-lag-recovered")
+		}
+		m.lagging = false
+		return
+	}
+
+	m.lagging = true
+	emitLagWarnings.Increment()
+	logger.Info("emit-lag-near-prune-threshold", lager.Data{
+		"emit-duration":   duration.String(),
+		"emit-spacing":    spacing.String(),
+		"prune-threshold": m.pruneThreshold.String(),
+		"shedding-load":   m.shedLoad,
+	})
+}
+
+// EmitDropped records that an emit was not started, because the previous one
+// was still in progress.
+func (m *EmitMonitor) EmitDropped() {
+	if m == nil {
+		return
+	}
+	emitsDropped.Increment()
+}
+
+// LastEmit returns the time the last successful emit completed at, or the
+// zero time before the first one.
+func (m *EmitMonitor) LastEmit() time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.lastEmit
+}
+
+// ShedLoad reports whether low priority work should be skipped to help emits
+// catch up.
+func (m *EmitMonitor) ShedLoad() bool {
+	if m == nil {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.shedLoad && m.lagging
+}
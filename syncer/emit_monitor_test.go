@@ -0,0 +1,147 @@
+package syncer_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	fake_metrics_sender "github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	"github.com/cloudfoundry/dropsonde/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("EmitMonitor", func() {
+	var (
+		clock            *fakeclock.FakeClock
+		logger           *lagertest.TestLogger
+		fakeMetricSender *fake_metrics_sender.FakeMetricSender
+		shedLoad         bool
+		monitor          *syncer.EmitMonitor
+	)
+
+	emit := func(duration time.Duration, err error) {
+		started := monitor.EmitStarted()
+		clock.Increment(duration)
+		monitor.EmitCompleted(logger, started, err)
+	}
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger = lagertest.NewTestLogger("test")
+		shedLoad = true
+
+		fakeMetricSender = fake_metrics_sender.NewFakeMetricSender()
+		metrics.Initialize(fakeMetricSender, nil)
+	})
+
+	JustBeforeEach(func() {
+		monitor = syncer.NewEmitMonitor(clock, 0.5, shedLoad)
+		monitor.SetPruneThreshold(10 * time.Second)
+	})
+
+	It("sends the duration of emits and the spacing between them", func() {
+		emit(time.Second, nil)
+		clock.Increment(2 * time.Second)
+		emit(time.Second, nil)
+
+		Expect(fakeMetricSender.GetValue("RouteEmitterEmitDuration").Value).To(BeEquivalentTo(time.Second))
+		Expect(fakeMetricSender.GetValue("RouteEmitterEmitSpacing").Value).To(BeEquivalentTo(3 * time.Second))
+	})
+
+	It("remembers when the last successful emit completed", func() {
+		Expect(monitor.LastEmit()).To(BeZero())
+
+		emit(time.Second, nil)
+		completed := clock.Now()
+		emit(time.Second, errors.New("boom"))
+		Expect(monitor.LastEmit()).To(Equal(completed))
+	})
+
+	Context("when an emit takes close to the prune threshold", func() {
+		JustBeforeEach(func() {
+			emit(6*time.Second, nil)
+		})
+
+		It("warns about it", func() {
+			Expect(fakeMetricSender.GetCounter("RouteEmitterEmitLagWarnings")).To(BeEquivalentTo(1))
+			Expect(logger).To(gbytes.Say("emit-lag-near-prune-threshold"))
+		})
+
+		It("sheds load", func() {
+			Expect(monitor.ShedLoad()).To(BeTrue())
+		})
+
+		Context("and the next emit is quick", func() {
+			JustBeforeEach(func() {
+				emit(time.Second, nil)
+			})
+
+			It("stops shedding load", func() {
+				Expect(logger).To(gbytes.Say("emit-lag-recovered"))
+				Expect(monitor.ShedLoad()).To(BeFalse())
+			})
+		})
+
+		Context("when shedding load is disabled", func() {
+			BeforeEach(func() {
+				shedLoad = false
+			})
+
+			It("only warns", func() {
+				Expect(fakeMetricSender.GetCounter("RouteEmitterEmitLagWarnings")).To(BeEquivalentTo(1))
+				Expect(monitor.ShedLoad()).To(BeFalse())
+			})
+		})
+	})
+
+	Context("when successful emits are far apart", func() {
+		It("warns about it", func() {
+			emit(time.Second, nil)
+			emit(time.Second, errors.New("boom"))
+			clock.Increment(4 * time.Second)
+			emit(time.Second, errors.New("boom"))
+
+			Expect(fakeMetricSender.GetCounter("RouteEmitterEmitLagWarnings")).To(BeEquivalentTo(1))
+			Expect(monitor.ShedLoad()).To(BeTrue())
+		})
+	})
+
+	Context("when emits are quick and frequent", func() {
+		It("does not warn", func() {
+			for i := 0; i < 5; i++ {
+				emit(time.Second, nil)
+				clock.Increment(time.Second)
+			}
+
+			Expect(fakeMetricSender.GetCounter("RouteEmitterEmitLagWarnings")).To(BeZero())
+			Expect(monitor.ShedLoad()).To(BeFalse())
+		})
+	})
+
+	Context("when the prune threshold is not known", func() {
+		JustBeforeEach(func() {
+			monitor.SetPruneThreshold(0)
+		})
+
+		It("does not warn", func() {
+			emit(time.Minute, nil)
+			Expect(fakeMetricSender.GetCounter("RouteEmitterEmitLagWarnings")).To(BeZero())
+		})
+	})
+
+	Context("when the monitor is nil", func() {
+		It("measures nothing", func() {
+			var monitor *syncer.EmitMonitor
+			monitor.SetPruneThreshold(time.Second)
+			monitor.EmitCompleted(logger, monitor.EmitStarted(), nil)
+			monitor.EmitDropped()
+			Expect(monitor.ShedLoad()).To(BeFalse())
+			Expect(monitor.LastEmit()).To(BeZero())
+		})
+	})
+})
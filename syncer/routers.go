@@ -0,0 +1,68 @@
+package syncer
+
+import (
+	"time"
+)
+
+type routerGreeting struct {
+	routerID       string
+	interval       time.Duration
+	pruneThreshold time.Duration
+	start          bool
+}
+
+type trackedRouter struct {
+	interval       time.Duration
+	pruneThreshold time.Duration
+	lastSeen       time.Time
+}
+
+// routers tracks the register interval each router has asked for. It is only
+// used from the syncer's run loop.
+type routers map[string]trackedRouter
+
+func (r routers) greeted(greeting routerGreeting, now time.Time) {
+	r[greeting.routerID] = trackedRouter{
+		interval:       greeting.interval,
+		pruneThreshold: greeting.pruneThreshold,
+		lastSeen:       now,
+	}
+}
+
+// expire forgets the routers that have not been heard from since the given
+// time, and returns their ids.
+func (r routers) expire(since time.Time) []string {
+	expired := []string{}
+	for id, router := range r {
+		if router.lastSeen.Before(since) {
+			expired = append(expired, id)
+			delete(r, id)
+		}
+	}
+	return expired
+}
+
+// minimumInterval returns the shortest interval any router has asked for, so
+// that no router prunes routes between two emits. It returns false when no
+// router is known.
+func (r routers) minimumInterval() (time.Duration, bool) {
+	var minimum time.Duration
+	for _, router := range r {
+		if router.interval > 0 && (minimum == 0 || router.interval < minimum) {
+			minimum = router.interval
+		}
+	}
+	return minimum, minimum > 0
+}
+
+// minimumPruneThreshold returns the shortest time any router keeps routes
+// without hearing about them again, or 0 when no router is known.
+func (r routers) minimumPruneThreshold() time.Duration {
+	var minimum time.Duration
+	for _, router := range r {
+		if router.pruneThreshold > 0 && (minimum == 0 || router.pruneThreshold < minimum) {
+			minimum = router.pruneThreshold
+		}
+	}
+	return minimum
+}
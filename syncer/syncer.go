@@ -11,35 +11,86 @@ import (
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"github.com/nats-io/nats"
 	uuid "github.com/nu7hatch/gouuid"
+	"github.com/tedsuo/ifrit"
 )
 
+// Syncer tells the watcher when to sync its routing tables with the BBS and
+// when to emit all of their routes.
+type Syncer interface {
+	ifrit.Runner
+	Events() Events
+}
+
+// DefaultEmitInterval is the interval routes are emitted at when no router
+// answers the greeting in time and no fallback interval is configured. It
+// matches the default minimum register interval of the gorouter.
+const DefaultEmitInterval = 20 * time.Second
+
+// DefaultRouterStartDebounce is how long the syncer waits for more routers to
+// start before emitting routes to the ones that did.
+const DefaultRouterStartDebounce = 5 * time.Second
+
 type NatsSyncer struct {
-	natsClient   diegonats.NATSClient
-	clock        clock.Clock
-	syncInterval time.Duration
-	events       Events
-	routerGreet  chan time.Duration
+	natsClient           diegonats.NATSClient
+	clock                clock.Clock
+	syncInterval         time.Duration
+	greetingTimeout      time.Duration
+	fallbackEmitInterval time.Duration
+	routerExpiry         time.Duration
+	routerStartDebounce  time.Duration
+	emitMonitor          *EmitMonitor
+	events               Events
+	routerGreet          chan routerGreeting
 
 	logger lager.Logger
 }
 
+var _ Syncer = new(NatsSyncer)
+
+// NewSyncer returns a syncer that greets the routers and emits routes at the
+// interval they ask for. If greetingTimeout is positive and no router answers
+// within it, the syncer starts emitting every fallbackEmitInterval instead,
+// until a router announces itself on router.start.
+//
+// Routes are emitted at the shortest interval any known router asked for. The
+// syncer keeps greeting the routers and forgets the ones that have not
+// answered within routerExpiry. If routerExpiry is 0, it is derived from the
+// known routers as the longer of their shortest prune threshold and three of
+// their shortest register intervals, and a negative routerExpiry disables
+// the expiry. Bursts of router.start are
+// coalesced into a single emit routerStartDebounce after the first of them.
+// The shortest prune threshold of the known routers is handed to emitMonitor.
 func NewSyncer(
 	clock clock.Clock,
 	syncInterval time.Duration,
+	greetingTimeout time.Duration,
+	fallbackEmitInterval time.Duration,
+	routerExpiry time.Duration,
+	routerStartDebounce time.Duration,
+	emitMonitor *EmitMonitor,
 	natsClient diegonats.NATSClient,
 	logger lager.Logger,
 ) *NatsSyncer {
+	if fallbackEmitInterval <= 0 {
+		fallbackEmitInterval = DefaultEmitInterval
+	}
+
 	return &NatsSyncer{
 		natsClient: natsClient,
 
-		clock:        clock,
-		syncInterval: syncInterval,
+		clock:                clock,
+		syncInterval:         syncInterval,
+		greetingTimeout:      greetingTimeout,
+		fallbackEmitInterval: fallbackEmitInterval,
+		routerExpiry:         routerExpiry,
+		routerStartDebounce:  routerStartDebounce,
+		emitMonitor:          emitMonitor,
 		events: Events{
 			Sync: make(chan struct{}, 1),
 			Emit: make(chan struct{}, 1),
 		},
 
-		routerGreet: make(chan time.Duration),
+		routerGreet: make(chan routerGreeting),
 
 		logger: logger.Session("syncer"),
 	}
@@ -60,9 +111,17 @@ func (s *NatsSyncer) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	close(ready)
 	s.logger.Info("started")
 
+	knownRouters := routers{}
 	var routerPruneInterval time.Duration
 	retryGreetingTicker := s.clock.NewTicker(time.Second)
 
+	var greetingTimeout <-chan time.Time
+	if s.greetingTimeout > 0 {
+		greetingTimer := s.clock.NewTimer(s.greetingTimeout)
+		defer greetingTimer.Stop()
+		greetingTimeout = greetingTimer.C()
+	}
+
 	//keep trying to greet until we hear from the router
 GREET_LOOP:
 	for {
@@ -74,8 +133,20 @@ GREET_LOOP:
 		}
 
 		select {
-		case routerPruneInterval = <-s.routerGreet:
-			s.logger.Info("received-router-prune-interval", lager.Data{"interval": routerPruneInterval.String()})
+		case greeting := <-s.routerGreet:
+			knownRouters.greeted(greeting, s.clock.Now())
+			s.emitMonitor.SetPruneThreshold(knownRouters.minimumPruneThreshold())
+			routerPruneInterval = greeting.interval
+			s.logger.Info("received-router-prune-interval", lager.Data{"router-id": greeting.routerID, "interval": routerPruneInterval.String()})
+			break GREET_LOOP
+		case <-greetingTimeout:
+			// keep listening on router.start, the interval of the first router
+			// to come up replaces the fallback one
+			s.logger.Info("timed-out-greeting-router", lager.Data{
+				"greeting-timeout":       s.greetingTimeout.String(),
+				"fallback-emit-interval": s.fallbackEmitInterval.String(),
+			})
+			routerPruneInterval = s.fallbackEmitInterval
 			break GREET_LOOP
 		case <-retryGreetingTicker.C():
 		case <-signals:
@@ -91,13 +162,87 @@ GREET_LOOP:
 	syncTicker := s.clock.NewTicker(s.syncInterval)
 	routerTicker := s.clock.NewTicker(routerPruneInterval)
 
+	// routers only answer a greeting, so keep greeting them to tell the quiet
+	// ones apart
+	routerExpiry := s.routerExpiry
+	if routerExpiry == 0 {
+		routerExpiry = derivedRouterExpiry(knownRouters, routerPruneInterval)
+		s.logger.Info("derived-router-expiry", lager.Data{"router-expiry": routerExpiry.String()})
+	}
+	var regreetTicker clock.Ticker
+	var regreetTicks <-chan time.Time
+	if routerExpiry > 0 {
+		regreetTicker = s.clock.NewTicker(routerExpiry / 3)
+		regreetTicks = regreetTicker.C()
+	}
+
+	var debounceTimer clock.Timer
+	var debounced <-chan time.Time
+
+	updateInterval := func() {
+		if threshold := knownRouters.minimumPruneThreshold(); threshold > 0 {
+			s.emitMonitor.SetPruneThreshold(threshold)
+		}
+
+		interval, ok := knownRouters.minimumInterval()
+		if !ok || interval == routerPruneInterval {
+			return
+		}
+		routerPruneInterval = interval
+		s.logger.Info("received-new-router-prune-interval", lager.Data{"interval": routerPruneInterval.String()})
+		routerTicker.Stop()
+		routerTicker = s.clock.NewTicker(routerPruneInterval)
+	}
+
+	updateExpiry := func() {
+		if s.routerExpiry != 0 {
+			return
+		}
+
+		expiry := derivedRouterExpiry(knownRouters, routerPruneInterval)
+		if expiry <= 0 || expiry == routerExpiry {
+			return
+		}
+		routerExpiry = expiry
+		s.logger.Info("derived-router-expiry", lager.Data{"router-expiry": routerExpiry.String()})
+		if regreetTicker != nil {
+			regreetTicker.Stop()
+		}
+		regreetTicker = s.clock.NewTicker(routerExpiry / 3)
+		regreetTicks = regreetTicker.C()
+	}
+
 	for {
 		select {
-		case routerPruneInterval = <-s.routerGreet:
-			s.logger.Info("received-new-router-prune-interval", lager.Data{"interval": routerPruneInterval.String()})
-			routerTicker.Stop()
-			routerTicker = s.clock.NewTicker(routerPruneInterval)
+		case greeting := <-s.routerGreet:
+			knownRouters.greeted(greeting, s.clock.Now())
+			updateInterval()
+			updateExpiry()
+			if !greeting.start {
+				continue
+			}
+			s.logger.Info("router-started", lager.Data{"router-id": greeting.routerID})
+			if s.routerStartDebounce <= 0 {
+				s.emit()
+			} else if debounced == nil {
+				debounceTimer = s.clock.NewTimer(s.routerStartDebounce)
+				debounced = debounceTimer.C()
+			}
+		case <-debounced:
+			s.logger.Info("emitting-routes-for-started-routers")
+			debounced = nil
 			s.emit()
+		case <-regreetTicks:
+			for _, id := range knownRouters.expire(s.clock.Now().Add(-routerExpiry)) {
+				s.logger.Info("router-expired", lager.Data{"router-id": id})
+			}
+			// with no router left, keep emitting at the last known interval
+			updateInterval()
+			updateExpiry()
+			err := s.greetRouter(replyUuid.String())
+			if err != nil {
+				s.logger.Error("failed-to-greet-router", err)
+			}
 		case <-routerTicker.C():
 			s.logger.Info("emitting-routes")
 			s.emit()
@@ -108,6 +253,12 @@ GREET_LOOP:
 			s.logger.Info("stopping")
 			syncTicker.Stop()
 			routerTicker.Stop()
+			if regreetTicker != nil {
+				regreetTicker.Stop()
+			}
+			if debounceTimer != nil {
+				debounceTimer.Stop()
+			}
 			return nil
 		}
 	}
@@ -124,6 +275,7 @@ func (s *NatsSyncer) emit() {
 	case s.events.Emit <- struct{}{}:
 	default:
 		s.logger.Debug("emit-already-in-progress")
+		s.emitMonitor.EmitDropped()
 	}
 }
 
@@ -132,16 +284,24 @@ func (s *NatsSyncer) sync() {
 }
 
 func (s *NatsSyncer) listenForRouter(replyUUID string) error {
-	_, err := s.natsClient.Subscribe("router.start", s.handleRouterGreet)
+	_, err := s.natsClient.Subscribe("router.start", func(msg *nats.Msg) {
+		s.handleRouterGreet(msg, true)
+	})
 	if err != nil {
 		return err
 	}
 
-	sub, err := s.natsClient.Subscribe(replyUUID, s.handleRouterGreet)
+	sub, err := s.natsClient.Subscribe(replyUUID, func(msg *nats.Msg) {
+		s.handleRouterGreet(msg, false)
+	})
 	if err != nil {
 		return err
 	}
-	sub.AutoUnsubscribe(1)
+	// the routers are greeted again to expire the quiet ones, and all of them
+	// answer every greeting
+	if s.routerExpiry < 0 {
+		sub.AutoUnsubscribe(1)
+	}
 
 	return nil
 }
@@ -155,7 +315,7 @@ func (s *NatsSyncer) greetRouter(replyUUID string) error {
 	return nil
 }
 
-func (s *NatsSyncer) handleRouterGreet(msg *nats.Msg) {
+func (s *NatsSyncer) handleRouterGreet(msg *nats.Msg, start bool) {
 	var response routingtable.RouterGreetingMessage
 
 	err := json.Unmarshal(msg.Data, &response)
@@ -166,6 +326,22 @@ func (s *NatsSyncer) handleRouterGreet(msg *nats.Msg) {
 		return
 	}
 
-	greetInterval := response.MinimumRegisterInterval
-	s.routerGreet <- time.Duration(greetInterval) * time.Second
+	s.routerGreet <- routerGreeting{
+		routerID:       response.RouterID(),
+		interval:       time.Duration(response.MinimumRegisterInterval) * time.Second,
+		pruneThreshold: time.Duration(response.PruneThresholdInSeconds) * time.Second,
+		start:          start,
+	}
+}
+
+// derivedRouterExpiry returns how long a router may go without answering a
+// greeting before it is forgotten. A router that is still up answers each of
+// the three greetings sent within it, and no router that prunes routes is
+// forgotten before its prune threshold passes.
+func derivedRouterExpiry(knownRouters routers, interval time.Duration) time.Duration {
+	expiry := 3 * interval
+	if threshold := knownRouters.minimumPruneThreshold(); threshold > expiry {
+		expiry = threshold
+	}
+	return expiry
 }
@@ -19,6 +19,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 const logGuid = "some-log-guid"
@@ -41,6 +42,13 @@ var _ = Describe("NatsSyncer", func() {
 		clockStep    time.Duration
 		syncInterval time.Duration
 
+		greetingTimeout      time.Duration
+		fallbackEmitInterval time.Duration
+		routerExpiry         time.Duration
+		routerStartDebounce  time.Duration
+		emitMonitor          *syncer.EmitMonitor
+		logger               *lagertest.TestLogger
+
 		shutdown chan struct{}
 
 		schedulingInfoResponse *models.DesiredLRPSchedulingInfo
@@ -57,6 +65,11 @@ var _ = Describe("NatsSyncer", func() {
 		clock = fakeclock.NewFakeClock(time.Now())
 		clockStep = 1 * time.Second
 		syncInterval = 10 * time.Second
+		greetingTimeout = 0
+		fallbackEmitInterval = 0
+		routerExpiry = 0
+		routerStartDebounce = 0
+		emitMonitor = nil
 
 		startMessages := make(chan *nats.Msg)
 		routerStartMessages = startMessages
@@ -101,8 +114,8 @@ var _ = Describe("NatsSyncer", func() {
 	})
 
 	JustBeforeEach(func() {
-		logger := lagertest.NewTestLogger("test")
-		syncerRunner = syncer.NewSyncer(clock, syncInterval, natsClient, logger)
+		logger = lagertest.NewTestLogger("test")
+		syncerRunner = syncer.NewSyncer(clock, syncInterval, greetingTimeout, fallbackEmitInterval, routerExpiry, routerStartDebounce, emitMonitor, natsClient, logger)
 
 		shutdown = make(chan struct{})
 
@@ -151,6 +164,19 @@ var _ = Describe("NatsSyncer", func() {
 					Eventually(greetings).Should(Receive())
 					Consistently(greetings, 1).ShouldNot(Receive())
 				})
+
+				Context("with an emit monitor", func() {
+					BeforeEach(func() {
+						emitMonitor = syncer.NewEmitMonitor(clock, 0.5, true)
+					})
+
+					It("hands it the prune threshold of the router", func() {
+						Eventually(func() bool {
+							emitMonitor.EmitCompleted(logger, clock.Now().Add(-2*time.Second), nil)
+							return emitMonitor.ShedLoad()
+						}).Should(BeTrue())
+					})
+				})
 			})
 		})
 
@@ -170,6 +196,49 @@ var _ = Describe("NatsSyncer", func() {
 			})
 		})
 
+		Context("when no router answers within the greeting timeout", func() {
+			BeforeEach(func() {
+				greetingTimeout = 4500 * time.Millisecond
+				fallbackEmitInterval = 3 * time.Second
+			})
+
+			JustBeforeEach(func() {
+				Eventually(greetings).Should(Receive())
+				for i := 0; i < 4; i++ {
+					clock.WaitForWatcherAndIncrement(time.Second)
+					Eventually(greetings).Should(Receive())
+				}
+				clock.WaitForWatcherAndIncrement(500 * time.Millisecond)
+			})
+
+			It("syncs and emits at the fallback interval", func() {
+				Eventually(syncerRunner.Events().Sync).Should(Receive())
+
+				clock.WaitForWatcherAndIncrement(2 * time.Second)
+				Consistently(syncerRunner.Events().Emit).ShouldNot(Receive())
+
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(syncerRunner.Events().Emit).Should(Receive())
+			})
+
+			It("stops greeting the router", func() {
+				Eventually(syncerRunner.Events().Sync).Should(Receive())
+				Consistently(greetings).ShouldNot(Receive())
+			})
+
+			It("picks up the interval of a router that starts later", func() {
+				Eventually(syncerRunner.Events().Sync).Should(Receive())
+
+				routerStartMessages <- &nats.Msg{
+					Data: []byte(`{"minimumRegisterIntervalInSeconds":1, "pruneThresholdInSeconds": 3}`),
+				}
+				Eventually(syncerRunner.Events().Emit).Should(Receive())
+
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(syncerRunner.Events().Emit).Should(Receive())
+			})
+		})
+
 		Context("after getting the first interval, when a second interval arrives", func() {
 			JustBeforeEach(func() {
 				routerStartMessages <- &nats.Msg{
@@ -194,6 +263,92 @@ var _ = Describe("NatsSyncer", func() {
 			})
 		})
 
+		Context("when several routers start", func() {
+			JustBeforeEach(func() {
+				routerStartMessages <- &nats.Msg{
+					Data: []byte(`{"id":"router-1", "minimumRegisterIntervalInSeconds":2, "pruneThresholdInSeconds": 6}`),
+				}
+				Eventually(syncerRunner.Events().Sync).Should(Receive())
+			})
+
+			It("emits at the shortest of their intervals", func() {
+				routerStartMessages <- &nats.Msg{
+					Data: []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":4, "pruneThresholdInSeconds": 12}`),
+				}
+				Eventually(syncerRunner.Events().Emit).Should(Receive())
+
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Consistently(syncerRunner.Events().Emit).ShouldNot(Receive())
+
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(syncerRunner.Events().Emit).Should(Receive())
+			})
+
+			Context("with a router start debounce", func() {
+				BeforeEach(func() {
+					routerStartDebounce = 2 * time.Second
+				})
+
+				It("coalesces their starts into a single emit", func() {
+					routerStartMessages <- &nats.Msg{
+						Data: []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 30}`),
+					}
+					routerStartMessages <- &nats.Msg{
+						Data: []byte(`{"id":"router-3", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 30}`),
+					}
+					Consistently(syncerRunner.Events().Emit).ShouldNot(Receive())
+
+					clock.WaitForNWatchersAndIncrement(routerStartDebounce, 4)
+					Eventually(syncerRunner.Events().Emit).Should(Receive())
+					Consistently(syncerRunner.Events().Emit).ShouldNot(Receive())
+				})
+			})
+
+			Context("with the default router expiry", func() {
+				It("derives the expiry from the routers and forgets the ones that stop answering greetings", func() {
+					Eventually(logger).Should(gbytes.Say("derived-router-expiry.*6s"))
+					Eventually(greetings).Should(Receive())
+					routerStartMessages <- &nats.Msg{
+						Data: []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 30}`),
+					}
+
+					for i := 0; i < 4; i++ {
+						clock.WaitForNWatchersAndIncrement(2*time.Second, 3)
+						var msg *nats.Msg
+						Eventually(greetings).Should(Receive(&msg))
+						go natsClient.Publish(msg.Reply, []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 30}`))
+					}
+
+					Eventually(logger).Should(gbytes.Say("router-expired.*router-1"))
+					Eventually(logger).Should(gbytes.Say("received-new-router-prune-interval.*10s"))
+					Eventually(logger).Should(gbytes.Say("derived-router-expiry.*30s"))
+				})
+			})
+
+			Context("with a router expiry", func() {
+				BeforeEach(func() {
+					routerExpiry = 3 * time.Second
+				})
+
+				It("forgets the routers that stop answering greetings", func() {
+					Eventually(greetings).Should(Receive())
+					routerStartMessages <- &nats.Msg{
+						Data: []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 30}`),
+					}
+
+					for i := 0; i < 4; i++ {
+						clock.WaitForNWatchersAndIncrement(time.Second, 3)
+						var msg *nats.Msg
+						Eventually(greetings).Should(Receive(&msg))
+						go natsClient.Publish(msg.Reply, []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 30}`))
+					}
+
+					Eventually(logger).Should(gbytes.Say("router-expired.*router-1"))
+					Eventually(logger).Should(gbytes.Say("received-new-router-prune-interval.*10s"))
+				})
+			})
+		})
+
 		Context("if it never hears anything from a router anywhere", func() {
 			It("should still be able to shutdown", func() {
 				process.Signal(os.Interrupt)
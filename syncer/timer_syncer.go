@@ -0,0 +1,97 @@
+package syncer
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+// TimerSyncer syncs and emits at fixed intervals. It needs no NATS, so it
+// drives emitters that do not register routes with the gorouters, such as
+// the TCP emitter on its own.
+type TimerSyncer struct {
+	clock        clock.Clock
+	syncInterval time.Duration
+	emitInterval time.Duration
+	events       Events
+
+	logger lager.Logger
+}
+
+var _ Syncer = new(TimerSyncer)
+
+func NewTimerSyncer(
+	clock clock.Clock,
+	syncInterval time.Duration,
+	emitInterval time.Duration,
+	logger lager.Logger,
+) *TimerSyncer {
+	if emitInterval <= 0 {
+		emitInterval = DefaultEmitInterval
+	}
+
+	return &TimerSyncer{
+		clock:        clock,
+		syncInterval: syncInterval,
+		emitInterval: emitInterval,
+		events: Events{
+			Sync: make(chan struct{}, 1),
+			Emit: make(chan struct{}, 1),
+		},
+
+		logger: logger.Session("timer-syncer"),
+	}
+}
+
+func (s *TimerSyncer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	s.logger.Info("starting", lager.Data{
+		"sync-interval": s.syncInterval.String(),
+		"emit-interval": s.emitInterval.String(),
+	})
+
+	close(ready)
+	s.logger.Info("started")
+
+	s.sync()
+
+	syncTicker := s.clock.NewTicker(s.syncInterval)
+	emitTicker := s.clock.NewTicker(s.emitInterval)
+	defer syncTicker.Stop()
+	defer emitTicker.Stop()
+
+	for {
+		select {
+		case <-emitTicker.C():
+			s.logger.Info("emitting-routes")
+			s.emit()
+		case <-syncTicker.C():
+			s.logger.Info("syncing")
+			s.sync()
+		case <-signals:
+			s.logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+func (s *TimerSyncer) Events() Events {
+	return s.events
+}
+
+func (s *TimerSyncer) emit() {
+	select {
+	case s.events.Emit <- struct{}{}:
+	default:
+		s.logger.Debug("emit-already-in-progress")
+	}
+}
+
+func (s *TimerSyncer) sync() {
+	select {
+	case s.events.Sync <- struct{}{}:
+	default:
+		s.logger.Debug("sync-already-pending")
+	}
+}
@@ -0,0 +1,57 @@
+package syncer_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TimerSyncer", func() {
+	const (
+		syncInterval = 10 * time.Second
+		emitInterval = 3 * time.Second
+	)
+
+	var (
+		clock       *fakeclock.FakeClock
+		timerSyncer *syncer.TimerSyncer
+		process     ifrit.Process
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		timerSyncer = syncer.NewTimerSyncer(clock, syncInterval, emitInterval, lagertest.NewTestLogger("test"))
+		process = ifrit.Invoke(timerSyncer)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("syncs right away", func() {
+		Eventually(timerSyncer.Events().Sync).Should(Receive())
+	})
+
+	It("emits at the emit interval", func() {
+		Eventually(timerSyncer.Events().Sync).Should(Receive())
+
+		clock.WaitForNWatchersAndIncrement(emitInterval, 2)
+		Eventually(timerSyncer.Events().Emit).Should(Receive())
+		Consistently(timerSyncer.Events().Sync).ShouldNot(Receive())
+	})
+
+	It("syncs at the sync interval", func() {
+		Eventually(timerSyncer.Events().Sync).Should(Receive())
+
+		clock.WaitForNWatchersAndIncrement(syncInterval, 2)
+		Eventually(timerSyncer.Events().Sync).Should(Receive())
+	})
+})
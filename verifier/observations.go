@@ -0,0 +1,153 @@
+package verifier
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// EmitterComponent is the component tag of the registrations of the route
+// emitters.
+const EmitterComponent = "route-emitter"
+
+// registrationKey is what the routers route: a hostname to an address.
+type registrationKey struct {
+	Hostname string
+	Host     string
+	Port     uint32
+}
+
+func registrationKeys(message routingtable.RegistryMessage) []registrationKey {
+	keys := make([]registrationKey, 0, len(message.URIs))
+	for _, uri := range message.URIs {
+		keys = append(keys, registrationKey{Hostname: uri, Host: message.Host, Port: message.Port})
+	}
+	return keys
+}
+
+func emitterOwned(message routingtable.RegistryMessage) bool {
+	return message.Tags["component"] == EmitterComponent
+}
+
+// observation is when a registration was last seen on NATS, by who it was
+// published. The routers forget it once it is not registered again for a
+// stale threshold.
+type observation struct {
+	App               string
+	PrivateInstanceID string
+
+	// registered by a route emitter, this one or another
+	emitterSeen time.Time
+	// registered by a route emitter other than this one
+	otherEmitterSeen time.Time
+	// registered by anything but a route emitter
+	foreignSeen time.Time
+}
+
+func (o *observation) stale(now time.Time, threshold time.Duration) bool {
+	return !fresh(o.emitterSeen, now, threshold) && !fresh(o.foreignSeen, now, threshold)
+}
+
+func fresh(seen time.Time, now time.Time, threshold time.Duration) bool {
+	return !seen.IsZero() && now.Sub(seen) <= threshold
+}
+
+// observations rebuilds the registrations on NATS. It tells the registrations
+// this emitter published apart from those of other emitters by matching them
+// with the echoes it expects, the registrations published in the last
+// EchoTimeout.
+type observations struct {
+	registrations map[registrationKey]*observation
+	echoes        map[registrationKey][]time.Time
+}
+
+func newObservations() *observations {
+	return &observations{
+		registrations: map[registrationKey]*observation{},
+		echoes:        map[registrationKey][]time.Time{},
+	}
+}
+
+func (o *observations) published(message routingtable.RegistryMessage, now time.Time) {
+	for _, key := range registrationKeys(message) {
+		o.echoes[key] = append(o.echoes[key], now)
+	}
+}
+
+func (o *observations) registered(message routingtable.RegistryMessage, now time.Time) {
+	owned := emitterOwned(message)
+	for _, key := range registrationKeys(message) {
+		obs, ok := o.registrations[key]
+		if !ok {
+			obs = &observation{}
+			o.registrations[key] = obs
+		}
+		obs.App = message.App
+		obs.PrivateInstanceID = message.PrivateInstanceId
+
+		if !owned {
+			obs.foreignSeen = now
+			continue
+		}
+
+		obs.emitterSeen = now
+		if !o.echoed(key, now) {
+			obs.otherEmitterSeen = now
+		}
+	}
+}
+
+// echoed consumes the oldest echo of the registration still expected.
+func (o *observations) echoed(key registrationKey, now time.Time) bool {
+	echoes := o.echoes[key]
+	for len(echoes) > 0 && now.Sub(echoes[0]) > EchoTimeout {
+		echoes = echoes[1:]
+	}
+	if len(echoes) == 0 {
+		delete(o.echoes, key)
+		return false
+	}
+
+	if len(echoes) == 1 {
+		delete(o.echoes, key)
+	} else {
+		o.echoes[key] = echoes[1:]
+	}
+	return true
+}
+
+func (o *observations) unregistered(message routingtable.RegistryMessage) {
+	owned := emitterOwned(message)
+	for _, key := range registrationKeys(message) {
+		obs, ok := o.registrations[key]
+		if !ok {
+			continue
+		}
+
+		if owned {
+			obs.emitterSeen = time.Time{}
+			obs.otherEmitterSeen = time.Time{}
+		} else {
+			obs.foreignSeen = time.Time{}
+		}
+		if obs.emitterSeen.IsZero() && obs.foreignSeen.IsZero() {
+			delete(o.registrations, key)
+		}
+	}
+}
+
+// prune forgets the registrations the routers have pruned, and the echoes
+// that never came.
+func (o *observations) prune(now time.Time, threshold time.Duration) {
+	for key, obs := range o.registrations {
+		if obs.stale(now, threshold) {
+			delete(o.registrations, key)
+		}
+	}
+
+	for key, echoes := range o.echoes {
+		if now.Sub(echoes[len(echoes)-1]) > EchoTimeout {
+			delete(o.echoes, key)
+		}
+	}
+}
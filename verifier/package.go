@@ -0,0 +1 @@
+package verifier // import "code.cloudfoundry.org/route-emitter/verifier"
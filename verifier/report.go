@@ -0,0 +1,121 @@
+package verifier
+
+import (
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// Report compares the registrations observed on NATS with the registrations
+// expected from the source.
+type Report struct {
+	Time                  time.Time `json:"time"`
+	Source                string    `json:"source"`
+	ExpectedRegistrations int       `json:"expected_registrations"`
+	ObservedRegistrations int       `json:"observed_registrations"`
+
+	// expected, but not registered by any route emitter
+	UnderRegistered []Registration `json:"under_registered"`
+	// registered by a route emitter, but not expected
+	OverRegistered []Registration `json:"over_registered"`
+	// registered by something else than a route emitter, to an expected
+	// hostname
+	ForeignCollisions []Registration `json:"foreign_collisions"`
+	// expected, and registered by other route emitters as well
+	DuplicateEndpoints []Registration `json:"duplicate_endpoints"`
+}
+
+// Registration is a hostname routed to an address.
+type Registration struct {
+	Hostname          string `json:"hostname"`
+	Host              string `json:"host"`
+	Port              uint32 `json:"port"`
+	App               string `json:"app,omitempty"`
+	PrivateInstanceID string `json:"private_instance_id,omitempty"`
+}
+
+func newReport(source string, now time.Time) Report {
+	return Report{
+		Time:               now,
+		Source:             source,
+		UnderRegistered:    []Registration{},
+		OverRegistered:     []Registration{},
+		ForeignCollisions:  []Registration{},
+		DuplicateEndpoints: []Registration{},
+	}
+}
+
+// compare fills the report from the expected registrations and the fresh
+// observations. In local mode, the registrations of other cells are none of
+// the emitter's business, so only the observed registrations to the hosts of
+// the expected ones are compared.
+func (r *Report) compare(expected []routingtable.RegistryMessage, observed map[registrationKey]*observation, threshold time.Duration, localMode bool) {
+	expectedKeys := map[registrationKey]routingtable.RegistryMessage{}
+	expectedHostnames := map[string]bool{}
+	expectedHosts := map[string]bool{}
+	for _, message := range expected {
+		for _, key := range registrationKeys(message) {
+			expectedKeys[key] = message
+			expectedHostnames[key.Hostname] = true
+			expectedHosts[key.Host] = true
+		}
+	}
+
+	r.ExpectedRegistrations = len(expectedKeys)
+	r.ObservedRegistrations = len(observed)
+
+	for key, message := range expectedKeys {
+		obs, ok := observed[key]
+		if !ok || !fresh(obs.emitterSeen, r.Time, threshold) {
+			r.UnderRegistered = append(r.UnderRegistered, registration(key, message.App, message.PrivateInstanceId))
+			continue
+		}
+		if fresh(obs.otherEmitterSeen, r.Time, threshold) {
+			r.DuplicateEndpoints = append(r.DuplicateEndpoints, registration(key, obs.App, obs.PrivateInstanceID))
+		}
+	}
+
+	for key, obs := range observed {
+		if fresh(obs.foreignSeen, r.Time, threshold) && expectedHostnames[key.Hostname] {
+			r.ForeignCollisions = append(r.ForeignCollisions, registration(key, obs.App, obs.PrivateInstanceID))
+		}
+
+		if _, ok := expectedKeys[key]; ok || !fresh(obs.emitterSeen, r.Time, threshold) {
+			continue
+		}
+		if localMode && !expectedHosts[key.Host] {
+			continue
+		}
+		r.OverRegistered = append(r.OverRegistered, registration(key, obs.App, obs.PrivateInstanceID))
+	}
+
+	sort.Sort(byHostname(r.UnderRegistered))
+	sort.Sort(byHostname(r.OverRegistered))
+	sort.Sort(byHostname(r.ForeignCollisions))
+	sort.Sort(byHostname(r.DuplicateEndpoints))
+}
+
+func registration(key registrationKey, app, privateInstanceID string) Registration {
+	return Registration{
+		Hostname:          key.Hostname,
+		Host:              key.Host,
+		Port:              key.Port,
+		App:               app,
+		PrivateInstanceID: privateInstanceID,
+	}
+}
+
+type byHostname []Registration
+
+func (r byHostname) Len() int      { return len(r) }
+func (r byHostname) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byHostname) Less(i, j int) bool {
+	if r[i].Hostname != r[j].Hostname {
+		return r[i].Hostname < r[j].Hostname
+	}
+	if r[i].Host != r[j].Host {
+		return r[i].Host < r[j].Host
+	}
+	return r[i].Port < r[j].Port
+}
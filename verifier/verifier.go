@@ -0,0 +1,311 @@
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/runtimeschema/metric"
+	"github.com/nats-io/nats"
+)
+
+const (
+	// SourceTable compares the registrations with the HTTP routing table of
+	// the emitter.
+	SourceTable = "table"
+	// SourceBBS compares the registrations with the routes a sync would
+	// build from the BBS.
+	SourceBBS = "bbs"
+
+	DefaultInterval       = 30 * time.Second
+	DefaultStaleThreshold = 2 * time.Minute
+
+	// EchoTimeout is how long a registration this emitter published may take
+	// to come back from NATS to be told apart from those of other emitters.
+	EchoTimeout = 10 * time.Second
+
+	ReportPath = "/v1/report"
+)
+
+var (
+	underRegistered      = metric.Metric("VerifierUnderRegisteredRoutes")
+	overRegistered       = metric.Metric("VerifierOverRegisteredRoutes")
+	foreignCollisions    = metric.Metric("VerifierForeignRouteCollisions")
+	duplicateEndpoints   = metric.Metric("VerifierDuplicateEndpoints")
+	verificationFailures = metric.Counter("VerifierFailures")
+)
+
+// Config configures the verification of the registrations on NATS, served as
+// a JSON report on ListenAddress. It needs the HTTP emitter.
+type Config struct {
+	ListenAddress  string                `json:"listen_address,omitempty"`
+	Source         string                `json:"source,omitempty"`
+	Interval       durationjson.Duration `json:"interval,omitempty"`
+	StaleThreshold durationjson.Duration `json:"stale_threshold,omitempty"`
+}
+
+func (c Config) Enabled() bool {
+	return c.ListenAddress != ""
+}
+
+// Verifier listens to the registrations and unregistrations on NATS to
+// rebuild the routes the routers hold, and compares them with the routes of
+// its source at every interval. A registration the routers have not seen
+// again for the stale threshold counts as pruned, so the first comparison
+// waits for that long.
+//
+// The registrations of the route emitters are told apart from the others by
+// their component tag, and those of this emitter from those of other
+// emitters by the NATS emitter it wraps.
+type Verifier struct {
+	logger         lager.Logger
+	clock          clock.Clock
+	natsClient     diegonats.NATSClient
+	bbsClient      bbs.Client
+	cellID         string
+	source         string
+	interval       time.Duration
+	staleThreshold time.Duration
+
+	lock         sync.Mutex
+	table        routingtable.NATSRoutingTable
+	observations *observations
+	report       *Report
+}
+
+func NewVerifier(
+	logger lager.Logger,
+	clock clock.Clock,
+	natsClient diegonats.NATSClient,
+	bbsClient bbs.Client,
+	cellID string,
+	config Config,
+) (*Verifier, error) {
+	source := config.Source
+	switch source {
+	case "":
+		source = SourceTable
+	case SourceTable, SourceBBS:
+	default:
+		return nil, fmt.Errorf("unknown verifier source %q, expected %q or %q", source, SourceTable, SourceBBS)
+	}
+
+	interval := time.Duration(config.Interval)
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	staleThreshold := time.Duration(config.StaleThreshold)
+	if staleThreshold <= 0 {
+		staleThreshold = DefaultStaleThreshold
+	}
+
+	return &Verifier{
+		logger:         logger.Session("verifier"),
+		clock:          clock,
+		natsClient:     natsClient,
+		bbsClient:      bbsClient,
+		cellID:         cellID,
+		source:         source,
+		interval:       interval,
+		staleThreshold: staleThreshold,
+		observations:   newObservations(),
+	}, nil
+}
+
+// NATSTable compares the registrations with the table when it is the source.
+func (v *Verifier) NATSTable(table routingtable.NATSRoutingTable) routingtable.NATSRoutingTable {
+	v.lock.Lock()
+	v.table = table
+	v.lock.Unlock()
+
+	return table
+}
+
+// NATSEmitter returns an emitter that records the registrations before
+// handing them to next, for the verifier to know its own.
+func (v *Verifier) NATSEmitter(next emitter.NATSEmitter) emitter.NATSEmitter {
+	return &natsEmitter{verifier: v, next: next}
+}
+
+type natsEmitter struct {
+	verifier *Verifier
+	next     emitter.NATSEmitter
+}
+
+func (e *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	e.verifier.published(messagesToEmit.RegistrationMessages)
+	return e.next.Emit(messagesToEmit)
+}
+
+func (v *Verifier) published(messages []routingtable.RegistryMessage) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	now := v.clock.Now()
+	for _, message := range messages {
+		v.observations.published(message, now)
+	}
+}
+
+// Report returns the last report, and false until the first one.
+func (v *Verifier) Report() (Report, bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.report == nil {
+		return Report{}, false
+	}
+	return *v.report, true
+}
+
+// Handler serves the last report on ReportPath.
+func (v *Verifier) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ReportPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report, ok := v.Report()
+		if !ok {
+			http.Error(w, "no report yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(report)
+		if err != nil {
+			v.logger.Error("failed-to-write-report", err)
+		}
+	})
+	return mux
+}
+
+func (v *Verifier) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := v.logger.Session("run")
+	logger.Info("starting", lager.Data{
+		"source":          v.source,
+		"interval":        v.interval.String(),
+		"stale-threshold": v.staleThreshold.String(),
+	})
+
+	for _, subject := range []string{"router.register", "router.unregister"} {
+		subscription, err := v.natsClient.Subscribe(subject, v.handleMessage(logger, subject))
+		if err != nil {
+			logger.Error("failed-to-subscribe", err, lager.Data{"subject": subject})
+			return err
+		}
+		defer v.natsClient.Unsubscribe(subscription)
+	}
+
+	started := v.clock.Now()
+	ticker := v.clock.NewTicker(v.interval)
+	defer ticker.Stop()
+
+	close(ready)
+	logger.Info("started")
+
+	for {
+		select {
+		case <-ticker.C():
+			if v.clock.Since(started) < v.staleThreshold {
+				continue
+			}
+			v.verify(logger)
+		case <-signals:
+			logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+func (v *Verifier) handleMessage(logger lager.Logger, subject string) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		var message routingtable.RegistryMessage
+		err := json.Unmarshal(msg.Data, &message)
+		if err != nil {
+			logger.Error("failed-to-unmarshal-message", err, lager.Data{"subject": subject})
+			return
+		}
+
+		v.lock.Lock()
+		defer v.lock.Unlock()
+
+		if subject == "router.register" {
+			v.observations.registered(message, v.clock.Now())
+		} else {
+			v.observations.unregistered(message)
+		}
+	}
+}
+
+func (v *Verifier) verify(logger lager.Logger) {
+	logger = logger.Session("verify")
+
+	expected, err := v.expected(logger)
+	if err != nil {
+		logger.Error("failed-to-get-expected-registrations", err, lager.Data{"source": v.source})
+		verificationFailures.Increment()
+		return
+	}
+
+	v.lock.Lock()
+	now := v.clock.Now()
+	v.observations.prune(now, v.staleThreshold)
+	report := newReport(v.source, now)
+	report.compare(expected, v.observations.registrations, v.staleThreshold, v.cellID != "")
+	v.report = &report
+	v.lock.Unlock()
+
+	underRegistered.Send(len(report.UnderRegistered))
+	overRegistered.Send(len(report.OverRegistered))
+	foreignCollisions.Send(len(report.ForeignCollisions))
+	duplicateEndpoints.Send(len(report.DuplicateEndpoints))
+
+	data := lager.Data{
+		"expected":            report.ExpectedRegistrations,
+		"observed":            report.ObservedRegistrations,
+		"under-registered":    len(report.UnderRegistered),
+		"over-registered":     len(report.OverRegistered),
+		"foreign-collisions":  len(report.ForeignCollisions),
+		"duplicate-endpoints": len(report.DuplicateEndpoints),
+	}
+	if len(report.UnderRegistered)+len(report.OverRegistered)+len(report.ForeignCollisions)+len(report.DuplicateEndpoints) > 0 {
+		logger.Info("found-inconsistencies", data)
+	} else {
+		logger.Debug("consistent", data)
+	}
+}
+
+func (v *Verifier) expected(logger lager.Logger) ([]routingtable.RegistryMessage, error) {
+	var table routingtable.NATSRoutingTable
+	if v.source == SourceBBS {
+		var err error
+		table, err = watcher.FetchNATSTable(logger, v.bbsClient, v.cellID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		v.lock.Lock()
+		table = v.table
+		v.lock.Unlock()
+	}
+
+	if table == nil {
+		return nil, nil
+	}
+	return table.MessagesToEmit().RegistrationMessages, nil
+}
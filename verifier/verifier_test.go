@@ -0,0 +1,226 @@
+package verifier_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/verifier"
+	"code.cloudfoundry.org/workpool"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verifier", func() {
+	const interval = 10 * time.Second
+
+	var (
+		logger      *lagertest.TestLogger
+		clock       *fakeclock.FakeClock
+		natsClient  *diegonats.FakeNATSClient
+		cellID      string
+		v           *verifier.Verifier
+		natsEmitter emitter.NATSEmitter
+		process     ifrit.Process
+	)
+
+	keyA := endpoint.RoutingKey{ProcessGUID: "process-guid-a", ContainerPort: 8080}
+	keyB := endpoint.RoutingKey{ProcessGUID: "process-guid-b", ContainerPort: 8080}
+	routeA := routingtable.Route{Hostname: "a.example.com", LogGuid: "log-guid-a"}
+	routeB := routingtable.Route{Hostname: "b.example.com", LogGuid: "log-guid-b"}
+	endpointA := routingtable.Endpoint{InstanceGuid: "ig-a", Host: "10.0.0.1", Port: 61001, ContainerPort: 8080}
+	endpointB := routingtable.Endpoint{InstanceGuid: "ig-b", Host: "10.0.0.2", Port: 61002, ContainerPort: 8080}
+
+	publish := func(subject string, message routingtable.RegistryMessage) {
+		payload, err := json.Marshal(message)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(natsClient.Publish(subject, payload)).To(Succeed())
+	}
+
+	emitterMessage := func(hostname, host string, port uint32) routingtable.RegistryMessage {
+		return routingtable.RegistryMessage{
+			URIs: []string{hostname},
+			Host: host,
+			Port: port,
+			Tags: map[string]string{"component": verifier.EmitterComponent},
+		}
+	}
+
+	report := func() verifier.Report {
+		var report verifier.Report
+		Eventually(func() bool {
+			var ok bool
+			report, ok = v.Report()
+			return ok
+		}).Should(BeTrue())
+		return report
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		natsClient = diegonats.NewFakeClient()
+		cellID = ""
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		v, err = verifier.NewVerifier(logger, clock, natsClient, nil, cellID, verifier.Config{
+			ListenAddress:  "127.0.0.1:0",
+			Interval:       durationjson.Duration(interval),
+			StaleThreshold: durationjson.Duration(interval),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		table := v.NATSTable(routingtable.NewNATSTable(logger))
+		table.SetRoutes(keyA, []routingtable.Route{routeA}, nil)
+		table.AddEndpoint(keyA, endpointA)
+		table.SetRoutes(keyB, []routingtable.Route{routeB}, nil)
+		table.AddEndpoint(keyB, endpointB)
+
+		workPool, err := workpool.NewWorkPool(1)
+		Expect(err).NotTo(HaveOccurred())
+		natsEmitter = v.NATSEmitter(emitter.NewNATSEmitter(natsClient, workPool, logger))
+
+		process = ifrit.Invoke(v)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	Context("once it has listened for the stale threshold", func() {
+		JustBeforeEach(func() {
+			err := natsEmitter.Emit(routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{routingtable.RegistryMessageFor(endpointA, routeA)},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports the registrations that do not match the table", func() {
+			publish("router.register", routingtable.RegistryMessage{URIs: []string{"a.example.com"}, Host: "10.9.9.9", Port: 80})
+			publish("router.register", emitterMessage("c.example.com", "10.0.0.3", 61003))
+			publish("router.register", routingtable.RegistryMessageFor(endpointA, routeA))
+			clock.WaitForWatcherAndIncrement(interval)
+
+			r := report()
+			Expect(r.Source).To(Equal(verifier.SourceTable))
+			Expect(r.ExpectedRegistrations).To(Equal(2))
+			Expect(r.ObservedRegistrations).To(Equal(3))
+			Expect(r.UnderRegistered).To(Equal([]verifier.Registration{
+				{Hostname: "b.example.com", Host: "10.0.0.2", Port: 61002, App: "log-guid-b", PrivateInstanceID: "ig-b"},
+			}))
+			Expect(r.OverRegistered).To(Equal([]verifier.Registration{
+				{Hostname: "c.example.com", Host: "10.0.0.3", Port: 61003},
+			}))
+			Expect(r.ForeignCollisions).To(Equal([]verifier.Registration{
+				{Hostname: "a.example.com", Host: "10.9.9.9", Port: 80},
+			}))
+			Expect(r.DuplicateEndpoints).To(Equal([]verifier.Registration{
+				{Hostname: "a.example.com", Host: "10.0.0.1", Port: 61001, App: "log-guid-a", PrivateInstanceID: "ig-a"},
+			}))
+		})
+
+		It("does not count its own registrations as duplicates", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+
+			r := report()
+			Expect(r.UnderRegistered).To(HaveLen(1))
+			Expect(r.DuplicateEndpoints).To(BeEmpty())
+		})
+
+		It("forgets the unregistered routes", func() {
+			publish("router.register", emitterMessage("c.example.com", "10.0.0.3", 61003))
+			publish("router.unregister", emitterMessage("c.example.com", "10.0.0.3", 61003))
+			clock.WaitForWatcherAndIncrement(interval)
+
+			r := report()
+			Expect(r.ObservedRegistrations).To(Equal(1))
+			Expect(r.OverRegistered).To(BeEmpty())
+		})
+
+		It("counts the routes that are not registered again as pruned", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			first := report()
+
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(func() time.Time {
+				r, _ := v.Report()
+				return r.Time
+			}).Should(BeTemporally(">", first.Time))
+
+			r, _ := v.Report()
+			Expect(r.ObservedRegistrations).To(BeZero())
+			Expect(r.UnderRegistered).To(HaveLen(2))
+		})
+
+		Context("when the emitter runs on a cell", func() {
+			BeforeEach(func() {
+				cellID = "cell-id"
+			})
+
+			It("only reports the over-registered routes of the hosts of the cell", func() {
+				publish("router.register", emitterMessage("c.example.com", "10.0.0.3", 61003))
+				publish("router.register", emitterMessage("c.example.com", "10.0.0.1", 61009))
+				clock.WaitForWatcherAndIncrement(interval)
+
+				Expect(report().OverRegistered).To(Equal([]verifier.Registration{
+					{Hostname: "c.example.com", Host: "10.0.0.1", Port: 61009},
+				}))
+			})
+		})
+	})
+
+	Describe("the report handler", func() {
+		var server *httptest.Server
+
+		JustBeforeEach(func() {
+			server = httptest.NewServer(v.Handler())
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("is unavailable until the first report", func() {
+			resp, err := http.Get(server.URL + verifier.ReportPath)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		It("serves the last report as JSON", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			expected := report()
+
+			resp, err := http.Get(server.URL + verifier.ReportPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			var served verifier.Report
+			Expect(json.Unmarshal(body, &served)).To(Succeed())
+			Expect(served.UnderRegistered).To(Equal(expected.UnderRegistered))
+		})
+	})
+
+	It("rejects unknown sources", func() {
+		_, err := verifier.NewVerifier(logger, clock, natsClient, nil, "", verifier.Config{Source: "routers"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,153 @@
+package watcher
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/cellwatcher"
+)
+
+// suppressedCell is a cell that left the BBS cell registry while instances
+// were running on it. The hosts of those instances stay suppressed in the
+// route handler until the cell is back, every instance has a running
+// replacement on another cell, or a sync that read the BBS after the cell was
+// lost completes.
+type suppressedCell struct {
+	hosts     map[string]struct{}
+	instances map[models.ActualLRPKey]struct{}
+	// set once a sync starts after the cell was lost
+	liftOnSync bool
+}
+
+// handleCellChange suppresses the hosts of the instances on a lost cell, and
+// lifts the suppression once the cell is back. It returns true when the
+// suppression was lifted, as only a sync registers the endpoints whose events
+// were dropped in the meantime.
+func (w *Watcher) handleCellChange(change cellwatcher.Change) bool {
+	suppressor, ok := w.routeHandler.(HostSuppressor)
+	if !ok {
+		return false
+	}
+
+	logger := w.logger.Session("cell-change", lager.Data{"cell-id": change.CellID})
+	if !change.Lost {
+		return w.liftCell(logger, suppressor, change.CellID, "cell-recovered")
+	}
+
+	if _, ok := w.suppressedCells[change.CellID]; ok {
+		return false
+	}
+
+	// the instances are looked up by the cell id they carry, as the address
+	// the cell registered with need not be the one its instances are reached
+	// on
+	actuals, err := w.getRunningActuals(logger, models.ActualLRPFilter{CellID: change.CellID})
+	if err != nil {
+		logger.Error("failed-getting-actual-lrps", err)
+		return false
+	}
+
+	cell := &suppressedCell{
+		hosts:     map[string]struct{}{},
+		instances: map[models.ActualLRPKey]struct{}{},
+	}
+	for _, actual := range actuals {
+		cell.hosts[actual.ActualLRP.Address] = struct{}{}
+		cell.instances[actual.ActualLRP.ActualLRPKey] = struct{}{}
+	}
+	if len(cell.instances) == 0 {
+		logger.Info("no-instances-on-lost-cell")
+		return false
+	}
+
+	w.suppressedCells[change.CellID] = cell
+	for host := range cell.hosts {
+		suppressor.SuppressHost(logger, host)
+	}
+	logger.Info("suppressed-cell", lager.Data{"num-instances": len(cell.instances)})
+	return false
+}
+
+// liftReplacedCells lifts the suppression of the lost cells whose instances
+// all have a running replacement once the event is handled.
+func (w *Watcher) liftReplacedCells(logger lager.Logger, event models.Event) {
+	if len(w.suppressedCells) == 0 {
+		return
+	}
+	suppressor, ok := w.routeHandler.(HostSuppressor)
+	if !ok {
+		return
+	}
+
+	var lrp *models.ActualLRP
+	switch e := event.(type) {
+	case *models.ActualLRPCreatedEvent:
+		lrp, _ = e.ActualLrpGroup.Resolve()
+	case *models.ActualLRPChangedEvent:
+		lrp, _ = e.After.Resolve()
+	}
+	if lrp == nil || lrp.State != models.ActualLRPStateRunning {
+		return
+	}
+
+	for cellID, cell := range w.suppressedCells {
+		if lrp.CellId == cellID {
+			continue
+		}
+		delete(cell.instances, lrp.ActualLRPKey)
+		if len(cell.instances) == 0 {
+			w.liftCell(logger, suppressor, cellID, "instances-replaced")
+		}
+	}
+}
+
+// markCellsForLiftOnSync marks the cells lost before a sync starts, so that
+// the sync lifts their suppression when it completes.
+func (w *Watcher) markCellsForLiftOnSync() {
+	for _, cell := range w.suppressedCells {
+		cell.liftOnSync = true
+	}
+}
+
+// liftCellsOnSync lifts the suppression of the cells marked when the sync
+// started. The sync read what the BBS knows about their instances after they
+// were lost, so the synced table can be trusted with them.
+func (w *Watcher) liftCellsOnSync(logger lager.Logger) {
+	suppressor, ok := w.routeHandler.(HostSuppressor)
+	if !ok {
+		return
+	}
+
+	for cellID, cell := range w.suppressedCells {
+		if cell.liftOnSync {
+			w.liftCell(logger, suppressor, cellID, "synced")
+		}
+	}
+}
+
+// liftCell lifts the suppression of the hosts of a lost cell, but for those
+// of another lost cell, and returns false if the cell was not suppressed.
+func (w *Watcher) liftCell(logger lager.Logger, suppressor HostSuppressor, cellID, reason string) bool {
+	cell, ok := w.suppressedCells[cellID]
+	if !ok {
+		return false
+	}
+	delete(w.suppressedCells, cellID)
+
+	for host := range cell.hosts {
+		if w.hostSuppressed(host) {
+			continue
+		}
+		suppressor.UnsuppressHost(logger, host)
+	}
+	logger.Info("lifted-cell-suppression", lager.Data{"cell-id": cellID, "reason": reason})
+	return true
+}
+
+func (w *Watcher) hostSuppressed(host string) bool {
+	for _, cell := range w.suppressedCells {
+		if _, ok := cell.hosts[host]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package watcher
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// DefaultMaxCachedEvents is the number of events the watcher caches during a
+// sync when no limit is configured.
+const DefaultMaxCachedEvents = 10000
+
+// eventLog holds the events received while a sync is in progress, in the
+// order they were received, so that route handlers can replay them on top of
+// the synced table in that same order. It holds at most limit events. Once
+// full it drops every further event and remembers that it overflowed, as the
+// dropped events can only be made up for by another sync.
+type eventLog struct {
+	limit      int
+	events     []models.Event
+	receivedAt []time.Time
+	overflowed bool
+}
+
+func newEventLog(limit int) *eventLog {
+	return &eventLog{limit: limit}
+}
+
+// append adds the event to the log and returns false if the log is full.
+func (l *eventLog) append(event models.Event, receivedAt time.Time) bool {
+	if len(l.events) >= l.limit {
+		l.overflowed = true
+		return false
+	}
+
+	l.events = append(l.events, event)
+	l.receivedAt = append(l.receivedAt, receivedAt)
+	return true
+}
+
+// reset empties the log. Whether it overflowed is kept until the next call to
+// clearOverflow, so that a failed sync does not lose track of it.
+func (l *eventLog) reset() {
+	l.events = nil
+	l.receivedAt = nil
+}
+
+func (l *eventLog) clearOverflow() {
+	l.overflowed = false
+}
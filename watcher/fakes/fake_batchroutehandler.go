@@ -0,0 +1,351 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher"
+)
+
+type FakeBatchRouteHandler struct {
+	HandleEventStub        func(logger lager.Logger, event models.Event)
+	handleEventMutex       sync.RWMutex
+	handleEventArgsForCall []struct {
+		logger lager.Logger
+		event  models.Event
+	}
+	SyncStub        func(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, cachedEvents []models.Event)
+	syncMutex       sync.RWMutex
+	syncArgsForCall []struct {
+		logger        lager.Logger
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+		domains       models.DomainSet
+		cachedEvents  []models.Event
+	}
+	EmitStub        func(logger lager.Logger)
+	emitMutex       sync.RWMutex
+	emitArgsForCall []struct {
+		logger lager.Logger
+	}
+	ShouldRefreshDesiredStub        func(*endpoint.ActualLRPRoutingInfo) bool
+	shouldRefreshDesiredMutex       sync.RWMutex
+	shouldRefreshDesiredArgsForCall []struct {
+		arg1 *endpoint.ActualLRPRoutingInfo
+	}
+	shouldRefreshDesiredReturns struct {
+		result1 bool
+	}
+	RefreshDesiredStub        func(lager.Logger, []*models.DesiredLRPSchedulingInfo)
+	refreshDesiredMutex       sync.RWMutex
+	refreshDesiredArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 []*models.DesiredLRPSchedulingInfo
+	}
+	SyncBatchStub        func(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo)
+	syncBatchMutex       sync.RWMutex
+	syncBatchArgsForCall []struct {
+		logger        lager.Logger
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+	}
+	CompleteBatchSyncStub        func(logger lager.Logger, domains models.DomainSet, cachedEvents []models.Event)
+	completeBatchSyncMutex       sync.RWMutex
+	completeBatchSyncArgsForCall []struct {
+		logger       lager.Logger
+		domains      models.DomainSet
+		cachedEvents []models.Event
+	}
+	AbortBatchSyncStub        func(logger lager.Logger)
+	abortBatchSyncMutex       sync.RWMutex
+	abortBatchSyncArgsForCall []struct {
+		logger lager.Logger
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeBatchRouteHandler) HandleEvent(logger lager.Logger, event models.Event) {
+	fake.handleEventMutex.Lock()
+	fake.handleEventArgsForCall = append(fake.handleEventArgsForCall, struct {
+		logger lager.Logger
+		event  models.Event
+	}{logger, event})
+	fake.recordInvocation("HandleEvent", []interface{}{logger, event})
+	fake.handleEventMutex.Unlock()
+	if fake.HandleEventStub != nil {
+		fake.HandleEventStub(logger, event)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) HandleEventCallCount() int {
+	fake.handleEventMutex.RLock()
+	defer fake.handleEventMutex.RUnlock()
+	return len(fake.handleEventArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) HandleEventArgsForCall(i int) (lager.Logger, models.Event) {
+	fake.handleEventMutex.RLock()
+	defer fake.handleEventMutex.RUnlock()
+	return fake.handleEventArgsForCall[i].logger, fake.handleEventArgsForCall[i].event
+}
+
+func (fake *FakeBatchRouteHandler) Sync(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, cachedEvents []models.Event) {
+	var desiredCopy []*models.DesiredLRPSchedulingInfo
+	if desired != nil {
+		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
+		copy(desiredCopy, desired)
+	}
+	var runningActualCopy []*endpoint.ActualLRPRoutingInfo
+	if runningActual != nil {
+		runningActualCopy = make([]*endpoint.ActualLRPRoutingInfo, len(runningActual))
+		copy(runningActualCopy, runningActual)
+	}
+	var cachedEventsCopy []models.Event
+	if cachedEvents != nil {
+		cachedEventsCopy = make([]models.Event, len(cachedEvents))
+		copy(cachedEventsCopy, cachedEvents)
+	}
+	fake.syncMutex.Lock()
+	fake.syncArgsForCall = append(fake.syncArgsForCall, struct {
+		logger        lager.Logger
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+		domains       models.DomainSet
+		cachedEvents  []models.Event
+	}{logger, desiredCopy, runningActualCopy, domains, cachedEventsCopy})
+	fake.recordInvocation("Sync", []interface{}{logger, desiredCopy, runningActualCopy, domains, cachedEventsCopy})
+	fake.syncMutex.Unlock()
+	if fake.SyncStub != nil {
+		fake.SyncStub(logger, desired, runningActual, domains, cachedEvents)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) SyncCallCount() int {
+	fake.syncMutex.RLock()
+	defer fake.syncMutex.RUnlock()
+	return len(fake.syncArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) SyncArgsForCall(i int) (lager.Logger, []*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, []models.Event) {
+	fake.syncMutex.RLock()
+	defer fake.syncMutex.RUnlock()
+	return fake.syncArgsForCall[i].logger, fake.syncArgsForCall[i].desired, fake.syncArgsForCall[i].runningActual, fake.syncArgsForCall[i].domains, fake.syncArgsForCall[i].cachedEvents
+}
+
+func (fake *FakeBatchRouteHandler) Emit(logger lager.Logger) {
+	fake.emitMutex.Lock()
+	fake.emitArgsForCall = append(fake.emitArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("Emit", []interface{}{logger})
+	fake.emitMutex.Unlock()
+	if fake.EmitStub != nil {
+		fake.EmitStub(logger)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) EmitCallCount() int {
+	fake.emitMutex.RLock()
+	defer fake.emitMutex.RUnlock()
+	return len(fake.emitArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) EmitArgsForCall(i int) lager.Logger {
+	fake.emitMutex.RLock()
+	defer fake.emitMutex.RUnlock()
+	return fake.emitArgsForCall[i].logger
+}
+
+func (fake *FakeBatchRouteHandler) ShouldRefreshDesired(arg1 *endpoint.ActualLRPRoutingInfo) bool {
+	fake.shouldRefreshDesiredMutex.Lock()
+	fake.shouldRefreshDesiredArgsForCall = append(fake.shouldRefreshDesiredArgsForCall, struct {
+		arg1 *endpoint.ActualLRPRoutingInfo
+	}{arg1})
+	fake.recordInvocation("ShouldRefreshDesired", []interface{}{arg1})
+	fake.shouldRefreshDesiredMutex.Unlock()
+	if fake.ShouldRefreshDesiredStub != nil {
+		return fake.ShouldRefreshDesiredStub(arg1)
+	} else {
+		return fake.shouldRefreshDesiredReturns.result1
+	}
+}
+
+func (fake *FakeBatchRouteHandler) ShouldRefreshDesiredCallCount() int {
+	fake.shouldRefreshDesiredMutex.RLock()
+	defer fake.shouldRefreshDesiredMutex.RUnlock()
+	return len(fake.shouldRefreshDesiredArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) ShouldRefreshDesiredArgsForCall(i int) *endpoint.ActualLRPRoutingInfo {
+	fake.shouldRefreshDesiredMutex.RLock()
+	defer fake.shouldRefreshDesiredMutex.RUnlock()
+	return fake.shouldRefreshDesiredArgsForCall[i].arg1
+}
+
+func (fake *FakeBatchRouteHandler) ShouldRefreshDesiredReturns(result1 bool) {
+	fake.ShouldRefreshDesiredStub = nil
+	fake.shouldRefreshDesiredReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeBatchRouteHandler) RefreshDesired(arg1 lager.Logger, arg2 []*models.DesiredLRPSchedulingInfo) {
+	var arg2Copy []*models.DesiredLRPSchedulingInfo
+	if arg2 != nil {
+		arg2Copy = make([]*models.DesiredLRPSchedulingInfo, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.refreshDesiredMutex.Lock()
+	fake.refreshDesiredArgsForCall = append(fake.refreshDesiredArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 []*models.DesiredLRPSchedulingInfo
+	}{arg1, arg2Copy})
+	fake.recordInvocation("RefreshDesired", []interface{}{arg1, arg2Copy})
+	fake.refreshDesiredMutex.Unlock()
+	if fake.RefreshDesiredStub != nil {
+		fake.RefreshDesiredStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) RefreshDesiredCallCount() int {
+	fake.refreshDesiredMutex.RLock()
+	defer fake.refreshDesiredMutex.RUnlock()
+	return len(fake.refreshDesiredArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) RefreshDesiredArgsForCall(i int) (lager.Logger, []*models.DesiredLRPSchedulingInfo) {
+	fake.refreshDesiredMutex.RLock()
+	defer fake.refreshDesiredMutex.RUnlock()
+	return fake.refreshDesiredArgsForCall[i].arg1, fake.refreshDesiredArgsForCall[i].arg2
+}
+
+func (fake *FakeBatchRouteHandler) SyncBatch(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo) {
+	var desiredCopy []*models.DesiredLRPSchedulingInfo
+	if desired != nil {
+		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
+		copy(desiredCopy, desired)
+	}
+	var runningActualCopy []*endpoint.ActualLRPRoutingInfo
+	if runningActual != nil {
+		runningActualCopy = make([]*endpoint.ActualLRPRoutingInfo, len(runningActual))
+		copy(runningActualCopy, runningActual)
+	}
+	fake.syncBatchMutex.Lock()
+	fake.syncBatchArgsForCall = append(fake.syncBatchArgsForCall, struct {
+		logger        lager.Logger
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+	}{logger, desiredCopy, runningActualCopy})
+	fake.recordInvocation("SyncBatch", []interface{}{logger, desiredCopy, runningActualCopy})
+	fake.syncBatchMutex.Unlock()
+	if fake.SyncBatchStub != nil {
+		fake.SyncBatchStub(logger, desired, runningActual)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) SyncBatchCallCount() int {
+	fake.syncBatchMutex.RLock()
+	defer fake.syncBatchMutex.RUnlock()
+	return len(fake.syncBatchArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) SyncBatchArgsForCall(i int) (lager.Logger, []*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo) {
+	fake.syncBatchMutex.RLock()
+	defer fake.syncBatchMutex.RUnlock()
+	return fake.syncBatchArgsForCall[i].logger, fake.syncBatchArgsForCall[i].desired, fake.syncBatchArgsForCall[i].runningActual
+}
+
+func (fake *FakeBatchRouteHandler) CompleteBatchSync(logger lager.Logger, domains models.DomainSet, cachedEvents []models.Event) {
+	var cachedEventsCopy []models.Event
+	if cachedEvents != nil {
+		cachedEventsCopy = make([]models.Event, len(cachedEvents))
+		copy(cachedEventsCopy, cachedEvents)
+	}
+	fake.completeBatchSyncMutex.Lock()
+	fake.completeBatchSyncArgsForCall = append(fake.completeBatchSyncArgsForCall, struct {
+		logger       lager.Logger
+		domains      models.DomainSet
+		cachedEvents []models.Event
+	}{logger, domains, cachedEventsCopy})
+	fake.recordInvocation("CompleteBatchSync", []interface{}{logger, domains, cachedEventsCopy})
+	fake.completeBatchSyncMutex.Unlock()
+	if fake.CompleteBatchSyncStub != nil {
+		fake.CompleteBatchSyncStub(logger, domains, cachedEvents)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) CompleteBatchSyncCallCount() int {
+	fake.completeBatchSyncMutex.RLock()
+	defer fake.completeBatchSyncMutex.RUnlock()
+	return len(fake.completeBatchSyncArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) CompleteBatchSyncArgsForCall(i int) (lager.Logger, models.DomainSet, []models.Event) {
+	fake.completeBatchSyncMutex.RLock()
+	defer fake.completeBatchSyncMutex.RUnlock()
+	return fake.completeBatchSyncArgsForCall[i].logger, fake.completeBatchSyncArgsForCall[i].domains, fake.completeBatchSyncArgsForCall[i].cachedEvents
+}
+
+func (fake *FakeBatchRouteHandler) AbortBatchSync(logger lager.Logger) {
+	fake.abortBatchSyncMutex.Lock()
+	fake.abortBatchSyncArgsForCall = append(fake.abortBatchSyncArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("AbortBatchSync", []interface{}{logger})
+	fake.abortBatchSyncMutex.Unlock()
+	if fake.AbortBatchSyncStub != nil {
+		fake.AbortBatchSyncStub(logger)
+	}
+}
+
+func (fake *FakeBatchRouteHandler) AbortBatchSyncCallCount() int {
+	fake.abortBatchSyncMutex.RLock()
+	defer fake.abortBatchSyncMutex.RUnlock()
+	return len(fake.abortBatchSyncArgsForCall)
+}
+
+func (fake *FakeBatchRouteHandler) AbortBatchSyncArgsForCall(i int) lager.Logger {
+	fake.abortBatchSyncMutex.RLock()
+	defer fake.abortBatchSyncMutex.RUnlock()
+	return fake.abortBatchSyncArgsForCall[i].logger
+}
+
+func (fake *FakeBatchRouteHandler) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.handleEventMutex.RLock()
+	defer fake.handleEventMutex.RUnlock()
+	fake.syncMutex.RLock()
+	defer fake.syncMutex.RUnlock()
+	fake.emitMutex.RLock()
+	defer fake.emitMutex.RUnlock()
+	fake.shouldRefreshDesiredMutex.RLock()
+	defer fake.shouldRefreshDesiredMutex.RUnlock()
+	fake.refreshDesiredMutex.RLock()
+	defer fake.refreshDesiredMutex.RUnlock()
+	fake.syncBatchMutex.RLock()
+	defer fake.syncBatchMutex.RUnlock()
+	fake.completeBatchSyncMutex.RLock()
+	defer fake.completeBatchSyncMutex.RUnlock()
+	fake.abortBatchSyncMutex.RLock()
+	defer fake.abortBatchSyncMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeBatchRouteHandler) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ watcher.BatchRouteHandler = new(FakeBatchRouteHandler)
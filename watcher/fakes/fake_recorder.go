@@ -0,0 +1,215 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher"
+)
+
+type FakeRecorder struct {
+	RecordEventStub        func(event models.Event)
+	recordEventMutex       sync.RWMutex
+	recordEventArgsForCall []struct {
+		event models.Event
+	}
+	RecordSyncStartStub        func()
+	recordSyncStartMutex       sync.RWMutex
+	recordSyncStartArgsForCall []struct{}
+	RecordSyncBatchStub        func(desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo)
+	recordSyncBatchMutex       sync.RWMutex
+	recordSyncBatchArgsForCall []struct {
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+	}
+	RecordSyncStub        func(desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, err error)
+	recordSyncMutex       sync.RWMutex
+	recordSyncArgsForCall []struct {
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+		domains       models.DomainSet
+		err           error
+	}
+	RecordDesiredRefreshStub        func(desired []*models.DesiredLRPSchedulingInfo, err error)
+	recordDesiredRefreshMutex       sync.RWMutex
+	recordDesiredRefreshArgsForCall []struct {
+		desired []*models.DesiredLRPSchedulingInfo
+		err     error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRecorder) RecordEvent(event models.Event) {
+	fake.recordEventMutex.Lock()
+	fake.recordEventArgsForCall = append(fake.recordEventArgsForCall, struct {
+		event models.Event
+	}{event})
+	fake.recordInvocation("RecordEvent", []interface{}{event})
+	fake.recordEventMutex.Unlock()
+	if fake.RecordEventStub != nil {
+		fake.RecordEventStub(event)
+	}
+}
+
+func (fake *FakeRecorder) RecordEventCallCount() int {
+	fake.recordEventMutex.RLock()
+	defer fake.recordEventMutex.RUnlock()
+	return len(fake.recordEventArgsForCall)
+}
+
+func (fake *FakeRecorder) RecordEventArgsForCall(i int) models.Event {
+	fake.recordEventMutex.RLock()
+	defer fake.recordEventMutex.RUnlock()
+	return fake.recordEventArgsForCall[i].event
+}
+
+func (fake *FakeRecorder) RecordSyncStart() {
+	fake.recordSyncStartMutex.Lock()
+	fake.recordSyncStartArgsForCall = append(fake.recordSyncStartArgsForCall, struct{}{})
+	fake.recordInvocation("RecordSyncStart", []interface{}{})
+	fake.recordSyncStartMutex.Unlock()
+	if fake.RecordSyncStartStub != nil {
+		fake.RecordSyncStartStub()
+	}
+}
+
+func (fake *FakeRecorder) RecordSyncStartCallCount() int {
+	fake.recordSyncStartMutex.RLock()
+	defer fake.recordSyncStartMutex.RUnlock()
+	return len(fake.recordSyncStartArgsForCall)
+}
+
+func (fake *FakeRecorder) RecordSyncBatch(desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo) {
+	var desiredCopy []*models.DesiredLRPSchedulingInfo
+	if desired != nil {
+		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
+		copy(desiredCopy, desired)
+	}
+	var runningActualCopy []*endpoint.ActualLRPRoutingInfo
+	if runningActual != nil {
+		runningActualCopy = make([]*endpoint.ActualLRPRoutingInfo, len(runningActual))
+		copy(runningActualCopy, runningActual)
+	}
+	fake.recordSyncBatchMutex.Lock()
+	fake.recordSyncBatchArgsForCall = append(fake.recordSyncBatchArgsForCall, struct {
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+	}{desiredCopy, runningActualCopy})
+	fake.recordInvocation("RecordSyncBatch", []interface{}{desiredCopy, runningActualCopy})
+	fake.recordSyncBatchMutex.Unlock()
+	if fake.RecordSyncBatchStub != nil {
+		fake.RecordSyncBatchStub(desired, runningActual)
+	}
+}
+
+func (fake *FakeRecorder) RecordSyncBatchCallCount() int {
+	fake.recordSyncBatchMutex.RLock()
+	defer fake.recordSyncBatchMutex.RUnlock()
+	return len(fake.recordSyncBatchArgsForCall)
+}
+
+func (fake *FakeRecorder) RecordSyncBatchArgsForCall(i int) ([]*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo) {
+	fake.recordSyncBatchMutex.RLock()
+	defer fake.recordSyncBatchMutex.RUnlock()
+	return fake.recordSyncBatchArgsForCall[i].desired, fake.recordSyncBatchArgsForCall[i].runningActual
+}
+
+func (fake *FakeRecorder) RecordSync(desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, err error) {
+	var desiredCopy []*models.DesiredLRPSchedulingInfo
+	if desired != nil {
+		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
+		copy(desiredCopy, desired)
+	}
+	var runningActualCopy []*endpoint.ActualLRPRoutingInfo
+	if runningActual != nil {
+		runningActualCopy = make([]*endpoint.ActualLRPRoutingInfo, len(runningActual))
+		copy(runningActualCopy, runningActual)
+	}
+	fake.recordSyncMutex.Lock()
+	fake.recordSyncArgsForCall = append(fake.recordSyncArgsForCall, struct {
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*endpoint.ActualLRPRoutingInfo
+		domains       models.DomainSet
+		err           error
+	}{desiredCopy, runningActualCopy, domains, err})
+	fake.recordInvocation("RecordSync", []interface{}{desiredCopy, runningActualCopy, domains, err})
+	fake.recordSyncMutex.Unlock()
+	if fake.RecordSyncStub != nil {
+		fake.RecordSyncStub(desired, runningActual, domains, err)
+	}
+}
+
+func (fake *FakeRecorder) RecordSyncCallCount() int {
+	fake.recordSyncMutex.RLock()
+	defer fake.recordSyncMutex.RUnlock()
+	return len(fake.recordSyncArgsForCall)
+}
+
+func (fake *FakeRecorder) RecordSyncArgsForCall(i int) ([]*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, error) {
+	fake.recordSyncMutex.RLock()
+	defer fake.recordSyncMutex.RUnlock()
+	return fake.recordSyncArgsForCall[i].desired, fake.recordSyncArgsForCall[i].runningActual, fake.recordSyncArgsForCall[i].domains, fake.recordSyncArgsForCall[i].err
+}
+
+func (fake *FakeRecorder) RecordDesiredRefresh(desired []*models.DesiredLRPSchedulingInfo, err error) {
+	var desiredCopy []*models.DesiredLRPSchedulingInfo
+	if desired != nil {
+		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
+		copy(desiredCopy, desired)
+	}
+	fake.recordDesiredRefreshMutex.Lock()
+	fake.recordDesiredRefreshArgsForCall = append(fake.recordDesiredRefreshArgsForCall, struct {
+		desired []*models.DesiredLRPSchedulingInfo
+		err     error
+	}{desiredCopy, err})
+	fake.recordInvocation("RecordDesiredRefresh", []interface{}{desiredCopy, err})
+	fake.recordDesiredRefreshMutex.Unlock()
+	if fake.RecordDesiredRefreshStub != nil {
+		fake.RecordDesiredRefreshStub(desired, err)
+	}
+}
+
+func (fake *FakeRecorder) RecordDesiredRefreshCallCount() int {
+	fake.recordDesiredRefreshMutex.RLock()
+	defer fake.recordDesiredRefreshMutex.RUnlock()
+	return len(fake.recordDesiredRefreshArgsForCall)
+}
+
+func (fake *FakeRecorder) RecordDesiredRefreshArgsForCall(i int) ([]*models.DesiredLRPSchedulingInfo, error) {
+	fake.recordDesiredRefreshMutex.RLock()
+	defer fake.recordDesiredRefreshMutex.RUnlock()
+	return fake.recordDesiredRefreshArgsForCall[i].desired, fake.recordDesiredRefreshArgsForCall[i].err
+}
+
+func (fake *FakeRecorder) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.recordEventMutex.RLock()
+	defer fake.recordEventMutex.RUnlock()
+	fake.recordSyncStartMutex.RLock()
+	defer fake.recordSyncStartMutex.RUnlock()
+	fake.recordSyncBatchMutex.RLock()
+	defer fake.recordSyncBatchMutex.RUnlock()
+	fake.recordSyncMutex.RLock()
+	defer fake.recordSyncMutex.RUnlock()
+	fake.recordDesiredRefreshMutex.RLock()
+	defer fake.recordDesiredRefreshMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeRecorder) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ watcher.Recorder = new(FakeRecorder)
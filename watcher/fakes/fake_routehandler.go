@@ -17,14 +17,14 @@ type FakeRouteHandler struct {
 		logger lager.Logger
 		event  models.Event
 	}
-	SyncStub        func(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, cachedEvents map[string]models.Event)
+	SyncStub        func(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, cachedEvents []models.Event)
 	syncMutex       sync.RWMutex
 	syncArgsForCall []struct {
 		logger        lager.Logger
 		desired       []*models.DesiredLRPSchedulingInfo
 		runningActual []*endpoint.ActualLRPRoutingInfo
 		domains       models.DomainSet
-		cachedEvents  map[string]models.Event
+		cachedEvents  []models.Event
 	}
 	EmitStub        func(logger lager.Logger)
 	emitMutex       sync.RWMutex
@@ -74,7 +74,7 @@ func (fake *FakeRouteHandler) HandleEventArgsForCall(i int) (lager.Logger, model
 	return fake.handleEventArgsForCall[i].logger, fake.handleEventArgsForCall[i].event
 }
 
-func (fake *FakeRouteHandler) Sync(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, cachedEvents map[string]models.Event) {
+func (fake *FakeRouteHandler) Sync(logger lager.Logger, desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo, domains models.DomainSet, cachedEvents []models.Event) {
 	var desiredCopy []*models.DesiredLRPSchedulingInfo
 	if desired != nil {
 		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
@@ -85,15 +85,20 @@ func (fake *FakeRouteHandler) Sync(logger lager.Logger, desired []*models.Desire
 		runningActualCopy = make([]*endpoint.ActualLRPRoutingInfo, len(runningActual))
 		copy(runningActualCopy, runningActual)
 	}
+	var cachedEventsCopy []models.Event
+	if cachedEvents != nil {
+		cachedEventsCopy = make([]models.Event, len(cachedEvents))
+		copy(cachedEventsCopy, cachedEvents)
+	}
 	fake.syncMutex.Lock()
 	fake.syncArgsForCall = append(fake.syncArgsForCall, struct {
 		logger        lager.Logger
 		desired       []*models.DesiredLRPSchedulingInfo
 		runningActual []*endpoint.ActualLRPRoutingInfo
 		domains       models.DomainSet
-		cachedEvents  map[string]models.Event
-	}{logger, desiredCopy, runningActualCopy, domains, cachedEvents})
-	fake.recordInvocation("Sync", []interface{}{logger, desiredCopy, runningActualCopy, domains, cachedEvents})
+		cachedEvents  []models.Event
+	}{logger, desiredCopy, runningActualCopy, domains, cachedEventsCopy})
+	fake.recordInvocation("Sync", []interface{}{logger, desiredCopy, runningActualCopy, domains, cachedEventsCopy})
 	fake.syncMutex.Unlock()
 	if fake.SyncStub != nil {
 		fake.SyncStub(logger, desired, runningActual, domains, cachedEvents)
@@ -106,7 +111,7 @@ func (fake *FakeRouteHandler) SyncCallCount() int {
 	return len(fake.syncArgsForCall)
 }
 
-func (fake *FakeRouteHandler) SyncArgsForCall(i int) (lager.Logger, []*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, map[string]models.Event) {
+func (fake *FakeRouteHandler) SyncArgsForCall(i int) (lager.Logger, []*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, []models.Event) {
 	fake.syncMutex.RLock()
 	defer fake.syncMutex.RUnlock()
 	return fake.syncArgsForCall[i].logger, fake.syncArgsForCall[i].desired, fake.syncArgsForCall[i].runningActual, fake.syncArgsForCall[i].domains, fake.syncArgsForCall[i].cachedEvents
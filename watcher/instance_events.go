@@ -0,0 +1,191 @@
+package watcher
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+// EventFamily selects which of the two families of actual LRP events and
+// endpoints the watcher reads from the BBS.
+type EventFamily string
+
+const (
+	// GroupEvents are the ActualLRPCreated/Changed/Removed events, which carry
+	// actual LRP groups.
+	GroupEvents EventFamily = "group"
+	// InstanceEvents are the ActualLRPInstanceCreated/Changed/Removed events,
+	// which carry one actual LRP instance each along with its presence.
+	InstanceEvents EventFamily = "instance"
+	// NegotiateEvents subscribes to instance events and falls back to group
+	// events on BBSes that do not serve them. It negotiates again on every
+	// subscription, so an upgraded BBS is picked up on the next resubscribe.
+	NegotiateEvents EventFamily = "negotiate"
+)
+
+// SuspectPolicy decides whether suspect actual LRPs, which run on cells the
+// BBS has lost contact with, are routed.
+type SuspectPolicy string
+
+const (
+	// RouteSuspect keeps routing suspect instances, as they are most likely
+	// still serving requests. This is what group events do, as the suspect
+	// instance is the one a group resolves to until its replacement runs.
+	RouteSuspect SuspectPolicy = "route"
+	// UnrouteSuspect stops routing instances as soon as they become suspect.
+	UnrouteSuspect SuspectPolicy = "unroute"
+)
+
+// ActualLRPEvents configures how the watcher reads actual LRPs from the BBS.
+// The zero value reads group events and routes suspect instances.
+type ActualLRPEvents struct {
+	Family        EventFamily
+	SuspectPolicy SuspectPolicy
+}
+
+// Validate returns an error for an unknown event family or suspect policy.
+func (e ActualLRPEvents) Validate() error {
+	switch e.Family {
+	case "", GroupEvents, InstanceEvents, NegotiateEvents:
+	default:
+		return fmt.Errorf("unknown actual LRP event family: %q", e.Family)
+	}
+
+	switch e.SuspectPolicy {
+	case "", RouteSuspect, UnrouteSuspect:
+	default:
+		return fmt.Errorf("unknown suspect actual LRP routing policy: %q", e.SuspectPolicy)
+	}
+	return nil
+}
+
+// subscribeToEvents subscribes to the event family the watcher is configured
+// for and records which family the subscription delivers, so that syncs read
+// the same family.
+func (w *Watcher) subscribeToEvents(logger lager.Logger) (events.EventSource, error) {
+	switch w.lrpEvents.Family {
+	case InstanceEvents:
+		return w.bbsClient.SubscribeToInstanceEvents(logger)
+	case NegotiateEvents:
+		es, err := w.bbsClient.SubscribeToInstanceEvents(logger)
+		if err == nil {
+			w.setInstanceEvents(true)
+			return es, nil
+		}
+		logger.Info("instance-events-unavailable", lager.Data{"error": err.Error()})
+
+		es, err = w.bbsClient.SubscribeToEvents(logger)
+		if err == nil {
+			w.setInstanceEvents(false)
+		}
+		return es, err
+	default:
+		return w.bbsClient.SubscribeToEvents(logger)
+	}
+}
+
+// translateEvent turns instance events into the equivalent group events, so
+// that route handlers and recordings only ever deal with one event family.
+// It applies the suspect policy on the way, and returns nil for events that
+// are of no consequence under it.
+func (w *Watcher) translateEvent(event models.Event) models.Event {
+	switch e := event.(type) {
+	case *models.ActualLRPInstanceCreatedEvent:
+		event = models.NewActualLRPCreatedEvent(instanceGroup(e.ActualLrp))
+	case *models.ActualLRPInstanceChangedEvent:
+		before, after := instanceChangeLRPs(e)
+		event = models.NewActualLRPChangedEvent(instanceGroup(before), instanceGroup(after))
+	case *models.ActualLRPInstanceRemovedEvent:
+		event = models.NewActualLRPRemovedEvent(instanceGroup(e.ActualLrp))
+	}
+
+	if w.lrpEvents.SuspectPolicy != UnrouteSuspect {
+		return event
+	}
+
+	switch e := event.(type) {
+	case *models.ActualLRPCreatedEvent:
+		if isSuspect(e.ActualLrpGroup) {
+			return nil
+		}
+	case *models.ActualLRPChangedEvent:
+		switch {
+		case isSuspect(e.Before) && isSuspect(e.After):
+			return nil
+		case isSuspect(e.After):
+			return models.NewActualLRPRemovedEvent(e.Before)
+		case isSuspect(e.Before):
+			return models.NewActualLRPCreatedEvent(e.After)
+		}
+	case *models.ActualLRPRemovedEvent:
+		if isSuspect(e.ActualLrpGroup) {
+			return nil
+		}
+	}
+	return event
+}
+
+// runningActualLRPInstanceRoutingInfos is endpoint.RunningActualLRPRoutingInfos
+// for actual LRP instances.
+func runningActualLRPInstanceRoutingInfos(actualLRPs []*models.ActualLRP) []*endpoint.ActualLRPRoutingInfo {
+	runningActualLRPs := make([]*endpoint.ActualLRPRoutingInfo, 0, len(actualLRPs))
+	for _, actualLRP := range actualLRPs {
+		if actualLRP.State != models.ActualLRPStateRunning {
+			continue
+		}
+		runningActualLRPs = append(runningActualLRPs, endpoint.NewActualLRPRoutingInfoFromInstance(actualLRP))
+	}
+	return runningActualLRPs
+}
+
+// routableActualLRPs applies the suspect policy to the routing infos a sync
+// read, whichever event family they were read from. Under the unroute policy,
+// suspect instances are left out, as the events for them are dropped.
+func (w *Watcher) routableActualLRPs(routingInfos []*endpoint.ActualLRPRoutingInfo) []*endpoint.ActualLRPRoutingInfo {
+	if w.lrpEvents.SuspectPolicy != UnrouteSuspect {
+		return routingInfos
+	}
+
+	routable := make([]*endpoint.ActualLRPRoutingInfo, 0, len(routingInfos))
+	for _, routingInfo := range routingInfos {
+		if !routingInfo.Suspect {
+			routable = append(routable, routingInfo)
+		}
+	}
+	return routable
+}
+
+func isSuspect(group *models.ActualLRPGroup) bool {
+	return endpoint.NewActualLRPRoutingInfo(group).Suspect
+}
+
+// instanceGroup returns a group holding just the given instance, which
+// resolves to the instance and to whether it is evacuating.
+func instanceGroup(lrp *models.ActualLRP) *models.ActualLRPGroup {
+	if lrp.Presence == models.ActualLRP_Evacuating {
+		return &models.ActualLRPGroup{Evacuating: lrp}
+	}
+	return &models.ActualLRPGroup{Instance: lrp}
+}
+
+func instanceChangeLRPs(event *models.ActualLRPInstanceChangedEvent) (before, after *models.ActualLRP) {
+	return instanceLRP(event, event.Before), instanceLRP(event, event.After)
+}
+
+func instanceLRP(event *models.ActualLRPInstanceChangedEvent, info *models.ActualLRPInfo) *models.ActualLRP {
+	return &models.ActualLRP{
+		ActualLRPKey:         event.ActualLRPKey,
+		ActualLRPInstanceKey: event.ActualLRPInstanceKey,
+		ActualLRPNetInfo:     info.ActualLRPNetInfo,
+		CrashCount:           info.CrashCount,
+		CrashReason:          info.CrashReason,
+		State:                info.State,
+		PlacementError:       info.PlacementError,
+		Since:                info.Since,
+		ModificationTag:      info.ModificationTag,
+		Presence:             info.Presence,
+	}
+}
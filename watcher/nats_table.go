@@ -0,0 +1,45 @@
+package watcher
+
+import (
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+)
+
+// FetchNATSTable builds the HTTP routing table a sync would build from the
+// BBS, out of the LRPs running on the cell when cellID is set.
+func FetchNATSTable(logger lager.Logger, bbsClient bbs.Client, cellID string) (routingtable.NATSRoutingTable, error) {
+	actualLRPGroups, err := bbsClient.ActualLRPGroups(logger, models.ActualLRPFilter{CellID: cellID})
+	if err != nil {
+		return nil, err
+	}
+	runningActuals := endpoint.RunningActualLRPRoutingInfos(actualLRPGroups)
+
+	// on a cell, only the LRPs running on it are routed
+	var desired []*models.DesiredLRPSchedulingInfo
+	if cellID == "" || len(runningActuals) > 0 {
+		var guids []string
+		if cellID != "" {
+			for _, actual := range runningActuals {
+				guids = append(guids, actual.ActualLRP.ProcessGuid)
+			}
+		}
+
+		desired, err = bbsClient.DesiredLRPSchedulingInfos(logger, models.DesiredLRPFilter{ProcessGuids: guids})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	schedulingInfos := make(map[string]*models.DesiredLRPSchedulingInfo, len(desired))
+	for _, schedulingInfo := range desired {
+		schedulingInfos[schedulingInfo.ProcessGuid] = schedulingInfo
+	}
+
+	return routingtable.NewTempTable(
+		routingtable.RoutesByRoutingKeyFromSchedulingInfos(desired),
+		routingtable.EndpointsByRoutingKeyFromActuals(runningActuals, schedulingInfos),
+	), nil
+}
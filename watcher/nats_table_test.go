@@ -0,0 +1,95 @@
+package watcher_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FetchNATSTable", func() {
+	var (
+		logger    *lagertest.TestLogger
+		bbsClient *fake_bbs.FakeClient
+	)
+
+	schedulingInfo := &models.DesiredLRPSchedulingInfo{
+		DesiredLRPKey: models.NewDesiredLRPKey("pg-1", "tests", "lg-1"),
+		Routes: cfroutes.CFRoutes{
+			cfroutes.CFRoute{Hostnames: []string{"foo.example.com"}, Port: 8080},
+		}.RoutingInfo(),
+		Instances: 2,
+	}
+
+	actualLRPGroup := func(index int32, instanceGuid, host string, state string) *models.ActualLRPGroup {
+		return &models.ActualLRPGroup{
+			Instance: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey("pg-1", index, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo(host, "container-ip", models.NewPortMapping(61000, 8080)),
+				State:                state,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		bbsClient = new(fake_bbs.FakeClient)
+		bbsClient.DesiredLRPSchedulingInfosReturns([]*models.DesiredLRPSchedulingInfo{schedulingInfo}, nil)
+		bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{
+			actualLRPGroup(0, "ig-1", "1.1.1.1", models.ActualLRPStateRunning),
+			actualLRPGroup(1, "ig-2", "2.2.2.2", models.ActualLRPStateClaimed),
+		}, nil)
+	})
+
+	It("routes the running instances of the desired LRPs", func() {
+		table, err := watcher.FetchNATSTable(logger, bbsClient, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		entries := table.Snapshot()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Key).To(Equal(endpoint.RoutingKey{ProcessGUID: "pg-1", ContainerPort: 8080}))
+		Expect(entries[0].Routes).To(ConsistOf(routingtable.Route{Hostname: "foo.example.com", LogGuid: "lg-1"}))
+		Expect(entries[0].Endpoints).To(HaveLen(1))
+		Expect(entries[0].Endpoints[0].InstanceGuid).To(Equal("ig-1"))
+
+		_, filter := bbsClient.DesiredLRPSchedulingInfosArgsForCall(0)
+		Expect(filter.ProcessGuids).To(BeEmpty())
+	})
+
+	Context("when fetching from a cell", func() {
+		It("only fetches the desired LRPs running on the cell", func() {
+			_, err := watcher.FetchNATSTable(logger, bbsClient, "cell-id")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, actualFilter := bbsClient.ActualLRPGroupsArgsForCall(0)
+			Expect(actualFilter.CellID).To(Equal("cell-id"))
+			_, desiredFilter := bbsClient.DesiredLRPSchedulingInfosArgsForCall(0)
+			Expect(desiredFilter.ProcessGuids).To(Equal([]string{"pg-1"}))
+		})
+
+		It("does not fetch any desired LRP when nothing runs on the cell", func() {
+			bbsClient.ActualLRPGroupsReturns(nil, nil)
+
+			table, err := watcher.FetchNATSTable(logger, bbsClient, "cell-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(table.Snapshot()).To(BeEmpty())
+			Expect(bbsClient.DesiredLRPSchedulingInfosCallCount()).To(BeZero())
+		})
+	})
+
+	It("fails when the BBS does", func() {
+		bbsClient.ActualLRPGroupsReturns(nil, errors.New("boom"))
+
+		_, err := watcher.FetchNATSTable(logger, bbsClient, "")
+		Expect(err).To(MatchError("boom"))
+	})
+})
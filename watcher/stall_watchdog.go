@@ -0,0 +1,35 @@
+package watcher
+
+import (
+	"time"
+)
+
+// stallWatchdog tracks the activity on the BBS event stream. The stream has
+// no heartbeat, so a connection that hangs without erroring looks just like
+// a quiet deployment. Once nothing has been received for longer than the
+// timeout, the watcher probes the BBS with a cheap request; if the BBS
+// answers, the stream is judged dead and is replaced.
+type stallWatchdog struct {
+	timeout     time.Duration
+	lastEventAt time.Time
+	lastSyncAt  time.Time
+	probing     bool
+}
+
+func newStallWatchdog(timeout time.Duration, now time.Time) *stallWatchdog {
+	return &stallWatchdog{
+		timeout:     timeout,
+		lastEventAt: now,
+		lastSyncAt:  now,
+	}
+}
+
+func (w *stallWatchdog) stalled(now time.Time) bool {
+	return now.Sub(w.lastEventAt) >= w.timeout
+}
+
+// shouldProbe returns true if the stream looks stalled and no probe is in
+// flight yet.
+func (w *stallWatchdog) shouldProbe(now time.Time) bool {
+	return !w.probing && w.stalled(now)
+}
@@ -3,6 +3,7 @@ package watcher
 import (
 	"fmt"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/cellwatcher"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/routingtable/util"
 	"code.cloudfoundry.org/route-emitter/syncer"
@@ -20,6 +22,19 @@ import (
 
 var (
 	routeSyncDuration = metric.Duration("RouteEmitterSyncDuration")
+	eventWaitDuration = metric.Duration("RouteEmitterEventWaitDuration")
+	cachedEventsCount = metric.Metric("RouteEmitterCachedEventsCount")
+	droppedEvents     = metric.Counter("RouteEmitterCachedEventsDropped")
+	eventStreamStalls = metric.Counter("RouteEmitterEventStreamStalls")
+
+	eventHandlingDurations = map[string]metric.Duration{
+		models.EventTypeDesiredLRPCreated: metric.Duration("RouteEmitterDesiredLRPCreatedHandlingDuration"),
+		models.EventTypeDesiredLRPChanged: metric.Duration("RouteEmitterDesiredLRPChangedHandlingDuration"),
+		models.EventTypeDesiredLRPRemoved: metric.Duration("RouteEmitterDesiredLRPRemovedHandlingDuration"),
+		models.EventTypeActualLRPCreated:  metric.Duration("RouteEmitterActualLRPCreatedHandlingDuration"),
+		models.EventTypeActualLRPChanged:  metric.Duration("RouteEmitterActualLRPChangedHandlingDuration"),
+		models.EventTypeActualLRPRemoved:  metric.Duration("RouteEmitterActualLRPRemovedHandlingDuration"),
+	}
 )
 
 //go:generate counterfeiter -o fakes/fake_routehandler.go . RouteHandler
@@ -30,20 +45,121 @@ type RouteHandler interface {
 		desired []*models.DesiredLRPSchedulingInfo,
 		runningActual []*endpoint.ActualLRPRoutingInfo,
 		domains models.DomainSet,
-		cachedEvents map[string]models.Event,
+		cachedEvents []models.Event,
 	)
 	Emit(logger lager.Logger)
 	ShouldRefreshDesired(*endpoint.ActualLRPRoutingInfo) bool
 	RefreshDesired(lager.Logger, []*models.DesiredLRPSchedulingInfo)
 }
 
+//go:generate counterfeiter -o fakes/fake_batchroutehandler.go . BatchRouteHandler
+
+// BatchRouteHandler is implemented by route handlers that can build their
+// sync table incrementally. The watcher uses it instead of Sync when a sync
+// batch size is configured, handing over the BBS state one batch of process
+// guids at a time so that it never has to be held in memory all at once.
+type BatchRouteHandler interface {
+	RouteHandler
+	SyncBatch(
+		logger lager.Logger,
+		desired []*models.DesiredLRPSchedulingInfo,
+		runningActual []*endpoint.ActualLRPRoutingInfo,
+	)
+	CompleteBatchSync(
+		logger lager.Logger,
+		domains models.DomainSet,
+		cachedEvents []models.Event,
+	)
+	AbortBatchSync(logger lager.Logger)
+}
+
+// batchSyncChecker is implemented by batch route handlers that can only sync
+// in batches when the handlers they hand the batches to can, such as the
+// MultiHandler. The watcher syncs them all at once otherwise.
+type batchSyncChecker interface {
+	CanSyncInBatches() bool
+}
+
+// HostSuppressor is implemented by route handlers that can stop routing to
+// every instance on a host at once. The watcher suppresses the hosts of the
+// instances on a cell that leaves the BBS cell registry, and lifts the
+// suppression once the cell is back, the instances are replaced, or a sync
+// completes.
+type HostSuppressor interface {
+	SuppressHost(logger lager.Logger, host string)
+	UnsuppressHost(logger lager.Logger, host string)
+}
+
+// SyncRequester is implemented by route handlers that can miss calls, such as
+// the MultiHandler when a sub handler falls behind. The watcher starts a sync
+// when one is requested and none is in progress, as a sync repairs whatever
+// the handler missed.
+type SyncRequester interface {
+	SyncRequests() <-chan struct{}
+}
+
+// EventHandlingTimer is implemented by route handlers that hand events over to
+// other goroutines, such as the MultiHandler. Timing HandleEvent would only
+// time the hand-over, so they send the event handling durations themselves,
+// with SendEventHandlingDuration, and the watcher does not.
+type EventHandlingTimer interface {
+	TimesEventHandling()
+}
+
+//go:generate counterfeiter -o fakes/fake_recorder.go . Recorder
+
+// Recorder is told about everything the watcher reads from the BBS: every
+// event received on the event stream, the start and the result of every
+// sync, and every desired LRP refresh. A recording is enough to replay the
+// exact sequence of calls the watcher made into its route handler.
+type Recorder interface {
+	RecordEvent(event models.Event)
+	RecordSyncStart()
+	RecordSyncBatch(desired []*models.DesiredLRPSchedulingInfo, runningActual []*endpoint.ActualLRPRoutingInfo)
+	RecordSync(
+		desired []*models.DesiredLRPSchedulingInfo,
+		runningActual []*endpoint.ActualLRPRoutingInfo,
+		domains models.DomainSet,
+		err error,
+	)
+	RecordDesiredRefresh(desired []*models.DesiredLRPSchedulingInfo, err error)
+}
+
+type noopRecorder struct{}
+
+func (noopRecorder) RecordEvent(models.Event) {}
+
+func (noopRecorder) RecordSyncStart() {}
+
+func (noopRecorder) RecordSyncBatch([]*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo) {
+}
+
+func (noopRecorder) RecordSync([]*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, error) {
+}
+
+func (noopRecorder) RecordDesiredRefresh([]*models.DesiredLRPSchedulingInfo, error) {}
+
 type Watcher struct {
-	cellID       string
-	bbsClient    bbs.Client
-	clock        clock.Clock
-	routeHandler RouteHandler
-	syncEvents   syncer.Events
-	logger       lager.Logger
+	cellID          string
+	bbsClient       bbs.Client
+	clock           clock.Clock
+	routeHandler    RouteHandler
+	syncEvents      syncer.Events
+	cellChanges     <-chan cellwatcher.Change
+	syncBatchSize   int
+	maxCachedEvents int
+	stallTimeout    time.Duration
+	lrpEvents       ActualLRPEvents
+	recorder        Recorder
+	logger          lager.Logger
+
+	// suppressedCells is only used by the goroutine running Run
+	suppressedCells map[string]*suppressedCell
+
+	// instanceEvents is 1 while the watcher reads actual LRP instances rather
+	// than actual LRP groups. It only changes when the event family is
+	// negotiated.
+	instanceEvents int32
 }
 
 func NewWatcher(
@@ -52,16 +168,50 @@ func NewWatcher(
 	clock clock.Clock,
 	routeHandler RouteHandler,
 	syncEvents syncer.Events,
+	cellChanges <-chan cellwatcher.Change,
+	syncBatchSize int,
+	maxCachedEvents int,
+	stallTimeout time.Duration,
+	lrpEvents ActualLRPEvents,
+	recorder Recorder,
 	logger lager.Logger,
 ) *Watcher {
-	return &Watcher{
-		cellID:       cellID,
-		bbsClient:    bbsClient,
-		clock:        clock,
-		routeHandler: routeHandler,
-		syncEvents:   syncEvents,
-		logger:       logger.Session("watcher"),
+	if recorder == nil {
+		recorder = noopRecorder{}
+	}
+	if maxCachedEvents <= 0 {
+		maxCachedEvents = DefaultMaxCachedEvents
+	}
+
+	w := &Watcher{
+		cellID:          cellID,
+		bbsClient:       bbsClient,
+		clock:           clock,
+		routeHandler:    routeHandler,
+		syncEvents:      syncEvents,
+		cellChanges:     cellChanges,
+		syncBatchSize:   syncBatchSize,
+		maxCachedEvents: maxCachedEvents,
+		stallTimeout:    stallTimeout,
+		lrpEvents:       lrpEvents,
+		recorder:        recorder,
+		logger:          logger.Session("watcher"),
+		suppressedCells: map[string]*suppressedCell{},
+	}
+	w.setInstanceEvents(lrpEvents.Family == InstanceEvents)
+	return w
+}
+
+func (w *Watcher) setInstanceEvents(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
 	}
+	atomic.StoreInt32(&w.instanceEvents, value)
+}
+
+func (w *Watcher) usesInstanceEvents() bool {
+	return atomic.LoadInt32(&w.instanceEvents) == 1
 }
 
 type syncEventResult struct {
@@ -69,102 +219,261 @@ type syncEventResult struct {
 	desired       []*models.DesiredLRPSchedulingInfo
 	runningActual []*endpoint.ActualLRPRoutingInfo
 	domains       models.DomainSet
+	batched       bool
 	err           error
 }
 
+// receivedEvent is an event together with the time it was read off the BBS
+// event stream.
+type receivedEvent struct {
+	event      models.Event
+	receivedAt time.Time
+}
+
+type syncBatch struct {
+	desired       []*models.DesiredLRPSchedulingInfo
+	runningActual []*endpoint.ActualLRPRoutingInfo
+}
+
 func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	watcher.logger.Debug("starting", lager.Data{"cell-id": watcher.cellID})
 	defer watcher.logger.Debug("finished")
 
-	eventChan := make(chan models.Event)
+	eventChan := make(chan receivedEvent)
 	resubscribeChannel := make(chan error)
 
 	eventSource := &atomic.Value{}
 	var stopEventSource int32
 
-	go checkForEvents(watcher.bbsClient, resubscribeChannel,
-		eventChan, eventSource, watcher.logger)
+	go checkForEvents(watcher.subscribeToEvents, watcher.translateEvent, resubscribeChannel,
+		eventChan, eventSource, watcher.clock, watcher.logger)
 	watcher.logger.Debug("listening-on-channels")
 	close(ready)
 	watcher.logger.Debug("started")
 
-	cachedEvents := make(map[string]models.Event)
+	// closed when Run returns, for a sync in progress to stop sending to it
+	done := make(chan struct{})
+	defer close(done)
+
+	cachedEvents := newEventLog(watcher.maxCachedEvents)
 	syncEnd := make(chan *syncEventResult)
+	syncBatches := make(chan *syncBatch)
 	syncing := false
 
+	watchdog := newStallWatchdog(watcher.stallTimeout, watcher.clock.Now())
+	probeResults := make(chan error, 1)
+	var watchdogTicks <-chan time.Time
+	if watcher.stallTimeout > 0 {
+		ticker := watcher.clock.NewTicker(watcher.stallTimeout / 2)
+		defer ticker.Stop()
+		watchdogTicks = ticker.C()
+	}
+
+	var syncRequests <-chan struct{}
+	if requester, ok := watcher.routeHandler.(SyncRequester); ok {
+		syncRequests = requester.SyncRequests()
+	}
+
+	startSync := func() {
+		logger := watcher.logger.Session("sync")
+		logger.Debug("starting")
+		watcher.recorder.RecordSyncStart()
+		watcher.markCellsForLiftOnSync()
+		if watcher.batchRouteHandler() != nil {
+			go watcher.syncInBatches(logger, done, syncBatches, syncEnd)
+		} else {
+			go watcher.sync(logger, done, syncEnd)
+		}
+		syncing = true
+	}
+
 	for {
 		select {
-		case event := <-eventChan:
+		case received := <-eventChan:
+			event := received.event
+			watchdog.lastEventAt = received.receivedAt
+			// recorded here rather than as it is read, so that the recording
+			// has the events and the syncs in the order they were processed
+			watcher.recorder.RecordEvent(event)
 			if syncing {
 				if watcher.eventCellIDMatches(watcher.logger, event) {
 					watcher.logger.Info("caching-event", lager.Data{
 						"type": event.EventType(),
 					})
-					cachedEvents[event.Key()] = event
+					if !cachedEvents.append(event, received.receivedAt) {
+						watcher.logger.Info("dropping-event-cache-full", lager.Data{
+							"type":              event.EventType(),
+							"max-cached-events": watcher.maxCachedEvents,
+						})
+						droppedEvents.Increment()
+						continue
+					}
+					watcher.sendCachedEventsCount(len(cachedEvents.events))
 				} else {
 					logSkippedEvent(watcher.logger, event)
 				}
 				continue
 			}
-			logger := watcher.logger.Session("handling-event")
-			watcher.handleEvent(logger, event)
+			watcher.processEvent(event, received.receivedAt)
+		case change := <-watcher.cellChanges:
+			// the endpoints on a recovered cell are only registered again by a
+			// sync, as the events for its instances were dropped while it was
+			// suppressed
+			if watcher.handleCellChange(change) && !syncing {
+				watcher.logger.Info("syncing-after-cell-recovered", lager.Data{"cell-id": change.CellID})
+				startSync()
+			}
 		case <-watcher.syncEvents.Emit:
 			logger := watcher.logger.Session("emit")
 			watcher.routeHandler.Emit(logger)
+		case batch := <-syncBatches:
+			logger := watcher.logger.Session("sync")
+			logger.Debug("calling-handler-sync-batch", lager.Data{
+				"num-desired": len(batch.desired),
+				"num-actuals": len(batch.runningActual),
+			})
+			watcher.recorder.RecordSyncBatch(batch.desired, batch.runningActual)
+			watcher.batchRouteHandler().SyncBatch(logger, batch.desired, batch.runningActual)
 		case syncEvent := <-syncEnd:
 			syncing = false
 			logger := watcher.logger.Session("sync")
+			watcher.recorder.RecordSync(syncEvent.desired, syncEvent.runningActual, syncEvent.domains, syncEvent.err)
+
+			if syncEvent.err != nil {
+				logger.Error("failed-to-sync-events", syncEvent.err)
+				if syncEvent.batched {
+					watcher.batchRouteHandler().AbortBatchSync(logger)
+				}
+
+				// the table is left as it was before the sync, so the cached
+				// events are handled in order as if they were just received
+				for i, e := range cachedEvents.events {
+					watcher.processEvent(e, cachedEvents.receivedAt[i])
+				}
+				cachedEvents.reset()
+				watcher.sendCachedEventsCount(0)
+
+				if cachedEvents.overflowed {
+					cachedEvents.clearOverflow()
+					logger.Info("forcing-sync-after-dropping-events")
+					startSync()
+				}
+				continue
+			}
+
+			watcher.liftCellsOnSync(logger)
+
 			var cachedDesired []*models.DesiredLRPSchedulingInfo
-			for _, e := range cachedEvents {
+			for _, e := range cachedEvents.events {
 				desired := watcher.retrieveDesired(logger, e)
 				if len(desired) > 0 {
 					cachedDesired = append(cachedDesired, desired...)
 				}
 			}
 
-			if syncEvent.err != nil {
-				logger.Error("failed-to-sync-events", syncEvent.err)
-				continue
-			}
+			if syncEvent.batched {
+				batchHandler := watcher.batchRouteHandler()
+				if len(cachedDesired) > 0 {
+					batchHandler.SyncBatch(logger, cachedDesired, nil)
+				}
 
-			if len(cachedDesired) > 0 {
-				syncEvent.desired = append(syncEvent.desired, cachedDesired...)
-			}
+				logger.Debug("calling-handler-complete-batch-sync")
+				batchHandler.CompleteBatchSync(logger, syncEvent.domains, cachedEvents.events)
+			} else {
+				if len(cachedDesired) > 0 {
+					syncEvent.desired = append(syncEvent.desired, cachedDesired...)
+				}
 
-			logger.Debug("calling-handler-sync")
-			watcher.routeHandler.Sync(logger,
-				syncEvent.desired,
-				syncEvent.runningActual,
-				syncEvent.domains,
-				cachedEvents,
-			)
+				logger.Debug("calling-handler-sync")
+				watcher.routeHandler.Sync(logger,
+					syncEvent.desired,
+					syncEvent.runningActual,
+					syncEvent.domains,
+					cachedEvents.events,
+				)
+			}
 
 			after := watcher.clock.Now()
 			if err := routeSyncDuration.Send(after.Sub(syncEvent.startTime)); err != nil {
 				watcher.logger.Error("failed-to-send-route-sync-duration-metric", err)
 			}
 
-			cachedEvents = make(map[string]models.Event)
+			for _, receivedAt := range cachedEvents.receivedAt {
+				watcher.sendEventWaitDuration(receivedAt)
+			}
+
+			watchdog.lastSyncAt = after
+			cachedEvents.reset()
+			watcher.sendCachedEventsCount(0)
 			logger.Debug("complete")
+
+			// events were dropped while syncing, so the routes are only brought
+			// up to date by another sync
+			if cachedEvents.overflowed {
+				cachedEvents.clearOverflow()
+				logger.Info("forcing-sync-after-dropping-events")
+				startSync()
+			}
 		case <-watcher.syncEvents.Sync:
 			if syncing {
 				watcher.logger.Debug("sync-already-in-progress")
 				continue
 			}
-			logger := watcher.logger.Session("sync")
-			logger.Debug("starting")
-			go watcher.sync(logger, syncEnd)
-			syncing = true
+			startSync()
+		case <-syncRequests:
+			if syncing {
+				watcher.logger.Debug("sync-already-in-progress")
+				continue
+			}
+			watcher.logger.Info("syncing-on-handler-request")
+			startSync()
+		case <-watchdogTicks:
+			if !watchdog.shouldProbe(watcher.clock.Now()) {
+				continue
+			}
+			watchdog.probing = true
+			go watcher.probeBBS(probeResults)
+		case err := <-probeResults:
+			watchdog.probing = false
+			logger := watcher.logger.Session("stall-watchdog")
+			if err != nil {
+				logger.Error("failed-to-probe-bbs", err)
+				continue
+			}
+
+			now := watcher.clock.Now()
+			if !watchdog.stalled(now) {
+				continue
+			}
+
+			logger.Info("event-stream-stalled", lager.Data{
+				"since-last-event": now.Sub(watchdog.lastEventAt).String(),
+				"since-last-sync":  now.Sub(watchdog.lastSyncAt).String(),
+			})
+			eventStreamStalls.Increment()
+			watchdog.lastEventAt = now
+
+			// closing the event source makes checkForEvents resubscribe
+			if es := eventSource.Load(); es != nil {
+				err := es.(events.EventSource).Close()
+				if err != nil {
+					logger.Error("failed-closing-event-source", err)
+				}
+			}
+			if !syncing {
+				startSync()
+			}
 		case err := <-resubscribeChannel:
 			watcher.logger.Error("event-source-error", err)
+			watchdog.lastEventAt = watcher.clock.Now()
 			if es := eventSource.Load(); es != nil {
 				err := es.(events.EventSource).Close()
 				if err != nil {
 					watcher.logger.Error("failed-closing-event-source", err)
 				}
 			}
-			go checkForEvents(watcher.bbsClient, resubscribeChannel,
-				eventChan, eventSource, watcher.logger)
+			go checkForEvents(watcher.subscribeToEvents, watcher.translateEvent, resubscribeChannel,
+				eventChan, eventSource, watcher.clock, watcher.logger)
 
 		case <-signals:
 			watcher.logger.Info("stopping")
@@ -180,24 +489,66 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 	}
 }
 
-func (w *Watcher) cacheIncomingEvents(
-	eventChan chan models.Event,
-	cachedEventsChan chan map[string]models.Event,
-	done chan struct{},
-) {
-	cachedEvents := make(map[string]models.Event)
-	for {
-		select {
-		case event := <-eventChan:
-			w.logger.Info("caching-event", lager.Data{
-				"type": event.EventType(),
-			})
-			cachedEvents[event.Key()] = event
-		case <-done:
-			cachedEventsChan <- cachedEvents
-			return
-		}
+// probeBBS checks that the BBS is reachable with a cheap request, so that the
+// event stream is only replaced when there is a BBS to resubscribe to.
+func (w *Watcher) probeBBS(results chan<- error) {
+	_, err := w.bbsClient.Domains(w.logger.Session("probe-bbs"))
+	results <- err
+}
+
+// processEvent hands an event received while no sync is in progress to the
+// route handler.
+func (w *Watcher) processEvent(event models.Event, receivedAt time.Time) {
+	w.sendEventWaitDuration(receivedAt)
+	logger := w.logger.Session("handling-event")
+	start := w.clock.Now()
+	w.handleEvent(logger, event)
+	if _, ok := w.routeHandler.(EventHandlingTimer); !ok {
+		SendEventHandlingDuration(w.logger, event, w.clock.Since(start))
+	}
+	w.liftReplacedCells(logger, event)
+}
+
+func (w *Watcher) sendEventWaitDuration(receivedAt time.Time) {
+	if err := eventWaitDuration.Send(w.clock.Since(receivedAt)); err != nil {
+		w.logger.Error("failed-to-send-event-wait-duration-metric", err)
+	}
+}
+
+// SendEventHandlingDuration sends the handling duration metric of the type of
+// the event.
+func SendEventHandlingDuration(logger lager.Logger, event models.Event, duration time.Duration) {
+	handlingDuration, ok := eventHandlingDurations[event.EventType()]
+	if !ok {
+		return
+	}
+	if err := handlingDuration.Send(duration); err != nil {
+		logger.Error("failed-to-send-event-handling-duration-metric", err, lager.Data{"type": event.EventType()})
+	}
+}
+
+func (w *Watcher) sendCachedEventsCount(count int) {
+	if err := cachedEventsCount.Send(count); err != nil {
+		w.logger.Error("failed-to-send-cached-events-count-metric", err)
+	}
+}
+
+// batchRouteHandler returns the route handler as a BatchRouteHandler if
+// batched syncing is enabled and supported, and nil otherwise. Batching only
+// applies in global mode; in local mode the sync is already bounded by the
+// number of instances on the cell.
+func (w *Watcher) batchRouteHandler() BatchRouteHandler {
+	if w.syncBatchSize <= 0 || w.cellID != "" {
+		return nil
 	}
+	batchHandler, ok := w.routeHandler.(BatchRouteHandler)
+	if !ok {
+		return nil
+	}
+	if checker, ok := batchHandler.(batchSyncChecker); ok && !checker.CanSyncInBatches() {
+		return nil
+	}
+	return batchHandler
 }
 
 func (w *Watcher) retrieveDesired(logger lager.Logger, event models.Event) []*models.DesiredLRPSchedulingInfo {
@@ -220,6 +571,7 @@ func (w *Watcher) retrieveDesired(logger lager.Logger, event models.Event) []*mo
 			if err != nil {
 				logger.Error("failed-getting-desired-lrps-for-missing-actual-lrp", err)
 			}
+			w.recorder.RecordDesiredRefresh(desiredLRPs, err)
 		}
 	}
 
@@ -289,7 +641,7 @@ func (watcher *Watcher) eventCellIDMatches(logger lager.Logger, event models.Eve
 	}
 }
 
-func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult) {
+func (w *Watcher) sync(logger lager.Logger, done <-chan struct{}, ch chan<- *syncEventResult) {
 	var desiredSchedulingInfo []*models.DesiredLRPSchedulingInfo
 	var runningActualLRPs []*endpoint.ActualLRPRoutingInfo
 	var domains models.DomainSet
@@ -303,24 +655,11 @@ func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult) {
 	go func() {
 		defer wg.Done()
 		logger.Debug("getting-actual-lrps")
-		var actualLRPGroups []*models.ActualLRPGroup
-		actualLRPGroups, actualErr = w.bbsClient.ActualLRPGroups(logger, models.ActualLRPFilter{CellID: w.cellID})
+		runningActualLRPs, actualErr = w.getRunningActuals(logger, models.ActualLRPFilter{CellID: w.cellID})
 		if actualErr != nil {
 			logger.Error("failed-getting-actual-lrps", actualErr)
 			return
 		}
-		logger.Debug("succeeded-getting-actual-lrps", lager.Data{"num-actual-responses": len(actualLRPGroups)})
-
-		runningActualLRPs = make([]*endpoint.ActualLRPRoutingInfo, 0, len(actualLRPGroups))
-		for _, actualLRPGroup := range actualLRPGroups {
-			actualLRP, evacuating := actualLRPGroup.Resolve()
-			if actualLRP.State == models.ActualLRPStateRunning {
-				runningActualLRPs = append(runningActualLRPs, &endpoint.ActualLRPRoutingInfo{
-					ActualLRP:  actualLRP,
-					Evacuating: evacuating,
-				})
-			}
-		}
 
 		if w.cellID != "" {
 			guids := make([]string, 0, len(runningActualLRPs))
@@ -364,22 +703,179 @@ func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult) {
 		err = fmt.Errorf("failed to sync: %s, %s, %s", actualErr, desiredErr, domainsErr)
 	}
 
-	ch <- &syncEventResult{
+	select {
+	case ch <- &syncEventResult{
 		startTime:     before,
 		desired:       desiredSchedulingInfo,
 		runningActual: runningActualLRPs,
 		domains:       domains,
 		err:           err,
+	}:
+	case <-done:
+	}
+}
+
+// syncInBatches walks the process guids of the desired and the running actual
+// LRPs in batches of syncBatchSize. The scheduling infos of a batch are
+// fetched with a single filtered request, and its actual LRPs by process
+// guid. The BBS cannot list process guids alone, so they are taken from a
+// listing of the scheduling infos and one of the actual LRPs, which are
+// dropped before the first batch is fetched. From then on only the guids and
+// the LRPs of one batch are in memory at a time, as the batches channel is
+// unbuffered.
+//
+// Desired LRPs without running instances, and running instances without a
+// desired LRP, are part of the batches, so that the batches add up to the
+// same table as sync builds.
+func (w *Watcher) syncInBatches(logger lager.Logger, done <-chan struct{}, batches chan<- *syncBatch, ch chan<- *syncEventResult) {
+	var domains models.DomainSet
+	var desiredErr, domainsErr error
+	before := w.clock.Now()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var domainArray []string
+		logger.Debug("getting-domains")
+		domainArray, domainsErr = w.bbsClient.Domains(logger)
+		if domainsErr != nil {
+			logger.Error("failed-getting-domains", domainsErr)
+			return
+		}
+
+		domains = models.NewDomainSet(domainArray)
+		logger.Debug("succeeded-getting-domains", lager.Data{"num-domains": len(domains)})
+	}()
+
+	// the listings are only kept until their process guids are taken
+	guidSet := map[string]struct{}{}
+	logger.Debug("getting-actual-lrps")
+	runningActualLRPs, actualErr := w.getRunningActuals(logger, models.ActualLRPFilter{})
+	if actualErr != nil {
+		logger.Error("failed-getting-actual-lrps", actualErr)
+	}
+	for _, actual := range runningActualLRPs {
+		guidSet[actual.ActualLRP.ProcessGuid] = struct{}{}
+	}
+	runningActualLRPs = nil
+
+	if actualErr == nil {
+		var schedulingInfos []*models.DesiredLRPSchedulingInfo
+		schedulingInfos, desiredErr = getSchedulingInfos(logger, w.bbsClient, nil)
+		for _, schedulingInfo := range schedulingInfos {
+			guidSet[schedulingInfo.ProcessGuid] = struct{}{}
+		}
+	}
+
+	guids := make([]string, 0, len(guidSet))
+	for guid := range guidSet {
+		guids = append(guids, guid)
+	}
+	guidSet = nil
+	sort.Strings(guids)
+
+	numBatches := 0
+	for start := 0; actualErr == nil && desiredErr == nil && start < len(guids); start += w.syncBatchSize {
+		end := start + w.syncBatchSize
+		if end > len(guids) {
+			end = len(guids)
+		}
+
+		batchGuids := guids[start:end]
+		var batchDesired []*models.DesiredLRPSchedulingInfo
+		batchDesired, desiredErr = getSchedulingInfos(logger, w.bbsClient, batchGuids)
+		if desiredErr != nil {
+			break
+		}
+
+		var batchActual []*endpoint.ActualLRPRoutingInfo
+		batchActual, actualErr = w.getRunningActualsByProcessGuids(logger, batchGuids)
+		if actualErr != nil {
+			logger.Error("failed-getting-actual-lrps", actualErr)
+			break
+		}
+
+		select {
+		case batches <- &syncBatch{desired: batchDesired, runningActual: batchActual}:
+		case <-done:
+			logger.Info("stopped-sending-sync-batches", lager.Data{"num-batches": numBatches})
+			return
+		}
+		numBatches++
+	}
+	if actualErr == nil && desiredErr == nil {
+		logger.Debug("succeeded-sending-sync-batches", lager.Data{"num-batches": numBatches})
+	}
+
+	wg.Wait()
+
+	var err error
+	if actualErr != nil || desiredErr != nil || domainsErr != nil {
+		err = fmt.Errorf("failed to sync: %s, %s, %s", actualErr, desiredErr, domainsErr)
+	}
+
+	select {
+	case ch <- &syncEventResult{
+		startTime: before,
+		domains:   domains,
+		batched:   true,
+		err:       err,
+	}:
+	case <-done:
 	}
 }
 
-func checkForEvents(bbsClient bbs.Client, resubscribeChannel chan error,
-	eventChan chan models.Event, eventSource *atomic.Value, logger lager.Logger) {
+// getRunningActualsByProcessGuids returns the routing infos of the running
+// actual LRPs of the process guids, fetched one process guid at a time.
+func (w *Watcher) getRunningActualsByProcessGuids(logger lager.Logger, guids []string) ([]*endpoint.ActualLRPRoutingInfo, error) {
+	runningActualLRPs := []*endpoint.ActualLRPRoutingInfo{}
+	for _, guid := range guids {
+		if w.usesInstanceEvents() {
+			actualLRPs, err := w.bbsClient.ActualLRPs(logger, models.ActualLRPFilter{ProcessGuid: guid})
+			if err != nil {
+				return nil, err
+			}
+			runningActualLRPs = append(runningActualLRPs, runningActualLRPInstanceRoutingInfos(actualLRPs)...)
+			continue
+		}
+
+		actualLRPGroups, err := w.bbsClient.ActualLRPGroupsByProcessGuid(logger, guid)
+		if err != nil {
+			return nil, err
+		}
+		runningActualLRPs = append(runningActualLRPs, endpoint.RunningActualLRPRoutingInfos(actualLRPGroups)...)
+	}
+	return w.routableActualLRPs(runningActualLRPs), nil
+}
+
+// getRunningActuals returns the routing infos of the running actual LRPs that
+// match the filter, read from the endpoint of the event family in use.
+func (w *Watcher) getRunningActuals(logger lager.Logger, filter models.ActualLRPFilter) ([]*endpoint.ActualLRPRoutingInfo, error) {
+	if w.usesInstanceEvents() {
+		actualLRPs, err := w.bbsClient.ActualLRPs(logger, filter)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("succeeded-getting-actual-lrps", lager.Data{"num-actual-responses": len(actualLRPs)})
+		return w.routableActualLRPs(runningActualLRPInstanceRoutingInfos(actualLRPs)), nil
+	}
+
+	actualLRPGroups, err := w.bbsClient.ActualLRPGroups(logger, filter)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("succeeded-getting-actual-lrps", lager.Data{"num-actual-responses": len(actualLRPGroups)})
+	return w.routableActualLRPs(endpoint.RunningActualLRPRoutingInfos(actualLRPGroups)), nil
+}
+
+func checkForEvents(subscribe func(lager.Logger) (events.EventSource, error), translate func(models.Event) models.Event,
+	resubscribeChannel chan error, eventChan chan receivedEvent, eventSource *atomic.Value, clock clock.Clock, logger lager.Logger) {
 	var err error
 	var es events.EventSource
 
 	logger.Info("subscribing-to-bbs-events")
-	es, err = bbsClient.SubscribeToEvents(logger)
+	es, err = subscribe(logger)
 	if err != nil {
 		resubscribeChannel <- err
 		return
@@ -402,7 +898,10 @@ func checkForEvents(bbsClient bbs.Client, resubscribeChannel chan error,
 		}
 
 		if event != nil {
-			eventChan <- event
+			event = translate(event)
+		}
+		if event != nil {
+			eventChan <- receivedEvent{event: event, receivedAt: clock.Now()}
 		}
 	}
 }
@@ -3,6 +3,7 @@ package watcher_test
 import (
 	"errors"
 	"os"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/bbs/events/eventfakes"
@@ -15,6 +16,7 @@ import (
 	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/route-emitter/watcher"
 	"code.cloudfoundry.org/routing-api/fake_routing_api"
@@ -26,6 +28,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
 )
 
 var _ = Describe("Watcher Integration", func() {
@@ -58,21 +61,30 @@ var _ = Describe("Watcher Integration", func() {
 		Expect(err).NotTo(HaveOccurred())
 		natsEmitter := emitter.NewNATSEmitter(natsClient, workPool, logger)
 		natsTable := routingtable.NewNATSTable(logger)
-		natsHandler := routehandlers.NewNATSHandler(natsTable, natsEmitter, false)
 
 		uaaClient := uaaclient.NewNoOpUaaClient()
 		routingAPIEmitter := emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaClient, 100)
 		tcpTable := routingtable.NewTCPTable(logger, nil)
-		routingAPIHandler := routehandlers.NewRoutingAPIHandler(tcpTable, routingAPIEmitter, false)
+		routingAPIHandler := routehandlers.NewRoutingAPIHandler(tcpTable, routingAPIEmitter, nil, false)
 
-		handler := routehandlers.NewMultiHandler(natsHandler, routingAPIHandler)
 		clock := fakeclock.NewFakeClock(time.Now())
+		natsHandler := routehandlers.NewNATSHandler(clock, natsTable, natsEmitter, nil, false)
+		handler := routehandlers.NewMultiHandler(clock, 0, logger,
+			routehandlers.NamedHandler{Name: "NATSHandler", Handler: natsHandler},
+			routehandlers.NamedHandler{Name: "RoutingAPIHandler", Handler: routingAPIHandler},
+		)
 		testWatcher = watcher.NewWatcher(
 			cellID,
 			bbsClient,
 			clock,
 			handler,
 			syncEvents,
+			nil,
+			0,
+			0,
+			0,
+			watcher.ActualLRPEvents{},
+			nil,
 			logger,
 		)
 	})
@@ -209,4 +221,132 @@ var _ = Describe("Watcher Integration", func() {
 			})
 		})
 	})
+
+	Describe("syncing in batches", func() {
+		var (
+			schedulingInfos []*models.DesiredLRPSchedulingInfo
+			actualLRPGroups []*models.ActualLRPGroup
+		)
+
+		newActualLRPGroup := func(processGuid, instanceGuid, host string, port uint32) *models.ActualLRPGroup {
+			return &models.ActualLRPGroup{
+				Instance: &models.ActualLRP{
+					ActualLRPKey:         models.NewActualLRPKey(processGuid, 0, "domain"),
+					ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+					ActualLRPNetInfo:     models.NewActualLRPNetInfo(host, "container-ip", models.NewPortMapping(port, 8080)),
+					State:                models.ActualLRPStateRunning,
+				},
+			}
+		}
+
+		newSchedulingInfo := func(processGuid, hostname string) *models.DesiredLRPSchedulingInfo {
+			return &models.DesiredLRPSchedulingInfo{
+				DesiredLRPKey: models.NewDesiredLRPKey(processGuid, "domain", "lg-"+processGuid),
+				Routes: cfroutes.CFRoutes{
+					cfroutes.CFRoute{Hostnames: []string{hostname}, Port: 8080},
+				}.RoutingInfo(),
+				Instances: 1,
+			}
+		}
+
+		// startWatcher syncs a NATS table from the same BBS state as the other
+		// watchers, and returns the table once the sync has filled it
+		startWatcher := func(batchSize int) (routingtable.NATSRoutingTable, ifrit.Process) {
+			closed := make(chan struct{})
+			var closeOnce sync.Once
+			source := new(eventfakes.FakeEventSource)
+			source.CloseStub = func() error {
+				closeOnce.Do(func() { close(closed) })
+				return nil
+			}
+			source.NextStub = func() (models.Event, error) {
+				select {
+				case <-closed:
+					return nil, errors.New("closed")
+				case <-time.After(10 * time.Millisecond):
+					return nil, nil
+				}
+			}
+
+			client := new(fake_bbs.FakeClient)
+			client.SubscribeToEventsReturns(source, nil)
+			client.DomainsReturns([]string{"domain"}, nil)
+			client.ActualLRPGroupsReturns(actualLRPGroups, nil)
+			client.ActualLRPGroupsByProcessGuidStub = func(_ lager.Logger, processGuid string) ([]*models.ActualLRPGroup, error) {
+				groups := []*models.ActualLRPGroup{}
+				for _, group := range actualLRPGroups {
+					if group.Instance.ProcessGuid == processGuid {
+						groups = append(groups, group)
+					}
+				}
+				return groups, nil
+			}
+			client.DesiredLRPSchedulingInfosStub = func(_ lager.Logger, f models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+				if len(f.ProcessGuids) == 0 {
+					return schedulingInfos, nil
+				}
+				filtered := []*models.DesiredLRPSchedulingInfo{}
+				for _, schedulingInfo := range schedulingInfos {
+					for _, guid := range f.ProcessGuids {
+						if schedulingInfo.ProcessGuid == guid {
+							filtered = append(filtered, schedulingInfo)
+						}
+					}
+				}
+				return filtered, nil
+			}
+
+			workPool, err := workpool.NewWorkPool(1)
+			Expect(err).NotTo(HaveOccurred())
+			table := routingtable.NewNATSTable(logger)
+			clock := fakeclock.NewFakeClock(time.Now())
+			handler := routehandlers.NewNATSHandler(clock, table, emitter.NewNATSEmitter(diegonats.NewFakeClient(), workPool, logger), nil, false)
+			events := syncer.Events{
+				Sync: make(chan struct{}),
+				Emit: make(chan struct{}),
+			}
+
+			w := watcher.NewWatcher("", client, clock, handler, events, nil, batchSize, 0, 0, watcher.ActualLRPEvents{}, nil, logger)
+			p := ifrit.Invoke(w)
+			events.Sync <- struct{}{}
+			Eventually(table.EntryCount).Should(Equal(2))
+			return table, p
+		}
+
+		BeforeEach(func() {
+			schedulingInfos = []*models.DesiredLRPSchedulingInfo{
+				newSchedulingInfo("pg-1", "one.example.com"),
+				newSchedulingInfo("pg-2", "two.example.com"),
+				newSchedulingInfo("pg-3", "three.example.com"),
+				// desired without running instances
+				newSchedulingInfo("pg-4", "four.example.com"),
+			}
+			actualLRPGroups = []*models.ActualLRPGroup{
+				newActualLRPGroup("pg-3", "ig-3", "3.3.3.3", 33),
+				newActualLRPGroup("pg-1", "ig-1", "1.1.1.1", 11),
+				// running without a desired lrp
+				newActualLRPGroup("pg-5", "ig-5", "5.5.5.5", 55),
+			}
+		})
+
+		It("builds the same table as syncing everything at once", func() {
+			unbatchedTable, unbatched := startWatcher(0)
+			defer ginkgomon.Interrupt(unbatched)
+			batchedTable, batched := startWatcher(1)
+			defer ginkgomon.Interrupt(batched)
+
+			Expect(batchedTable.Snapshot()).To(Equal(unbatchedTable.Snapshot()))
+			Expect(batchedTable.RouteCount()).To(Equal(unbatchedTable.RouteCount()))
+			for _, guid := range []string{"pg-1", "pg-2", "pg-3", "pg-4", "pg-5"} {
+				key := endpoint.RoutingKey{ProcessGUID: guid, ContainerPort: 8080}
+				Expect(batchedTable.GetRoutes(key)).To(Equal(unbatchedTable.GetRoutes(key)))
+				Expect(batchedTable.EndpointsForIndex(key, 0)).To(Equal(unbatchedTable.EndpointsForIndex(key, 0)))
+			}
+
+			desiredOnly := endpoint.RoutingKey{ProcessGUID: "pg-4", ContainerPort: 8080}
+			Expect(batchedTable.GetRoutes(desiredOnly)).To(HaveLen(1))
+			actualOnly := endpoint.RoutingKey{ProcessGUID: "pg-5", ContainerPort: 8080}
+			Expect(batchedTable.EndpointsForIndex(actualOnly, 0)).To(HaveLen(1))
+		})
+	})
 })
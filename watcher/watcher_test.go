@@ -3,6 +3,8 @@ package watcher_test
 import (
 	"errors"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/bbs/events"
@@ -12,6 +14,7 @@ import (
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cellwatcher"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
 	"code.cloudfoundry.org/route-emitter/syncer"
@@ -92,11 +95,18 @@ var _ = Describe("Watcher", func() {
 		eventSource  *eventfakes.FakeEventSource
 		bbsClient    *fake_bbs.FakeClient
 		routeHandler *fakes.FakeRouteHandler
+		handler      watcher.RouteHandler
 		testWatcher  *watcher.Watcher
 		clock        *fakeclock.FakeClock
 		process      ifrit.Process
 		cellID       string
 		syncEvents   syncer.Events
+		cellChanges  chan cellwatcher.Change
+		batchSize    int
+		maxCached    int
+		stallTimeout time.Duration
+		lrpEvents    watcher.ActualLRPEvents
+		recorder     *fakes.FakeRecorder
 	)
 
 	BeforeEach(func() {
@@ -104,6 +114,12 @@ var _ = Describe("Watcher", func() {
 		eventSource = new(eventfakes.FakeEventSource)
 		bbsClient = new(fake_bbs.FakeClient)
 		routeHandler = new(fakes.FakeRouteHandler)
+		handler = routeHandler
+		batchSize = 0
+		maxCached = 0
+		stallTimeout = 0
+		lrpEvents = watcher.ActualLRPEvents{}
+		recorder = new(fakes.FakeRecorder)
 
 		clock = fakeclock.NewFakeClock(time.Now())
 		bbsClient.SubscribeToEventsReturns(eventSource, nil)
@@ -112,11 +128,12 @@ var _ = Describe("Watcher", func() {
 			Sync: make(chan struct{}),
 			Emit: make(chan struct{}),
 		}
+		cellChanges = make(chan cellwatcher.Change)
 		cellID = ""
 	})
 
 	JustBeforeEach(func() {
-		testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, logger)
+		testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, handler, syncEvents, cellChanges, batchSize, maxCached, stallTimeout, lrpEvents, recorder, logger)
 		process = ifrit.Invoke(testWatcher)
 	})
 
@@ -141,6 +158,11 @@ var _ = Describe("Watcher", func() {
 			_, createEvent := routeHandler.HandleEventArgsForCall(0)
 			Expect(createEvent).Should(Equal(event))
 		})
+
+		It("records the event", func() {
+			Eventually(recorder.RecordEventCallCount).Should(BeNumerically(">=", 1))
+			Expect(recorder.RecordEventArgsForCall(0)).To(Equal(event))
+		})
 	})
 
 	Context("handle DesiredLRPChangedEvent", func() {
@@ -319,7 +341,7 @@ var _ = Describe("Watcher", func() {
 			)
 
 			bbsClient.SubscribeToEventsReturns(fakeEventSource, nil)
-			testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, logger)
+			testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, nil, 0, 0, 0, watcher.ActualLRPEvents{}, nil, logger)
 		})
 
 		It("should not close the current connection", func() {
@@ -359,7 +381,7 @@ var _ = Describe("Watcher", func() {
 				return eventSource, nil
 			}
 
-			testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, logger)
+			testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, nil, 0, 0, 0, watcher.ActualLRPEvents{}, nil, logger)
 		})
 
 		JustBeforeEach(func() {
@@ -373,6 +395,419 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 
+	Context("when reading actual LRP instances", func() {
+		var ordinaryLRP, suspectLRP *models.ActualLRP
+
+		BeforeEach(func() {
+			lrpEvents = watcher.ActualLRPEvents{Family: watcher.InstanceEvents}
+			bbsClient.SubscribeToInstanceEventsReturns(eventSource, nil)
+
+			ordinaryLRP = getActualLRP("process-guid-1", "instance-guid-1", "some-ip", "container-ip", 61000, 5222, false).Instance
+			ordinaryLRP.Presence = models.ActualLRP_Ordinary
+			suspectLRP = getActualLRP("process-guid-1", "instance-guid-1", "some-ip", "container-ip", 61000, 5222, false).Instance
+			suspectLRP.Presence = models.ActualLRP_Suspect
+		})
+
+		It("rejects unknown event families and suspect policies", func() {
+			Expect(lrpEvents.Validate()).To(Succeed())
+			Expect(watcher.ActualLRPEvents{Family: "bogus"}.Validate()).To(HaveOccurred())
+			Expect(watcher.ActualLRPEvents{SuspectPolicy: "bogus"}.Validate()).To(HaveOccurred())
+		})
+
+		It("subscribes to instance events", func() {
+			Eventually(bbsClient.SubscribeToInstanceEventsCallCount).Should(Equal(1))
+			Expect(bbsClient.SubscribeToEventsCallCount()).To(Equal(0))
+		})
+
+		Context("when an instance is created", func() {
+			BeforeEach(func() {
+				eventSource.NextReturns(models.NewActualLRPInstanceCreatedEvent(ordinaryLRP), nil)
+			})
+
+			It("hands the route handler the equivalent group event", func() {
+				Eventually(routeHandler.HandleEventCallCount).Should(BeNumerically(">=", 1))
+				_, event := routeHandler.HandleEventArgsForCall(0)
+				Expect(event).To(Equal(models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{Instance: ordinaryLRP})))
+			})
+
+			Context("and the cell id doesn't match", func() {
+				BeforeEach(func() {
+					cellID = "random-cell-id"
+				})
+
+				It("ignores the event", func() {
+					Consistently(routeHandler.HandleEventCallCount).Should(BeZero())
+				})
+			})
+		})
+
+		Context("when an instance becomes suspect", func() {
+			BeforeEach(func() {
+				eventSource.NextReturns(models.NewActualLRPInstanceChangedEvent(ordinaryLRP, suspectLRP), nil)
+			})
+
+			It("keeps routing it by default", func() {
+				Eventually(routeHandler.HandleEventCallCount).Should(BeNumerically(">=", 1))
+				_, event := routeHandler.HandleEventArgsForCall(0)
+				Expect(event).To(BeAssignableToTypeOf(&models.ActualLRPChangedEvent{}))
+			})
+
+			Context("and suspect instances are unrouted", func() {
+				BeforeEach(func() {
+					lrpEvents.SuspectPolicy = watcher.UnrouteSuspect
+				})
+
+				It("removes the instance", func() {
+					Eventually(routeHandler.HandleEventCallCount).Should(BeNumerically(">=", 1))
+					_, event := routeHandler.HandleEventArgsForCall(0)
+					Expect(event).To(Equal(models.NewActualLRPRemovedEvent(&models.ActualLRPGroup{Instance: ordinaryLRP})))
+				})
+			})
+		})
+
+		Context("when a suspect instance is created and suspect instances are unrouted", func() {
+			BeforeEach(func() {
+				lrpEvents.SuspectPolicy = watcher.UnrouteSuspect
+				eventSource.NextReturns(models.NewActualLRPInstanceCreatedEvent(suspectLRP), nil)
+			})
+
+			It("ignores the event", func() {
+				Consistently(routeHandler.HandleEventCallCount).Should(BeZero())
+			})
+		})
+
+		Context("when syncing", func() {
+			BeforeEach(func() {
+				lrpEvents.SuspectPolicy = watcher.UnrouteSuspect
+				bbsClient.ActualLRPsReturns([]*models.ActualLRP{ordinaryLRP, suspectLRP}, nil)
+			})
+
+			JustBeforeEach(func() {
+				syncEvents.Sync <- struct{}{}
+			})
+
+			It("reads the actual LRP instances and leaves out suspect ones", func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				Expect(bbsClient.ActualLRPGroupsCallCount()).To(Equal(0))
+
+				_, _, runningActual, _, _ := routeHandler.SyncArgsForCall(0)
+				Expect(runningActual).To(Equal([]*endpoint.ActualLRPRoutingInfo{
+					endpoint.NewActualLRPRoutingInfoFromInstance(ordinaryLRP),
+				}))
+			})
+		})
+
+		Context("when syncing with the default policy", func() {
+			BeforeEach(func() {
+				bbsClient.ActualLRPsReturns([]*models.ActualLRP{ordinaryLRP, suspectLRP}, nil)
+			})
+
+			JustBeforeEach(func() {
+				syncEvents.Sync <- struct{}{}
+			})
+
+			It("keeps the suspect instances", func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				_, _, runningActual, _, _ := routeHandler.SyncArgsForCall(0)
+				Expect(runningActual).To(HaveLen(2))
+			})
+		})
+
+		Context("when the event family is negotiated", func() {
+			BeforeEach(func() {
+				lrpEvents.Family = watcher.NegotiateEvents
+			})
+
+			Context("and the BBS does not serve instance events", func() {
+				BeforeEach(func() {
+					bbsClient.SubscribeToInstanceEventsReturns(nil, models.ErrResourceNotFound)
+					bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{{Instance: ordinaryLRP}}, nil)
+				})
+
+				It("falls back to group events and syncs actual LRP groups", func() {
+					Eventually(bbsClient.SubscribeToEventsCallCount).Should(Equal(1))
+					Eventually(logger).Should(gbytes.Say("instance-events-unavailable"))
+
+					syncEvents.Sync <- struct{}{}
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					Expect(bbsClient.ActualLRPsCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and the BBS serves instance events", func() {
+				BeforeEach(func() {
+					bbsClient.ActualLRPsReturns([]*models.ActualLRP{ordinaryLRP}, nil)
+				})
+
+				It("syncs actual LRP instances", func() {
+					Eventually(bbsClient.SubscribeToInstanceEventsCallCount).Should(Equal(1))
+
+					Eventually(func() int {
+						select {
+						case syncEvents.Sync <- struct{}{}:
+						default:
+						}
+						return bbsClient.ActualLRPsCallCount()
+					}).Should(BeNumerically(">=", 1))
+					Expect(bbsClient.SubscribeToEventsCallCount()).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	Context("when reading actual LRP groups and suspect instances are unrouted", func() {
+		var ordinaryGroup, suspectGroup *models.ActualLRPGroup
+
+		BeforeEach(func() {
+			lrpEvents = watcher.ActualLRPEvents{SuspectPolicy: watcher.UnrouteSuspect}
+
+			ordinaryGroup = getActualLRP("process-guid-1", "instance-guid-1", "some-ip", "container-ip", 61000, 5222, false)
+			suspectGroup = getActualLRP("process-guid-2", "instance-guid-2", "other-ip", "container-ip", 61001, 5222, false)
+			suspectGroup.Instance.Presence = models.ActualLRP_Suspect
+		})
+
+		Context("when syncing", func() {
+			BeforeEach(func() {
+				bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{ordinaryGroup, suspectGroup}, nil)
+			})
+
+			JustBeforeEach(func() {
+				syncEvents.Sync <- struct{}{}
+			})
+
+			It("leaves out the suspect instances", func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				_, _, runningActual, _, _ := routeHandler.SyncArgsForCall(0)
+				Expect(runningActual).To(Equal([]*endpoint.ActualLRPRoutingInfo{
+					endpoint.NewActualLRPRoutingInfo(ordinaryGroup),
+				}))
+			})
+		})
+
+		Context("when an instance becomes suspect", func() {
+			BeforeEach(func() {
+				before := getActualLRP("process-guid-2", "instance-guid-2", "other-ip", "container-ip", 61001, 5222, false)
+				eventSource.NextReturns(models.NewActualLRPChangedEvent(before, suspectGroup), nil)
+			})
+
+			It("removes the instance", func() {
+				Eventually(routeHandler.HandleEventCallCount).Should(BeNumerically(">=", 1))
+				_, event := routeHandler.HandleEventArgsForCall(0)
+				Expect(event).To(BeAssignableToTypeOf(&models.ActualLRPRemovedEvent{}))
+			})
+		})
+	})
+
+	Context("when a cell changes", func() {
+		var (
+			suppressor *hostSuppressingHandler
+			events     chan models.Event
+			lostLRP    *models.ActualLRPGroup
+		)
+
+		BeforeEach(func() {
+			suppressor = &hostSuppressingHandler{
+				FakeRouteHandler: routeHandler,
+				suppressed:       make(chan string, 1),
+				unsuppressed:     make(chan string, 1),
+			}
+			handler = suppressor
+
+			events = make(chan models.Event, 1)
+			nextEvent := events
+			eventSource.NextStub = func() (models.Event, error) {
+				select {
+				case event := <-nextEvent:
+					return event, nil
+				case <-time.After(10 * time.Millisecond):
+					return nil, nil
+				}
+			}
+
+			// the rep of the cell registered with another address than the one
+			// its instances are reached on
+			lostLRP = getActualLRP("process-guid-1", "instance-guid-1", "10.0.0.1", "container-ip", 61000, 5222, false)
+			bbsClient.ActualLRPGroupsStub = func(_ lager.Logger, filter models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+				if filter.CellID == "cell-id-1" {
+					return []*models.ActualLRPGroup{lostLRP}, nil
+				}
+				return []*models.ActualLRPGroup{}, nil
+			}
+		})
+
+		loseCell := func() {
+			cellChanges <- cellwatcher.Change{CellID: "cell-id-1", Lost: true}
+			Eventually(suppressor.suppressed).Should(Receive(Equal("10.0.0.1")))
+		}
+
+		It("suppresses the hosts of the instances the BBS reports on a lost cell", func() {
+			loseCell()
+			_, filter := bbsClient.ActualLRPGroupsArgsForCall(0)
+			Expect(filter.CellID).To(Equal("cell-id-1"))
+		})
+
+		It("syncs to register the endpoints on a cell that was lost and is back", func() {
+			loseCell()
+			Consistently(routeHandler.SyncCallCount).Should(Equal(0))
+
+			cellChanges <- cellwatcher.Change{CellID: "cell-id-1"}
+			Eventually(suppressor.unsuppressed).Should(Receive(Equal("10.0.0.1")))
+			Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+		})
+
+		It("lifts the suppression once the instances run on another cell", func() {
+			loseCell()
+
+			replacement := getActualLRP("process-guid-1", "instance-guid-2", "10.0.0.2", "container-ip", 61000, 5222, false)
+			replacement.Instance.CellId = "cell-id-2"
+			events <- models.NewActualLRPChangedEvent(lostLRP, replacement)
+
+			Eventually(suppressor.unsuppressed).Should(Receive(Equal("10.0.0.1")))
+			Expect(routeHandler.SyncCallCount()).To(Equal(0))
+		})
+
+		It("keeps the suppression while the instances are not replaced", func() {
+			loseCell()
+
+			other := getActualLRP("process-guid-2", "instance-guid-2", "10.0.0.2", "container-ip", 61000, 5222, false)
+			other.Instance.CellId = "cell-id-2"
+			events <- models.NewActualLRPCreatedEvent(other)
+
+			Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+			Consistently(suppressor.unsuppressed).ShouldNot(Receive())
+		})
+
+		It("lifts the suppression once a sync completes", func() {
+			loseCell()
+
+			syncEvents.Sync <- struct{}{}
+			Eventually(suppressor.unsuppressed).Should(Receive(Equal("10.0.0.1")))
+			Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+		})
+
+		Context("when the BBS reports no instances on the lost cell", func() {
+			BeforeEach(func() {
+				bbsClient.ActualLRPGroupsStub = nil
+				bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{}, nil)
+			})
+
+			It("suppresses nothing", func() {
+				cellChanges <- cellwatcher.Change{CellID: "cell-id-1", Lost: true}
+				Eventually(bbsClient.ActualLRPGroupsCallCount).Should(Equal(1))
+				Consistently(suppressor.suppressed).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the route handler cannot suppress hosts", func() {
+			BeforeEach(func() {
+				handler = routeHandler
+			})
+
+			It("ignores the change", func() {
+				cellChanges <- cellwatcher.Change{CellID: "cell-id-1", Lost: true}
+				Eventually(cellChanges).Should(BeSent(cellwatcher.Change{CellID: "cell-id-1"}))
+				Expect(bbsClient.ActualLRPGroupsCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when the route handler requests a sync", func() {
+		var requests chan struct{}
+
+		BeforeEach(func() {
+			requests = make(chan struct{})
+			handler = &syncRequestingHandler{FakeRouteHandler: routeHandler, requests: requests}
+		})
+
+		It("syncs", func() {
+			Eventually(requests).Should(BeSent(struct{}{}))
+			Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+		})
+	})
+
+	Context("when the event stream stalls", func() {
+		var fakeMetricSender *fake_metrics_sender.FakeMetricSender
+
+		BeforeEach(func() {
+			stallTimeout = 10 * time.Second
+			fakeMetricSender = fake_metrics_sender.NewFakeMetricSender()
+			metrics.Initialize(fakeMetricSender, nil)
+
+			// every subscription hangs until it is closed
+			bbsClient.SubscribeToEventsStub = func(lager.Logger) (events.EventSource, error) {
+				source := new(eventfakes.FakeEventSource)
+				closed := make(chan struct{})
+				var once sync.Once
+				source.CloseStub = func() error {
+					once.Do(func() { close(closed) })
+					return nil
+				}
+				source.NextStub = func() (models.Event, error) {
+					<-closed
+					return nil, errors.New("closed")
+				}
+				return source, nil
+			}
+		})
+
+		It("does not probe the BBS before the stall timeout", func() {
+			clock.WaitForWatcherAndIncrement(stallTimeout / 2)
+			Consistently(bbsClient.DomainsCallCount).Should(Equal(0))
+		})
+
+		Context("when the BBS can be reached", func() {
+			JustBeforeEach(func() {
+				clock.WaitForWatcherAndIncrement(stallTimeout)
+			})
+
+			It("replaces the event source", func() {
+				Eventually(logger).Should(gbytes.Say("event-stream-stalled"))
+				Eventually(bbsClient.SubscribeToEventsCallCount).Should(Equal(2))
+				Consistently(bbsClient.SubscribeToEventsCallCount).Should(Equal(2))
+			})
+
+			It("forces a sync", func() {
+				Eventually(bbsClient.ActualLRPGroupsCallCount).Should(Equal(1))
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+			})
+
+			It("counts the stall", func() {
+				Eventually(func() uint64 {
+					return fakeMetricSender.GetCounter("RouteEmitterEventStreamStalls")
+				}).Should(BeEquivalentTo(1))
+			})
+		})
+
+		Context("when the BBS cannot be reached", func() {
+			BeforeEach(func() {
+				bbsClient.DomainsReturns(nil, errors.New("unreachable"))
+			})
+
+			JustBeforeEach(func() {
+				clock.WaitForWatcherAndIncrement(stallTimeout)
+			})
+
+			It("keeps the event source", func() {
+				Eventually(bbsClient.DomainsCallCount).Should(Equal(1))
+				Eventually(logger).Should(gbytes.Say("failed-to-probe-bbs"))
+				Consistently(bbsClient.SubscribeToEventsCallCount).Should(Equal(1))
+				Expect(bbsClient.ActualLRPGroupsCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the stall timeout is not set", func() {
+			BeforeEach(func() {
+				stallTimeout = 0
+			})
+
+			It("never probes the BBS", func() {
+				clock.Increment(time.Hour)
+				Consistently(bbsClient.DomainsCallCount).Should(Equal(0))
+				Expect(bbsClient.SubscribeToEventsCallCount()).To(Equal(1))
+			})
+		})
+	})
+
 	Describe("emit event", func() {
 		It("emits registrations", func() {
 			syncEvents.Emit <- struct{}{}
@@ -527,9 +962,8 @@ var _ = Describe("Watcher", func() {
 					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
 					_, _, _, _, event := routeHandler.SyncArgsForCall(0)
 
-					Expect(event).To(HaveLen(1))
 					expectedEvent := models.NewActualLRPRemovedEvent(actualLRPGroup1)
-					Expect(event[actualLRPGroup1.Instance.InstanceGuid]).To(Equal(expectedEvent))
+					Expect(event).To(Equal([]models.Event{expectedEvent}))
 				})
 			})
 
@@ -537,12 +971,124 @@ var _ = Describe("Watcher", func() {
 				Consistently(routeHandler.HandleEventCallCount).Should(Equal(0))
 			})
 
+			It("reports how long the cached events waited for the sync", func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				Eventually(func() string {
+					return fakeMetricSender.GetValue("RouteEmitterEventWaitDuration").Unit
+				}).Should(Equal("nanos"))
+			})
+
+			Context("while the sync is in progress", func() {
+				var unblock chan struct{}
+
+				BeforeEach(func() {
+					unblock = make(chan struct{})
+					bbsClient.ActualLRPGroupsStub = func(lager.Logger, models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+						defer GinkgoRecover()
+						sendEvent()
+						<-unblock
+						return nil, nil
+					}
+				})
+
+				It("reports the number of cached events until the sync completes", func() {
+					cachedEventsCount := func() float64 {
+						return fakeMetricSender.GetValue("RouteEmitterCachedEventsCount").Value
+					}
+					Eventually(cachedEventsCount).Should(BeEquivalentTo(1))
+
+					close(unblock)
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					Eventually(cachedEventsCount).Should(BeZero())
+				})
+			})
+
 			It("applies cached events after syncing is complete", func() {
 				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
 				_, _, _, _, event := routeHandler.SyncArgsForCall(0)
 
 				expectedEvent := models.NewActualLRPRemovedEvent(actualLRPGroup1)
-				Expect(event[actualLRPGroup1.Instance.InstanceGuid]).To(Equal(expectedEvent))
+				Expect(event).To(Equal([]models.Event{expectedEvent}))
+			})
+
+			Context("when recording", func() {
+				var (
+					recordedLock sync.Mutex
+					recorded     []string
+				)
+
+				BeforeEach(func() {
+					recorded = nil
+					recorder.RecordEventStub = func(event models.Event) {
+						recordedLock.Lock()
+						defer recordedLock.Unlock()
+						recorded = append(recorded, event.EventType())
+					}
+					recorder.RecordSyncStub = func([]*models.DesiredLRPSchedulingInfo, []*endpoint.ActualLRPRoutingInfo, models.DomainSet, error) {
+						recordedLock.Lock()
+						defer recordedLock.Unlock()
+						recorded = append(recorded, "sync")
+					}
+				})
+
+				It("records the cached events before the sync they are applied with", func() {
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					recordedLock.Lock()
+					defer recordedLock.Unlock()
+					Expect(recorded).To(Equal([]string{models.EventTypeActualLRPRemoved, "sync"}))
+				})
+			})
+
+			Context("when several events are received", func() {
+				var (
+					removedEvent, createdEvent models.Event
+					actualLRPGroupsCalls       int32
+				)
+
+				BeforeEach(func() {
+					removedEvent = models.NewActualLRPRemovedEvent(actualLRPGroup1)
+					createdEvent = models.NewActualLRPCreatedEvent(actualLRPGroup1)
+					actualLRPGroupsCalls = 0
+
+					bbsClient.ActualLRPGroupsStub = func(lager.Logger, models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+						defer GinkgoRecover()
+						if atomic.AddInt32(&actualLRPGroupsCalls, 1) == 1 {
+							Eventually(eventCh).Should(BeSent(EventHolder{removedEvent}))
+							Eventually(eventCh).Should(BeSent(EventHolder{createdEvent}))
+							Eventually(logger).Should(gbytes.Say("caching-event"))
+							Eventually(logger).Should(gbytes.Say("caching-event|dropping-event-cache-full"))
+						}
+						return nil, nil
+					}
+				})
+
+				It("hands them over in the order they were received", func() {
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					_, _, _, _, events := routeHandler.SyncArgsForCall(0)
+					Expect(events).To(Equal([]models.Event{removedEvent, createdEvent}))
+					Consistently(routeHandler.SyncCallCount).Should(Equal(1))
+				})
+
+				Context("when more events are received than can be cached", func() {
+					BeforeEach(func() {
+						maxCached = 1
+					})
+
+					It("drops the events that do not fit", func() {
+						Eventually(routeHandler.SyncCallCount).Should(BeNumerically(">=", 1))
+						_, _, _, _, events := routeHandler.SyncArgsForCall(0)
+						Expect(events).To(Equal([]models.Event{removedEvent}))
+						Expect(fakeMetricSender.GetCounter("RouteEmitterCachedEventsDropped")).To(BeEquivalentTo(1))
+					})
+
+					It("forces another sync", func() {
+						Eventually(routeHandler.SyncCallCount).Should(Equal(2))
+						Expect(logger).To(gbytes.Say("forcing-sync-after-dropping-events"))
+						_, _, _, _, events := routeHandler.SyncArgsForCall(1)
+						Expect(events).To(BeEmpty())
+						Consistently(routeHandler.SyncCallCount).Should(Equal(2))
+					})
+				})
 			})
 		})
 
@@ -599,6 +1145,63 @@ var _ = Describe("Watcher", func() {
 			})
 		})
 
+		Context("when a sync fails while events are cached", func() {
+			var (
+				removedEvent, createdEvent models.Event
+				actualLRPGroupsCalls       int32
+			)
+
+			BeforeEach(func() {
+				removedEvent = models.NewActualLRPRemovedEvent(actualLRPGroup1)
+				createdEvent = models.NewActualLRPCreatedEvent(actualLRPGroup1)
+				actualLRPGroupsCalls = 0
+
+				bbsClient.ActualLRPGroupsStub = func(lager.Logger, models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+					defer GinkgoRecover()
+					if atomic.AddInt32(&actualLRPGroupsCalls, 1) == 1 {
+						Eventually(eventCh).Should(BeSent(EventHolder{removedEvent}))
+						Eventually(eventCh).Should(BeSent(EventHolder{createdEvent}))
+						Eventually(logger).Should(gbytes.Say("caching-event"))
+						Eventually(logger).Should(gbytes.Say("caching-event|dropping-event-cache-full"))
+						return nil, errors.New("bam")
+					}
+					return nil, nil
+				}
+			})
+
+			It("handles the cached events in order right away", func() {
+				Eventually(routeHandler.HandleEventCallCount).Should(Equal(2))
+				_, event := routeHandler.HandleEventArgsForCall(0)
+				Expect(event).To(Equal(removedEvent))
+				_, event = routeHandler.HandleEventArgsForCall(1)
+				Expect(event).To(Equal(createdEvent))
+				Expect(routeHandler.SyncCallCount()).To(Equal(0))
+			})
+
+			It("does not hand them over again with the next sync", func() {
+				Eventually(routeHandler.HandleEventCallCount).Should(Equal(2))
+				syncEvents.Sync <- struct{}{}
+
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				_, _, _, _, events := routeHandler.SyncArgsForCall(0)
+				Expect(events).To(BeEmpty())
+			})
+
+			Context("when more events are received than can be cached", func() {
+				BeforeEach(func() {
+					maxCached = 1
+				})
+
+				It("forces another sync", func() {
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					Expect(logger).To(gbytes.Say("forcing-sync-after-dropping-events"))
+					Expect(routeHandler.HandleEventCallCount()).To(Equal(1))
+					_, _, _, _, events := routeHandler.SyncArgsForCall(0)
+					Expect(events).To(BeEmpty())
+				})
+			})
+		})
+
 		Context("when fetching desireds fails", func() {
 			var (
 				errCh chan error
@@ -709,11 +1312,231 @@ var _ = Describe("Watcher", func() {
 				Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
 			})
 
+			It("reports how long events waited and took to handle", func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				sendEvent()
+				Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+
+				Eventually(func() string {
+					return fakeMetricSender.GetValue("RouteEmitterActualLRPRemovedHandlingDuration").Unit
+				}).Should(Equal("nanos"))
+				Expect(fakeMetricSender.GetValue("RouteEmitterEventWaitDuration").Unit).To(Equal("nanos"))
+			})
+
+			Context("when the route handler times the event handling itself", func() {
+				BeforeEach(func() {
+					handler = eventTimingHandler{routeHandler}
+				})
+
+				It("does not report how long the events took to hand over", func() {
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					sendEvent()
+					Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+
+					Consistently(func() string {
+						return fakeMetricSender.GetValue("RouteEmitterActualLRPRemovedHandlingDuration").Unit
+					}).Should(BeEmpty())
+				})
+			})
+
 			It("gets all the desired lrps", func() {
 				Eventually(bbsClient.DesiredLRPSchedulingInfosCallCount).Should(Equal(1))
 				_, filter := bbsClient.DesiredLRPSchedulingInfosArgsForCall(0)
 				Expect(filter.ProcessGuids).To(BeEmpty())
 			})
+
+			It("records the start and the result of the sync", func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				Expect(recorder.RecordSyncStartCallCount()).To(Equal(1))
+				Expect(recorder.RecordSyncCallCount()).To(Equal(1))
+
+				desired, actuals, domains, err := recorder.RecordSyncArgsForCall(0)
+				Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo1, schedulingInfo2}))
+				Expect(actuals).To(HaveLen(3))
+				Expect(domains).To(Equal(models.DomainSet{}))
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when syncing in batches", func() {
+			var batchHandler *fakes.FakeBatchRouteHandler
+
+			BeforeEach(func() {
+				batchHandler = new(fakes.FakeBatchRouteHandler)
+				handler = batchHandler
+				batchSize = 2
+
+				bbsClient.DomainsReturns([]string{"domain"}, nil)
+				allSchedulingInfos := []*models.DesiredLRPSchedulingInfo{schedulingInfo1, schedulingInfo2, schedulingInfo3}
+				bbsClient.DesiredLRPSchedulingInfosStub = func(_ lager.Logger, f models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+					if len(f.ProcessGuids) == 0 {
+						return allSchedulingInfos, nil
+					}
+					schedulingInfos := []*models.DesiredLRPSchedulingInfo{}
+					for _, si := range allSchedulingInfos {
+						for _, guid := range f.ProcessGuids {
+							if si.ProcessGuid == guid {
+								schedulingInfos = append(schedulingInfos, si)
+							}
+						}
+					}
+					return schedulingInfos, nil
+				}
+				allActualLRPGroups := []*models.ActualLRPGroup{actualLRPGroup3, actualLRPGroup1, actualLRPGroup2}
+				bbsClient.ActualLRPGroupsReturns(allActualLRPGroups, nil)
+				bbsClient.ActualLRPGroupsByProcessGuidStub = func(_ lager.Logger, processGuid string) ([]*models.ActualLRPGroup, error) {
+					groups := []*models.ActualLRPGroup{}
+					for _, group := range allActualLRPGroups {
+						if group.Instance.ProcessGuid == processGuid {
+							groups = append(groups, group)
+						}
+					}
+					return groups, nil
+				}
+			})
+
+			It("hands the desired and actual lrps to the handler in batches of process guids", func() {
+				Eventually(batchHandler.CompleteBatchSyncCallCount).Should(Equal(1))
+				Expect(batchHandler.SyncBatchCallCount()).To(Equal(2))
+
+				_, desired, actuals := batchHandler.SyncBatchArgsForCall(0)
+				Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo1, schedulingInfo2}))
+				Expect(actuals).To(Equal([]*endpoint.ActualLRPRoutingInfo{
+					endpoint.NewActualLRPRoutingInfo(actualLRPGroup1),
+					endpoint.NewActualLRPRoutingInfo(actualLRPGroup2),
+				}))
+
+				_, desired, actuals = batchHandler.SyncBatchArgsForCall(1)
+				Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo3}))
+				Expect(actuals).To(Equal([]*endpoint.ActualLRPRoutingInfo{
+					endpoint.NewActualLRPRoutingInfo(actualLRPGroup3),
+				}))
+
+				_, domains, _ := batchHandler.CompleteBatchSyncArgsForCall(0)
+				Expect(domains).To(Equal(models.NewDomainSet([]string{"domain"})))
+			})
+
+			It("records every batch", func() {
+				Eventually(batchHandler.CompleteBatchSyncCallCount).Should(Equal(1))
+				Expect(recorder.RecordSyncBatchCallCount()).To(Equal(2))
+				desired, _ := recorder.RecordSyncBatchArgsForCall(1)
+				Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo3}))
+				Expect(recorder.RecordSyncCallCount()).To(Equal(1))
+			})
+
+			It("fetches the desired and actual lrps of each batch by process guid", func() {
+				Eventually(batchHandler.CompleteBatchSyncCallCount).Should(Equal(1))
+				Expect(batchHandler.SyncCallCount()).To(Equal(0))
+
+				Expect(bbsClient.ActualLRPGroupsCallCount()).To(Equal(1))
+				Expect(bbsClient.ActualLRPGroupsByProcessGuidCallCount()).To(Equal(3))
+				_, processGuid := bbsClient.ActualLRPGroupsByProcessGuidArgsForCall(2)
+				Expect(processGuid).To(Equal("pg-3"))
+
+				Expect(bbsClient.DesiredLRPSchedulingInfosCallCount()).To(Equal(3))
+				_, filter := bbsClient.DesiredLRPSchedulingInfosArgsForCall(0)
+				Expect(filter.ProcessGuids).To(BeEmpty())
+				_, filter = bbsClient.DesiredLRPSchedulingInfosArgsForCall(1)
+				Expect(filter.ProcessGuids).To(Equal([]string{"pg-1", "pg-2"}))
+				_, filter = bbsClient.DesiredLRPSchedulingInfosArgsForCall(2)
+				Expect(filter.ProcessGuids).To(Equal([]string{"pg-3"}))
+			})
+
+			Context("when a desired lrp has no running instances", func() {
+				BeforeEach(func() {
+					bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{actualLRPGroup1}, nil)
+					bbsClient.ActualLRPGroupsByProcessGuidStub = func(_ lager.Logger, processGuid string) ([]*models.ActualLRPGroup, error) {
+						if processGuid == "pg-1" {
+							return []*models.ActualLRPGroup{actualLRPGroup1}, nil
+						}
+						return []*models.ActualLRPGroup{}, nil
+					}
+				})
+
+				It("hands its routes to the handler", func() {
+					Eventually(batchHandler.CompleteBatchSyncCallCount).Should(Equal(1))
+					Expect(batchHandler.SyncBatchCallCount()).To(Equal(2))
+
+					_, desired, actuals := batchHandler.SyncBatchArgsForCall(1)
+					Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo3}))
+					Expect(actuals).To(BeEmpty())
+				})
+			})
+
+			It("emits the sync duration", func() {
+				Eventually(batchHandler.CompleteBatchSyncCallCount).Should(Equal(1))
+				Eventually(func() float64 {
+					return fakeMetricSender.GetValue("RouteEmitterSyncDuration").Value
+				}).Should(BeNumerically(">=", 0))
+			})
+
+			Context("when fetching the actual lrps fails", func() {
+				BeforeEach(func() {
+					bbsClient.ActualLRPGroupsReturns(nil, errors.New("bam"))
+				})
+
+				It("aborts the batched sync", func() {
+					Eventually(batchHandler.AbortBatchSyncCallCount).Should(Equal(1))
+					Consistently(batchHandler.CompleteBatchSyncCallCount).Should(Equal(0))
+					Expect(batchHandler.SyncBatchCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when fetching the desired lrps of a batch fails", func() {
+				BeforeEach(func() {
+					bbsClient.DesiredLRPSchedulingInfosStub = func(_ lager.Logger, f models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+						if bbsClient.DesiredLRPSchedulingInfosCallCount() > 2 {
+							return nil, errors.New("bam")
+						}
+						return []*models.DesiredLRPSchedulingInfo{schedulingInfo1, schedulingInfo2}, nil
+					}
+				})
+
+				It("aborts the batched sync without fetching the other batches", func() {
+					Eventually(batchHandler.AbortBatchSyncCallCount).Should(Equal(1))
+					Consistently(batchHandler.CompleteBatchSyncCallCount).Should(Equal(0))
+					Expect(batchHandler.SyncBatchCallCount()).To(Equal(1))
+					Expect(bbsClient.DesiredLRPSchedulingInfosCallCount()).To(Equal(3))
+				})
+			})
+
+			Context("when fetching the actual lrps of a batch fails", func() {
+				BeforeEach(func() {
+					bbsClient.ActualLRPGroupsByProcessGuidStub = nil
+					bbsClient.ActualLRPGroupsByProcessGuidReturns(nil, errors.New("bam"))
+				})
+
+				It("aborts the batched sync without fetching the other batches", func() {
+					Eventually(batchHandler.AbortBatchSyncCallCount).Should(Equal(1))
+					Consistently(batchHandler.CompleteBatchSyncCallCount).Should(Equal(0))
+					Expect(batchHandler.SyncBatchCallCount()).To(Equal(0))
+					Expect(bbsClient.ActualLRPGroupsByProcessGuidCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when the handler cannot sync in batches", func() {
+				BeforeEach(func() {
+					handler = unbatchableHandler{batchHandler}
+				})
+
+				It("syncs everything at once", func() {
+					Eventually(batchHandler.SyncCallCount).Should(Equal(1))
+					Expect(batchHandler.SyncBatchCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the cell id is set", func() {
+				BeforeEach(func() {
+					cellID = "cell-id"
+					bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{actualLRPGroup1}, nil)
+				})
+
+				It("syncs everything at once", func() {
+					Eventually(batchHandler.SyncCallCount).Should(Equal(1))
+					Expect(batchHandler.SyncBatchCallCount()).To(Equal(0))
+					Expect(batchHandler.CompleteBatchSyncCallCount()).To(Equal(0))
+				})
+			})
 		})
 
 		Context("when the cell id is set", func() {
@@ -721,7 +1544,7 @@ var _ = Describe("Watcher", func() {
 				cellID = "cell-id"
 				actualLRPGroup2.Instance.ActualLRPInstanceKey.CellId = cellID
 
-				testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, logger)
+				testWatcher = watcher.NewWatcher(cellID, bbsClient, clock, routeHandler, syncEvents, nil, 0, 0, 0, watcher.ActualLRPEvents{}, nil, logger)
 			})
 
 			Context("when the cell has actual lrps running", func() {
@@ -825,6 +1648,13 @@ var _ = Describe("Watcher", func() {
 
 						Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
 					})
+
+					It("records the refreshed desired lrp", func() {
+						Eventually(recorder.RecordDesiredRefreshCallCount).Should(Equal(1))
+						desired, err := recorder.RecordDesiredRefreshArgsForCall(0)
+						Expect(desired).To(ConsistOf(schedulingInfo3))
+						Expect(err).NotTo(HaveOccurred())
+					})
 				})
 
 				Context("and the event is cached", func() {
@@ -924,3 +1754,44 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 })
+
+type hostSuppressingHandler struct {
+	*fakes.FakeRouteHandler
+	suppressed   chan string
+	unsuppressed chan string
+}
+
+func (h *hostSuppressingHandler) SuppressHost(logger lager.Logger, host string) {
+	h.suppressed <- host
+}
+
+func (h *hostSuppressingHandler) UnsuppressHost(logger lager.Logger, host string) {
+	h.unsuppressed <- host
+}
+
+type syncRequestingHandler struct {
+	*fakes.FakeRouteHandler
+	requests chan struct{}
+}
+
+func (h *syncRequestingHandler) SyncRequests() <-chan struct{} {
+	return h.requests
+}
+
+// unbatchableHandler is a batch route handler that reports it cannot sync in
+// batches, as a MultiHandler with a sub handler that cannot does.
+type unbatchableHandler struct {
+	*fakes.FakeBatchRouteHandler
+}
+
+func (unbatchableHandler) CanSyncInBatches() bool {
+	return false
+}
+
+// eventTimingHandler is a route handler that sends the event handling
+// durations itself, as a MultiHandler does.
+type eventTimingHandler struct {
+	*fakes.FakeRouteHandler
+}
+
+func (eventTimingHandler) TimesEventHandling() {}
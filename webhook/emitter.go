@@ -0,0 +1,310 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/runtimeschema/metric"
+)
+
+const (
+	DefaultBatchSize     = 500
+	DefaultQueueSize     = 10000
+	DefaultFlushInterval = time.Second
+	DefaultMaxRetries    = 3
+	DefaultRetryBackoff  = time.Second
+
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the body, keyed
+	// with the shared secret.
+	SignatureHeader = "X-Route-Emitter-Signature"
+)
+
+var (
+	webhookChangesDropped   = metric.Counter("WebhookRouteChangesDropped")
+	webhookDeliveryFailures = metric.Counter("WebhookDeliveryFailures")
+	webhookPayloadsSent     = metric.Counter("WebhookPayloadsSent")
+)
+
+// Config configures the webhooks route changes are POSTed to.
+type Config struct {
+	URLs              []string              `json:"urls,omitempty"`
+	Secret            string                `json:"secret,omitempty"`
+	BatchSize         int                   `json:"batch_size,omitempty"`
+	QueueSize         int                   `json:"queue_size,omitempty"`
+	FlushInterval     durationjson.Duration `json:"flush_interval,omitempty"`
+	MaxRetries        int                   `json:"max_retries,omitempty"`
+	RetryBackoff      durationjson.Duration `json:"retry_backoff,omitempty"`
+	FullStateInterval durationjson.Duration `json:"full_state_interval,omitempty"`
+	RequestTimeout    durationjson.Duration `json:"request_timeout,omitempty"`
+}
+
+// Enabled reports whether any webhook is configured.
+func (c Config) Enabled() bool {
+	return len(c.URLs) > 0
+}
+
+// Emitter turns the messages handed to the NATS and routing API emitters into
+// route changes, and POSTs them to the webhooks in batches.
+//
+// Changes wait in a bounded queue. When it overflows, or a webhook cannot be
+// reached after all retries, the next payload is a full push instead, so that
+// the webhooks catch up with every change they missed. A full push holds every
+// route the emitter knows of, so the emitter only runs in global mode.
+type Emitter struct {
+	logger     lager.Logger
+	clock      clock.Clock
+	httpClient *http.Client
+
+	urls              []string
+	secret            []byte
+	batchSize         int
+	flushInterval     time.Duration
+	maxRetries        int
+	retryBackoff      time.Duration
+	fullStateInterval time.Duration
+
+	routes   *routes
+	changes  chan RouteChange
+	sequence uint64
+
+	// set by the run loop when it is signalled while retrying
+	stopping bool
+
+	// set when changes were lost on the way to the webhooks
+	needsFullPush int32
+}
+
+func NewEmitter(logger lager.Logger, clock clock.Clock, httpClient *http.Client, config Config) *Emitter {
+	e := &Emitter{
+		logger:            logger.Session("webhook-emitter"),
+		clock:             clock,
+		httpClient:        httpClient,
+		urls:              config.URLs,
+		secret:            []byte(config.Secret),
+		batchSize:         config.BatchSize,
+		flushInterval:     time.Duration(config.FlushInterval),
+		maxRetries:        config.MaxRetries,
+		retryBackoff:      time.Duration(config.RetryBackoff),
+		fullStateInterval: time.Duration(config.FullStateInterval),
+		routes:            newRoutes(),
+	}
+
+	if e.batchSize <= 0 {
+		e.batchSize = DefaultBatchSize
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	e.changes = make(chan RouteChange, queueSize)
+	if e.flushInterval <= 0 {
+		e.flushInterval = DefaultFlushInterval
+	}
+	if e.maxRetries <= 0 {
+		e.maxRetries = DefaultMaxRetries
+	}
+	if e.retryBackoff <= 0 {
+		e.retryBackoff = DefaultRetryBackoff
+	}
+
+	return e
+}
+
+// NATSEmitter returns an emitter that records the route changes in the
+// messages before handing them to next, which may be nil.
+func (e *Emitter) NATSEmitter(next emitter.NATSEmitter) emitter.NATSEmitter {
+	return &natsEmitter{webhook: e, next: next}
+}
+
+// RoutingAPIEmitter returns an emitter that records the route changes in the
+// routing events before handing them to next, which may be nil.
+func (e *Emitter) RoutingAPIEmitter(next emitter.RoutingAPIEmitter) emitter.RoutingAPIEmitter {
+	return &routingAPIEmitter{webhook: e, next: next}
+}
+
+func (e *Emitter) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	e.logger.Info("starting", lager.Data{"urls": e.urls})
+	flushTicker := e.clock.NewTicker(e.flushInterval)
+	defer flushTicker.Stop()
+
+	var fullStateTicks <-chan time.Time
+	if e.fullStateInterval > 0 {
+		fullStateTicker := e.clock.NewTicker(e.fullStateInterval)
+		defer fullStateTicker.Stop()
+		fullStateTicks = fullStateTicker.C()
+	}
+
+	close(ready)
+	e.logger.Info("started")
+
+	batch := []RouteChange{}
+	for !e.stopping {
+		select {
+		case change := <-e.changes:
+			batch = append(batch, change)
+			if len(batch) >= e.batchSize {
+				e.deliver(signals, Payload{Kind: KindDelta, Changes: batch})
+				batch = []RouteChange{}
+			}
+		case <-flushTicker.C():
+			if atomic.CompareAndSwapInt32(&e.needsFullPush, 1, 0) {
+				e.pushFullState(signals)
+				batch = []RouteChange{}
+			} else if len(batch) > 0 {
+				e.deliver(signals, Payload{Kind: KindDelta, Changes: batch})
+				batch = []RouteChange{}
+			}
+		case <-fullStateTicks:
+			atomic.StoreInt32(&e.needsFullPush, 0)
+			e.pushFullState(signals)
+			batch = []RouteChange{}
+		case <-signals:
+			e.stopping = true
+		}
+	}
+
+	e.logger.Info("stopping")
+	return nil
+}
+
+func (e *Emitter) record(changes []RouteChange) {
+	for _, change := range changes {
+		select {
+		case e.changes <- change:
+		default:
+			webhookChangesDropped.Increment()
+			if atomic.CompareAndSwapInt32(&e.needsFullPush, 0, 1) {
+				e.logger.Info("queue-full-falling-back-to-full-push")
+			}
+		}
+	}
+}
+
+// pushFullState sends every route. The changes already in the batch are part
+// of it.
+func (e *Emitter) pushFullState(signals <-chan os.Signal) {
+	e.deliver(signals, Payload{Kind: KindFull, Routes: e.routes.snapshot()})
+}
+
+func (e *Emitter) deliver(signals <-chan os.Signal, payload Payload) {
+	payload.Sequence = atomic.AddUint64(&e.sequence, 1)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		e.logger.Error("failed-to-marshal-payload", err)
+		return
+	}
+
+	for _, url := range e.urls {
+		if e.stopping {
+			return
+		}
+
+		err := e.post(signals, url, body)
+		if err != nil {
+			webhookDeliveryFailures.Increment()
+			e.logger.Error("failed-to-deliver-payload", err, lager.Data{
+				"url":      url,
+				"kind":     payload.Kind,
+				"sequence": payload.Sequence,
+			})
+			// the webhook missed these changes, catch it up with the next
+			// payload
+			atomic.StoreInt32(&e.needsFullPush, 1)
+			continue
+		}
+		webhookPayloadsSent.Increment()
+	}
+}
+
+// post POSTs the body, retrying with exponential backoff. It gives up early
+// when signalled.
+func (e *Emitter) post(signals <-chan os.Signal, url string, body []byte) error {
+	backoff := e.retryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = e.postOnce(url, body)
+		if err == nil || attempt >= e.maxRetries {
+			return err
+		}
+
+		e.logger.Debug("retrying-post", lager.Data{"url": url, "attempt": attempt + 1, "error": err.Error()})
+		timer := e.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-signals:
+			timer.Stop()
+			e.stopping = true
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func (e *Emitter) postOnce(url string, body []byte) error {
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(e.secret) > 0 {
+		request.Header.Set(SignatureHeader, Sign(e.secret, body))
+	}
+
+	response, err := e.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature of the body, as sent in the SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type natsEmitter struct {
+	webhook *Emitter
+	next    emitter.NATSEmitter
+}
+
+func (e *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	e.webhook.record(e.webhook.routes.applyMessages(messagesToEmit))
+	if e.next == nil {
+		return nil
+	}
+	return e.next.Emit(messagesToEmit)
+}
+
+type routingAPIEmitter struct {
+	webhook *Emitter
+	next    emitter.RoutingAPIEmitter
+}
+
+func (e *routingAPIEmitter) Emit(routingEvents event.RoutingEvents) (int, int, error) {
+	e.webhook.record(e.webhook.routes.applyRoutingEvents(routingEvents))
+	if e.next == nil {
+		return 0, 0, nil
+	}
+	return e.next.Emit(routingEvents)
+}
@@ -0,0 +1,287 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/route-emitter/webhook"
+	fake_metrics_sender "github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	"github.com/cloudfoundry/dropsonde/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Emitter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		clock            *fakeclock.FakeClock
+		server           *ghttp.Server
+		config           webhook.Config
+		webhookEmitter   *webhook.Emitter
+		fakeNATSEmitter  *fakes.FakeNATSEmitter
+		delayStart       bool
+		process          ifrit.Process
+		payloads         chan webhook.Payload
+		fakeMetricSender *fake_metrics_sender.FakeMetricSender
+	)
+
+	registration := func(host string, port uint32, uris ...string) routingtable.MessagesToEmit {
+		return routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{{Host: host, Port: port, URIs: uris}},
+		}
+	}
+
+	unregistration := func(host string, port uint32, uris ...string) routingtable.MessagesToEmit {
+		return routingtable.MessagesToEmit{
+			UnregistrationMessages: []routingtable.RegistryMessage{{Host: host, Port: port, URIs: uris}},
+		}
+	}
+
+	recordPayload := func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Header.Get(webhook.SignatureHeader)).To(Equal(webhook.Sign([]byte("secret"), body)))
+
+		var payload webhook.Payload
+		Expect(json.Unmarshal(body, &payload)).To(Succeed())
+		payloads <- payload
+	}
+
+	accept := func() http.HandlerFunc {
+		return ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/routes"),
+			ghttp.VerifyContentType("application/json"),
+			recordPayload,
+		)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		server = ghttp.NewServer()
+		server.SetAllowUnhandledRequests(false)
+		payloads = make(chan webhook.Payload, 10)
+		fakeNATSEmitter = &fakes.FakeNATSEmitter{}
+		delayStart = false
+
+		fakeMetricSender = fake_metrics_sender.NewFakeMetricSender()
+		metrics.Initialize(fakeMetricSender, nil)
+
+		config = webhook.Config{
+			URLs:          []string{server.URL() + "/routes"},
+			Secret:        "secret",
+			FlushInterval: durationjson.Duration(time.Second),
+			RetryBackoff:  durationjson.Duration(time.Second),
+			MaxRetries:    2,
+		}
+	})
+
+	JustBeforeEach(func() {
+		webhookEmitter = webhook.NewEmitter(logger, clock, http.DefaultClient, config)
+		if !delayStart {
+			process = ifrit.Invoke(webhookEmitter)
+		}
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		server.Close()
+	})
+
+	It("hands the messages to the next emitter", func() {
+		messages := registration("1.1.1.1", 61000, "a.example.com")
+		Expect(webhookEmitter.NATSEmitter(fakeNATSEmitter).Emit(messages)).To(Succeed())
+		Expect(fakeNATSEmitter.EmitCallCount()).To(Equal(1))
+		Expect(fakeNATSEmitter.EmitArgsForCall(0)).To(Equal(messages))
+	})
+
+	It("POSTs the changes in a signed delta on every flush", func() {
+		server.AppendHandlers(accept())
+
+		e := webhookEmitter.NATSEmitter(nil)
+		Expect(e.Emit(registration("1.1.1.1", 61000, "a.example.com", "b.example.com"))).To(Succeed())
+		Expect(e.Emit(unregistration("1.1.1.1", 61000, "b.example.com"))).To(Succeed())
+
+		clock.WaitForWatcherAndIncrement(time.Second)
+
+		var payload webhook.Payload
+		Eventually(payloads).Should(Receive(&payload))
+		Expect(payload.Kind).To(Equal(webhook.KindDelta))
+		Expect(payload.Sequence).To(BeEquivalentTo(1))
+		Expect(payload.Changes).To(Equal([]webhook.RouteChange{
+			{Protocol: webhook.ProtocolHTTP, Route: "a.example.com", Backend: "1.1.1.1:61000", Action: webhook.ActionAdd},
+			{Protocol: webhook.ProtocolHTTP, Route: "b.example.com", Backend: "1.1.1.1:61000", Action: webhook.ActionAdd},
+			{Protocol: webhook.ProtocolHTTP, Route: "b.example.com", Backend: "1.1.1.1:61000", Action: webhook.ActionRemove},
+		}))
+	})
+
+	It("does not POST routes that are emitted again unchanged", func() {
+		server.AppendHandlers(accept())
+
+		e := webhookEmitter.NATSEmitter(nil)
+		Expect(e.Emit(registration("1.1.1.1", 61000, "a.example.com"))).To(Succeed())
+		clock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(payloads).Should(Receive())
+
+		Expect(e.Emit(registration("1.1.1.1", 61000, "a.example.com"))).To(Succeed())
+		clock.Increment(time.Second)
+		Consistently(payloads).ShouldNot(Receive())
+	})
+
+	It("records the changes in the routing events", func() {
+		server.AppendHandlers(accept())
+
+		routingEvents := event.RoutingEvents{
+			{
+				EventType: event.RouteRegistrationEvent,
+				Entry: endpoint.RoutableEndpoints{
+					ExternalEndpoints: endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 5222)},
+					Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+						endpoint.EndpointKey{InstanceGUID: "ig-1"}: {InstanceGUID: "ig-1", Host: "1.1.1.1", Port: 61000},
+					},
+				},
+			},
+		}
+		fakeRoutingAPIEmitter := &fakes.FakeRoutingAPIEmitter{}
+		_, _, err := webhookEmitter.RoutingAPIEmitter(fakeRoutingAPIEmitter).Emit(routingEvents)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
+
+		clock.WaitForWatcherAndIncrement(time.Second)
+
+		var payload webhook.Payload
+		Eventually(payloads).Should(Receive(&payload))
+		Expect(payload.Changes).To(Equal([]webhook.RouteChange{
+			{Protocol: webhook.ProtocolTCP, Route: "router-group:5222", Backend: "1.1.1.1:61000", Action: webhook.ActionAdd},
+		}))
+	})
+
+	Context("when the batch is full", func() {
+		BeforeEach(func() {
+			config.BatchSize = 2
+		})
+
+		It("POSTs it without waiting for the flush", func() {
+			server.AppendHandlers(accept())
+
+			Expect(webhookEmitter.NATSEmitter(nil).Emit(registration("1.1.1.1", 61000, "a.example.com", "b.example.com"))).To(Succeed())
+
+			var payload webhook.Payload
+			Eventually(payloads).Should(Receive(&payload))
+			Expect(payload.Changes).To(HaveLen(2))
+		})
+	})
+
+	Context("when a full state interval is configured", func() {
+		BeforeEach(func() {
+			config.FullStateInterval = durationjson.Duration(time.Minute)
+			config.FlushInterval = durationjson.Duration(time.Hour)
+		})
+
+		It("periodically POSTs every route", func() {
+			server.AppendHandlers(accept())
+
+			e := webhookEmitter.NATSEmitter(nil)
+			Expect(e.Emit(registration("1.1.1.2", 61000, "a.example.com"))).To(Succeed())
+			Expect(e.Emit(registration("1.1.1.1", 61000, "a.example.com"))).To(Succeed())
+
+			clock.WaitForNWatchersAndIncrement(time.Minute, 2)
+
+			var payload webhook.Payload
+			Eventually(payloads).Should(Receive(&payload))
+			Expect(payload.Kind).To(Equal(webhook.KindFull))
+			Expect(payload.Routes).To(Equal([]webhook.Route{
+				{Protocol: webhook.ProtocolHTTP, Route: "a.example.com", Backends: []string{"1.1.1.1:61000", "1.1.1.2:61000"}},
+			}))
+		})
+	})
+
+	Context("when the webhook fails", func() {
+		It("retries with backoff", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusInternalServerError, nil),
+				ghttp.RespondWith(http.StatusInternalServerError, nil),
+				accept(),
+			)
+
+			Expect(webhookEmitter.NATSEmitter(nil).Emit(registration("1.1.1.1", 61000, "a.example.com"))).To(Succeed())
+			clock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(server.ReceivedRequests).Should(HaveLen(1))
+			clock.WaitForNWatchersAndIncrement(time.Second, 2)
+			Eventually(server.ReceivedRequests).Should(HaveLen(2))
+			clock.WaitForNWatchersAndIncrement(2*time.Second, 2)
+
+			var payload webhook.Payload
+			Eventually(payloads).Should(Receive(&payload))
+			Expect(payload.Kind).To(Equal(webhook.KindDelta))
+		})
+
+		It("catches the webhook up with a full push once it gives up", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusInternalServerError, nil),
+				ghttp.RespondWith(http.StatusInternalServerError, nil),
+				ghttp.RespondWith(http.StatusInternalServerError, nil),
+				accept(),
+			)
+
+			Expect(webhookEmitter.NATSEmitter(nil).Emit(registration("1.1.1.1", 61000, "a.example.com"))).To(Succeed())
+			clock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(server.ReceivedRequests).Should(HaveLen(1))
+			clock.WaitForNWatchersAndIncrement(time.Second, 2)
+			Eventually(server.ReceivedRequests).Should(HaveLen(2))
+			clock.WaitForNWatchersAndIncrement(2*time.Second, 2)
+			Eventually(server.ReceivedRequests).Should(HaveLen(3))
+			Eventually(logger).Should(gbytes.Say("failed-to-deliver-payload"))
+			Eventually(func() uint64 {
+				return fakeMetricSender.GetCounter("WebhookDeliveryFailures")
+			}).Should(BeEquivalentTo(1))
+
+			clock.WaitForWatcherAndIncrement(time.Second)
+
+			var payload webhook.Payload
+			Eventually(payloads).Should(Receive(&payload))
+			Expect(payload.Kind).To(Equal(webhook.KindFull))
+			Expect(payload.Routes).To(HaveLen(1))
+		})
+	})
+
+	Context("when the queue overflows", func() {
+		BeforeEach(func() {
+			config.QueueSize = 1
+			config.BatchSize = 10
+			delayStart = true
+		})
+
+		It("drops the changes and falls back to a full push", func() {
+			server.AppendHandlers(accept())
+
+			Expect(webhookEmitter.NATSEmitter(nil).Emit(registration("1.1.1.1", 61000, "a.example.com", "b.example.com", "c.example.com"))).To(Succeed())
+			Expect(fakeMetricSender.GetCounter("WebhookRouteChangesDropped")).To(BeEquivalentTo(2))
+			Expect(logger).To(gbytes.Say("queue-full-falling-back-to-full-push"))
+
+			process = ifrit.Invoke(webhookEmitter)
+
+			clock.WaitForWatcherAndIncrement(time.Second)
+
+			var payload webhook.Payload
+			Eventually(payloads).Should(Receive(&payload))
+			Expect(payload.Kind).To(Equal(webhook.KindFull))
+			Expect(payload.Routes).To(HaveLen(3))
+		})
+	})
+})
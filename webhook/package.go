@@ -0,0 +1 @@
+package webhook // import "code.cloudfoundry.org/route-emitter/webhook"
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+)
+
+const (
+	ProtocolHTTP = "http"
+	ProtocolTCP  = "tcp"
+
+	ActionAdd    = "add"
+	ActionRemove = "remove"
+)
+
+// RouteChange is a backend gained or lost by a route. HTTP routes are
+// hostnames, TCP routes are router group guids and ports.
+type RouteChange struct {
+	Protocol string `json:"protocol"`
+	Route    string `json:"route"`
+	Backend  string `json:"backend"`
+	Action   string `json:"action"`
+}
+
+// Route is a route and all of its backends.
+type Route struct {
+	Protocol string   `json:"protocol"`
+	Route    string   `json:"route"`
+	Backends []string `json:"backends"`
+}
+
+const (
+	KindDelta = "delta"
+	KindFull  = "full"
+)
+
+// Payload is the body POSTed to the webhooks. Deltas carry the changes since
+// the previous payload, full pushes carry every route, so that receivers can
+// reconcile. The sequence increases with every payload.
+type Payload struct {
+	Kind     string        `json:"kind"`
+	Sequence uint64        `json:"sequence"`
+	Changes  []RouteChange `json:"changes,omitempty"`
+	Routes   []Route       `json:"routes,omitempty"`
+}
+
+type routeKey struct {
+	protocol string
+	route    string
+}
+
+// routes is the state of every route as seen through the emitted messages.
+// The emitters re-emit unchanged routes all the time, so it is what turns
+// their messages into changes.
+type routes struct {
+	lock     sync.Mutex
+	backends map[routeKey]map[string]struct{}
+}
+
+func newRoutes() *routes {
+	return &routes{
+		backends: map[routeKey]map[string]struct{}{},
+	}
+}
+
+func (r *routes) applyMessages(messagesToEmit routingtable.MessagesToEmit) []RouteChange {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	changes := []RouteChange{}
+	for _, message := range messagesToEmit.RegistrationMessages {
+		backend := fmt.Sprintf("%s:%d", message.Host, message.Port)
+		for _, uri := range message.URIs {
+			changes = r.add(changes, routeKey{ProtocolHTTP, uri}, backend)
+		}
+	}
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		backend := fmt.Sprintf("%s:%d", message.Host, message.Port)
+		for _, uri := range message.URIs {
+			changes = r.remove(changes, routeKey{ProtocolHTTP, uri}, backend)
+		}
+	}
+	return changes
+}
+
+func (r *routes) applyRoutingEvents(routingEvents event.RoutingEvents) []RouteChange {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	changes := []RouteChange{}
+	for _, routingEvent := range routingEvents {
+		for _, external := range routingEvent.Entry.ExternalEndpoints {
+			key := routeKey{ProtocolTCP, fmt.Sprintf("%s:%d", external.RouterGroupGUID, external.Port)}
+			for _, e := range routingEvent.Entry.Endpoints {
+				backend := fmt.Sprintf("%s:%d", e.Host, e.Port)
+				switch routingEvent.EventType {
+				case event.RouteRegistrationEvent:
+					changes = r.add(changes, key, backend)
+				case event.RouteUnregistrationEvent:
+					changes = r.remove(changes, key, backend)
+				}
+			}
+		}
+	}
+	return changes
+}
+
+func (r *routes) add(changes []RouteChange, key routeKey, backend string) []RouteChange {
+	if _, ok := r.backends[key][backend]; ok {
+		return changes
+	}
+	if r.backends[key] == nil {
+		r.backends[key] = map[string]struct{}{}
+	}
+	r.backends[key][backend] = struct{}{}
+	return append(changes, RouteChange{Protocol: key.protocol, Route: key.route, Backend: backend, Action: ActionAdd})
+}
+
+func (r *routes) remove(changes []RouteChange, key routeKey, backend string) []RouteChange {
+	if _, ok := r.backends[key][backend]; !ok {
+		return changes
+	}
+	delete(r.backends[key], backend)
+	if len(r.backends[key]) == 0 {
+		delete(r.backends, key)
+	}
+	return append(changes, RouteChange{Protocol: key.protocol, Route: key.route, Backend: backend, Action: ActionRemove})
+}
+
+// snapshot returns every route with its backends, in order.
+func (r *routes) snapshot() []Route {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	all := make([]Route, 0, len(r.backends))
+	for key, backends := range r.backends {
+		route := Route{Protocol: key.protocol, Route: key.route, Backends: make([]string, 0, len(backends))}
+		for backend := range backends {
+			route.Backends = append(route.Backends, backend)
+		}
+		sort.Strings(route.Backends)
+		all = append(all, route)
+	}
+	sort.Sort(byRoute(all))
+	return all
+}
+
+type byRoute []Route
+
+func (s byRoute) Len() int      { return len(s) }
+func (s byRoute) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byRoute) Less(i, j int) bool {
+	if s[i].Protocol != s[j].Protocol {
+		return s[i].Protocol < s[j].Protocol
+	}
+	return s[i].Route < s[j].Route
+}
@@ -0,0 +1,337 @@
+package xds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxyv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+)
+
+const (
+	// HTTPListenerName names both the HTTP listener and its route
+	// configuration.
+	HTTPListenerName = "http"
+
+	clusterConnectTimeout = 5 * time.Second
+)
+
+// HTTPClusterName is the cluster of the backends of an HTTP route, which is
+// a hostname optionally followed by a path.
+func HTTPClusterName(route string) string {
+	return "http:" + route
+}
+
+// TCPClusterName is the cluster of the backends of a TCP external port.
+func TCPClusterName(port uint32) string {
+	return fmt.Sprintf("tcp:%d", port)
+}
+
+// TCPListenerName is the listener of a TCP external port.
+func TCPListenerName(port uint32) string {
+	return fmt.Sprintf("tcp:%d", port)
+}
+
+// BuildResources turns the registrations of the HTTP and TCP routing tables
+// into Envoy resources. Every HTTP route gets an EDS cluster, and a route in
+// the virtual host of its hostname on a single listener on httpListenerPort.
+// Every TCP external port gets an EDS cluster and a TCP proxy listener.
+// Router groups are not distinguished, so the backends of an external port
+// are merged across them.
+//
+// No HTTP listener is built when httpListenerPort is 0. All resources refer
+// to each other through ADS.
+func BuildResources(
+	messagesToEmit routingtable.MessagesToEmit,
+	routingEvents event.RoutingEvents,
+	httpListenerPort uint32,
+) (map[resource.Type][]types.Resource, error) {
+	resources := map[resource.Type][]types.Resource{
+		resource.ClusterType:  {},
+		resource.EndpointType: {},
+		resource.RouteType:    {},
+		resource.ListenerType: {},
+	}
+
+	if httpListenerPort != 0 {
+		httpBackends := backends{}
+		for _, message := range messagesToEmit.RegistrationMessages {
+			for _, uri := range message.URIs {
+				httpBackends.add(uri, message.Host, message.Port)
+			}
+		}
+
+		for _, route := range httpBackends.keys() {
+			name := HTTPClusterName(route)
+			resources[resource.ClusterType] = append(resources[resource.ClusterType], edsCluster(name))
+			resources[resource.EndpointType] = append(resources[resource.EndpointType], loadAssignment(name, httpBackends[route]))
+		}
+
+		resources[resource.RouteType] = append(resources[resource.RouteType], routeConfiguration(httpBackends.keys()))
+
+		listener, err := httpListener(httpListenerPort)
+		if err != nil {
+			return nil, err
+		}
+		resources[resource.ListenerType] = append(resources[resource.ListenerType], listener)
+	}
+
+	tcpBackends := map[uint32]map[address]struct{}{}
+	for _, routingEvent := range routingEvents {
+		if routingEvent.EventType != event.RouteRegistrationEvent {
+			continue
+		}
+		for _, external := range routingEvent.Entry.ExternalEndpoints {
+			if tcpBackends[external.Port] == nil {
+				tcpBackends[external.Port] = map[address]struct{}{}
+			}
+			for _, e := range routingEvent.Entry.Endpoints {
+				tcpBackends[external.Port][address{e.Host, e.Port}] = struct{}{}
+			}
+		}
+	}
+
+	ports := make([]uint32, 0, len(tcpBackends))
+	for port := range tcpBackends {
+		ports = append(ports, port)
+	}
+	sort.Sort(byPort(ports))
+
+	for _, port := range ports {
+		name := TCPClusterName(port)
+		resources[resource.ClusterType] = append(resources[resource.ClusterType], edsCluster(name))
+		resources[resource.EndpointType] = append(resources[resource.EndpointType], loadAssignment(name, tcpBackends[port]))
+
+		listener, err := tcpListener(port, name)
+		if err != nil {
+			return nil, err
+		}
+		resources[resource.ListenerType] = append(resources[resource.ListenerType], listener)
+	}
+
+	return resources, nil
+}
+
+type address struct {
+	host string
+	port uint32
+}
+
+// backends are the distinct addresses of each route.
+type backends map[string]map[address]struct{}
+
+func (b backends) add(key, host string, port uint32) {
+	if b[key] == nil {
+		b[key] = map[address]struct{}{}
+	}
+	b[key][address{host, port}] = struct{}{}
+}
+
+func (b backends) keys() []string {
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func adsConfigSource() *corev3.ConfigSource {
+	return &corev3.ConfigSource{
+		ResourceApiVersion:    corev3.ApiVersion_V3,
+		ConfigSourceSpecifier: &corev3.ConfigSource_Ads{Ads: &corev3.AggregatedConfigSource{}},
+	}
+}
+
+func socketAddress(host string, port uint32) *corev3.Address {
+	return &corev3.Address{
+		Address: &corev3.Address_SocketAddress{
+			SocketAddress: &corev3.SocketAddress{
+				Protocol:      corev3.SocketAddress_TCP,
+				Address:       host,
+				PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: port},
+			},
+		},
+	}
+}
+
+func edsCluster(name string) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:                 name,
+		ConnectTimeout:       ptypes.DurationProto(clusterConnectTimeout),
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		EdsClusterConfig:     &clusterv3.Cluster_EdsClusterConfig{EdsConfig: adsConfigSource()},
+	}
+}
+
+func loadAssignment(clusterName string, addresses map[address]struct{}) *endpointv3.ClusterLoadAssignment {
+	sorted := make([]address, 0, len(addresses))
+	for a := range addresses {
+		sorted = append(sorted, a)
+	}
+	sort.Sort(byAddress(sorted))
+
+	lbEndpoints := make([]*endpointv3.LbEndpoint, 0, len(sorted))
+	for _, a := range sorted {
+		lbEndpoints = append(lbEndpoints, &endpointv3.LbEndpoint{
+			HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+				Endpoint: &endpointv3.Endpoint{Address: socketAddress(a.host, a.port)},
+			},
+		})
+	}
+
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: clusterName,
+		Endpoints:   []*endpointv3.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}},
+	}
+}
+
+// routeConfiguration puts the routes in the virtual host of their hostname,
+// longest paths first so that they take precedence.
+func routeConfiguration(routes []string) *routev3.RouteConfiguration {
+	paths := map[string][]string{}
+	for _, route := range routes {
+		hostname, path := splitRoute(route)
+		paths[hostname] = append(paths[hostname], path)
+	}
+
+	hostnames := make([]string, 0, len(paths))
+	for hostname := range paths {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	virtualHosts := make([]*routev3.VirtualHost, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		sort.Sort(byPathLength(paths[hostname]))
+
+		virtualHost := &routev3.VirtualHost{
+			Name:    hostname,
+			Domains: []string{hostname},
+		}
+		for _, path := range paths[hostname] {
+			route := hostname
+			if path != "/" {
+				route += path
+			}
+			virtualHost.Routes = append(virtualHost.Routes, &routev3.Route{
+				Match: &routev3.RouteMatch{
+					PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: path},
+				},
+				Action: &routev3.Route_Route{
+					Route: &routev3.RouteAction{
+						ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: HTTPClusterName(route)},
+					},
+				},
+			})
+		}
+		virtualHosts = append(virtualHosts, virtualHost)
+	}
+
+	return &routev3.RouteConfiguration{
+		Name:         HTTPListenerName,
+		VirtualHosts: virtualHosts,
+	}
+}
+
+func splitRoute(route string) (string, string) {
+	i := strings.Index(route, "/")
+	if i < 0 {
+		return route, "/"
+	}
+	return route[:i], route[i:]
+}
+
+func httpListener(port uint32) (*listenerv3.Listener, error) {
+	router, err := ptypes.MarshalAny(&routerv3.Router{})
+	if err != nil {
+		return nil, err
+	}
+
+	manager := &hcmv3.HttpConnectionManager{
+		StatPrefix: HTTPListenerName,
+		CodecType:  hcmv3.HttpConnectionManager_AUTO,
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				ConfigSource:    adsConfigSource(),
+				RouteConfigName: HTTPListenerName,
+			},
+		},
+		HttpFilters: []*hcmv3.HttpFilter{{
+			Name:       wellknown.Router,
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{TypedConfig: router},
+		}},
+	}
+
+	return listener(HTTPListenerName, port, wellknown.HTTPConnectionManager, manager)
+}
+
+func tcpListener(port uint32, clusterName string) (*listenerv3.Listener, error) {
+	tcpProxy := &tcpproxyv3.TcpProxy{
+		StatPrefix:       clusterName,
+		ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{Cluster: clusterName},
+	}
+
+	return listener(TCPListenerName(port), port, wellknown.TCPProxy, tcpProxy)
+}
+
+func listener(name string, port uint32, filterName string, filterConfig proto.Message) (*listenerv3.Listener, error) {
+	typedConfig, err := ptypes.MarshalAny(filterConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listenerv3.Listener{
+		Name:    name,
+		Address: socketAddress("0.0.0.0", port),
+		FilterChains: []*listenerv3.FilterChain{{
+			Filters: []*listenerv3.Filter{{
+				Name:       filterName,
+				ConfigType: &listenerv3.Filter_TypedConfig{TypedConfig: typedConfig},
+			}},
+		}},
+	}, nil
+}
+
+type byPort []uint32
+
+func (s byPort) Len() int           { return len(s) }
+func (s byPort) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byPort) Less(i, j int) bool { return s[i] < s[j] }
+
+type byAddress []address
+
+func (s byAddress) Len() int      { return len(s) }
+func (s byAddress) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byAddress) Less(i, j int) bool {
+	if s[i].host != s[j].host {
+		return s[i].host < s[j].host
+	}
+	return s[i].port < s[j].port
+}
+
+type byPathLength []string
+
+func (s byPathLength) Len() int      { return len(s) }
+func (s byPathLength) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPathLength) Less(i, j int) bool {
+	if len(s[i]) != len(s[j]) {
+		return len(s[i]) > len(s[j])
+	}
+	return s[i] < s[j]
+}
@@ -0,0 +1,131 @@
+package xds_test
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/route-emitter/xds"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BuildResources", func() {
+	var (
+		messagesToEmit routingtable.MessagesToEmit
+		routingEvents  event.RoutingEvents
+		resources      map[resource.Type][]types.Resource
+	)
+
+	names := func(resources []types.Resource) []string {
+		names := []string{}
+		for _, r := range resources {
+			switch r := r.(type) {
+			case *clusterv3.Cluster:
+				names = append(names, r.Name)
+			case *endpointv3.ClusterLoadAssignment:
+				names = append(names, r.ClusterName)
+			case *routev3.RouteConfiguration:
+				names = append(names, r.Name)
+			case *listenerv3.Listener:
+				names = append(names, r.Name)
+			}
+		}
+		return names
+	}
+
+	addresses := func(assignment *endpointv3.ClusterLoadAssignment) []string {
+		addresses := []string{}
+		for _, locality := range assignment.Endpoints {
+			for _, lbEndpoint := range locality.LbEndpoints {
+				socketAddress := lbEndpoint.GetEndpoint().Address.GetSocketAddress()
+				addresses = append(addresses, fmt.Sprintf("%s:%d", socketAddress.Address, socketAddress.GetPortValue()))
+			}
+		}
+		return addresses
+	}
+
+	BeforeEach(func() {
+		messagesToEmit = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.2", Port: 2, URIs: []string{"a.example.com", "a.example.com/api"}},
+				{Host: "1.1.1.1", Port: 1, URIs: []string{"a.example.com"}},
+				{Host: "1.1.1.3", Port: 3, URIs: []string{"b.example.com"}},
+			},
+		}
+		routingEvents = event.RoutingEvents{
+			{
+				EventType: event.RouteRegistrationEvent,
+				Entry: endpoint.RoutableEndpoints{
+					ExternalEndpoints: endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 5222)},
+					Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+						endpoint.EndpointKey{InstanceGUID: "ig-1"}: {InstanceGUID: "ig-1", Host: "1.1.1.4", Port: 4},
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		resources, err = xds.BuildResources(messagesToEmit, routingEvents, 8080)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("builds a cluster and load assignment for every HTTP route and TCP port", func() {
+		expected := []string{"http:a.example.com", "http:a.example.com/api", "http:b.example.com", "tcp:5222"}
+		Expect(names(resources[resource.ClusterType])).To(Equal(expected))
+		Expect(names(resources[resource.EndpointType])).To(Equal(expected))
+	})
+
+	It("assigns the backends of each route to its cluster", func() {
+		assignment := resources[resource.EndpointType][0].(*endpointv3.ClusterLoadAssignment)
+		Expect(addresses(assignment)).To(Equal([]string{"1.1.1.1:1", "1.1.1.2:2"}))
+
+		assignment = resources[resource.EndpointType][3].(*endpointv3.ClusterLoadAssignment)
+		Expect(addresses(assignment)).To(Equal([]string{"1.1.1.4:4"}))
+	})
+
+	It("routes every hostname to its clusters, longest paths first", func() {
+		Expect(resources[resource.RouteType]).To(HaveLen(1))
+		routeConfiguration := resources[resource.RouteType][0].(*routev3.RouteConfiguration)
+		Expect(routeConfiguration.Name).To(Equal(xds.HTTPListenerName))
+		Expect(routeConfiguration.VirtualHosts).To(HaveLen(2))
+
+		virtualHost := routeConfiguration.VirtualHosts[0]
+		Expect(virtualHost.Domains).To(Equal([]string{"a.example.com"}))
+		Expect(virtualHost.Routes).To(HaveLen(2))
+		Expect(virtualHost.Routes[0].Match.GetPrefix()).To(Equal("/api"))
+		Expect(virtualHost.Routes[0].GetRoute().GetCluster()).To(Equal("http:a.example.com/api"))
+		Expect(virtualHost.Routes[1].Match.GetPrefix()).To(Equal("/"))
+		Expect(virtualHost.Routes[1].GetRoute().GetCluster()).To(Equal("http:a.example.com"))
+	})
+
+	It("builds the HTTP listener and a listener for every TCP port", func() {
+		Expect(names(resources[resource.ListenerType])).To(Equal([]string{xds.HTTPListenerName, "tcp:5222"}))
+
+		tcpListener := resources[resource.ListenerType][1].(*listenerv3.Listener)
+		Expect(tcpListener.Address.GetSocketAddress().GetPortValue()).To(BeEquivalentTo(5222))
+	})
+
+	Context("when there are no routes", func() {
+		BeforeEach(func() {
+			messagesToEmit = routingtable.MessagesToEmit{}
+			routingEvents = nil
+		})
+
+		It("still builds the HTTP listener and its route configuration", func() {
+			Expect(resources[resource.ClusterType]).To(BeEmpty())
+			Expect(names(resources[resource.RouteType])).To(Equal([]string{xds.HTTPListenerName}))
+			Expect(names(resources[resource.ListenerType])).To(Equal([]string{xds.HTTPListenerName}))
+		})
+	})
+})
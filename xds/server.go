@@ -0,0 +1,196 @@
+package xds
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/route-emitter/xds/xdsconfig"
+	"code.cloudfoundry.org/runtimeschema/metric"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+)
+
+var xdsSnapshotVersion = metric.Metric("XDSSnapshotVersion")
+
+// every Envoy gets the same resources
+const snapshotKey = "route-emitter"
+
+type allNodes struct{}
+
+func (allNodes) ID(*corev3.Node) string {
+	return snapshotKey
+}
+
+// Server serves the routing tables to Envoy over the state-of-the-world
+// aggregated discovery service, ADS, as the clusters and listeners it serves
+// refer to their endpoints and routes through ADS. The CDS, EDS, RDS and LDS
+// services answer from the same snapshots, but in ADS mode a request naming
+// only some of the endpoints or routes waits until it names all of them. The
+// version of the resources is bumped with every mutation of the tables, and
+// the resources are rebuilt from the tables as soon as the server catches up.
+type Server struct {
+	logger           lager.Logger
+	listenAddress    string
+	httpListenerPort uint32
+	cache            cachev3.SnapshotCache
+
+	version uint64
+	changed chan struct{}
+
+	lock      sync.Mutex
+	natsTable routingtable.NATSRoutingTable
+	tcpTable  routingtable.TCPRoutingTable
+}
+
+func NewServer(logger lager.Logger, config xdsconfig.Config) *Server {
+	httpListenerPort := config.HTTPListenerPort
+	if httpListenerPort == 0 {
+		httpListenerPort = xdsconfig.DefaultHTTPListenerPort
+	}
+
+	return &Server{
+		logger:           logger.Session("xds-server"),
+		listenAddress:    config.ListenAddress,
+		httpListenerPort: httpListenerPort,
+		cache:            cachev3.NewSnapshotCache(true, allNodes{}, nil),
+		changed:          make(chan struct{}, 1),
+	}
+}
+
+// NATSTable serves the HTTP routes of the table, and returns the table the
+// emitter has to mutate for the server to notice.
+func (s *Server) NATSTable(table routingtable.NATSRoutingTable) routingtable.NATSRoutingTable {
+	s.lock.Lock()
+	s.natsTable = table
+	s.lock.Unlock()
+
+	s.tableChanged()
+	return routingtable.NewObservedNATSTable(table, s.tableChanged)
+}
+
+// TCPTable serves the TCP routes of the table, and returns the table the
+// emitter has to mutate for the server to notice.
+func (s *Server) TCPTable(table routingtable.TCPRoutingTable) routingtable.TCPRoutingTable {
+	s.lock.Lock()
+	s.tcpTable = table
+	s.lock.Unlock()
+
+	s.tableChanged()
+	return routingtable.NewObservedTCPTable(table, s.tableChanged)
+}
+
+// Version returns the version of the resources.
+func (s *Server) Version() string {
+	return strconv.FormatUint(atomic.LoadUint64(&s.version), 10)
+}
+
+func (s *Server) tableChanged() {
+	atomic.AddUint64(&s.version, 1)
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := s.logger.Session("run", lager.Data{"listen-address": s.listenAddress})
+	logger.Info("starting")
+
+	listener, err := net.Listen("tcp", s.listenAddress)
+	if err != nil {
+		logger.Error("failed-to-listen", err)
+		return err
+	}
+
+	grpcServer := grpc.NewServer()
+	xdsServer := serverv3.NewServer(context.Background(), s.cache, nil)
+	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
+	clusterservice.RegisterClusterDiscoveryServiceServer(grpcServer, xdsServer)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(grpcServer, xdsServer)
+	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, xdsServer)
+	listenerservice.RegisterListenerDiscoveryServiceServer(grpcServer, xdsServer)
+
+	s.publish(logger)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- grpcServer.Serve(listener)
+	}()
+
+	close(ready)
+	logger.Info("started")
+
+	for {
+		select {
+		case <-s.changed:
+			s.publish(logger)
+		case err := <-errChan:
+			logger.Error("failed-to-serve", err)
+			return err
+		case <-signals:
+			logger.Info("stopping")
+			// the xDS streams never end, so there is no point in waiting for
+			// them
+			grpcServer.Stop()
+			return nil
+		}
+	}
+}
+
+// publish rebuilds the resources from the tables, under the latest version.
+func (s *Server) publish(logger lager.Logger) {
+	s.lock.Lock()
+	natsTable, tcpTable := s.natsTable, s.tcpTable
+	s.lock.Unlock()
+
+	version := s.Version()
+
+	var httpListenerPort uint32
+	messagesToEmit := routingtable.MessagesToEmit{}
+	if natsTable != nil {
+		httpListenerPort = s.httpListenerPort
+		messagesToEmit = natsTable.MessagesToEmit()
+	}
+	routingEvents := event.RoutingEvents{}
+	if tcpTable != nil {
+		routingEvents = tcpTable.GetRoutingEvents()
+	}
+
+	resources, err := BuildResources(messagesToEmit, routingEvents, httpListenerPort)
+	if err != nil {
+		logger.Error("failed-to-build-resources", err, lager.Data{"version": version})
+		return
+	}
+
+	snapshot, err := cachev3.NewSnapshot(version, resources)
+	if err != nil {
+		logger.Error("failed-to-create-snapshot", err, lager.Data{"version": version})
+		return
+	}
+
+	err = s.cache.SetSnapshot(context.Background(), snapshotKey, snapshot)
+	if err != nil {
+		logger.Error("failed-to-set-snapshot", err, lager.Data{"version": version})
+		return
+	}
+
+	logger.Debug("published-snapshot", lager.Data{"version": version})
+	err = xdsSnapshotVersion.Send(int(atomic.LoadUint64(&s.version)))
+	if err != nil {
+		logger.Error("failed-to-send-xds-snapshot-version-metric", err)
+	}
+}
@@ -0,0 +1,171 @@
+package xds_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/endpoint"
+	"code.cloudfoundry.org/route-emitter/routingtable/schema/event"
+	"code.cloudfoundry.org/route-emitter/xds"
+	"code.cloudfoundry.org/route-emitter/xds/xdsconfig"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/tedsuo/ifrit"
+	"google.golang.org/grpc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		logger        *lagertest.TestLogger
+		listenAddress string
+		server        *xds.Server
+		natsTable     routingtable.NATSRoutingTable
+		fakeTCPTable  *fakeroutingtable.FakeTCPRoutingTable
+		process       ifrit.Process
+
+		conn   *grpc.ClientConn
+		cancel context.CancelFunc
+		stream discoverygrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	)
+
+	key := endpoint.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+
+	request := func(typeURL string, previous *discoverygrpc.DiscoveryResponse) {
+		discoveryRequest := &discoverygrpc.DiscoveryRequest{
+			Node:    &corev3.Node{Id: "envoy"},
+			TypeUrl: typeURL,
+		}
+		if previous != nil {
+			discoveryRequest.VersionInfo = previous.VersionInfo
+			discoveryRequest.ResponseNonce = previous.Nonce
+		}
+		Expect(stream.Send(discoveryRequest)).To(Succeed())
+	}
+
+	clusterNames := func(response *discoverygrpc.DiscoveryResponse) []string {
+		names := []string{}
+		for _, r := range response.Resources {
+			var cluster clusterv3.Cluster
+			Expect(ptypes.UnmarshalAny(r, &cluster)).To(Succeed())
+			names = append(names, cluster.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		listenAddress = fmt.Sprintf("127.0.0.1:%d", 18000+GinkgoParallelNode())
+		server = xds.NewServer(logger, xdsconfig.Config{ListenAddress: listenAddress})
+
+		natsTable = server.NATSTable(routingtable.NewNATSTable(logger))
+		natsTable.SetRoutes(key, []routingtable.Route{{Hostname: "a.example.com"}}, nil)
+		natsTable.AddEndpoint(key, routingtable.Endpoint{InstanceGuid: "ig-1", Host: "1.1.1.1", Port: 61000, ContainerPort: 8080})
+
+		fakeTCPTable = &fakeroutingtable.FakeTCPRoutingTable{}
+		fakeTCPTable.GetRoutingEventsReturns(event.RoutingEvents{
+			{
+				EventType: event.RouteRegistrationEvent,
+				Entry: endpoint.RoutableEndpoints{
+					ExternalEndpoints: endpoint.ExternalEndpointInfos{endpoint.NewExternalEndpointInfo("router-group", 5222)},
+					Endpoints: map[endpoint.EndpointKey]endpoint.Endpoint{
+						endpoint.EndpointKey{InstanceGUID: "ig-2"}: {InstanceGUID: "ig-2", Host: "1.1.1.2", Port: 61001},
+					},
+				},
+			},
+		})
+		server.TCPTable(fakeTCPTable)
+
+		process = ifrit.Invoke(server)
+
+		var err error
+		conn, err = grpc.Dial(listenAddress, grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		stream, err = discoverygrpc.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+		conn.Close()
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("serves the routing tables to xDS clients", func() {
+		request(resource.ClusterType, nil)
+
+		response, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.TypeUrl).To(Equal(resource.ClusterType))
+		Expect(response.VersionInfo).To(Equal(server.Version()))
+		Expect(clusterNames(response)).To(Equal([]string{"http:a.example.com", "tcp:5222"}))
+	})
+
+	It("bumps the version and pushes the resources when a table changes", func() {
+		request(resource.ClusterType, nil)
+		first, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+
+		otherKey := endpoint.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+		natsTable.SetRoutes(otherKey, []routingtable.Route{{Hostname: "b.example.com"}}, nil)
+		natsTable.AddEndpoint(otherKey, routingtable.Endpoint{InstanceGuid: "ig-3", Host: "1.1.1.3", Port: 61002, ContainerPort: 8080})
+		Expect(server.Version()).NotTo(Equal(first.VersionInfo))
+
+		// the server may catch up with the table in between the mutations
+		response := first
+		Eventually(func() []string {
+			request(resource.ClusterType, response)
+			response, err = stream.Recv()
+			Expect(err).NotTo(HaveOccurred())
+			return clusterNames(response)
+		}).Should(Equal([]string{"http:a.example.com", "http:b.example.com", "tcp:5222"}))
+		Expect(response.VersionInfo).NotTo(Equal(first.VersionInfo))
+	})
+
+	It("answers a request for endpoints once it names every cluster, as in ADS mode", func() {
+		responses := make(chan *discoverygrpc.DiscoveryResponse, 1)
+		go func() {
+			defer GinkgoRecover()
+			response, err := stream.Recv()
+			if err == nil {
+				responses <- response
+			}
+		}()
+
+		Expect(stream.Send(&discoverygrpc.DiscoveryRequest{
+			Node:          &corev3.Node{Id: "envoy"},
+			TypeUrl:       resource.EndpointType,
+			ResourceNames: []string{"http:a.example.com"},
+		})).To(Succeed())
+		Consistently(responses).ShouldNot(Receive())
+
+		Expect(stream.Send(&discoverygrpc.DiscoveryRequest{
+			Node:          &corev3.Node{Id: "envoy"},
+			TypeUrl:       resource.EndpointType,
+			ResourceNames: []string{"http:a.example.com", "tcp:5222"},
+		})).To(Succeed())
+		var response *discoverygrpc.DiscoveryResponse
+		Eventually(responses).Should(Receive(&response))
+		Expect(response.TypeUrl).To(Equal(resource.EndpointType))
+		Expect(response.Resources).To(HaveLen(2))
+	})
+
+	It("bumps the version when a table is swapped", func() {
+		before := server.Version()
+		natsTable.Swap(routingtable.NewTempTable(nil, nil), nil)
+		Expect(server.Version()).NotTo(Equal(before))
+	})
+})
@@ -0,0 +1,13 @@
+package xds_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestXDS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "XDS Suite")
+}
@@ -0,0 +1,17 @@
+package xdsconfig
+
+// DefaultHTTPListenerPort is the port of the HTTP listener when none is
+// configured.
+const DefaultHTTPListenerPort = 8080
+
+// Config configures the xDS server. It is disabled without a listen address.
+// It lives apart from the server, so that the route-emitter configuration
+// does not pull in the Envoy and gRPC packages.
+type Config struct {
+	ListenAddress    string `json:"listen_address,omitempty"`
+	HTTPListenerPort uint32 `json:"http_listener_port,omitempty"`
+}
+
+func (c Config) Enabled() bool {
+	return c.ListenAddress != ""
+}
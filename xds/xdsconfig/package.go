@@ -0,0 +1 @@
+package xdsconfig // import "code.cloudfoundry.org/route-emitter/xds/xdsconfig"